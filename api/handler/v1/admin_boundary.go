@@ -0,0 +1,288 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/geo"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errAdminUnitMismatch = errors.New("does not match the location")
+
+// @Security ApiKeyAuth
+// @Router /v1/admin-boundary/{level}/{unit_id} [put]
+// @Summary Update administrative unit boundary
+// @Description API for setting the territory of a city, region or district. Entity locations are looked up in these boundaries.
+// @Tags admin-boundary
+// @Accept json
+// @Produce json
+// @Param level path string true "city, region or district"
+// @Param unit_id path string true "unit_id"
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
+// @Param boundary body models.GeoBoundary true "Polygon or MultiPolygon"
+// @Success 200 {object} models.AdminBoundary
+func (h *handlerV1) UpdateAdminBoundary(c *gin.Context) {
+	var (
+		level    = c.Param("level")
+		unitID   = c.Param("unit_id")
+		boundary models.GeoBoundary
+	)
+	staffInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminBoundary.Update.ParseUnitId", validateAdminUnit(level, unitID)) {
+		return
+	}
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminBoundary.Update.ParseCRS", err) {
+		return
+	}
+	if err := c.ShouldBindJSON(&boundary); HandleHTTPError(c, http.StatusBadRequest, "AdminBoundary.Update.BindingJson", err) {
+		return
+	}
+	boundary = *boundary.Transform(crs.ToWGS84)
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminBoundary.Update.Validate", boundary.ValidateLimit(geo.MaxAdminRingPositions)) {
+		return
+	}
+
+	soato, err := h.adminUnitSoato(context.Background(), level, unitID)
+	if handleStorageError(c, "AdminBoundary.Update.GetUnit", err) {
+		return
+	}
+	adminBoundary := &models.AdminBoundary{
+		Level:     level,
+		UnitID:    unitID,
+		Soato:     soato,
+		Boundary:  &boundary,
+		UpdatedAt: time.Now(),
+		UpdatedBy: staffInfo.ID,
+	}
+	err = h.storage.AdminBoundary().Upsert(context.Background(), adminBoundary)
+	if handleStorageError(c, "AdminBoundary.Update", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, adminBoundary)
+}
+
+// @Router /v1/admin-boundary/{level}/{unit_id} [get]
+// @Summary Get administrative unit boundary
+// @Tags admin-boundary
+// @Accept json
+// @Produce json
+// @Param level path string true "city, region or district"
+// @Param unit_id path string true "unit_id"
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
+// @Success 200 {object} models.AdminBoundary
+func (h *handlerV1) GetAdminBoundary(c *gin.Context) {
+	var (
+		level  = c.Param("level")
+		unitID = c.Param("unit_id")
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminBoundary.Get.ParseUnitId", validateAdminUnit(level, unitID)) {
+		return
+	}
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminBoundary.Get.ParseCRS", err) {
+		return
+	}
+	adminBoundary, err := h.storage.AdminBoundary().Get(context.Background(), level, unitID)
+	if handleStorageError(c, "AdminBoundary.Get", err) {
+		return
+	}
+	_, adminBoundary.Boundary = geometryToCRS(crs, nil, adminBoundary.Boundary)
+
+	c.JSON(http.StatusOK, adminBoundary)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/admin-boundary/{level}/{unit_id} [delete]
+// @Summary Delete administrative unit boundary
+// @Tags admin-boundary
+// @Accept json
+// @Produce json
+// @Param level path string true "city, region or district"
+// @Param unit_id path string true "unit_id"
+// @Success 204
+func (h *handlerV1) DeleteAdminBoundary(c *gin.Context) {
+	var (
+		level  = c.Param("level")
+		unitID = c.Param("unit_id")
+	)
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminBoundary.Delete.ParseUnitId", validateAdminUnit(level, unitID)) {
+		return
+	}
+	err := h.storage.AdminBoundary().Delete(context.Background(), level, unitID)
+	if handleStorageError(c, "AdminBoundary.Delete", err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Router /v1/admin-unit [get]
+// @Summary Look up administrative units
+// @Description API for finding the city, region and district a point is in, and the soato an entity there gets. Levels without a loaded boundary are empty.
+// @Tags admin-boundary
+// @Accept json
+// @Produce json
+// @Param longitude query number true "longitude"
+// @Param latitude query number true "latitude"
+// @Success 200 {object} models.AdminUnits
+func (h *handlerV1) LookupAdminUnits(c *gin.Context) {
+	longitude, err := strconv.ParseFloat(c.Query("longitude"), 64)
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminUnit.Lookup.ParseLongitude", err) {
+		return
+	}
+	latitude, err := strconv.ParseFloat(c.Query("latitude"), 64)
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminUnit.Lookup.ParseLatitude", err) {
+		return
+	}
+	point := models.NewGeoPoint(latitude, longitude)
+	if HandleHTTPError(c, http.StatusBadRequest, "AdminUnit.Lookup.Validate", point.Validate()) {
+		return
+	}
+
+	units, err := h.lookupAdminUnits(context.Background(), point)
+	if HandleHTTPError(c, http.StatusInternalServerError, "AdminUnit.Lookup", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, units)
+}
+
+func validateAdminUnit(level, unitID string) error {
+	switch level {
+	case models.AdminUnitCity, models.AdminUnitRegion, models.AdminUnitDistrict:
+	default:
+		return fmt.Errorf("unknown level %q", level)
+	}
+	_, err := primitive.ObjectIDFromHex(unitID)
+	return err
+}
+
+func (h *handlerV1) adminUnitSoato(ctx context.Context, level, unitID string) (uint32, error) {
+	switch level {
+	case models.AdminUnitCity:
+		city, err := h.storage.City().Get(ctx, unitID)
+		if err != nil {
+			return 0, err
+		}
+		return city.Soato, nil
+	case models.AdminUnitRegion:
+		region, err := h.storage.Region().Get(ctx, unitID)
+		if err != nil {
+			return 0, err
+		}
+		return region.Soato, nil
+	}
+	district, err := h.storage.District().Get(ctx, unitID)
+	if err != nil {
+		return 0, err
+	}
+	return district.Soato, nil
+}
+
+// lookupAdminUnits finds the active units whose boundaries contain the
+// point. Where two units of a level touch the first by id is taken, so a
+// point on the border always gets the same answer.
+func (h *handlerV1) lookupAdminUnits(ctx context.Context, point *models.GeoPoint) (*models.AdminUnits, error) {
+	boundaries, err := h.storage.AdminBoundary().Lookup(ctx, point)
+	if err != nil {
+		return nil, err
+	}
+
+	units := &models.AdminUnits{}
+	for _, boundary := range boundaries {
+		switch {
+		case boundary.Level == models.AdminUnitCity && units.City == nil:
+			var city *models.City
+			city, err = h.storage.City().Get(ctx, boundary.UnitID)
+			if err == nil && city.Active {
+				units.City = city
+			}
+		case boundary.Level == models.AdminUnitRegion && units.Region == nil:
+			var region *models.Region
+			region, err = h.storage.Region().Get(ctx, boundary.UnitID)
+			if err == nil && region.Active {
+				units.Region = region
+			}
+		case boundary.Level == models.AdminUnitDistrict && units.District == nil:
+			var district *models.District
+			district, err = h.storage.District().Get(ctx, boundary.UnitID)
+			if err == nil && district.Active {
+				units.District = district
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", boundary.Level, boundary.UnitID, err)
+		}
+	}
+	if units.District != nil {
+		units.EntitySoato = strconv.Itoa(int(units.District.Soato))
+	}
+	return units, nil
+}
+
+// dropAdminBoundary removes the boundary of an abolished unit. Lookups skip
+// abolished units anyway, so a failure is only logged.
+func (h *handlerV1) dropAdminBoundary(level, unitID string) {
+	err := h.storage.AdminBoundary().Delete(context.Background(), level, unitID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		h.log.Error("error while deleting boundary of abolished unit", logger.String("unit", models.AdminBoundaryID(level, unitID)), logger.Error(err))
+	}
+}
+
+// deriveAdminUnits replaces the units sent by the client with the ones the
+// location is in. A unit the client sent has to be the same by id and soato,
+// errAdminUnitMismatch tells which one is not. Levels without a boundary
+// around the location keep what the client sent.
+func (h *handlerV1) deriveAdminUnits(ctx context.Context, location *models.GeoPoint, sent *models.AdminUnits) (*models.AdminUnits, error) {
+	units, err := h.lookupAdminUnits(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	if units.City == nil {
+		units.City = sent.City
+	} else if sent.City != nil && !sameAdminUnit(sent.City.ID, sent.City.Soato, units.City.ID, units.City.Soato) {
+		return nil, fmt.Errorf("city %w", errAdminUnitMismatch)
+	}
+	if units.Region == nil {
+		units.Region = sent.Region
+	} else if sent.Region != nil && !sameAdminUnit(sent.Region.ID, sent.Region.Soato, units.Region.ID, units.Region.Soato) {
+		return nil, fmt.Errorf("region %w", errAdminUnitMismatch)
+	}
+	if units.District == nil {
+		units.District = sent.District
+	} else if sent.District != nil && !sameAdminUnit(sent.District.ID, sent.District.Soato, units.District.ID, units.District.Soato) {
+		return nil, fmt.Errorf("district %w", errAdminUnitMismatch)
+	}
+	return units, nil
+}
+
+// sameAdminUnit leaves out what the client did not send
+func sameAdminUnit(sentID string, sentSoato uint32, id string, soato uint32) bool {
+	return (sentID == "" || sentID == id) && (sentSoato == 0 || sentSoato == soato)
+}
+
+// handleAdminUnitsError answers a mismatch with 409
+func handleAdminUnitsError(c *gin.Context, message string, err error) bool {
+	if errors.Is(err, errAdminUnitMismatch) {
+		return HandleHTTPError(c, http.StatusConflict, message, err)
+	}
+	return HandleHTTPError(c, http.StatusInternalServerError, message, err)
+}
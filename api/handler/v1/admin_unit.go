@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/e-space-uz/backend/pkg/soato"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errParentAbolished  = errors.New("parent unit is abolished")
+	errRegionNotInCity  = errors.New("region does not belong to the city")
+	errActiveChildUnits = errors.New("unit has active units under it, abolish them first")
+	errChildUnitCodes   = errors.New("units under the unit have codes that do not start with the new soato, change them first")
+)
+
+// checkChildCodes refuses a new code for a unit while the units under it
+// have codes of the old one. count counts them outside the codes the new one
+// gives.
+func checkChildCodes(c *gin.Context, message, level string, current, code uint32, count func(from, to uint64) (int64, error)) bool {
+	if code == current || code == 0 {
+		return true
+	}
+	from, to := soato.ChildCodes(level, code)
+	misplaced, err := count(from, to)
+	if HandleHTTPError(c, http.StatusInternalServerError, message+".CountChildCodes", err) {
+		return false
+	}
+	if misplaced > 0 {
+		HandleHTTPError(c, http.StatusConflict, message+".CountChildCodes", errChildUnitCodes)
+		return false
+	}
+	return true
+}
+
+// unitActive is what the update leaves the flag at, it is kept when the body
+// does not have it
+func unitActive(current bool, sent *bool) bool {
+	if sent == nil {
+		return current
+	}
+	return *sent
+}
+
+func parseUnitID(c *gin.Context, param, message string) (primitive.ObjectID, bool) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param(param))
+	if HandleHTTPError(c, 400, message, err) {
+		return objectID, false
+	}
+	return objectID, true
+}
@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/geo"
+	"github.com/e-space-uz/backend/pkg/geoimport"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// preview sizes of the candidates and of the chosen boundary
+const (
+	candidatePreviewSize = 128
+	boundaryPreviewSize  = 256
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/boundary-import [post]
+// @Summary Import entity boundary
+// @Description API for setting the boundary of the entity from a DXF, Shapefile (.shp or .zip with the .dbf), KML or KMZ file. When the file has several polygons they are returned as candidates with previews and nothing is attached, the upload is repeated with the index of one in feature. The attached boundary is checked like on the location API.
+// @Tags entity
+// @Accept multipart/form-data
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param file formData file true "file"
+// @Param crs query string false "coordinate reference system of the file, EPSG:4326 by default and always for KML"
+// @Param feature query integer false "index of the polygon to attach"
+// @Success 200 {object} models.BoundaryImportResponse
+func (h *handlerV1) ImportEntityBoundary(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.ImportBoundary.ParseEntityId", err) {
+		return
+	}
+	entity, err := h.storage.Entity().Get(context.Background(), entityID.Hex())
+	if handleStorageError(c, "Entity.ImportBoundary.GetEntity", err) {
+		return
+	}
+	response, ok := h.importBoundary(c, "Entity.ImportBoundary")
+	if !ok {
+		return
+	}
+
+	if response.Boundary != nil {
+		response.Topology, err = h.entityTopology(context.Background(), response.Boundary, entity)
+		if handleTopologyError(c, "Entity.ImportBoundary.CheckTopology", err) {
+			return
+		}
+		err = h.storage.Entity().UpdateBoundary(context.Background(), entityID.Hex(), response.Boundary, response.Topology)
+		if handleStorageError(c, "Entity.ImportBoundary.UpdateBoundary", err) {
+			return
+		}
+		response.Attached = true
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/boundary-import [post]
+// @Summary Import entity draft boundary
+// @Description API for setting the boundary of the draft from a DXF, Shapefile (.shp or .zip with the .dbf), KML or KMZ file. When the file has several polygons they are returned as candidates with previews and nothing is attached, the upload is repeated with the index of one in feature. The attached boundary is checked like on the location API.
+// @Tags entity-draft
+// @Accept multipart/form-data
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param file formData file true "file"
+// @Param crs query string false "coordinate reference system of the file, EPSG:4326 by default and always for KML"
+// @Param feature query integer false "index of the polygon to attach"
+// @Success 200 {object} models.BoundaryImportResponse
+func (h *handlerV1) ImportEntityDraftBoundary(c *gin.Context) {
+	if _, err := h.UserInfo(c, true); err != nil {
+		return
+	}
+	entityDraftID, err := primitive.ObjectIDFromHex(c.Param("entity_draft_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.ImportBoundary.ParseEntityDraftId", err) {
+		return
+	}
+	draft, err := h.storage.EntityDraft().Get(context.Background(), entityDraftID.Hex())
+	if handleStorageError(c, "EntityDraft.ImportBoundary.GetEntityDraft", err) {
+		return
+	}
+	if draft.Status != models.EntityDraftStatusInProgress {
+		HandleHTTPError(c, http.StatusConflict, "EntityDraft.ImportBoundary", errEntityDraftNotInProgress)
+		return
+	}
+	response, ok := h.importBoundary(c, "EntityDraft.ImportBoundary")
+	if !ok {
+		return
+	}
+
+	if response.Boundary != nil {
+		response.Topology, err = h.draftTopology(context.Background(), response.Boundary, draft)
+		if handleTopologyError(c, "EntityDraft.ImportBoundary.CheckTopology", err) {
+			return
+		}
+		err = h.storage.EntityDraft().UpdateBoundary(context.Background(), entityDraftID.Hex(), response.Boundary, response.Topology)
+		if handleStorageError(c, "EntityDraft.ImportBoundary.UpdateBoundary", err) {
+			return
+		}
+		response.Attached = true
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// importBoundary reads the uploaded file and converts its polygons to WGS84.
+// The crs parameter is left out for KML, which is WGS84 anyway. The boundary
+// of the response is set when one is chosen, by the feature parameter or by
+// being the only one.
+func (h *handlerV1) importBoundary(c *gin.Context, message string) (*models.BoundaryImportResponse, bool) {
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, message+".ParseCRS", err) {
+		return nil, false
+	}
+	wgs84, err := h.crs.Get(geo.WGS84)
+	if HandleHTTPError(c, http.StatusInternalServerError, message+".GetWGS84", err) {
+		return nil, false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.BoundaryImportMaxSize)
+	file, err := c.FormFile("file")
+	if HandleHTTPError(c, http.StatusBadRequest, message+".FormFile", err) {
+		return nil, false
+	}
+	uploaded, err := file.Open()
+	if HandleHTTPError(c, http.StatusBadRequest, message+".Open", err) {
+		return nil, false
+	}
+	defer uploaded.Close()
+	data, err := ioutil.ReadAll(uploaded)
+	if HandleHTTPError(c, http.StatusBadRequest, message+".Read", err) {
+		return nil, false
+	}
+
+	features, err := geoimport.Parse(file.Filename, data, h.cfg.BoundaryImportMaxSize)
+	if HandleHTTPError(c, http.StatusBadRequest, message+".Parse", err) {
+		return nil, false
+	}
+	selected := -1
+	if feature := c.Query("feature"); feature != "" {
+		selected, err = strconv.Atoi(feature)
+		if err == nil && (selected < 0 || selected >= len(features)) {
+			err = fmt.Errorf("feature must be between 0 and %d", len(features)-1)
+		}
+		if HandleHTTPError(c, http.StatusBadRequest, message+".ParseFeature", err) {
+			return nil, false
+		}
+	} else if len(features) == 1 {
+		selected = 0
+	}
+
+	var (
+		response = &models.BoundaryImportResponse{
+			Candidates: make([]*models.BoundaryCandidate, 0, len(features)),
+		}
+		boundaries = make([]*models.GeoBoundary, 0, len(features))
+	)
+	for i, feature := range features {
+		featureCRS := crs
+		if feature.WGS84 {
+			featureCRS = wgs84
+		}
+		boundary := models.NewGeoBoundary(feature.Polygons).Transform(featureCRS.ToWGS84)
+		candidate := &models.BoundaryCandidate{
+			Index: i,
+			Name:  feature.Name,
+			Type:  boundary.Type,
+		}
+		if err := boundary.Validate(); err != nil {
+			candidate.Error = err.Error()
+		} else {
+			bounds := geo.Bounds(boundary.Polygons)
+			candidate.BBox = bounds[:]
+			candidate.Preview = geo.SVG(boundary.Polygons, candidatePreviewSize)
+		}
+		response.Candidates = append(response.Candidates, candidate)
+		boundaries = append(boundaries, boundary)
+	}
+
+	if selected >= 0 {
+		if candidate := response.Candidates[selected]; candidate.Error != "" {
+			HandleHTTPError(c, http.StatusBadRequest, message+".Validate", errors.New(candidate.Error))
+			return nil, false
+		}
+		response.Selected = &selected
+		response.Boundary = boundaries[selected]
+		response.Preview = geo.SVG(response.Boundary.Polygons, boundaryPreviewSize)
+	}
+	return response, true
+}
@@ -3,7 +3,10 @@ package v1
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/soato"
 	"github.com/gin-gonic/gin"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -68,3 +71,158 @@ func (h *handlerV1) GetAllCities(c *gin.Context) {
 
 	c.JSON(http.StatusOK, cities)
 }
+
+// @Security ApiKeyAuth
+// @Router /v1/city [post]
+// @Summary Create City
+// @Description API for adding a city, the soato is unique among active cities
+// @Tags city
+// @Accept json
+// @Produce json
+// @Param city body models.CitySwag true "city"
+// @Success 201 {object} models.City
+func (h *handlerV1) CreateCity(c *gin.Context) {
+	var body models.CitySwag
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&body); HandleHTTPError(c, http.StatusBadRequest, "SettingService.CreateCity.BindingJson", err) {
+		return
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "SettingService.CreateCity.ValidateSoato", soato.ValidateCode(models.AdminUnitCity, 0, body.Soato)) {
+		return
+	}
+	cityID, err := h.storage.City().Create(context.Background(), &models.CreateUpdateCity{
+		ID:        primitive.NewObjectID(),
+		Name:      body.Name,
+		RuName:    body.RuName,
+		Soato:     body.Soato,
+		Code:      body.Code,
+		Active:    unitActive(true, body.Active),
+		UpdatedAt: time.Now(),
+	})
+	if handleStorageError(c, "SettingService.CreateCity.Create", err) {
+		return
+	}
+	city, err := h.storage.City().Get(context.Background(), cityID)
+	if handleStorageError(c, "SettingService.CreateCity.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, city)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/city/{city_id} [put]
+// @Summary Update City
+// @Description API for renaming, abolishing or restoring a city. A city with active regions can not be abolished, its soato can not change while regions have codes of the old one.
+// @Tags city
+// @Accept json
+// @Produce json
+// @Param city_id path string true "city_id"
+// @Param city body models.CitySwag true "city"
+// @Success 200 {object} models.City
+func (h *handlerV1) UpdateCity(c *gin.Context) {
+	var body models.CitySwag
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	cityID, ok := parseUnitID(c, "city_id", "SettingService.UpdateCity.ParseCityID")
+	if !ok {
+		return
+	}
+	if err := c.ShouldBindJSON(&body); HandleHTTPError(c, http.StatusBadRequest, "SettingService.UpdateCity.BindingJson", err) {
+		return
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "SettingService.UpdateCity.ValidateSoato", soato.ValidateCode(models.AdminUnitCity, 0, body.Soato)) {
+		return
+	}
+	city, err := h.storage.City().Get(context.Background(), cityID.Hex())
+	if handleStorageError(c, "SettingService.UpdateCity.Get", err) {
+		return
+	}
+	active := unitActive(city.Active, body.Active)
+	if city.Active && !active && !h.checkCityAbolishable(c, "SettingService.UpdateCity", cityID.Hex()) {
+		return
+	}
+	if !checkChildCodes(c, "SettingService.UpdateCity", models.AdminUnitCity, city.Soato, body.Soato, func(from, to uint64) (int64, error) {
+		return h.storage.Region().CountCodedOutside(context.Background(), cityID.Hex(), from, to)
+	}) {
+		return
+	}
+	err = h.storage.City().Update(context.Background(), &models.CreateUpdateCity{
+		ID:        cityID,
+		Name:      body.Name,
+		RuName:    body.RuName,
+		Soato:     body.Soato,
+		Code:      body.Code,
+		Active:    active,
+		UpdatedAt: time.Now(),
+	})
+	if handleStorageError(c, "SettingService.UpdateCity.Update", err) {
+		return
+	}
+	if city.Active && !active {
+		h.dropAdminBoundary(models.AdminUnitCity, cityID.Hex())
+	}
+	city, err = h.storage.City().Get(context.Background(), cityID.Hex())
+	if handleStorageError(c, "SettingService.UpdateCity.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, city)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/city/{city_id} [delete]
+// @Summary Abolish City
+// @Description API for abolishing a city. It is kept with active false, entities registered in it still point to it.
+// @Tags city
+// @Accept json
+// @Produce json
+// @Param city_id path string true "city_id"
+// @Success 204
+func (h *handlerV1) DeleteCity(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	cityID, ok := parseUnitID(c, "city_id", "SettingService.DeleteCity.ParseCityID")
+	if !ok {
+		return
+	}
+	city, err := h.storage.City().Get(context.Background(), cityID.Hex())
+	if handleStorageError(c, "SettingService.DeleteCity.Get", err) {
+		return
+	}
+	if city.Active {
+		if !h.checkCityAbolishable(c, "SettingService.DeleteCity", cityID.Hex()) {
+			return
+		}
+		err = h.storage.City().Update(context.Background(), &models.CreateUpdateCity{
+			ID:        cityID,
+			Name:      city.Name,
+			RuName:    city.RuName,
+			Soato:     city.Soato,
+			Code:      city.Code,
+			UpdatedAt: time.Now(),
+		})
+		if handleStorageError(c, "SettingService.DeleteCity.Update", err) {
+			return
+		}
+		h.dropAdminBoundary(models.AdminUnitCity, cityID.Hex())
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handlerV1) checkCityAbolishable(c *gin.Context, message, cityID string) bool {
+	count, err := h.storage.Region().CountActiveByCity(context.Background(), cityID)
+	if HandleHTTPError(c, http.StatusInternalServerError, message+".CountRegions", err) {
+		return false
+	}
+	if count > 0 {
+		HandleHTTPError(c, http.StatusConflict, message+".CountRegions", errActiveChildUnits)
+		return false
+	}
+	return true
+}
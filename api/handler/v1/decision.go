@@ -0,0 +1,500 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/cms"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// decisionTimeFormat keeps milliseconds, which is what MongoDB stores
+const decisionTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// decisionSignatureWindow is how long a payload fetched for E-IMZO may be
+// signed and sent
+const decisionSignatureWindow = 15 * time.Minute
+
+var (
+	errDecisionStatus        = errors.New("draft can only be approved or rejected")
+	errDecisionUnknownStatus = errors.New("status is not one entities can have")
+	errDraftNotSubmitted     = errors.New("entity draft has not been submitted")
+	errDecisionUntrusted     = errors.New("decision signature is not valid")
+	errDecisionTimestamp     = fmt.Errorf("timestamp must be within %s of now", decisionSignatureWindow)
+	errDecisionPayloadCopy   = errors.New("signed content is not the decision payload")
+	errDecisionSigner        = errors.New("decision is not signed by the staff member making it")
+	errStaffNoPinfl          = errors.New("staff member has no pinfl to match the signature with")
+)
+
+// decisionSubject is the entity or the draft a decision is about, with what
+// the decision is checked against
+type decisionSubject struct {
+	models.GetDecisionsRequest
+	version uint64
+	status  string
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/decision [post]
+// @Summary Decide on entity status
+// @Description API for changing the status of an entity. The decision is signed with the server key, or with E-IMZO when timestamp and signature of the payload from /decision-payload are sent, and added to the audit trail of the entity. An E-IMZO certificate has to carry the pinfl of the staff member.
+// @Tags decision
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param decision body models.DecisionSwag true "decision"
+// @Success 201 {object} models.Decision
+func (h *handlerV1) CreateEntityDecision(c *gin.Context) {
+	subject, ok := h.entityDecisionSubject(c)
+	if !ok {
+		return
+	}
+
+	h.createDecision(c, subject)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/decision-payload [get]
+// @Summary Get entity decision payload
+// @Description API for getting the payload a staff member signs with E-IMZO for /decision. The canonical text is signed as it is, the timestamp is sent with the decision.
+// @Tags decision
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param status query string true "status"
+// @Param comment query string false "comment"
+// @Success 200 {object} models.DecisionPayloadResponse
+func (h *handlerV1) GetEntityDecisionPayload(c *gin.Context) {
+	subject, ok := h.entityDecisionSubject(c)
+	if !ok {
+		return
+	}
+
+	h.getDecisionPayload(c, subject)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/decision [get]
+// @Summary Verify entity decisions
+// @Description API for auditors, it returns the decisions on an entity and checks their hashes, their signatures, that they form one chain and that the last one is the status the entity has
+// @Tags decision
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Success 200 {object} models.DecisionChainResponse
+func (h *handlerV1) GetEntityDecisions(c *gin.Context) {
+	subject, ok := h.entityDecisionSubject(c)
+	if !ok {
+		return
+	}
+
+	h.getDecisions(c, subject)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/decision [post]
+// @Summary Decide on entity draft
+// @Description API for approving or rejecting a submitted draft. The decision is signed like the ones on entities.
+// @Tags decision
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param decision body models.DecisionSwag true "decision"
+// @Success 201 {object} models.Decision
+func (h *handlerV1) CreateEntityDraftDecision(c *gin.Context) {
+	subject, ok := h.entityDraftDecisionSubject(c)
+	if !ok {
+		return
+	}
+
+	h.createDecision(c, subject)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/decision-payload [get]
+// @Summary Get entity draft decision payload
+// @Tags decision
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param status query string true "approved or rejected"
+// @Param comment query string false "comment"
+// @Success 200 {object} models.DecisionPayloadResponse
+func (h *handlerV1) GetEntityDraftDecisionPayload(c *gin.Context) {
+	subject, ok := h.entityDraftDecisionSubject(c)
+	if !ok {
+		return
+	}
+
+	h.getDecisionPayload(c, subject)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/decision [get]
+// @Summary Verify entity draft decisions
+// @Tags decision
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Success 200 {object} models.DecisionChainResponse
+func (h *handlerV1) GetEntityDraftDecisions(c *gin.Context) {
+	subject, ok := h.entityDraftDecisionSubject(c)
+	if !ok {
+		return
+	}
+
+	h.getDecisions(c, subject)
+}
+
+// @Router /v1/decision-key [get]
+// @Summary Get decision key
+// @Description Public API for the key decisions are signed with and the keys they were signed with before, so they can be verified without the service
+// @Tags decision
+// @Produce json
+// @Success 200 {object} models.DecisionKeyResponse
+func (h *handlerV1) GetDecisionKey(c *gin.Context) {
+	response := models.DecisionKeyResponse{
+		DecisionKey: models.DecisionKey{
+			KeyID:     h.decisionSigner.KeyID(),
+			Algorithm: h.decisionSigner.Algorithm(),
+			PublicKey: string(h.decisionSigner.PublicKeyPEM()),
+		},
+		Previous: []models.DecisionKey{},
+	}
+	for _, key := range h.decisionKeys.Previous() {
+		response.Previous = append(response.Previous, models.DecisionKey{
+			KeyID:     key.KeyID,
+			Algorithm: key.Algorithm,
+			PublicKey: string(key.PEM),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handlerV1) entityDecisionSubject(c *gin.Context) (*decisionSubject, bool) {
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Decision.ParseEntityId", err) {
+		return nil, false
+	}
+	entity, err := h.storage.Entity().Get(context.Background(), entityID.Hex())
+	if handleStorageError(c, "Decision.GetEntity", err) {
+		return nil, false
+	}
+
+	subject := &decisionSubject{version: entity.Version, status: entity.Status}
+	subject.EntityID = entityID.Hex()
+	return subject, true
+}
+
+func (h *handlerV1) entityDraftDecisionSubject(c *gin.Context) (*decisionSubject, bool) {
+	entityDraftID, err := primitive.ObjectIDFromHex(c.Param("entity_draft_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Decision.ParseEntityDraftId", err) {
+		return nil, false
+	}
+	draft, err := h.storage.EntityDraft().Get(context.Background(), entityDraftID.Hex())
+	if handleStorageError(c, "Decision.GetEntityDraft", err) {
+		return nil, false
+	}
+
+	subject := &decisionSubject{version: draft.Version, status: draft.Status}
+	subject.EntityDraftID = entityDraftID.Hex()
+	return subject, true
+}
+
+func (h *handlerV1) createDecision(c *gin.Context, subject *decisionSubject) {
+	var (
+		body models.DecisionSwag
+	)
+	userInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&body); HandleHTTPError(c, http.StatusBadRequest, "Decision.Create.BindingJson", err) {
+		return
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "Decision.Create.Status", h.checkDecisionStatus(subject, body.Status)) {
+		return
+	}
+
+	decision, err := h.nextDecision(subject, userInfo, body.Status, body.Comment)
+	if HandleHTTPError(c, http.StatusBadRequest, "Decision.Create.GetDecisions", err) {
+		return
+	}
+
+	if body.Signature == "" {
+		payload, err := canonicalDecision(decision)
+		if HandleHTTPError(c, http.StatusInternalServerError, "Decision.Create.Payload", err) {
+			return
+		}
+		decision.SignatureType = models.DecisionSignatureServer
+		decision.KeyID = h.decisionSigner.KeyID()
+		decision.Signature, err = h.decisionSigner.Sign(payload)
+		if HandleHTTPError(c, http.StatusInternalServerError, "Decision.Create.Sign", err) {
+			return
+		}
+	} else {
+		timestamp, err := time.Parse(time.RFC3339Nano, body.Timestamp)
+		if HandleHTTPError(c, http.StatusBadRequest, "Decision.Create.Timestamp", err) {
+			return
+		}
+		if age := time.Since(timestamp); age > decisionSignatureWindow || age < -time.Minute {
+			HandleHTTPError(c, http.StatusBadRequest, "Decision.Create.Timestamp", errDecisionTimestamp)
+			return
+		}
+		decision.Timestamp = timestamp.UTC().Truncate(time.Millisecond)
+		decision.SignatureType = models.DecisionSignatureCMS
+		decision.Signature, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body.Signature), ""))
+		if HandleHTTPError(c, http.StatusBadRequest, "Decision.Create.DecodeSignature", err) {
+			return
+		}
+		signature, err := h.verifyDecisionCMS(decision)
+		if HandleHTTPError(c, http.StatusBadRequest, "Decision.Create.VerifySignature", err) {
+			return
+		}
+		staff, err := h.storage.Staff().Get(context.Background(), userInfo.ID)
+		if handleStorageError(c, "Decision.Create.GetStaff", err) {
+			return
+		}
+		if HandleHTTPError(c, http.StatusForbidden, "Decision.Create.Signer", decisionSigner(staff, signature)) {
+			return
+		}
+		decision.Signers = signature.Signers
+	}
+	decision.Hash, err = decisionHash(decision)
+	if HandleHTTPError(c, http.StatusInternalServerError, "Decision.Create.Hash", err) {
+		return
+	}
+
+	// the decision takes its place in the chain together with the status
+	// change, a subject changed since it was read rolls both back
+	err = h.storage.Decision().Create(context.Background(), decision, func(ctx context.Context) error {
+		if subject.EntityID != "" {
+			return h.storage.Entity().UpdateStatus(ctx, subject.EntityID, subject.version, decision.Status)
+		}
+		return h.storage.EntityDraft().SetStatus(ctx, subject.EntityDraftID, subject.version, subject.status, decision.Status)
+	})
+	if handleStorageError(c, "Decision.Create", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, decision)
+}
+
+func (h *handlerV1) getDecisionPayload(c *gin.Context, subject *decisionSubject) {
+	userInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	status := c.Query("status")
+	if HandleHTTPError(c, http.StatusBadRequest, "Decision.GetPayload.Status", h.checkDecisionStatus(subject, status)) {
+		return
+	}
+
+	decision, err := h.nextDecision(subject, userInfo, status, c.Query("comment"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Decision.GetPayload.GetDecisions", err) {
+		return
+	}
+	payload, err := canonicalDecision(decision)
+	if HandleHTTPError(c, http.StatusInternalServerError, "Decision.GetPayload", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DecisionPayloadResponse{
+		Payload:   decisionPayload(decision),
+		Canonical: string(payload),
+	})
+}
+
+func (h *handlerV1) getDecisions(c *gin.Context, subject *decisionSubject) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	decisions, err := h.storage.Decision().GetAll(context.Background(), &subject.GetDecisionsRequest)
+	if HandleHTTPError(c, http.StatusBadRequest, "Decision.GetAll", err) {
+		return
+	}
+
+	response := models.DecisionChainResponse{
+		Valid:     true,
+		Decisions: make([]*models.DecisionVerification, 0, len(decisions)),
+		Count:     len(decisions),
+	}
+	previous := ""
+	for i, decision := range decisions {
+		verification := &models.DecisionVerification{Decision: decision, Valid: true}
+		if err := h.verifyDecision(subject, decision, uint64(i+1), previous); err != nil {
+			verification.Valid = false
+			verification.Error = err.Error()
+			if response.Valid {
+				response.Valid = false
+				response.Error = fmt.Sprintf("decision %d: %s", i+1, err)
+			}
+		}
+		previous = decision.Hash
+		response.Decisions = append(response.Decisions, verification)
+	}
+	if n := len(decisions); n > 0 && response.Valid && decisions[n-1].Status != subject.status {
+		response.Valid = false
+		response.Error = fmt.Sprintf("status %q is not the one of the last decision", subject.status)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// nextDecision is the decision that would follow the chain of the subject
+func (h *handlerV1) nextDecision(subject *decisionSubject, userInfo *models.LoginInfo, status, comment string) (*models.Decision, error) {
+	decisions, err := h.storage.Decision().GetAll(context.Background(), &subject.GetDecisionsRequest)
+	if err != nil {
+		return nil, err
+	}
+	decision := &models.Decision{
+		EntityID:      subject.EntityID,
+		EntityDraftID: subject.EntityDraftID,
+		Sequence:      uint64(len(decisions)) + 1,
+		Version:       subject.version,
+		Status:        status,
+		Comment:       comment,
+		Actor:         userInfo.ID,
+		ActorLogin:    userInfo.Login,
+		Timestamp:     time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if len(decisions) > 0 {
+		decision.Previous = decisions[len(decisions)-1].Hash
+	}
+	decision.ID = fmt.Sprintf("%s%s-%d", subject.EntityID, subject.EntityDraftID, decision.Sequence)
+	return decision, nil
+}
+
+// verifyDecision checks one decision of a chain, the stored fields have to
+// give the payload that was signed
+func (h *handlerV1) verifyDecision(subject *decisionSubject, decision *models.Decision, sequence uint64, previous string) error {
+	if decision.Sequence != sequence {
+		return fmt.Errorf("sequence is %d, %d expected", decision.Sequence, sequence)
+	}
+	if decision.Previous != previous {
+		return errors.New("previous hash does not match the decision before")
+	}
+	if decision.EntityID != subject.EntityID || decision.EntityDraftID != subject.EntityDraftID {
+		return errors.New("decision is about another subject")
+	}
+	hash, err := decisionHash(decision)
+	if err != nil {
+		return err
+	}
+	if hash != decision.Hash {
+		return errors.New("hash does not match the decision")
+	}
+
+	switch decision.SignatureType {
+	case models.DecisionSignatureServer:
+		payload, err := canonicalDecision(decision)
+		if err != nil {
+			return err
+		}
+		return h.decisionKeys.Verify(decision.KeyID, payload, decision.Signature)
+	case models.DecisionSignatureCMS:
+		_, err := h.verifyDecisionCMS(decision)
+		return err
+	}
+	return fmt.Errorf("unknown signature type %q", decision.SignatureType)
+}
+
+// verifyDecisionCMS accepts detached signatures of the payload and attached
+// ones that carry it. Certificates are checked at the timestamp of the
+// decision, which was within decisionSignatureWindow of when it was made, so
+// a certificate expiring or revoked later does not break the chain.
+func (h *handlerV1) verifyDecisionCMS(decision *models.Decision) (*models.FileSignature, error) {
+	payload, err := canonicalDecision(decision)
+	if err != nil {
+		return nil, err
+	}
+	signedData, err := cms.Parse(decision.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if !signedData.Detached() && !bytes.Equal(signedData.Content, payload) {
+		return nil, errDecisionPayloadCopy
+	}
+	signature, err := h.verifySignature(signedData, bytes.NewReader(payload), decision.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if signature.Status != models.SignatureStatusValid {
+		return nil, fmt.Errorf("%w: %s", errDecisionUntrusted, signature.Status)
+	}
+	return signature, nil
+}
+
+// decisionSigner ties an E-IMZO signature to the staff member deciding,
+// every signer has to carry the pinfl of their staff record
+func decisionSigner(staff *models.Applicant, signature *models.FileSignature) error {
+	if staff.Pin == "" {
+		return errStaffNoPinfl
+	}
+	for _, signer := range signature.Signers {
+		if signer.Pinfl != staff.Pin {
+			return errDecisionSigner
+		}
+	}
+	return nil
+}
+
+func (h *handlerV1) checkDecisionStatus(subject *decisionSubject, status string) error {
+	if strings.TrimSpace(status) == "" {
+		return errors.New("status is required")
+	}
+	if subject.EntityDraftID == "" {
+		for _, known := range h.cfg.EntityStatuses {
+			if status == known {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s", errDecisionUnknownStatus, status)
+	}
+	if status != models.EntityDraftStatusApproved && status != models.EntityDraftStatusRejected {
+		return errDecisionStatus
+	}
+	if subject.status != models.EntityDraftStatusNew {
+		return errDraftNotSubmitted
+	}
+	return nil
+}
+
+func decisionPayload(decision *models.Decision) models.DecisionPayload {
+	return models.DecisionPayload{
+		EntityID:      decision.EntityID,
+		EntityDraftID: decision.EntityDraftID,
+		Sequence:      decision.Sequence,
+		Version:       decision.Version,
+		Status:        decision.Status,
+		Comment:       decision.Comment,
+		Actor:         decision.Actor,
+		Timestamp:     decision.Timestamp.UTC().Format(decisionTimeFormat),
+		Previous:      decision.Previous,
+	}
+}
+
+func canonicalDecision(decision *models.Decision) ([]byte, error) {
+	return json.Marshal(decisionPayload(decision))
+}
+
+// decisionHash covers the signature too, so the next decision vouches for
+// the signature of this one
+func decisionHash(decision *models.Decision) (string, error) {
+	payload, err := canonicalDecision(decision)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	hash.Write(payload)
+	hash.Write(decision.Signature)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
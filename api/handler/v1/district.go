@@ -3,7 +3,10 @@ package v1
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/soato"
 	"github.com/gin-gonic/gin"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -111,3 +114,180 @@ func (h *handlerV1) GetAllDistrictsByRegionID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// @Security ApiKeyAuth
+// @Router /v1/district [post]
+// @Summary Create district
+// @Description API for adding a district to an active region of the city, its soato starts with the soato of the region
+// @Tags district
+// @Accept json
+// @Produce json
+// @Param district body models.DistrictSwag true "district"
+// @Success 201 {object} models.District
+func (h *handlerV1) CreateDistrict(c *gin.Context) {
+	var body models.DistrictSwag
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&body); HandleHTTPError(c, http.StatusBadRequest, "SettingService.CreateDistrict.BindingJson", err) {
+		return
+	}
+	district := &models.CreateUpdateDistrict{
+		ID:         primitive.NewObjectID(),
+		Name:       body.Name,
+		RuName:     body.RuName,
+		Code:       body.Code,
+		ExternalID: body.ExternalID,
+		Soato:      body.Soato,
+		Active:     unitActive(true, body.Active),
+		UpdatedAt:  time.Now(),
+	}
+	if !h.setDistrictRegion(c, "SettingService.CreateDistrict", district, body.CityID, body.RegionID) {
+		return
+	}
+	districtID, err := h.storage.District().Create(context.Background(), district)
+	if handleStorageError(c, "SettingService.CreateDistrict.Create", err) {
+		return
+	}
+	response, err := h.storage.District().Get(context.Background(), districtID)
+	if handleStorageError(c, "SettingService.CreateDistrict.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/district/{district_id} [put]
+// @Summary Update district
+// @Description API for renaming, moving, abolishing or restoring a district
+// @Tags district
+// @Accept json
+// @Produce json
+// @Param district_id path string true "district_id"
+// @Param district body models.DistrictSwag true "district"
+// @Success 200 {object} models.District
+func (h *handlerV1) UpdateDistrict(c *gin.Context) {
+	var body models.DistrictSwag
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	districtID, ok := parseUnitID(c, "district_id", "SettingService.UpdateDistrict.ParseDistrictID")
+	if !ok {
+		return
+	}
+	if err := c.ShouldBindJSON(&body); HandleHTTPError(c, http.StatusBadRequest, "SettingService.UpdateDistrict.BindingJson", err) {
+		return
+	}
+	current, err := h.storage.District().Get(context.Background(), districtID.Hex())
+	if handleStorageError(c, "SettingService.UpdateDistrict.Get", err) {
+		return
+	}
+	district := &models.CreateUpdateDistrict{
+		ID:         districtID,
+		Name:       body.Name,
+		RuName:     body.RuName,
+		Code:       body.Code,
+		ExternalID: body.ExternalID,
+		Soato:      body.Soato,
+		Active:     unitActive(current.Active, body.Active),
+		UpdatedAt:  time.Now(),
+	}
+	if !h.setDistrictRegion(c, "SettingService.UpdateDistrict", district, body.CityID, body.RegionID) {
+		return
+	}
+	err = h.storage.District().Update(context.Background(), district)
+	if handleStorageError(c, "SettingService.UpdateDistrict.Update", err) {
+		return
+	}
+	if current.Active && !district.Active {
+		h.dropAdminBoundary(models.AdminUnitDistrict, districtID.Hex())
+	}
+	response, err := h.storage.District().Get(context.Background(), districtID.Hex())
+	if handleStorageError(c, "SettingService.UpdateDistrict.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/district/{district_id} [delete]
+// @Summary Abolish district
+// @Description API for abolishing a district. It is kept with active false, entities registered in it still point to it.
+// @Tags district
+// @Accept json
+// @Produce json
+// @Param district_id path string true "district_id"
+// @Success 204
+func (h *handlerV1) DeleteDistrict(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	districtID, ok := parseUnitID(c, "district_id", "SettingService.DeleteDistrict.ParseDistrictID")
+	if !ok {
+		return
+	}
+	district, err := h.storage.District().Get(context.Background(), districtID.Hex())
+	if handleStorageError(c, "SettingService.DeleteDistrict.Get", err) {
+		return
+	}
+	if district.Active {
+		cityID, err := primitive.ObjectIDFromHex(district.City.ID)
+		if HandleHTTPError(c, http.StatusInternalServerError, "SettingService.DeleteDistrict.ParseCityID", err) {
+			return
+		}
+		regionID, err := primitive.ObjectIDFromHex(district.Region.ID)
+		if HandleHTTPError(c, http.StatusInternalServerError, "SettingService.DeleteDistrict.ParseRegionID", err) {
+			return
+		}
+		err = h.storage.District().Update(context.Background(), &models.CreateUpdateDistrict{
+			ID:         districtID,
+			CityID:     cityID,
+			RegionID:   regionID,
+			Name:       district.Name,
+			RuName:     district.RuName,
+			Code:       district.Code,
+			ExternalID: district.ExternalID,
+			Soato:      district.Soato,
+			UpdatedAt:  time.Now(),
+		})
+		if handleStorageError(c, "SettingService.DeleteDistrict.Update", err) {
+			return
+		}
+		h.dropAdminBoundary(models.AdminUnitDistrict, districtID.Hex())
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setDistrictRegion checks that the region exists in the city, is active
+// when the district is, and that the soato of the district continues its
+// soato
+func (h *handlerV1) setDistrictRegion(c *gin.Context, message string, district *models.CreateUpdateDistrict, cityID, regionID string) bool {
+	cityObjectID, err := primitive.ObjectIDFromHex(cityID)
+	if HandleHTTPError(c, http.StatusBadRequest, message+".ParseCityID", err) {
+		return false
+	}
+	regionObjectID, err := primitive.ObjectIDFromHex(regionID)
+	if HandleHTTPError(c, http.StatusBadRequest, message+".ParseRegionID", err) {
+		return false
+	}
+	region, err := h.storage.Region().Get(context.Background(), regionID)
+	if handleStorageError(c, message+".GetRegion", err) {
+		return false
+	}
+	if region.City.ID != cityID {
+		HandleHTTPError(c, http.StatusBadRequest, message+".GetRegion", errRegionNotInCity)
+		return false
+	}
+	if district.Active && !region.Active {
+		HandleHTTPError(c, http.StatusConflict, message+".GetRegion", errParentAbolished)
+		return false
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, message+".ValidateSoato", soato.ValidateCode(models.AdminUnitDistrict, region.Soato, district.Soato)) {
+		return false
+	}
+	district.CityID, district.RegionID = cityObjectID, regionObjectID
+	return true
+}
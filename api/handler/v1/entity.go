@@ -7,6 +7,8 @@ import (
 	"net/http"
 
 	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
 	"github.com/gin-gonic/gin"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,6 +21,7 @@ import (
 // @Tags entity
 // @Accept json
 // @Produce json
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
 // @Param entity body models.CreateUpdateEntitySwag true "entity"
 // @Success 201 {object} models.CreateResponse
 func (h *handlerV1) CreateEntity(c *gin.Context) {
@@ -40,10 +43,47 @@ func (h *handlerV1) CreateEntity(c *gin.Context) {
 		return
 	}
 
-	if (entity.District.Soato) == 0 {
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.Create.ParseCRS", err) {
+		return
+	}
+	entity.Location, entity.Boundary = geometryFromCRS(crs, entity.Location, entity.Boundary)
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.Create.ValidateGeometry", validateGeometry(entity.Location, entity.Boundary)) {
+		return
+	}
+	if entity.Location != nil {
+		units, err := h.deriveAdminUnits(context.Background(), entity.Location, &models.AdminUnits{
+			City:     entity.City,
+			Region:   entity.Region,
+			District: entity.District,
+		})
+		if handleAdminUnitsError(c, "Entity.Entity.Create.DeriveAdminUnits", err) {
+			return
+		}
+		entity.City, entity.Region, entity.District = units.City, units.Region, units.District
+	}
+
+	if entity.City == nil || entity.Region == nil || entity.District == nil || entity.District.Soato == 0 {
 		HandleHTTPError(c, http.StatusConflict, "Entity.Entity.Create", errors.New("district soato required"))
 		return
 	}
+	if entity.Boundary != nil {
+		declaredArea, err := h.declaredAreaByID(context.Background(), entity.EntityProperties)
+		if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.Create.GetDeclaredArea", err) {
+			return
+		}
+		entity.Topology, err = h.checkTopology(context.Background(), entity.Boundary, "", declaredArea)
+		if handleTopologyError(c, "Entity.Entity.Create.CheckTopology", err) {
+			return
+		}
+	}
+
+	entity.FormVersions, err = h.storage.FormSchemaVersion().GetLatestVersions(
+		context.Background(),
+		uint32(entity.EntityTypeCode))
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.Create.GetLatestFormVersions", err) {
+		return
+	}
 
 	resp, err := h.storage.Entity().Create(
 		context.Background(),
@@ -71,6 +111,7 @@ func (h *handlerV1) CreateEntity(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param entity_id path string true "entity_id"
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
 // @Success 200 {object} models.GetEntity
 func (h *handlerV1) GetEntity(c *gin.Context) {
 	var (
@@ -80,6 +121,10 @@ func (h *handlerV1) GetEntity(c *gin.Context) {
 	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.ParseId", err) {
 		return
 	}
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.ParseCRS", err) {
+		return
+	}
 
 	entity, err := h.storage.Entity().Get(
 		context.Background(),
@@ -89,6 +134,15 @@ func (h *handlerV1) GetEntity(c *gin.Context) {
 	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.GetEntity", err) {
 		return
 	}
+	if lang := requestLanguage(c); lang != "" {
+		i18n.LocalizeEntityProperties(entity.EntityProperty, lang)
+	}
+	h.presignEntityFiles(entity.EntityFiles)
+	entity.EntityGalleryURLs = h.presignGallery(entity.EntityGallery)
+	entity.EntityGalleryPreviewURLs = h.presignThumbnails(entity.EntityGallery, thumbnail.Large)
+	entity.ThumbnailURL = h.thumbnailURL(entity.EntityGallery)
+	entity.EntityGalleryPhotos = h.galleryPhotos(entity)
+	entity.Location, entity.Boundary = geometryToCRS(crs, entity.Location, entity.Boundary)
 
 	c.JSON(http.StatusOK, entity)
 }
@@ -99,19 +153,24 @@ func (h *handlerV1) GetEntity(c *gin.Context) {
 // @Tags entity
 // @Accept json
 // @Produce json
-// @Param city_id query string  true "city_id"
-// @Param region_id query string  true "region_id"
+// @Param city_id query string  false "city_id"
+// @Param region_id query string  false "region_id"
+// @Param district_id query string  false "district_id"
+// @Param entity_soato query string  false "entity_soato"
 // @Param entity_type_code query string  false "entity_type_code"
 // @Param entity_number query string  false "entity_number"
 // @Param status_id query string  false "status_id"
+// @Param bbox query string  false "min longitude,min latitude,max longitude,max latitude"
+// @Param near query string  false "longitude,latitude"
+// @Param radius query number  false "meters from near"
+// @Param within query string  false "GeoJSON Polygon or MultiPolygon"
+// @Param crs query string  false "coordinate reference system of the returned geometry, the filters are in EPSG:4326"
 // @Success 200 {object} models.GetAllEntitiesResponse
 func (h *handlerV1) GetAllEntitiesWithProperties(c *gin.Context) {
-	var (
-		entityNumber = c.Query("entity_number")
-		request      = &models.GetAllEntitiesRequest{
-			EntityNumber: entityNumber,
-		}
-	)
+	request, err := parseEntityQuery(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.GetAllWithProperties.ParseQuery", err) {
+		return
+	}
 
 	page, err := ParseQueryParam(c, h.log, "page", "1")
 	if err != nil {
@@ -124,12 +183,20 @@ func (h *handlerV1) GetAllEntitiesWithProperties(c *gin.Context) {
 	}
 	request.Page = uint32(page)
 	request.Limit = uint32(limit)
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.GetAllWithProperties.ParseCRS", err) {
+		return
+	}
 	response, err := h.storage.Entity().GetAllWithProperties(
 		context.Background(),
 		request)
 	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Entity.GetAllWithProperties", err) {
 		return
 	}
+	for _, entity := range response {
+		entity.ThumbnailURL = h.thumbnailURL(entity.EntityGallery)
+		entity.Location, entity.Boundary = geometryToCRS(crs, entity.Location, entity.Boundary)
+	}
 
 	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,209 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/bundle"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/bundle.zip [get]
+// @Summary Download entity bundle
+// @Description API for downloading the files, gallery and properties of an entity as one ZIP. manifest.json lists the SHA-256 of every entry. The archive is streamed, a failure halfway leaves it truncated.
+// @Tags entity
+// @Produce application/zip
+// @Param entity_id path string true "entity_id"
+// @Success 200 {file} file
+func (h *handlerV1) GetEntityBundle(c *gin.Context) {
+	userInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Bundle.ParseEntityId", err) {
+		return
+	}
+	entity, err := h.storage.Entity().Get(context.Background(), entityID.Hex())
+	if handleStorageError(c, "Entity.Bundle.GetEntity", err) {
+		return
+	}
+	if lang := requestLanguage(c); lang != "" {
+		i18n.LocalizeEntityProperties(entity.EntityProperty, lang)
+	}
+
+	manifest := &models.EntityBundleManifest{
+		EntityID:     entity.ID,
+		EntityNumber: entity.EntityNumber,
+		GeneratedAt:  time.Now(),
+		GeneratedBy:  userInfo.ID,
+		Files:        []*models.EntityBundleFile{},
+		Skipped:      []*models.EntityBundleSkipped{},
+	}
+
+	fileName := entity.EntityNumber
+	if fileName == "" {
+		fileName = entity.ID
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="entity-%s.zip"`, bundle.SafeName(fileName)))
+	c.Status(http.StatusOK)
+
+	archive := bundle.NewWriter(c.Writer)
+	if err := h.writeEntityBundle(archive, entity, manifest); err != nil {
+		// the status is sent already, a truncated archive tells the client
+		h.log.Error("error while writing entity bundle", logger.String("entity", entity.ID), logger.Error(err))
+		c.Abort()
+		return
+	}
+	if err := archive.Close(); err != nil {
+		h.log.Error("error while closing entity bundle", logger.String("entity", entity.ID), logger.Error(err))
+		c.Abort()
+	}
+}
+
+func (h *handlerV1) writeEntityBundle(archive *bundle.Writer, entity *models.Entity, manifest *models.EntityBundleManifest) error {
+	for _, file := range entity.EntityFiles {
+		if file == nil {
+			continue
+		}
+		if file.ObjectName == "" {
+			manifest.Skipped = append(manifest.Skipped, &models.EntityBundleSkipped{
+				Source: file.ID,
+				Reason: "file is kept outside the file store at " + file.Url,
+			})
+			continue
+		}
+		name := file.FileName
+		if name == "" {
+			name = file.ObjectName
+		}
+		if err := h.addBundleObject(archive, manifest, "files/"+bundle.SafeName(name), file.ObjectName); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range entity.EntityGallery {
+		if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+			manifest.Skipped = append(manifest.Skipped, &models.EntityBundleSkipped{
+				Source: name,
+				Reason: "image is kept outside the file store",
+			})
+			continue
+		}
+		if err := h.addBundleObject(archive, manifest, "gallery/"+bundle.SafeName(name), name); err != nil {
+			return err
+		}
+	}
+
+	properties := make([]*models.EntityBundleProperty, 0, len(entity.EntityProperty))
+	for _, entityProperty := range entity.EntityProperty {
+		if entityProperty == nil || entityProperty.Property == nil {
+			continue
+		}
+		properties = append(properties, &models.EntityBundleProperty{
+			PropertyID: entityProperty.Property.ID,
+			Name:       entityProperty.Property.Name,
+			Label:      entityProperty.Property.Label,
+			Value:      entityProperty.Value,
+		})
+	}
+	propertiesJSON, err := json.MarshalIndent(properties, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addBundleBytes(archive, manifest, "properties.json", propertiesJSON, "application/json"); err != nil {
+		return err
+	}
+	propertiesCSV, err := entityPropertiesCSV(properties)
+	if err != nil {
+		return err
+	}
+	if err := addBundleBytes(archive, manifest, "properties.csv", propertiesCSV, "text/csv"); err != nil {
+		return err
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = archive.Add("manifest.json", bytes.NewReader(manifestJSON), "application/json", manifest.GeneratedAt)
+	return err
+}
+
+// addBundleObject copies a file from the file store. Files that can not be
+// opened are listed as skipped, an error means the archive is broken.
+func (h *handlerV1) addBundleObject(archive *bundle.Writer, manifest *models.EntityBundleManifest, path, name string) error {
+	object, info, err := h.fileStore.Get(context.Background(), name)
+	if err != nil {
+		reason := err.Error()
+		if errors.Is(err, filestore.ErrNotFound) {
+			reason = "file is missing from the file store"
+		}
+		manifest.Skipped = append(manifest.Skipped, &models.EntityBundleSkipped{
+			Source: name,
+			Reason: reason,
+		})
+		return nil
+	}
+	defer object.Close()
+
+	entry, err := archive.Add(path, object, info.ContentType, info.LastModified)
+	if err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, &models.EntityBundleFile{
+		Path:        entry.Path,
+		Size:        entry.Size,
+		SHA256:      entry.SHA256,
+		ContentType: info.ContentType,
+		Source:      name,
+	})
+	return nil
+}
+
+func addBundleBytes(archive *bundle.Writer, manifest *models.EntityBundleManifest, path string, content []byte, contentType string) error {
+	entry, err := archive.Add(path, bytes.NewReader(content), contentType, manifest.GeneratedAt)
+	if err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, &models.EntityBundleFile{
+		Path:        entry.Path,
+		Size:        entry.Size,
+		SHA256:      entry.SHA256,
+		ContentType: contentType,
+		Source:      "generated",
+	})
+	return nil
+}
+
+func entityPropertiesCSV(properties []*models.EntityBundleProperty) ([]byte, error) {
+	var buf bytes.Buffer
+	// a byte order mark makes Excel read the file as UTF-8
+	buf.WriteString("\ufeff")
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write([]string{"property_id", "name", "label", "value"}); err != nil {
+		return nil, err
+	}
+	for _, property := range properties {
+		err := writer.Write([]string{property.PropertyID, property.Name, property.Label, property.Value})
+		if err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
@@ -0,0 +1,274 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/document [post]
+// @Summary Attach entity document
+// @Description API for attaching a file registered with /v1/files/confirm to an entity. Attaching a document type again adds a new version and keeps the older ones.
+// @Tags entity_document
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param document body models.AttachDocumentSwag true "document"
+// @Success 201 {object} models.EntityFiles
+func (h *handlerV1) AttachEntityDocument(c *gin.Context) {
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.Attach.ParseEntityId", err) {
+		return
+	}
+	_, err = h.storage.Entity().Get(context.Background(), entityID.Hex())
+	if handleStorageError(c, "EntityDocument.Attach.GetEntity", err) {
+		return
+	}
+
+	h.attachDocument(c, &models.AttachEntityFile{EntityID: entityID})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/document [get]
+// @Summary Get entity documents
+// @Tags entity_document
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param document_type query string false "title_deed, survey_plan, passport_copy or cadastral_extract"
+// @Param all_versions query boolean false "include older versions"
+// @Success 200 {object} models.GetEntityDocumentsResponse
+func (h *handlerV1) GetEntityDocuments(c *gin.Context) {
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.GetAll.ParseEntityId", err) {
+		return
+	}
+
+	h.getDocuments(c, &models.GetEntityDocumentsRequest{EntityID: entityID.Hex()})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/document/{file_id} [delete]
+// @Summary Detach entity document
+// @Description API for detaching a document from an entity. The file is kept as an older version.
+// @Tags entity_document
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param file_id path string true "file_id"
+// @Success 204
+func (h *handlerV1) DetachEntityDocument(c *gin.Context) {
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.Detach.ParseEntityId", err) {
+		return
+	}
+
+	h.detachDocument(c, &models.AttachEntityFile{EntityID: entityID})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/document [post]
+// @Summary Attach entity draft document
+// @Description API for attaching a file registered with /v1/files/confirm to an entity draft. Attaching a document type again adds a new version and keeps the older ones.
+// @Tags entity_document
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param document body models.AttachDocumentSwag true "document"
+// @Success 201 {object} models.EntityFiles
+func (h *handlerV1) AttachEntityDraftDocument(c *gin.Context) {
+	entityDraftID, err := primitive.ObjectIDFromHex(c.Param("entity_draft_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.AttachDraft.ParseEntityDraftId", err) {
+		return
+	}
+	_, err = h.storage.EntityDraft().Get(context.Background(), entityDraftID.Hex())
+	if handleStorageError(c, "EntityDocument.AttachDraft.GetEntityDraft", err) {
+		return
+	}
+
+	h.attachDocument(c, &models.AttachEntityFile{EntityDraftID: entityDraftID})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/document [get]
+// @Summary Get entity draft documents
+// @Tags entity_document
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param document_type query string false "title_deed, survey_plan, passport_copy or cadastral_extract"
+// @Param all_versions query boolean false "include older versions"
+// @Success 200 {object} models.GetEntityDocumentsResponse
+func (h *handlerV1) GetEntityDraftDocuments(c *gin.Context) {
+	entityDraftID, err := primitive.ObjectIDFromHex(c.Param("entity_draft_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.GetAllDraft.ParseEntityDraftId", err) {
+		return
+	}
+
+	h.getDocuments(c, &models.GetEntityDocumentsRequest{EntityDraftID: entityDraftID.Hex()})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/document/{file_id} [delete]
+// @Summary Detach entity draft document
+// @Description API for detaching a document from an entity draft. The file is kept as an older version.
+// @Tags entity_document
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param file_id path string true "file_id"
+// @Success 204
+func (h *handlerV1) DetachEntityDraftDocument(c *gin.Context) {
+	entityDraftID, err := primitive.ObjectIDFromHex(c.Param("entity_draft_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.DetachDraft.ParseEntityDraftId", err) {
+		return
+	}
+
+	h.detachDocument(c, &models.AttachEntityFile{EntityDraftID: entityDraftID})
+}
+
+func (h *handlerV1) attachDocument(c *gin.Context, attach *models.AttachEntityFile) {
+	var (
+		document models.AttachDocumentSwag
+	)
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&document); HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.Attach.BindingJson", err) {
+		return
+	}
+	if !isDocumentType(document.DocumentType) {
+		HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.Attach.DocumentType",
+			fmt.Errorf("document_type must be one of %s", strings.Join(models.DocumentTypes, ", ")))
+		return
+	}
+
+	attach.FileID, err = primitive.ObjectIDFromHex(document.FileID)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.Attach.ParseFileId", err) {
+		return
+	}
+	file, err := h.storage.EntityFiles().Get(context.Background(), attach.FileID.Hex())
+	if handleStorageError(c, "EntityDocument.Attach.GetFile", err) {
+		return
+	}
+	if file.User != userInfo.ID {
+		HandleHTTPError(c, http.StatusForbidden, "EntityDocument.Attach.FileOwner", errors.New("file was uploaded by another user"))
+		return
+	}
+	attach.DocumentType = document.DocumentType
+	attach.Comment = document.Comment
+	attach.User = userInfo.ID
+
+	response, err := h.storage.EntityFiles().Attach(context.Background(), attach)
+	if handleStorageError(c, "EntityDocument.Attach", err) {
+		return
+	}
+	if h.syncEntityFiles(c, attach) {
+		return
+	}
+	h.presignEntityFiles([]*models.EntityFiles{response})
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *handlerV1) getDocuments(c *gin.Context, req *models.GetEntityDocumentsRequest) {
+	if _, err := h.UserInfo(c, true); err != nil {
+		return
+	}
+	req.DocumentType = c.Query("document_type")
+	if req.DocumentType != "" && !isDocumentType(req.DocumentType) && req.DocumentType != models.DocumentTypeCadastralExtract {
+		HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.GetAll.DocumentType",
+			fmt.Errorf("document_type must be one of %s, %s", strings.Join(models.DocumentTypes, ", "), models.DocumentTypeCadastralExtract))
+		return
+	}
+	allVersions, err := strconv.ParseBool(c.DefaultQuery("all_versions", "false"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.GetAll.ParseAllVersions", err) {
+		return
+	}
+	req.AllVersions = allVersions
+
+	documents, err := h.storage.EntityFiles().GetDocuments(context.Background(), req)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.GetAll", err) {
+		return
+	}
+	h.presignEntityFiles(documents)
+
+	c.JSON(http.StatusOK, models.GetEntityDocumentsResponse{
+		Documents: documents,
+		Count:     uint32(len(documents)),
+	})
+}
+
+func (h *handlerV1) detachDocument(c *gin.Context, detach *models.AttachEntityFile) {
+	if _, err := h.UserInfo(c, true); err != nil {
+		return
+	}
+	fileID, err := primitive.ObjectIDFromHex(c.Param("file_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.Detach.ParseFileId", err) {
+		return
+	}
+	detach.FileID = fileID
+	file, err := h.storage.EntityFiles().Get(context.Background(), fileID.Hex())
+	if handleStorageError(c, "EntityDocument.Detach.GetFile", err) {
+		return
+	}
+	detach.DocumentType = file.DocumentType
+
+	err = h.storage.EntityFiles().Detach(context.Background(), detach)
+	if handleStorageError(c, "EntityDocument.Detach", err) {
+		return
+	}
+	if h.syncEntityFiles(c, detach) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// syncEntityFiles keeps the current version of the document type in
+// Entity.EntityFiles and takes the older ones out. Files given when the
+// entity was created have no document type and are left. Drafts read their
+// documents from the files collection.
+func (h *handlerV1) syncEntityFiles(c *gin.Context, owner *models.AttachEntityFile) bool {
+	if owner.EntityID.IsZero() || owner.DocumentType == "" {
+		return false
+	}
+
+	versions, err := h.storage.EntityFiles().GetDocuments(context.Background(), &models.GetEntityDocumentsRequest{
+		EntityID:     owner.EntityID.Hex(),
+		DocumentType: owner.DocumentType,
+		AllVersions:  true,
+	})
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDocument.GetDocuments", err) {
+		return true
+	}
+	var add, remove []string
+	for _, version := range versions {
+		if version.Current {
+			add = append(add, version.ID)
+		} else {
+			remove = append(remove, version.ID)
+		}
+	}
+
+	err = h.storage.Entity().UpdateEntityFiles(context.Background(), owner.EntityID.Hex(), add, remove)
+	return handleStorageError(c, "EntityDocument.UpdateEntityFiles", err)
+}
+
+func isDocumentType(documentType string) bool {
+	for _, t := range models.DocumentTypes {
+		if t == documentType {
+			return true
+		}
+	}
+	return false
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/i18n"
 	"github.com/gin-gonic/gin"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,6 +21,7 @@ import (
 // @Tags entity-draft
 // @Accept json
 // @Produce json
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
 // @Param entity body models.EntityDraftSwag true "entity"
 // @Success 201 {object} models.CreateResponse
 
@@ -38,18 +40,66 @@ func (h *handlerV1) CreateEntityDraft(c *gin.Context) {
 
 	fmt.Println(entityDraft.ID)
 
+	if entityDraft.Wizard && entityDraft.EntityTypeCode == 0 {
+		HandleHTTPError(c, http.StatusBadRequest, "EntityService.CreateEntityDraft", errors.New("entity_type_code is required for wizard drafts"))
+		return
+	}
+
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityService.CreateEntityDraft.ParseCRS", err) {
+		return
+	}
+	entityDraft.Location, entityDraft.Boundary = geometryFromCRS(crs, entityDraft.Location, entityDraft.Boundary)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityService.CreateEntityDraft.ValidateGeometry", validateGeometry(entityDraft.Location, entityDraft.Boundary)) {
+		return
+	}
+	if entityDraft.Location != nil {
+		units, err := h.deriveAdminUnits(context.Background(), entityDraft.Location, &models.AdminUnits{
+			City:     &entityDraft.City,
+			Region:   &entityDraft.Region,
+			District: &entityDraft.District,
+		})
+		if handleAdminUnitsError(c, "EntityService.CreateEntityDraft.DeriveAdminUnits", err) {
+			return
+		}
+		entityDraft.City, entityDraft.Region, entityDraft.District = *units.City, *units.Region, *units.District
+	}
+
 	if (entityDraft.Region.Soato) == 0 {
 		HandleHTTPError(c, http.StatusConflict, "district soato required", errors.New("district soato required"))
 		return
 	}
+	if entityDraft.Boundary != nil {
+		declaredArea, err := h.declaredAreaByID(context.Background(), entityDraft.EntityProperties)
+		if HandleHTTPError(c, http.StatusBadRequest, "EntityService.CreateEntityDraft.GetDeclaredArea", err) {
+			return
+		}
+		excludeID := ""
+		if !entityDraft.EntityID.IsZero() {
+			excludeID = entityDraft.EntityID.Hex()
+		}
+		entityDraft.Topology, err = h.checkTopology(context.Background(), entityDraft.Boundary, excludeID, declaredArea)
+		if handleTopologyError(c, "EntityService.CreateEntityDraft.CheckTopology", err) {
+			return
+		}
+	}
 
 	_, err = h.storage.Applicant().Get(context.Background(), userInfo.ID)
 	if HandleHTTPError(c, http.StatusBadRequest, "EntityService.GetApplicant", err) {
 		return
 	}
+	entityDraft.ApplicantID = userInfo.ID
 	soato := strconv.Itoa(int(entityDraft.Region.Soato))
 	entityDraft.EntityDraftSoato = soato
 
+	entityDraft.FormVersions, err = h.storage.FormSchemaVersion().GetLatestVersions(
+		context.Background(),
+		uint32(entityDraft.EntityTypeCode),
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityService.CreateEntityDraft.GetLatestFormVersions", err) {
+		return
+	}
+
 	resp, err := h.storage.EntityDraft().Create(
 		context.Background(),
 		&entityDraft,
@@ -68,6 +118,7 @@ func (h *handlerV1) CreateEntityDraft(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param entity_draft_id path string true "entity_draft_id"
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
 // @Success 200 {object} models.EntityDraft
 
 func (h *handlerV1) GetEntityDraft(c *gin.Context) {
@@ -77,6 +128,10 @@ func (h *handlerV1) GetEntityDraft(c *gin.Context) {
 	if HandleHTTPError(c, http.StatusBadRequest, "error while parsing entity id", err) {
 		return
 	}
+	crs, err := h.requestCRS(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "error while parsing crs", err) {
+		return
+	}
 
 	entity, err := h.storage.EntityDraft().Get(
 		context.Background(),
@@ -86,6 +141,19 @@ func (h *handlerV1) GetEntityDraft(c *gin.Context) {
 	if HandleHTTPError(c, http.StatusBadRequest, "error while getting entity", err) {
 		return
 	}
+	if lang := requestLanguage(c); lang != "" {
+		i18n.LocalizeEntityProperties(entity.EntityProperty, lang)
+	}
+	entity.EntityGalleryURLs = h.presignGallery(entity.EntityGallery)
+
+	entity.EntityFiles, err = h.storage.EntityFiles().GetDocuments(context.Background(), &models.GetEntityDocumentsRequest{
+		EntityDraftID: ID,
+	})
+	if HandleHTTPError(c, http.StatusBadRequest, "error while getting entity draft documents", err) {
+		return
+	}
+	h.presignEntityFiles(entity.EntityFiles)
+	entity.Location, entity.Boundary = geometryToCRS(crs, entity.Location, entity.Boundary)
 
 	c.JSON(http.StatusOK, entity)
 }
@@ -0,0 +1,358 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/formschema"
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	errEntityDraftNotInProgress = errors.New("entity draft is not in progress")
+	errEntityDraftNotOwner      = errors.New("entity draft belongs to another applicant")
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/steps [get]
+// @Summary Get entity draft steps
+// @Description API for getting the steps of a wizard draft with the validation result of each step
+// @Tags entity-draft
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Success 200 {object} models.EntityDraftStepsResponse
+func (h *handlerV1) GetEntityDraftSteps(c *gin.Context) {
+	var (
+		ID     = c.Param("entity_draft_id")
+		_, err = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.GetSteps.ParseId", err) {
+		return
+	}
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+
+	draft, err := h.storage.EntityDraft().Get(context.Background(), ID)
+	if handleStorageError(c, "EntityDraft.GetSteps.GetEntityDraft", err) {
+		return
+	}
+	if draft.ApplicantID != userInfo.ID {
+		HandleHTTPError(c, http.StatusForbidden, "EntityDraft.GetSteps", errEntityDraftNotOwner)
+		return
+	}
+
+	groupsByStep, err := h.draftStepGroups(draft, requestLanguage(c))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.GetSteps.GetGroupProperties", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, entityDraftSteps(draft, groupsByStep, draftValues(draft)))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/step/{step} [put]
+// @Summary Save entity draft step
+// @Description API for saving the properties of one step of a wizard draft. The values replace the ones saved for the step before, and are saved even when the step is not valid yet. A valid step is marked as completed.
+// @Tags entity-draft
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param step path integer true "step"
+// @Param step_properties body models.SaveEntityDraftStepSwag true "step_properties"
+// @Success 200 {object} models.EntityDraftStepsResponse
+func (h *handlerV1) SaveEntityDraftStep(c *gin.Context) {
+	var (
+		stepProperties models.SaveEntityDraftStepSwag
+		ID             = c.Param("entity_draft_id")
+		objectID, err  = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.SaveStep.ParseId", err) {
+		return
+	}
+	step, err := strconv.ParseUint(c.Param("step"), 10, 32)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.SaveStep.ParseStep", err) {
+		return
+	}
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&stepProperties); HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.SaveStep.BindingJson", err) {
+		return
+	}
+
+	draft, err := h.storage.EntityDraft().Get(context.Background(), ID)
+	if handleStorageError(c, "EntityDraft.SaveStep.GetEntityDraft", err) {
+		return
+	}
+	if draft.ApplicantID != userInfo.ID {
+		HandleHTTPError(c, http.StatusForbidden, "EntityDraft.SaveStep", errEntityDraftNotOwner)
+		return
+	}
+	if draft.Status != models.EntityDraftStatusInProgress {
+		HandleHTTPError(c, http.StatusConflict, "EntityDraft.SaveStep", errEntityDraftNotInProgress)
+		return
+	}
+
+	groupsByStep, err := h.draftStepGroups(draft, requestLanguage(c))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.SaveStep.GetGroupProperties", err) {
+		return
+	}
+	groups, ok := groupsByStep[uint32(step)]
+	if !ok {
+		HandleHTTPError(c, http.StatusNotFound, "EntityDraft.SaveStep", errors.New("the form has no such step"))
+		return
+	}
+
+	inStep := map[string]bool{}
+	for _, group := range groups {
+		for _, property := range group.Properties {
+			inStep[property.ID] = true
+		}
+	}
+
+	// values of the other steps are kept, the ones of this step are replaced
+	var (
+		values = map[string]string{}
+		order  = []string{}
+	)
+	for _, entityProperty := range draft.EntityProperty {
+		if entityProperty.Property == nil || inStep[entityProperty.Property.ID] {
+			continue
+		}
+		if _, ok := values[entityProperty.Property.ID]; !ok {
+			order = append(order, entityProperty.Property.ID)
+		}
+		values[entityProperty.Property.ID] = entityProperty.Value
+	}
+	for _, entityProperty := range stepProperties.EntityProperties {
+		if !inStep[entityProperty.PropertyID] {
+			HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.SaveStep", errors.New("property "+entityProperty.PropertyID+" does not belong to the step"))
+			return
+		}
+		if _, ok := values[entityProperty.PropertyID]; !ok {
+			order = append(order, entityProperty.PropertyID)
+		}
+		values[entityProperty.PropertyID] = entityProperty.Value
+	}
+
+	update := &models.UpdateEntityDraftStep{
+		ID:               objectID,
+		Version:          draft.Version,
+		EntityProperties: []*models.CreateEntityProperty{},
+		CompletedSteps:   []uint32{},
+	}
+	for _, propertyID := range order {
+		propertyObjectID, err := primitive.ObjectIDFromHex(propertyID)
+		if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.SaveStep.ParsePropertyId", err) {
+			return
+		}
+		update.EntityProperties = append(update.EntityProperties, &models.CreateEntityProperty{
+			PropertyID: propertyObjectID,
+			Value:      values[propertyID],
+		})
+	}
+
+	stepResult := validateDraftStep(uint32(step), groups, values)
+	for _, completed := range draft.CompletedSteps {
+		if _, ok := groupsByStep[completed]; ok && completed != uint32(step) {
+			update.CompletedSteps = append(update.CompletedSteps, completed)
+		}
+	}
+	if stepResult.Valid {
+		update.CompletedSteps = append(update.CompletedSteps, uint32(step))
+	}
+	sort.Slice(update.CompletedSteps, func(i, j int) bool {
+		return update.CompletedSteps[i] < update.CompletedSteps[j]
+	})
+
+	err = h.storage.EntityDraft().UpdateStep(context.Background(), update)
+	if handleStorageError(c, "EntityDraft.SaveStep.UpdateStep", err) {
+		return
+	}
+
+	draft.CompletedSteps = update.CompletedSteps
+	c.JSON(http.StatusOK, entityDraftSteps(draft, groupsByStep, values))
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/submit [post]
+// @Summary Submit entity draft
+// @Description API for submitting a wizard draft for review. Every step has to be valid, otherwise 422 is returned with the steps and their errors.
+// @Tags entity-draft
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Success 200 {object} models.EntityDraftStepsResponse
+func (h *handlerV1) SubmitEntityDraft(c *gin.Context) {
+	var (
+		ID     = c.Param("entity_draft_id")
+		_, err = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.Submit.ParseId", err) {
+		return
+	}
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+
+	draft, err := h.storage.EntityDraft().Get(context.Background(), ID)
+	if handleStorageError(c, "EntityDraft.Submit.GetEntityDraft", err) {
+		return
+	}
+	if draft.ApplicantID != userInfo.ID {
+		HandleHTTPError(c, http.StatusForbidden, "EntityDraft.Submit", errEntityDraftNotOwner)
+		return
+	}
+	if draft.Status != models.EntityDraftStatusInProgress {
+		HandleHTTPError(c, http.StatusConflict, "EntityDraft.Submit", errEntityDraftNotInProgress)
+		return
+	}
+
+	groupsByStep, err := h.draftStepGroups(draft, requestLanguage(c))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.Submit.GetGroupProperties", err) {
+		return
+	}
+
+	response := entityDraftSteps(draft, groupsByStep, draftValues(draft))
+	if !response.CanSubmit {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"message": "entity draft has steps that are not valid",
+			"steps":   response,
+		})
+		return
+	}
+
+	completedSteps := []uint32{}
+	for _, step := range response.Steps {
+		completedSteps = append(completedSteps, step.Step)
+	}
+	err = h.storage.EntityDraft().Submit(context.Background(), ID, draft.Version, completedSteps)
+	if handleStorageError(c, "EntityDraft.Submit", err) {
+		return
+	}
+
+	response.Status = models.EntityDraftStatusNew
+	response.CompletedSteps = completedSteps
+	response.CanSubmit = false
+	c.JSON(http.StatusOK, response)
+}
+
+// draftStepGroups returns the active group properties of every step of the
+// draft form, taken from the form versions the draft was created with
+func (h *handlerV1) draftStepGroups(draft *models.EntityDraft, lang string) (map[uint32][]*models.GroupProperty, error) {
+	var (
+		groupsByStep = map[uint32][]*models.GroupProperty{}
+		groups       []*models.GroupProperty
+		typeOf       = uint32(draft.EntityTypeCode)
+	)
+	if typeOf == 0 {
+		return nil, errors.New("entity draft has no entity_type_code")
+	}
+
+	if len(draft.FormVersions) != 0 {
+		for _, formVersion := range draft.FormVersions {
+			step := formVersion.Step
+			stepGroups, err := h.getGroupPropertiesByType(typeOf, &step, formVersion.Version)
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, stepGroups...)
+		}
+	} else {
+		var err error
+		groups, err = h.getGroupPropertiesByType(typeOf, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if lang != "" {
+		i18n.LocalizeGroupProperties(groups, lang)
+	}
+
+	for _, group := range groups {
+		if group.Status {
+			groupsByStep[group.Step] = append(groupsByStep[group.Step], group)
+		}
+	}
+	return groupsByStep, nil
+}
+
+func draftValues(draft *models.EntityDraft) map[string]string {
+	values := make(map[string]string, len(draft.EntityProperty))
+	for _, entityProperty := range draft.EntityProperty {
+		if entityProperty.Property != nil {
+			values[entityProperty.Property.ID] = entityProperty.Value
+		}
+	}
+	return values
+}
+
+func entityDraftSteps(draft *models.EntityDraft, groupsByStep map[uint32][]*models.GroupProperty, values map[string]string) *models.EntityDraftStepsResponse {
+	response := &models.EntityDraftStepsResponse{
+		EntityDraftID:  draft.ID,
+		Status:         draft.Status,
+		Steps:          []*models.EntityDraftStep{},
+		CompletedSteps: draft.CompletedSteps,
+		CanSubmit:      draft.Status == models.EntityDraftStatusInProgress && len(groupsByStep) != 0,
+	}
+	if response.CompletedSteps == nil {
+		response.CompletedSteps = []uint32{}
+	}
+
+	steps := make([]uint32, 0, len(groupsByStep))
+	for step := range groupsByStep {
+		steps = append(steps, step)
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i] < steps[j] })
+
+	for _, step := range steps {
+		result := validateDraftStep(step, groupsByStep[step], values)
+		response.Steps = append(response.Steps, result)
+		response.CanSubmit = response.CanSubmit && result.Valid
+	}
+	return response
+}
+
+// validateDraftStep checks the values of the step against the form schema of
+// their properties
+func validateDraftStep(step uint32, groups []*models.GroupProperty, values map[string]string) *models.EntityDraftStep {
+	var (
+		result = &models.EntityDraftStep{
+			Step:   step,
+			Errors: []*models.EntityDraftPropertyError{},
+		}
+		checked = map[string]bool{}
+	)
+
+	for _, group := range groups {
+		for _, property := range group.Properties {
+			if property == nil || checked[property.ID] {
+				continue
+			}
+			checked[property.ID] = true
+
+			if message := formschema.Validate(property, values[property.ID]); message != "" {
+				result.Errors = append(result.Errors, &models.EntityDraftPropertyError{
+					PropertyID: property.ID,
+					Label:      property.Label,
+					Message:    message,
+				})
+			}
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
@@ -0,0 +1,222 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/geoexport"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// exportTable is the name of the feature table and layer of exports
+const exportTable = "entities"
+
+// exportFields are the attributes every exported feature has, the selected
+// properties follow them
+var exportFields = []string{"id", "entity_number", "status", "entity_soato", "address", "district", "created_at"}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-export [get]
+// @Summary Export entities
+// @Description API for exporting the entities matching the filters of the entity list as a GeoJSON FeatureCollection, KML or GeoPackage. Features carry the entity number, status, SOATO and the values of the selected properties, the geometry is the boundary or else the location, always in EPSG:4326. GeoJSON and KML are streamed, a failure halfway leaves them truncated.
+// @Tags entity
+// @Produce application/geo+json
+// @Param format query string false "geojson, kml or gpkg, geojson by default"
+// @Param properties query string false "comma separated property ids whose values are exported"
+// @Param city_id query string false "city_id"
+// @Param region_id query string false "region_id"
+// @Param district_id query string false "district_id"
+// @Param entity_soato query string false "entity_soato"
+// @Param entity_number query string false "entity_number"
+// @Param bbox query string false "min longitude,min latitude,max longitude,max latitude"
+// @Param near query string false "longitude,latitude"
+// @Param radius query number false "meters from near"
+// @Param within query string false "GeoJSON Polygon or MultiPolygon"
+// @Success 200 {file} file
+func (h *handlerV1) ExportEntities(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	format := c.DefaultQuery("format", geoexport.FormatGeoJSON)
+	if geoexport.ContentType(format) == "" {
+		HandleHTTPError(c, http.StatusBadRequest, "Entity.Export.Format", geoexport.ErrUnsupportedFormat)
+		return
+	}
+	request, err := parseEntityQuery(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.Export.ParseQuery", err) {
+		return
+	}
+	fields, propertyIDs, ok := h.exportFields(c)
+	if !ok {
+		return
+	}
+	lang := requestLanguage(c)
+
+	fileName := fmt.Sprintf("%s-%s%s", exportTable, time.Now().Format("20060102-150405"), geoexport.Extension(format))
+	if format == geoexport.FormatGeoPackage {
+		h.exportGeoPackage(c, request, fields, propertyIDs, lang, fileName)
+		return
+	}
+
+	c.Header("Content-Type", geoexport.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	buf := bufio.NewWriter(c.Writer)
+	var writer geoexport.Writer
+	if format == geoexport.FormatKML {
+		writer = geoexport.NewKML(buf, exportTable, fields)
+	} else {
+		writer = geoexport.NewGeoJSON(buf, fields)
+	}
+	err = h.storage.Entity().Each(context.Background(), request, func(entity *models.GetAllEntities) error {
+		return writer.Write(exportFeature(entity, propertyIDs, lang))
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err == nil {
+		return
+	}
+	if !c.Writer.Written() {
+		// nothing is sent yet, the client gets the error instead of the file
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		handleStorageError(c, "Entity.Export.Each", err)
+		return
+	}
+	h.log.Error("error while exporting entities", logger.String("format", format), logger.Error(err))
+	c.Abort()
+}
+
+// exportGeoPackage writes the GeoPackage to a temporary file, SQLite files
+// are not written front to back
+func (h *handlerV1) exportGeoPackage(c *gin.Context, request *models.GetAllEntitiesRequest, fields, propertyIDs []string, lang, fileName string) {
+	tmp, err := ioutil.TempFile("", "export-*.gpkg")
+	if HandleHTTPError(c, http.StatusInternalServerError, "Entity.Export.TempFile", err) {
+		return
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	writer := geoexport.NewGeoPackage(tmp, exportTable, fields)
+	err = h.storage.Entity().Each(context.Background(), request, func(entity *models.GetAllEntities) error {
+		return writer.Write(exportFeature(entity, propertyIDs, lang))
+	})
+	if handleStorageError(c, "Entity.Export.Each", err) {
+		return
+	}
+	if HandleHTTPError(c, http.StatusInternalServerError, "Entity.Export.Close", writer.Close()) {
+		return
+	}
+	size, err := tmp.Seek(0, io.SeekEnd)
+	if HandleHTTPError(c, http.StatusInternalServerError, "Entity.Export.Seek", err) {
+		return
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); HandleHTTPError(c, http.StatusInternalServerError, "Entity.Export.Seek", err) {
+		return
+	}
+	c.DataFromReader(http.StatusOK, size, geoexport.ContentType(geoexport.FormatGeoPackage), tmp, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, fileName),
+	})
+}
+
+// exportFields returns the attribute names of the export with the ids of the
+// properties after the fixed fields. A property is named by its name, one
+// whose name is taken gets its id too.
+func (h *handlerV1) exportFields(c *gin.Context) ([]string, []string, bool) {
+	var (
+		fields      = append([]string{}, exportFields...)
+		propertyIDs []string
+		used        = map[string]bool{}
+	)
+	for _, field := range fields {
+		used[field] = true
+	}
+	for _, id := range strings.Split(c.Query("properties"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			HandleHTTPError(c, http.StatusBadRequest, "Entity.Export.ParsePropertyId", errors.New("properties must be property ids"))
+			return nil, nil, false
+		}
+		property, err := h.storage.Property().Get(context.Background(), id)
+		if handleStorageError(c, "Entity.Export.GetProperty", err) {
+			return nil, nil, false
+		}
+		name := property.Name
+		if name == "" || used[name] {
+			name = strings.TrimSpace(name + " " + id)
+		}
+		if used[name] {
+			// the property is selected twice
+			continue
+		}
+		used[name] = true
+		fields = append(fields, name)
+		propertyIDs = append(propertyIDs, id)
+	}
+	return fields, propertyIDs, true
+}
+
+// exportFeature turns an entity into a feature with the values of
+// exportFields and the properties in order
+func exportFeature(entity *models.GetAllEntities, propertyIDs []string, lang string) *geoexport.Feature {
+	feature := &geoexport.Feature{
+		Name: entity.EntityNumber,
+		Properties: []string{
+			entity.ID,
+			entity.EntityNumber,
+			entity.Status,
+			entity.EntitySoato,
+			entity.Address,
+			"",
+			"",
+		},
+	}
+	if feature.Name == "" {
+		feature.Name = entity.ID
+	}
+	if entity.District != nil {
+		feature.Properties[5] = placeName(lang, entity.District.Name, entity.District.RuName)
+	}
+	if entity.CreatedAt != 0 {
+		feature.Properties[6] = entity.CreatedAt.Time().UTC().Format(time.RFC3339)
+	}
+
+	values := make(map[string]string, len(entity.EntityProperties))
+	for _, entityProperty := range entity.EntityProperties {
+		if entityProperty != nil {
+			values[entityProperty.PropertyID] = entityProperty.Value
+		}
+	}
+	for _, id := range propertyIDs {
+		feature.Properties = append(feature.Properties, values[id])
+	}
+
+	switch {
+	case entity.Boundary != nil && len(entity.Boundary.Polygons) > 0:
+		feature.Type = entity.Boundary.Type
+		feature.Polygons = entity.Boundary.Polygons
+	case entity.Location != nil && len(entity.Location.Coordinates) == 2:
+		feature.Type = geoexport.Point
+		feature.Point = entity.Location.Coordinates
+	}
+	return feature
+}
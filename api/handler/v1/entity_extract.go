@@ -0,0 +1,275 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/docgen"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errExtractNoApplicant is returned for owner data of an entity no applicant
+// filed a draft for
+var errExtractNoApplicant = errors.New("no applicant filed a draft for the entity")
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/extract [post]
+// @Summary Create cadastral extract
+// @Description API for generating a PDF cadastral extract of an entity. The PDF is attached to the entity as a cadastral_extract document, earlier extracts are kept as older versions. With owner it lists the applicant who filed the latest draft of the entity. It carries a verification code and a QR code for /v1/verify.
+// @Tags entity_document
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param extract body models.CreateEntityExtractSwag true "extract"
+// @Success 201 {object} models.EntityFiles
+func (h *handlerV1) CreateEntityExtract(c *gin.Context) {
+	var (
+		extract models.CreateEntityExtractSwag
+	)
+	userInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityExtract.Create.ParseEntityId", err) {
+		return
+	}
+	// the body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&extract); HandleHTTPError(c, http.StatusBadRequest, "EntityExtract.Create.BindingJson", err) {
+			return
+		}
+	}
+	lang := i18n.Normalize(extract.Lang)
+	if extract.Lang != "" && lang == "" {
+		HandleHTTPError(c, http.StatusBadRequest, "EntityExtract.Create.Lang",
+			errors.New("lang must be one of "+strings.Join(i18n.Languages, ", ")))
+		return
+	}
+	if lang == "" {
+		lang = requestLanguage(c)
+	}
+	if lang == "" {
+		lang = i18n.Default
+	}
+
+	entity, err := h.storage.Entity().Get(context.Background(), entityID.Hex())
+	if handleStorageError(c, "EntityExtract.Create.GetEntity", err) {
+		return
+	}
+	i18n.LocalizeEntityProperties(entity.EntityProperty, lang)
+
+	code, err := docgen.NewVerificationCode()
+	if HandleHTTPError(c, http.StatusInternalServerError, "EntityExtract.Create.NewVerificationCode", err) {
+		return
+	}
+
+	document := &docgen.CadastralExtract{
+		Lang:         lang,
+		EntityNumber: entity.EntityNumber,
+		Address:      entity.Address,
+		Soato:        entity.EntitySoato,
+		Thumbnail:    h.extractThumbnail(entity.EntityGallery),
+		IssuedAt:     time.Now(),
+		IssuedBy:     userInfo.Login,
+
+		VerificationCode: code,
+		VerificationURL:  h.cfg.DocumentVerifyURL + code,
+	}
+	if entity.City != nil {
+		document.City = docgen.Place{Name: placeName(lang, entity.City.Name, entity.City.RuName), Soato: entity.City.Soato}
+	}
+	if entity.Region != nil {
+		document.Region = docgen.Place{Name: placeName(lang, entity.Region.Name, entity.Region.RuName), Soato: entity.Region.Soato}
+	}
+	if entity.District != nil {
+		document.District = docgen.Place{Name: placeName(lang, entity.District.Name, entity.District.RuName), Soato: entity.District.Soato}
+	}
+	for _, entityProperty := range entity.EntityProperty {
+		if entityProperty == nil || entityProperty.Property == nil || entityProperty.Value == "" {
+			continue
+		}
+		document.Properties = append(document.Properties, docgen.Property{
+			Label: entityProperty.Property.Label,
+			Value: entityProperty.Value,
+		})
+	}
+
+	if extract.Owner {
+		applicantID, err := h.storage.EntityDraft().GetEntityApplicant(context.Background(), entityID.Hex())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			HandleHTTPError(c, http.StatusConflict, "EntityExtract.Create.GetEntityApplicant", errExtractNoApplicant)
+			return
+		}
+		if HandleHTTPError(c, http.StatusBadRequest, "EntityExtract.Create.GetEntityApplicant", err) {
+			return
+		}
+		applicant, err := h.storage.Applicant().Get(context.Background(), applicantID)
+		if handleStorageError(c, "EntityExtract.Create.GetApplicant", err) {
+			return
+		}
+		fullName := applicant.FullName
+		if fullName == "" {
+			fullName = strings.Join(strings.Fields(applicant.LastName+" "+applicant.FirstName+" "+applicant.MiddleName), " ")
+		}
+		document.Owner = &docgen.Owner{
+			FullName:       fullName,
+			Pin:            applicant.Pin,
+			Inn:            applicant.Inn,
+			PassportNumber: applicant.PassportNumber,
+			Address:        applicant.PermanentAddress,
+		}
+	}
+
+	var content bytes.Buffer
+	err = document.Render(&content, h.documentFont)
+	if HandleHTTPError(c, http.StatusInternalServerError, "EntityExtract.Create.Render", err) {
+		return
+	}
+
+	checksum := sha256.Sum256(content.Bytes())
+
+	objectName := primitive.NewObjectID().Hex() + ".pdf"
+	err = h.fileStore.Put(context.Background(), objectName, bytes.NewReader(content.Bytes()), int64(content.Len()), "application/pdf")
+	if HandleHTTPError(c, http.StatusInternalServerError, "EntityExtract.Create.Put", err) {
+		return
+	}
+
+	fileName := "cadastral-extract"
+	if entity.EntityNumber != "" {
+		fileName += "-" + entity.EntityNumber
+	}
+	fileID, err := h.storage.EntityFiles().Create(context.Background(), &models.CreateEntityFiles{
+		ID:          primitive.NewObjectID(),
+		FileName:    strings.ReplaceAll(fileName, ":", "-") + ".pdf",
+		User:        userInfo.ID,
+		ObjectName:  objectName,
+		ContentType: "application/pdf",
+		Size:        int64(content.Len()),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		h.deleteRejectedUpload(objectName)
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityExtract.Create.CreateEntityFiles", err) {
+		return
+	}
+
+	// the extract is verifiable before it is attached, what was made so far
+	// is removed when attaching fails so no orphan code or file is left
+	err = h.storage.IssuedDocument().Create(context.Background(), &models.IssuedDocument{
+		Code:         code,
+		DocumentType: models.DocumentTypeCadastralExtract,
+		EntityID:     entityID.Hex(),
+		EntityNumber: entity.EntityNumber,
+		FileID:       fileID,
+		ObjectName:   objectName,
+		SHA256:       hex.EncodeToString(checksum[:]),
+		Size:         int64(content.Len()),
+		IssuedBy:     userInfo.ID,
+		IssuedAt:     document.IssuedAt,
+	})
+	if err != nil {
+		h.discardExtract("", fileID, objectName)
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityExtract.Create.CreateIssuedDocument", err) {
+		return
+	}
+
+	attach := &models.AttachEntityFile{
+		EntityID:     entityID,
+		DocumentType: models.DocumentTypeCadastralExtract,
+		User:         userInfo.ID,
+	}
+	attach.FileID, err = primitive.ObjectIDFromHex(fileID)
+	if HandleHTTPError(c, http.StatusInternalServerError, "EntityExtract.Create.ParseFileId", err) {
+		return
+	}
+	response, err := h.storage.EntityFiles().Attach(context.Background(), attach)
+	if err != nil {
+		h.discardExtract(code, fileID, objectName)
+	}
+	if handleStorageError(c, "EntityExtract.Create.Attach", err) {
+		return
+	}
+	if h.syncEntityFiles(c, attach) {
+		return
+	}
+	h.presignEntityFiles([]*models.EntityFiles{response})
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// discardExtract removes the verification code, the file record and the
+// object of an extract that could not be issued
+func (h *handlerV1) discardExtract(code, fileID, objectName string) {
+	if code != "" {
+		if err := h.storage.IssuedDocument().Delete(context.Background(), code); err != nil {
+			h.log.Error("error while deleting issued document", logger.String("code", code), logger.Error(err))
+		}
+	}
+	if err := h.storage.EntityFiles().Delete(context.Background(), fileID); err != nil {
+		h.log.Error("error while deleting extract file", logger.String("file_id", fileID), logger.Error(err))
+	}
+	h.deleteRejectedUpload(objectName)
+}
+
+// extractThumbnail returns the small thumbnail of the first gallery image
+// that has one, generating it when the worker has not done it yet. Missing
+// images are not an error, the extract is issued without a picture.
+func (h *handlerV1) extractThumbnail(gallery []string) []byte {
+	for _, name := range gallery {
+		if !thumbnail.Supported(name) || strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+			continue
+		}
+		object, _, err := h.fileStore.Get(context.Background(), thumbnail.Name(name, thumbnail.Small))
+		if err == nil {
+			content, err := ioutil.ReadAll(object)
+			object.Close()
+			if err == nil {
+				return content
+			}
+		}
+
+		object, _, err = h.fileStore.Get(context.Background(), name)
+		if errors.Is(err, filestore.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			h.log.Warn("error while reading extract image", logger.String("name", name), logger.Error(err))
+			continue
+		}
+		thumbnails, err := thumbnail.GenerateFrom(object)
+		object.Close()
+		if err != nil {
+			h.log.Warn("error while generating extract thumbnail", logger.String("name", name), logger.Error(err))
+			continue
+		}
+		return thumbnails[thumbnail.Small]
+	}
+	return nil
+}
+
+// placeName picks the Russian name of a city, region or district for Russian
+// documents
+func placeName(lang, name, ruName string) string {
+	if lang == i18n.Ru && ruName != "" {
+		return ruName
+	}
+	return name
+}
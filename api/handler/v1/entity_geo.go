@@ -0,0 +1,258 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/geo"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxNearRadius keeps a near query inside a hemisphere, $centerSphere does
+// not take more
+const maxNearRadius = 10000000
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/location [put]
+// @Summary Update entity draft location
+// @Description API for setting where the entity of the draft is. A GeoJSON Point sets the location, it has to be in the district of the draft. A Polygon or MultiPolygon sets the boundary, it is checked against the declared area and the boundaries of entities and kept with what was found or rejected with 409 when a rule blocks it.
+// @Tags entity-draft
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
+// @Param location body models.GeoPoint true "Point, Polygon or MultiPolygon"
+// @Success 200 {object} models.GeoPoint
+func (h *handlerV1) UpdateEntityDraftLocation(c *gin.Context) {
+	if _, err := h.UserInfo(c, true); err != nil {
+		return
+	}
+	entityDraftID, err := primitive.ObjectIDFromHex(c.Param("entity_draft_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.UpdateLocation.ParseEntityDraftId", err) {
+		return
+	}
+	location, boundary, err := h.bindGeometry(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.UpdateLocation.BindingJson", err) {
+		return
+	}
+	draft, err := h.storage.EntityDraft().Get(context.Background(), entityDraftID.Hex())
+	if handleStorageError(c, "EntityDraft.UpdateLocation.GetEntityDraft", err) {
+		return
+	}
+	if draft.Status != models.EntityDraftStatusInProgress {
+		HandleHTTPError(c, http.StatusConflict, "EntityDraft.UpdateLocation", errEntityDraftNotInProgress)
+		return
+	}
+
+	if location != nil {
+		_, err = h.deriveAdminUnits(context.Background(), location, &models.AdminUnits{
+			City:     draft.City,
+			Region:   draft.Region,
+			District: draft.District,
+		})
+		if handleAdminUnitsError(c, "EntityDraft.UpdateLocation.DeriveAdminUnits", err) {
+			return
+		}
+		err = h.storage.EntityDraft().UpdateLocation(context.Background(), entityDraftID.Hex(), location)
+		if handleStorageError(c, "EntityDraft.UpdateLocation", err) {
+			return
+		}
+		c.JSON(http.StatusOK, location)
+		return
+	}
+	topology, err := h.draftTopology(context.Background(), boundary, draft)
+	if handleTopologyError(c, "EntityDraft.UpdateBoundary.CheckTopology", err) {
+		return
+	}
+	err = h.storage.EntityDraft().UpdateBoundary(context.Background(), entityDraftID.Hex(), boundary, topology)
+	if handleStorageError(c, "EntityDraft.UpdateBoundary", err) {
+		return
+	}
+	c.JSON(http.StatusOK, boundary)
+}
+
+// @Router /v1/crs [get]
+// @Summary Get coordinate reference systems
+// @Description API for listing the systems the crs parameter takes. Projected coordinates are easting and northing.
+// @Tags geo
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.CRS
+func (h *handlerV1) GetAllCRS(c *gin.Context) {
+	systems := h.crs.All()
+	response := make([]*models.CRS, 0, len(systems))
+	for _, crs := range systems {
+		response = append(response, &models.CRS{
+			Code:       crs.Code,
+			Name:       crs.Name,
+			Definition: crs.Definition,
+			Projected:  crs.Projected(),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// bindGeometry reads a validated GeoJSON Point or a Polygon or MultiPolygon
+// boundary in the crs of the request from the body, one of the two is
+// returned in WGS84
+func (h *handlerV1) bindGeometry(c *gin.Context) (*models.GeoPoint, *models.GeoBoundary, error) {
+	var geometry struct {
+		Type string `json:"type"`
+	}
+	crs, err := h.requestCRS(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, nil, err
+	}
+
+	switch geometry.Type {
+	case models.GeoJSONPoint:
+		var location *models.GeoPoint
+		if err := json.Unmarshal(data, &location); err != nil {
+			return nil, nil, err
+		}
+		location = location.Transform(crs.ToWGS84)
+		return location, nil, location.Validate()
+	case models.GeoJSONPolygon, models.GeoJSONMultiPolygon:
+		var boundary *models.GeoBoundary
+		if err := json.Unmarshal(data, &boundary); err != nil {
+			return nil, nil, err
+		}
+		boundary = boundary.Transform(crs.ToWGS84)
+		return nil, boundary, boundary.Validate()
+	}
+	return nil, nil, errors.New("type must be Point, Polygon or MultiPolygon")
+}
+
+// requestCRS is the crs query parameter, WGS84 when it is left out
+func (h *handlerV1) requestCRS(c *gin.Context) (*geo.CRS, error) {
+	return h.crs.Get(c.Query("crs"))
+}
+
+// geometryToCRS converts the stored WGS84 geometry for a response, geometry
+// that is not there stays nil
+func geometryToCRS(crs *geo.CRS, location *models.GeoPoint, boundary *models.GeoBoundary) (*models.GeoPoint, *models.GeoBoundary) {
+	if location != nil {
+		location = location.Transform(crs.FromWGS84)
+	}
+	if boundary != nil {
+		boundary = boundary.Transform(crs.FromWGS84)
+	}
+	return location, boundary
+}
+
+// geometryFromCRS converts geometry sent in the crs of the request to WGS84
+func geometryFromCRS(crs *geo.CRS, location *models.GeoPoint, boundary *models.GeoBoundary) (*models.GeoPoint, *models.GeoBoundary) {
+	if location != nil {
+		location = location.Transform(crs.ToWGS84)
+	}
+	if boundary != nil {
+		boundary = boundary.Transform(crs.ToWGS84)
+	}
+	return location, boundary
+}
+
+// validateGeometry checks the location and boundary sent with an entity or
+// draft, both may be left out
+func validateGeometry(location *models.GeoPoint, boundary *models.GeoBoundary) error {
+	if location != nil {
+		if err := location.Validate(); err != nil {
+			return fmt.Errorf("location: %w", err)
+		}
+	}
+	if boundary != nil {
+		if err := boundary.Validate(); err != nil {
+			return fmt.Errorf("boundary: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseEntityQuery reads the filters of the entity list and export, both
+// take the same query parameters
+func parseEntityQuery(c *gin.Context) (*models.GetAllEntitiesRequest, error) {
+	request := &models.GetAllEntitiesRequest{
+		CityID:       c.Query("city_id"),
+		RegionID:     c.Query("region_id"),
+		DistrictID:   c.Query("district_id"),
+		EntitySoato:  c.Query("entity_soato"),
+		EntityNumber: c.Query("entity_number"),
+	}
+	if err := parseGeoQuery(c, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// parseGeoQuery fills the geo filters of the request from the bbox, near,
+// radius and within query parameters
+func parseGeoQuery(c *gin.Context, request *models.GetAllEntitiesRequest) error {
+	if bbox := c.Query("bbox"); bbox != "" {
+		values, err := parseFloats(bbox, 4)
+		if err != nil {
+			return fmt.Errorf("bbox: %w", err)
+		}
+		minLon, minLat, maxLon, maxLat := values[0], values[1], values[2], values[3]
+		if minLon >= maxLon || minLat >= maxLat ||
+			models.NewGeoPoint(minLat, minLon).Validate() != nil || models.NewGeoPoint(maxLat, maxLon).Validate() != nil {
+			return errors.New("bbox must be min longitude, min latitude, max longitude, max latitude")
+		}
+		request.BBox = values
+	}
+
+	if near := c.Query("near"); near != "" {
+		values, err := parseFloats(near, 2)
+		if err != nil {
+			return fmt.Errorf("near: %w", err)
+		}
+		request.Near = models.NewGeoPoint(values[1], values[0])
+		if err := request.Near.Validate(); err != nil {
+			return fmt.Errorf("near: %w", err)
+		}
+		request.Radius, err = strconv.ParseFloat(c.Query("radius"), 64)
+		if err != nil || !(request.Radius > 0 && request.Radius <= maxNearRadius) {
+			return fmt.Errorf("radius must be meters up to %d with near", maxNearRadius)
+		}
+	}
+
+	if within := c.Query("within"); within != "" {
+		request.Within = &models.GeoBoundary{}
+		if err := json.Unmarshal([]byte(within), request.Within); err != nil {
+			return fmt.Errorf("within: %w", err)
+		}
+		if err := request.Within.Validate(); err != nil {
+			return fmt.Errorf("within: %w", err)
+		}
+	}
+	return nil
+}
+
+func parseFloats(value string, count int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("%d comma separated numbers expected", count)
+	}
+	values := make([]float64, count)
+	for i, part := range parts {
+		var err error
+		values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
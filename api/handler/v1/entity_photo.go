@@ -0,0 +1,217 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/exif"
+	"github.com/e-space-uz/backend/pkg/geo"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/location [put]
+// @Summary Update entity location
+// @Description API for setting where the entity is. A GeoJSON Point sets the location, it has to be in the district of the entity and gallery photos taken farther than the configured distance from it are flagged. A Polygon or MultiPolygon sets the boundary, it is checked against the declared area and the boundaries of other entities and kept with what was found or rejected with 409 when a rule blocks it.
+// @Tags entity
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param crs query string false "coordinate reference system of the geometry, EPSG:4326 by default"
+// @Param location body models.GeoPoint true "Point, Polygon or MultiPolygon"
+// @Success 200 {object} models.GeoPoint
+func (h *handlerV1) UpdateEntityLocation(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.UpdateLocation.ParseEntityId", err) {
+		return
+	}
+	location, boundary, err := h.bindGeometry(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.UpdateLocation.BindingJson", err) {
+		return
+	}
+
+	entity, err := h.storage.Entity().Get(context.Background(), entityID.Hex())
+	if handleStorageError(c, "Entity.UpdateLocation.GetEntity", err) {
+		return
+	}
+
+	if location != nil {
+		// the soato is part of the entity number, the entity can not move to
+		// another district
+		_, err = h.deriveAdminUnits(context.Background(), location, &models.AdminUnits{
+			City:     entity.City,
+			Region:   entity.Region,
+			District: entity.District,
+		})
+		if handleAdminUnitsError(c, "Entity.UpdateLocation.DeriveAdminUnits", err) {
+			return
+		}
+		err = h.storage.Entity().UpdateLocation(context.Background(), entityID.Hex(), location)
+		if handleStorageError(c, "Entity.UpdateLocation", err) {
+			return
+		}
+		c.JSON(http.StatusOK, location)
+		return
+	}
+	topology, err := h.entityTopology(context.Background(), boundary, entity)
+	if handleTopologyError(c, "Entity.UpdateBoundary.CheckTopology", err) {
+		return
+	}
+	err = h.storage.Entity().UpdateBoundary(context.Background(), entityID.Hex(), boundary, topology)
+	if handleStorageError(c, "Entity.UpdateBoundary", err) {
+		return
+	}
+	c.JSON(http.StatusOK, boundary)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/inspection [put]
+// @Summary Update entity inspection window
+// @Description API for setting when the entity is inspected. Gallery photos taken outside the window are flagged.
+// @Tags entity
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Param inspection body models.UpdateEntityInspectionSwag true "inspection"
+// @Success 200 {object} models.EntityInspection
+func (h *handlerV1) UpdateEntityInspection(c *gin.Context) {
+	var (
+		inspection models.UpdateEntityInspectionSwag
+	)
+	userInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.UpdateInspection.ParseEntityId", err) {
+		return
+	}
+	if err := c.ShouldBindJSON(&inspection); HandleHTTPError(c, http.StatusBadRequest, "Entity.UpdateInspection.BindingJson", err) {
+		return
+	}
+	if !inspection.EndsAt.After(inspection.StartsAt) {
+		HandleHTTPError(c, http.StatusBadRequest, "Entity.UpdateInspection", errors.New("ends_at must be after starts_at"))
+		return
+	}
+
+	response := &models.EntityInspection{
+		StartsAt:  inspection.StartsAt,
+		EndsAt:    inspection.EndsAt,
+		UpdatedBy: userInfo.ID,
+		UpdatedAt: time.Now(),
+	}
+	err = h.storage.Entity().UpdateInspection(context.Background(), entityID.Hex(), response)
+	if handleStorageError(c, "Entity.UpdateInspection", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// photoMetadata reads the EXIF of an uploaded image. Files without EXIF,
+// which includes every format but JPEG, get metadata with HasExif false.
+func (h *handlerV1) photoMetadata(objectName string, file io.Reader) *models.PhotoMetadata {
+	metadata := &models.PhotoMetadata{
+		ObjectName: objectName,
+		CreatedAt:  time.Now(),
+	}
+	data, err := exif.Read(file)
+	if err != nil {
+		return metadata
+	}
+
+	metadata.HasExif = true
+	metadata.Make = data.Make
+	metadata.Model = data.Model
+	metadata.Software = data.Software
+	if data.HasGPS {
+		metadata.Location = models.NewGeoPoint(data.Latitude, data.Longitude)
+	}
+	if data.HasAltitude {
+		altitude := data.Altitude
+		metadata.Altitude = &altitude
+	}
+	zone := time.FixedZone("", int(h.cfg.PhotoUTCOffset.Seconds()))
+	if capturedAt, ok := data.CapturedAt(zone); ok {
+		metadata.CapturedAt = &capturedAt
+	}
+	return metadata
+}
+
+// galleryPhotos checks the metadata of the gallery against the location and
+// the inspection window of the entity, photos without metadata are
+// unverified
+func (h *handlerV1) galleryPhotos(entity *models.Entity) []*models.GalleryPhoto {
+	photos := make([]*models.GalleryPhoto, 0, len(entity.EntityGallery))
+	if len(entity.EntityGallery) == 0 {
+		return photos
+	}
+
+	metadata, err := h.storage.PhotoMetadata().GetByObjectNames(context.Background(), entity.EntityGallery)
+	if err != nil {
+		h.log.Error("error while getting photo metadata", logger.Error(err))
+	}
+	byName := make(map[string]*models.PhotoMetadata, len(metadata))
+	for _, m := range metadata {
+		byName[m.ObjectName] = m
+	}
+
+	for i, name := range entity.EntityGallery {
+		photo := &models.GalleryPhoto{
+			Name:     name,
+			Metadata: byName[name],
+			Flags:    []string{},
+		}
+		if i < len(entity.EntityGalleryURLs) {
+			photo.URL = entity.EntityGalleryURLs[i]
+		}
+		switch {
+		case err != nil:
+			// flags can not be told without the metadata
+		case photo.Metadata == nil:
+			// only /v1/image-upload reads metadata, a photo that came any
+			// other way can not be told from a borrowed one
+			photo.Flags = append(photo.Flags, models.PhotoFlagUnverified)
+		default:
+			h.flagPhoto(photo, entity)
+		}
+		photos = append(photos, photo)
+	}
+	return photos
+}
+
+func (h *handlerV1) flagPhoto(photo *models.GalleryPhoto, entity *models.Entity) {
+	metadata := photo.Metadata
+	if !metadata.HasExif {
+		photo.Flags = append(photo.Flags, models.PhotoFlagNoExif)
+		return
+	}
+
+	if metadata.Location == nil {
+		photo.Flags = append(photo.Flags, models.PhotoFlagNoLocation)
+	} else if entity.Location != nil && entity.Location.Validate() == nil {
+		distance := geo.Distance(
+			metadata.Location.Latitude(), metadata.Location.Longitude(),
+			entity.Location.Latitude(), entity.Location.Longitude())
+		photo.DistanceMeters = &distance
+		if distance > h.cfg.PhotoMaxDistance {
+			photo.Flags = append(photo.Flags, models.PhotoFlagTooFar)
+		}
+	}
+
+	if metadata.CapturedAt == nil {
+		photo.Flags = append(photo.Flags, models.PhotoFlagNoCaptureTime)
+	} else if entity.Inspection != nil &&
+		(metadata.CapturedAt.Before(entity.Inspection.StartsAt) || metadata.CapturedAt.After(entity.Inspection.EndsAt)) {
+		photo.Flags = append(photo.Flags, models.PhotoFlagOutsideInspectionWindow)
+	}
+}
@@ -0,0 +1,310 @@
+package v1
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/filecheck"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// objectNamePattern matches the names given out by CreateUploadURL
+var objectNamePattern = regexp.MustCompile(`^[0-9a-f]{24}(\.[0-9A-Za-z]{1,10})?$`)
+
+var (
+	errFileRegistered = errors.New("file is already registered")
+	errUploadNotOwn   = errors.New("upload url was given to another user")
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/files/upload-url [post]
+// @Summary Create upload URL
+// @Description API for getting a presigned URL to upload a file straight to the file store. The upload has to be a PUT with the returned headers, then it is registered with /v1/files/confirm.
+// @Tags file
+// @Accept json
+// @Produce json
+// @Param upload body models.UploadURLSwag true "upload"
+// @Success 200 {object} models.UploadURLResponse
+func (h *handlerV1) CreateUploadURL(c *gin.Context) {
+	var (
+		upload models.UploadURLSwag
+	)
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&upload); HandleHTTPError(c, http.StatusBadRequest, "File.CreateUploadURL.BindingJson", err) {
+		return
+	}
+
+	contentType, err := h.allowedContentType(upload.ContentType)
+	if HandleHTTPError(c, http.StatusBadRequest, "File.CreateUploadURL.ContentType", err) {
+		return
+	}
+	if upload.Size <= 0 || upload.Size > h.cfg.MaxUploadSize {
+		HandleHTTPError(c, http.StatusBadRequest, "File.CreateUploadURL.Size",
+			fmt.Errorf("size must be between 1 and %d bytes", h.cfg.MaxUploadSize))
+		return
+	}
+
+	objectName := primitive.NewObjectID().Hex() + strings.ToLower(filepath.Ext(upload.FileName))
+	if !objectNamePattern.MatchString(objectName) {
+		objectName = primitive.NewObjectID().Hex()
+	}
+
+	uploadURL, err := h.fileStore.PresignPut(context.Background(), objectName, h.cfg.UploadURLExpiry, contentType, upload.Size)
+	if HandleHTTPError(c, http.StatusInternalServerError, "File.CreateUploadURL.PresignPut", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.UploadURLResponse{
+		ObjectName:  objectName,
+		UploadURL:   uploadURL,
+		Method:      http.MethodPut,
+		UploadToken: h.uploadToken(userInfo.ID, objectName),
+		Headers: map[string]string{
+			"Content-Type":   contentType,
+			"Content-Length": strconv.FormatInt(upload.Size, 10),
+		},
+		ExpiresAt: time.Now().Add(h.cfg.UploadURLExpiry),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/files/confirm [post]
+// @Summary Confirm upload
+// @Description API for registering a file uploaded to a presigned URL, so it can be attached to entities. The file is registered under a new object name and the uploaded one is deleted.
+// @Tags file
+// @Accept json
+// @Produce json
+// @Param confirm body models.ConfirmUploadSwag true "confirm"
+// @Success 201 {object} models.EntityFiles
+func (h *handlerV1) ConfirmUpload(c *gin.Context) {
+	var (
+		confirm models.ConfirmUploadSwag
+	)
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&confirm); HandleHTTPError(c, http.StatusBadRequest, "File.ConfirmUpload.BindingJson", err) {
+		return
+	}
+	if !objectNamePattern.MatchString(confirm.ObjectName) {
+		HandleHTTPError(c, http.StatusBadRequest, "File.ConfirmUpload", filestore.ErrInvalidName)
+		return
+	}
+	expected := h.uploadToken(userInfo.ID, confirm.ObjectName)
+	if !hmac.Equal([]byte(expected), []byte(confirm.UploadToken)) {
+		HandleHTTPError(c, http.StatusForbidden, "File.ConfirmUpload", errUploadNotOwn)
+		return
+	}
+
+	// the upload url stays valid after the upload, so the file is checked
+	// and registered under a name the client can not write to
+	storedName := primitive.NewObjectID().Hex() + filepath.Ext(confirm.ObjectName)
+	err = h.fileStore.Copy(context.Background(), confirm.ObjectName, storedName)
+	if errors.Is(err, filestore.ErrNotFound) {
+		HandleHTTPError(c, http.StatusNotFound, "File.ConfirmUpload", errors.New("file has not been uploaded"))
+		return
+	}
+	if HandleHTTPError(c, http.StatusInternalServerError, "File.ConfirmUpload.Copy", err) {
+		return
+	}
+	if err := h.fileStore.Delete(context.Background(), confirm.ObjectName); err != nil {
+		h.log.Error("error while deleting confirmed upload", logger.Error(err))
+	}
+
+	info, err := h.fileStore.Stat(context.Background(), storedName)
+	if HandleHTTPError(c, http.StatusInternalServerError, "File.ConfirmUpload.Stat", err) {
+		return
+	}
+
+	response, ok := h.registerStoredFile(c, info, &models.CreateEntityFiles{
+		FileName: confirm.FileName,
+		Comment:  confirm.Comment,
+		User:     userInfo.ID,
+	}, h.fileUploadPolicy())
+	if !ok {
+		return
+	}
+	h.presignEntityFiles([]*models.EntityFiles{response})
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// registerStoredFile checks a file that is in the file store already and
+// registers it in EntityFilesCollection. It returns false when the response
+// has been written already.
+func (h *handlerV1) registerStoredFile(c *gin.Context, info *filestore.ObjectInfo, entityFiles *models.CreateEntityFiles, policy filecheck.Policy) (*models.EntityFiles, bool) {
+	if !h.checkStoredFile(c, info, entityFiles, policy) {
+		return nil, false
+	}
+	id, err := h.storage.EntityFiles().Create(context.Background(), entityFiles)
+	if mongo.IsDuplicateKeyError(err) {
+		// another request registered the object meanwhile
+		HandleHTTPError(c, http.StatusConflict, "File.RegisterStoredFile.Create", errFileRegistered)
+		return nil, false
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "File.RegisterStoredFile.Create", err) {
+		return nil, false
+	}
+
+	response, err := h.storage.EntityFiles().Get(context.Background(), id)
+	if HandleHTTPError(c, http.StatusBadRequest, "File.RegisterStoredFile.Get", err) {
+		return nil, false
+	}
+	return response, true
+}
+
+// checkStoredFile checks a file that is in the file store already and fills
+// in what entityFiles gets from it. It returns false when the response has
+// been written already.
+func (h *handlerV1) checkStoredFile(c *gin.Context, info *filestore.ObjectInfo, entityFiles *models.CreateEntityFiles, policy filecheck.Policy) bool {
+	object, _, err := h.fileStore.Get(context.Background(), info.Name)
+	if HandleHTTPError(c, http.StatusInternalServerError, "File.CheckStoredFile.Get", err) {
+		return false
+	}
+	defer object.Close()
+	file, ok := object.(io.ReadSeeker)
+	if !ok {
+		HandleHTTPError(c, http.StatusInternalServerError, "File.CheckStoredFile", errors.New("file store returned a reader that can not seek"))
+		return false
+	}
+	contentType, ok := h.checkUpload(c, info.Name, file, info.Size, info.ContentType, policy, true)
+	if !ok {
+		return false
+	}
+
+	if signedContentTypes[contentType] {
+		entityFiles.Signature = h.attachedSignature(file, info.Size)
+	}
+
+	entityFiles.ID = primitive.NewObjectID()
+	entityFiles.ObjectName = info.Name
+	entityFiles.ContentType = contentType
+	entityFiles.Size = info.Size
+	entityFiles.CreatedAt = time.Now()
+	entityFiles.UpdatedAt = time.Now()
+	return true
+}
+
+// uploadToken signs the object name given out by CreateUploadURL for the
+// user, so only that user can confirm the upload
+func (h *handlerV1) uploadToken(userID, objectName string) string {
+	mac := hmac.New(sha256.New, []byte(h.cfg.LoginSecretAccessKey))
+	fmt.Fprintf(mac, "upload\n%s\n%s", userID, objectName)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// allowedContentType returns the media type without parameters when it is
+// one of the configured upload content types
+func (h *handlerV1) allowedContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	for _, allowed := range h.cfg.AllowedUploadContentTypes {
+		if strings.EqualFold(strings.TrimSpace(allowed), mediaType) {
+			return mediaType, nil
+		}
+	}
+	return "", fmt.Errorf("content type %s is not allowed", mediaType)
+}
+
+// presignEntityFiles replaces the urls of files kept in the file store with
+// presigned ones. Files registered before carry a public url and are left.
+func (h *handlerV1) presignEntityFiles(files []*models.EntityFiles) {
+	for _, file := range files {
+		if file == nil || file.ObjectName == "" {
+			continue
+		}
+		url, err := h.fileStore.PresignGet(context.Background(), file.ObjectName, h.cfg.FileURLExpiry)
+		if err != nil {
+			h.log.Error("error while presigning entity file url", logger.Error(err))
+			continue
+		}
+		file.Url = url
+	}
+}
+
+// presignGallery returns presigned urls for gallery file names, full urls
+// stored by older clients are returned as they are
+func (h *handlerV1) presignGallery(gallery []string) []string {
+	urls := make([]string, 0, len(gallery))
+	for _, name := range gallery {
+		if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+			urls = append(urls, name)
+			continue
+		}
+		url, err := h.fileStore.PresignGet(context.Background(), name, h.cfg.FileURLExpiry)
+		if err != nil {
+			h.log.Error("error while presigning gallery url", logger.Error(err))
+			url = ""
+		}
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// thumbnailURL returns the small thumbnail of the first gallery image
+func (h *handlerV1) thumbnailURL(gallery []string) string {
+	if len(gallery) == 0 {
+		return ""
+	}
+	return h.presignThumbnails(gallery[:1], thumbnail.Small)[0]
+}
+
+// presignThumbnails returns presigned urls of the thumbnails of size. Images
+// without thumbnails yet get them queued and their original url is returned
+// meanwhile, so are images the worker could not decode. The file store is
+// only asked about images the worker knows nothing of.
+func (h *handlerV1) presignThumbnails(gallery []string, size int) []string {
+	names := make([]string, 0, len(gallery))
+	for _, name := range gallery {
+		if !thumbnail.Supported(name) || strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+			names = append(names, name)
+			continue
+		}
+		switch h.thumbnails.State(name) {
+		case thumbnail.Ready:
+			names = append(names, thumbnail.Name(name, size))
+			continue
+		case thumbnail.Failed:
+			names = append(names, name)
+			continue
+		}
+		_, err := h.fileStore.Stat(context.Background(), thumbnail.Name(name, size))
+		if errors.Is(err, filestore.ErrNotFound) {
+			h.thumbnails.Enqueue(name)
+			names = append(names, name)
+			continue
+		}
+		if err != nil {
+			h.log.Error("error while getting thumbnail", logger.Error(err))
+			names = append(names, name)
+			continue
+		}
+		h.thumbnails.SetState(name, thumbnail.Ready)
+		names = append(names, thumbnail.Name(name, size))
+	}
+	return h.presignGallery(names)
+}
@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/cms"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDetachedSignatureSize is far above what a signature with a handful of
+// certificates takes
+const maxDetachedSignatureSize = 1 << 20
+
+// signedContentTypes are the files that are signatures themselves
+var signedContentTypes = map[string]bool{
+	"application/pkcs7-mime":      true,
+	"application/pkcs7-signature": true,
+}
+
+// signatureStatuses orders the statuses from the worst, the signature gets
+// the worst status of its signers
+var signatureStatuses = []string{
+	models.SignatureStatusInvalid,
+	models.SignatureStatusRevoked,
+	models.SignatureStatusExpired,
+	models.SignatureStatusUntrusted,
+	models.SignatureStatusUnsupported,
+	models.SignatureStatusValid,
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/files/{file_id}/signature [post]
+// @Summary Verify detached signature
+// @Description API for verifying a detached CMS signature, such as the one E-IMZO creates, against a registered file. Only the user who uploaded the file and staff can send one. The result is stored with the file, a signature that does not verify is stored too with its status.
+// @Tags file
+// @Accept json
+// @Produce json
+// @Param file_id path string true "file_id"
+// @Param signature body models.FileSignatureSwag true "signature"
+// @Success 200 {object} models.EntityFiles
+func (h *handlerV1) VerifyFileSignature(c *gin.Context) {
+	var (
+		signature models.FileSignatureSwag
+	)
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&signature); HandleHTTPError(c, http.StatusBadRequest, "File.VerifySignature.BindingJson", err) {
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(signature.Signature), ""))
+	if HandleHTTPError(c, http.StatusBadRequest, "File.VerifySignature.Decode", err) {
+		return
+	}
+	if len(data) > maxDetachedSignatureSize {
+		HandleHTTPError(c, http.StatusBadRequest, "File.VerifySignature", errors.New("signature is too large"))
+		return
+	}
+	signedData, err := cms.Parse(data)
+	if HandleHTTPError(c, http.StatusBadRequest, "File.VerifySignature.Parse", err) {
+		return
+	}
+	if !signedData.Detached() {
+		HandleHTTPError(c, http.StatusBadRequest, "File.VerifySignature", errors.New("signature carries its own content, upload it as a file"))
+		return
+	}
+
+	file, err := h.storage.EntityFiles().Get(context.Background(), c.Param("file_id"))
+	if handleStorageError(c, "File.VerifySignature.Get", err) {
+		return
+	}
+	if file.User != userInfo.ID && userInfo.UserType != "staff" {
+		HandleHTTPError(c, http.StatusForbidden, "File.VerifySignature.FileOwner", errors.New("only the owner of the file or staff can sign it"))
+		return
+	}
+	if file.ObjectName == "" {
+		HandleHTTPError(c, http.StatusBadRequest, "File.VerifySignature", errors.New("file is not kept in the file store"))
+		return
+	}
+	object, _, err := h.fileStore.Get(context.Background(), file.ObjectName)
+	if errors.Is(err, filestore.ErrNotFound) {
+		HandleHTTPError(c, http.StatusNotFound, "File.VerifySignature.GetObject", errors.New("file is not stored anymore"))
+		return
+	}
+	if HandleHTTPError(c, http.StatusInternalServerError, "File.VerifySignature.GetObject", err) {
+		return
+	}
+	defer object.Close()
+
+	result, err := h.verifySignature(signedData, object, time.Time{})
+	if HandleHTTPError(c, http.StatusInternalServerError, "File.VerifySignature.Verify", err) {
+		return
+	}
+	result.Data = data
+	result.VerifiedBy = userInfo.ID
+
+	err = h.storage.EntityFiles().SetSignature(context.Background(), file.ID, result)
+	if handleStorageError(c, "File.VerifySignature.SetSignature", err) {
+		return
+	}
+	file.Signature = result
+	h.presignEntityFiles([]*models.EntityFiles{file})
+
+	c.JSON(http.StatusOK, file)
+}
+
+// attachedSignature verifies a signed file being registered. Files that do
+// not verify are kept, the status tells what is wrong with them.
+func (h *handlerV1) attachedSignature(file io.Reader, size int64) *models.FileSignature {
+	if size > h.cfg.SignatureMaxSize {
+		return &models.FileSignature{
+			Status:     models.SignatureStatusUnsupported,
+			Error:      fmt.Sprintf("signed files over %d bytes are not verified", h.cfg.SignatureMaxSize),
+			VerifiedAt: time.Now(),
+		}
+	}
+	data, err := ioutil.ReadAll(file)
+	if err == nil {
+		var signedData *cms.SignedData
+		signedData, err = cms.Parse(data)
+		if err == nil && signedData.Detached() {
+			// a .p7s on its own, the document comes with /signature later
+			err = cms.ErrNoContent
+		}
+		if err == nil {
+			var result *models.FileSignature
+			if result, err = h.verifySignature(signedData, nil, time.Time{}); err == nil {
+				return result
+			}
+		}
+	}
+	return &models.FileSignature{
+		Status:     models.SignatureStatusInvalid,
+		Error:      err.Error(),
+		VerifiedAt: time.Now(),
+	}
+}
+
+// verifySignature checks the certificates of signers without a trusted
+// timestamp at the given time, now when it is zero
+func (h *handlerV1) verifySignature(signedData *cms.SignedData, content io.Reader, at time.Time) (*models.FileSignature, error) {
+	opts := h.signatureOptions
+	opts.Content = content
+	opts.CurrentTime = at
+	signers, err := signedData.Verify(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.FileSignature{
+		Status:     models.SignatureStatusValid,
+		Detached:   signedData.Detached(),
+		Signers:    make([]*models.FileSigner, 0, len(signers)),
+		VerifiedAt: time.Now(),
+	}
+	if len(signers) == 0 {
+		result.Status = models.SignatureStatusInvalid
+		result.Error = "signature has no signers"
+	}
+	for _, signer := range signers {
+		fileSigner := &models.FileSigner{
+			Status: signerStatus(signer.Err),
+		}
+		if signer.Err != nil {
+			fileSigner.Error = signer.Err.Error()
+		}
+		if !signer.SigningTime.IsZero() {
+			fileSigner.SigningTime = &signer.SigningTime
+		}
+		if !signer.Timestamp.IsZero() {
+			fileSigner.Timestamp = &signer.Timestamp
+		}
+		if certificate := signer.Certificate; certificate != nil {
+			fileSigner.Name = certificate.Subject.CommonName
+			if len(certificate.Subject.Organization) > 0 {
+				fileSigner.Organization = certificate.Subject.Organization[0]
+			}
+			fileSigner.Pinfl = cms.Pinfl(certificate)
+			fileSigner.Inn = cms.Inn(certificate)
+			fileSigner.SerialNumber = certificate.SerialNumber.Text(16)
+			fileSigner.Issuer = certificate.Issuer.CommonName
+			fileSigner.ValidFrom = &certificate.NotBefore
+			fileSigner.ValidTo = &certificate.NotAfter
+		}
+		result.Signers = append(result.Signers, fileSigner)
+		result.Status = worseSignatureStatus(result.Status, fileSigner.Status)
+	}
+	return result, nil
+}
+
+func signerStatus(err error) string {
+	switch {
+	case err == nil:
+		return models.SignatureStatusValid
+	case errors.Is(err, cms.ErrUnsupportedAlgorithm):
+		return models.SignatureStatusUnsupported
+	case errors.Is(err, cms.ErrRevoked):
+		return models.SignatureStatusRevoked
+	case errors.Is(err, cms.ErrUntrusted), errors.Is(err, cms.ErrRevocationUnknown):
+		return models.SignatureStatusUntrusted
+	case errors.Is(err, cms.ErrExpired):
+		return models.SignatureStatusExpired
+	}
+	return models.SignatureStatusInvalid
+}
+
+func worseSignatureStatus(a, b string) string {
+	for _, status := range signatureStatuses {
+		if a == status || b == status {
+			return status
+		}
+	}
+	return a
+}
@@ -2,13 +2,18 @@ package v1
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"strings"
 
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/filecheck"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
 	"github.com/gin-gonic/gin"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -17,8 +22,9 @@ type File struct {
 }
 
 type FilesResponse struct {
-	FilePath string `json:"file_path"`
-	URL      string `json:"file_url"`
+	FilePath string                `json:"file_path"`
+	URL      string                `json:"file_url"`
+	Photo    *models.PhotoMetadata `json:"photo,omitempty"`
 }
 type ImageResponse struct {
 	FileID   string `json:"file_id"`
@@ -30,14 +36,80 @@ type AllFilesResponse struct {
 	Files []FilesResponse `json:"files"`
 }
 
-// func validation(ext string) bool {
-// 	// for _, val := range allowedExtensions {
-// 	// 	if val == ext {
-// 	// 		return true
-// 	// 	}
-// 	// }
-// 	return false
-// }
+func (h *handlerV1) imageUploadPolicy() filecheck.Policy {
+	return filecheck.Policy{
+		ContentTypes:   h.cfg.ImageUploadContentTypes,
+		MaxSize:        h.cfg.ImageUploadMaxSize,
+		MaxImagePixels: h.cfg.MaxImagePixels,
+	}
+}
+
+func (h *handlerV1) fileUploadPolicy() filecheck.Policy {
+	return filecheck.Policy{
+		ContentTypes:   h.cfg.AllowedUploadContentTypes,
+		MaxSize:        h.cfg.MaxUploadSize,
+		MaxImagePixels: h.cfg.MaxImagePixels,
+	}
+}
+
+func (h *handlerV1) resumableUploadPolicy() filecheck.Policy {
+	return filecheck.Policy{
+		ContentTypes:   h.cfg.AllowedUploadContentTypes,
+		MaxSize:        h.cfg.ResumableUploadMaxSize,
+		MaxImagePixels: h.cfg.MaxImagePixels,
+	}
+}
+
+// checkUpload sniffs and scans an upload before it is kept. Infected files
+// are copied to the quarantine, stored ones are removed from the file store
+// when they are rejected. It returns false when the response has been written
+// already.
+func (h *handlerV1) checkUpload(c *gin.Context, name string, file io.ReadSeeker, size int64, declared string, policy filecheck.Policy, stored bool) (string, bool) {
+	contentType, err := filecheck.Check(file, size, declared, policy)
+	if HandleHTTPError(c, http.StatusBadRequest, "File.CheckUpload", err) {
+		if stored {
+			h.deleteRejectedUpload(name)
+		}
+		return "", false
+	}
+
+	result, err := h.scanner.Scan(context.Background(), file)
+	if HandleHTTPError(c, http.StatusInternalServerError, "File.CheckUpload.Scan", err) {
+		return "", false
+	}
+	if !result.Infected {
+		if _, err := file.Seek(0, io.SeekStart); HandleHTTPError(c, http.StatusInternalServerError, "File.CheckUpload.Seek", err) {
+			return "", false
+		}
+		return contentType, true
+	}
+
+	h.log.Warn("infected file uploaded",
+		logger.String("file", name),
+		logger.String("signature", result.Signature),
+		logger.String("ip", c.ClientIP()))
+	if _, err := file.Seek(0, io.SeekStart); err == nil {
+		err = h.fileStore.Put(context.Background(), filestore.QuarantinePrefix+name, file, size, contentType)
+		if err != nil {
+			h.log.Error("error while putting file to quarantine", logger.Error(err))
+		}
+	}
+	if stored {
+		h.deleteRejectedUpload(name)
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"success":   false,
+		"message":   "file is infected",
+		"signature": result.Signature,
+	})
+	return "", false
+}
+
+func (h *handlerV1) deleteRejectedUpload(name string) {
+	if err := h.fileStore.Delete(context.Background(), name); err != nil {
+		h.log.Error("error while deleting rejected upload", logger.Error(err))
+	}
+}
 
 // @Router /v1/image-upload [post]
 // @Tags file_upload
@@ -45,45 +117,53 @@ type AllFilesResponse struct {
 // @Accept multipart/form-data
 // @Success 200 {object} FilesResponse
 func (h *handlerV1) ImageUpload(c *gin.Context) {
-
-	minioClient, err := minio.New(h.cfg.MinioDomain, &minio.Options{
-		Creds:  credentials.NewStaticV4(h.cfg.MinioAccessKeyID, h.cfg.MinioSecretAccesKey, ""),
-		Secure: true,
-	})
-	if HandleHTTPError(c, http.StatusInternalServerError, "error while creating bucket in minio", err) {
+	file, err := c.FormFile("image")
+	if HandleHTTPError(c, http.StatusBadRequest, "error while getting file", err) {
 		return
 	}
+	fileName := file.Filename
+	file.Filename = primitive.NewObjectID().Hex() + strings.ToLower(filepath.Ext(fileName))
+	object, err := file.Open()
+	if HandleHTTPError(c, http.StatusInternalServerError, "error while opening file", err) {
+		return
+	}
+	defer object.Close()
 
-	exists, _ := minioClient.BucketExists(context.Background(), h.cfg.BucketName)
-	if !exists {
-		err = minioClient.MakeBucket(context.Background(), h.cfg.BucketName, minio.MakeBucketOptions{Region: h.cfg.MinioDomain})
-		if HandleHTTPError(c, http.StatusInternalServerError, "error while creating bucket in minio", err) {
-			return
-		}
+	contentType, ok := h.checkUpload(c, file.Filename, object, file.Size, file.Header.Get("Content-Type"), h.imageUploadPolicy(), false)
+	if !ok {
+		return
 	}
-	file, err := c.FormFile("image")
-	if HandleHTTPError(c, http.StatusInternalServerError, "error while getting file", err) {
+
+	photo := h.photoMetadata(file.Filename, object)
+	if _, err := object.Seek(0, io.SeekStart); HandleHTTPError(c, http.StatusInternalServerError, "error while reading file", err) {
 		return
 	}
-	contentType := file.Header["Content-Type"][0]
-	if len(contentType) <= 1 {
-		contentType = "multipart/form-data"
+
+	url, err := h.fileStore.PresignGet(context.Background(), file.Filename, h.cfg.FileURLExpiry)
+	if HandleHTTPError(c, http.StatusInternalServerError, "error while presigning file url", err) {
+		return
 	}
-	fileName := file.Filename
-	file.Filename = primitive.NewObjectID().Hex() + filepath.Ext(fileName)
-	object, err := file.Open()
-	if HandleHTTPError(c, http.StatusInternalServerError, "error while creating bucket in minio", err) {
+
+	err = h.fileStore.Put(context.Background(), file.Filename, object, file.Size, contentType)
+	if HandleHTTPError(c, http.StatusInternalServerError, "error while putting file to file store", err) {
 		return
 	}
-	defer object.Close()
-	_, err = minioClient.PutObject(context.Background(), h.cfg.BucketName, file.Filename, object, file.Size,
-		minio.PutObjectOptions{ContentType: contentType})
-	if HandleHTTPError(c, http.StatusInternalServerError, "error while creating bucket in minio", err) {
+	err = h.storage.PhotoMetadata().Create(context.Background(), photo)
+	if err != nil {
+		// the client never learns the name, the image would only be an
+		// orphan in the file store
+		h.deleteRejectedUpload(file.Filename)
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "error while saving photo metadata", err) {
 		return
 	}
+	if thumbnail.Supported(file.Filename) {
+		h.thumbnails.Enqueue(file.Filename)
+	}
 
 	c.JSON(http.StatusOK, FilesResponse{
 		FilePath: file.Filename,
-		URL:      h.cfg.MinioDomain + "/" + h.cfg.BucketName + "/" + file.Filename,
+		URL:      url,
+		Photo:    photo,
 	})
 }
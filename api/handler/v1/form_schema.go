@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/formschema"
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// @Router /v1/form-schema [get]
+// @Summary Get form JSON Schema
+// @Description API for getting the entity form of a type and step as JSON Schema (draft 2020-12) with a UI schema of rendering hints
+// @Tags form_schema
+// @Accept json
+// @Produce json
+// @Param type query integer true "type"
+// @Param step query integer false "step, all steps when not sent"
+// @Param version query integer false "version"
+// @Success 200 {object} formschema.Document
+func (h *handlerV1) GetFormSchema(c *gin.Context) {
+	typeOf, err := ParseQueryParam(c, h.log, "type", "0")
+	if err != nil {
+		return
+	}
+	if typeOf == 0 {
+		HandleHTTPError(c, http.StatusBadRequest, "FormSchema.ParseType", errors.New("type is required"))
+		return
+	}
+
+	step, err := parseStepQuery(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchema.ParseStep", err) {
+		return
+	}
+
+	version, err := ParseQueryParam(c, h.log, "version", "0")
+	if err != nil {
+		return
+	}
+
+	groupProperties, err := h.getGroupPropertiesByType(uint32(typeOf), step, uint32(version))
+	if handleStorageError(c, "FormSchema.GetGroupPropertiesByType", err) {
+		return
+	}
+
+	activeGroupProperties := []*models.GroupProperty{}
+	for _, groupProperty := range groupProperties {
+		if groupProperty.Status {
+			activeGroupProperties = append(activeGroupProperties, groupProperty)
+		}
+	}
+
+	if lang := requestLanguage(c); lang != "" {
+		i18n.LocalizeGroupProperties(activeGroupProperties, lang)
+	}
+
+	id := fmt.Sprintf("/v1/form-schema?type=%d", typeOf)
+	title := fmt.Sprintf("Entity form type %d", typeOf)
+	if step != nil {
+		id += fmt.Sprintf("&step=%d", *step)
+		title += fmt.Sprintf(" step %d", *step)
+	}
+	if version != 0 {
+		id += fmt.Sprintf("&version=%d", version)
+	}
+
+	c.JSON(http.StatusOK, formschema.Generate(id, title, activeGroupProperties))
+}
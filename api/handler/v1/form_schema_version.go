@@ -0,0 +1,166 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Security ApiKeyAuth
+// @Router /v1/form-schema-version [post]
+// @Summary Publish form schema version
+// @Description API for publishing an immutable snapshot of the group properties of a type and step
+// @Tags form_schema_version
+// @Accept json
+// @Produce json
+// @Param form_schema_version body models.PublishFormSchemaVersionSwag true "form_schema_version"
+// @Success 201 {object} models.FormSchemaVersion
+func (h *handlerV1) PublishFormSchemaVersion(c *gin.Context) {
+	var (
+		publish models.PublishFormSchemaVersionSwag
+	)
+
+	userInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&publish); HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.Publish.BindingJson", err) {
+		return
+	}
+
+	groupProperties, _, err := h.storage.GroupProperty().GetAllByType(
+		context.Background(),
+		publish.Type,
+		publish.Step,
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.Publish.GetAllGroupPropertiesByType", err) {
+		return
+	}
+
+	formSchemaVersion := &models.CreateFormSchemaVersion{
+		ID:              primitive.NewObjectID(),
+		Type:            publish.Type,
+		Step:            *publish.Step,
+		Comment:         publish.Comment,
+		PublishedBy:     userInfo.Login,
+		GroupProperties: []*models.GroupProperty{},
+	}
+	for _, groupProperty := range groupProperties {
+		if groupProperty.Status {
+			formSchemaVersion.GroupProperties = append(formSchemaVersion.GroupProperties, groupProperty)
+		}
+	}
+	if len(formSchemaVersion.GroupProperties) == 0 {
+		HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.Publish", errors.New("no active group properties for this type and step"))
+		return
+	}
+
+	id, err := h.storage.FormSchemaVersion().Create(
+		context.Background(),
+		formSchemaVersion,
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.Publish.Create", err) {
+		return
+	}
+
+	response, err := h.storage.FormSchemaVersion().Get(context.Background(), id)
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.Publish.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Router /v1/form-schema-version/{form_schema_version_id} [get]
+// @Summary Get form schema version
+// @Tags form_schema_version
+// @Accept json
+// @Produce json
+// @Param form_schema_version_id path string true "form_schema_version_id"
+// @Success 200 {object} models.FormSchemaVersion
+func (h *handlerV1) GetFormSchemaVersion(c *gin.Context) {
+	var (
+		ID     = c.Param("form_schema_version_id")
+		_, err = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.Get.ParseId", err) {
+		return
+	}
+
+	response, err := h.storage.FormSchemaVersion().Get(context.Background(), ID)
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Router /v1/form-schema-version [get]
+// @Summary Getting All form schema versions
+// @Description API for getting published form schema versions
+// @Tags form_schema_version
+// @Accept json
+// @Produce json
+// @Param type query integer false "type"
+// @Param step query integer false "step"
+// @Param page query integer false "page"
+// @Param limit query integer false "limit"
+// @Success 200 {object} models.GetAllFormSchemaVersionsResponse
+func (h *handlerV1) GetAllFormSchemaVersions(c *gin.Context) {
+	typeOf, err := ParseQueryParam(c, h.log, "type", "0")
+	if err != nil {
+		return
+	}
+
+	step, err := parseStepQuery(c)
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.GetAll.ParseStep", err) {
+		return
+	}
+
+	page, err := ParseQueryParam(c, h.log, "page", "1")
+	if err != nil {
+		return
+	}
+
+	limit, err := ParseQueryParam(c, h.log, "limit", "20")
+	if err != nil {
+		return
+	}
+
+	versions, count, err := h.storage.FormSchemaVersion().GetAll(
+		context.Background(),
+		&models.GetAllFormSchemaVersionsRequest{
+			Type:  uint32(typeOf),
+			Step:  step,
+			Page:  uint32(page),
+			Limit: uint32(limit),
+		})
+	if HandleHTTPError(c, http.StatusBadRequest, "FormSchemaVersion.GetAll", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.GetAllFormSchemaVersionsResponse{
+		FormSchemaVersions: versions,
+		Count:              count,
+	})
+}
+
+// parseStepQuery returns nil when the request has no step, which is not the
+// same as asking for step 0
+func parseStepQuery(c *gin.Context) (*uint32, error) {
+	stepQuery, ok := c.GetQuery("step")
+	if !ok {
+		return nil, nil
+	}
+	step, err := strconv.ParseUint(stepQuery, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	value := uint32(step)
+	return &value, nil
+}
@@ -2,10 +2,15 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/e-space-uz/backend/storage/repo"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // @Router /v1/group-property [get]
@@ -44,20 +49,20 @@ func (h *handlerV1) GetAllGroupProperties(c *gin.Context) {
 
 // @Router /v1/group-property-type [get]
 // @Summary Getting All Group Properties By Type
-// @Description API for getting all group properties by type
+// @Description API for getting all group properties by type, optionally as published in the given form schema version
 // @Tags group_property
 // @Accept json
 // @Produce json
-// @Param step query integer true "step"
+// @Param step query integer false "step, all steps when not sent"
 // @Param type query integer true "type"
+// @Param version query integer false "version"
 // @Success 200 {object} models.GetAllGroupPropertiesByTypeResponse
 
 func (h *handlerV1) GetAllGroupPropertiesByType(c *gin.Context) {
 	var (
-		stepQuery   = c.Query("step")
 		typeOfQuery = c.Query("type")
 	)
-	step, err := strconv.Atoi(stepQuery)
+	step, err := parseStepQuery(c)
 	if HandleHTTPError(c, http.StatusBadRequest, "error while parsing query to request", err) {
 		return
 	}
@@ -65,15 +70,454 @@ func (h *handlerV1) GetAllGroupPropertiesByType(c *gin.Context) {
 	if HandleHTTPError(c, http.StatusBadRequest, "error while parsing query to request", err) {
 		return
 	}
+	version, err := ParseQueryParam(c, h.log, "version", "0")
+	if err != nil {
+		return
+	}
+
+	groupProperties, err := h.getGroupPropertiesByType(uint32(typeOf), step, uint32(version))
+	if HandleHTTPError(c, http.StatusBadRequest, "Erro while getting all group properties by type", err) {
+		return
+	}
+	if lang := requestLanguage(c); lang != "" {
+		i18n.LocalizeGroupProperties(groupProperties, lang)
+	}
+	c.JSON(http.StatusOK, groupProperties)
+}
+
+// errVersionWithoutStep is returned for a form schema version without a step,
+// versions are numbered per step
+var errVersionWithoutStep = errors.New("step is required with version")
+
+// getGroupPropertiesByType returns the live group properties of the step, or
+// of every step when step is nil, or the ones published in the form schema
+// version when version is not zero
+func (h *handlerV1) getGroupPropertiesByType(typeOf uint32, step *uint32, version uint32) ([]*models.GroupProperty, error) {
+	if version != 0 {
+		if step == nil {
+			return nil, errVersionWithoutStep
+		}
+		formSchemaVersion, err := h.storage.FormSchemaVersion().GetByVersion(
+			context.Background(),
+			typeOf,
+			*step,
+			version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return formSchemaVersion.GroupProperties, nil
+	}
 
 	groupProperties, _, err := h.storage.GroupProperty().GetAllByType(
 		context.Background(),
-		uint32(step),
-		uint32(typeOf),
+		typeOf,
+		step,
 	)
+	return groupProperties, err
+}
 
-	if HandleHTTPError(c, http.StatusBadRequest, "Erro while getting all group properties by type", err) {
+// @Security ApiKeyAuth
+// @Router /v1/group-property [post]
+// @Summary Create group property
+// @Description API for creating group property
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property body models.GroupPropertySwag true "group_property"
+// @Success 201 {object} models.CreateResponse
+func (h *handlerV1) CreateGroupProperty(c *gin.Context) {
+	var (
+		groupPropertySwag models.GroupPropertySwag
+	)
+
+	if _, err := h.StaffInfo(c); err != nil {
 		return
 	}
-	c.JSON(http.StatusOK, groupProperties)
+	if err := c.ShouldBindJSON(&groupPropertySwag); HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Create.BindingJson", err) {
+		return
+	}
+
+	groupProperty, err := h.groupPropertyFromSwag(&groupPropertySwag.UpdateGroupPropertySwag)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Create.ParsingRequest", err) {
+		return
+	}
+	groupProperty.ID = primitive.NewObjectID()
+	groupProperty.Status = groupPropertySwag.Status == nil || *groupPropertySwag.Status
+
+	resp, err := h.storage.GroupProperty().Create(
+		context.Background(),
+		groupProperty,
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Create", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// @Router /v1/group-property/{group_property_id} [get]
+// @Summary Get group property
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Success 200 {object} models.GroupProperty
+func (h *handlerV1) GetGroupProperty(c *gin.Context) {
+	var (
+		ID     = c.Param("group_property_id")
+		_, err = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Get.ParseId", err) {
+		return
+	}
+
+	groupProperty, err := h.storage.GroupProperty().Get(context.Background(), ID)
+	if handleStorageError(c, "GroupProperty.Get", err) {
+		return
+	}
+	if lang := requestLanguage(c); lang != "" {
+		i18n.LocalizeGroupProperties([]*models.GroupProperty{groupProperty}, lang)
+	}
+
+	c.JSON(http.StatusOK, groupProperty)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id} [put]
+// @Summary Update group property
+// @Description API for updating group property, the status only changes through /status
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Param group_property body models.UpdateGroupPropertySwag true "group_property"
+// @Success 200 {object} models.GroupProperty
+func (h *handlerV1) UpdateGroupProperty(c *gin.Context) {
+	var (
+		ID                = c.Param("group_property_id")
+		groupPropertySwag models.UpdateGroupPropertySwag
+	)
+
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(ID)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Update.ParseId", err) {
+		return
+	}
+	if err := c.ShouldBindJSON(&groupPropertySwag); HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Update.BindingJson", err) {
+		return
+	}
+
+	groupProperty, err := h.groupPropertyFromSwag(&groupPropertySwag)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Update.ParsingRequest", err) {
+		return
+	}
+	groupProperty.ID = objectID
+
+	err = h.storage.GroupProperty().Update(context.Background(), groupProperty)
+	if handleStorageError(c, "GroupProperty.Update", err) {
+		return
+	}
+
+	h.respondGroupProperty(c, ID)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id} [delete]
+// @Summary Delete group property
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Success 204
+func (h *handlerV1) DeleteGroupProperty(c *gin.Context) {
+	var (
+		ID     = c.Param("group_property_id")
+		_, err = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Delete.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+
+	err = h.storage.GroupProperty().Delete(context.Background(), ID)
+	if handleStorageError(c, "GroupProperty.Delete", err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id}/status [put]
+// @Summary Activate or deactivate group property
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Param status body models.UpdateGroupPropertyStatusSwag true "status"
+// @Success 200 {object} models.GroupProperty
+func (h *handlerV1) UpdateGroupPropertyStatus(c *gin.Context) {
+	var (
+		ID     = c.Param("group_property_id")
+		status models.UpdateGroupPropertyStatusSwag
+		_, err = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.UpdateStatus.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&status); HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.UpdateStatus.BindingJson", err) {
+		return
+	}
+
+	err = h.storage.GroupProperty().UpdateStatus(context.Background(), ID, status.Status)
+	if handleStorageError(c, "GroupProperty.UpdateStatus", err) {
+		return
+	}
+
+	h.respondGroupProperty(c, ID)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id}/clone [post]
+// @Summary Clone group property
+// @Description API for copying a group property with its properties, the copy is inactive
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Param clone body models.CloneGroupPropertySwag false "clone"
+// @Success 201 {object} models.GroupProperty
+func (h *handlerV1) CloneGroupProperty(c *gin.Context) {
+	var (
+		ID     = c.Param("group_property_id")
+		clone  models.CloneGroupPropertySwag
+		_, err = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Clone.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&clone); HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Clone.BindingJson", err) {
+			return
+		}
+	}
+
+	cloneID, err := h.storage.GroupProperty().Clone(context.Background(), ID, clone.Name)
+	if handleStorageError(c, "GroupProperty.Clone", err) {
+		return
+	}
+
+	groupProperty, err := h.storage.GroupProperty().Get(context.Background(), cloneID)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Clone.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, groupProperty)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id}/property [post]
+// @Summary Add property to group property
+// @Description API for adding a property to a group at the given order, following properties are shifted
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Param property body models.GetProperties true "property"
+// @Success 200 {object} models.GroupProperty
+func (h *handlerV1) AddGroupPropertyProperty(c *gin.Context) {
+	var (
+		ID       = c.Param("group_property_id")
+		property models.GetProperties
+		_, err   = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.AddProperty.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&property); HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.AddProperty.BindingJson", err) {
+		return
+	}
+
+	propertyObjectID, err := primitive.ObjectIDFromHex(property.PropertyID)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.AddProperty.ParsePropertyId", err) {
+		return
+	}
+	_, err = h.storage.Property().Get(context.Background(), property.PropertyID)
+	if handleStorageError(c, "GroupProperty.AddProperty.GetProperty", err) {
+		return
+	}
+
+	err = h.storage.GroupProperty().AddProperty(
+		context.Background(),
+		ID,
+		&models.CreateProperties{
+			PropertyID: propertyObjectID,
+			Order:      property.Order,
+		})
+	if handleStorageError(c, "GroupProperty.AddProperty", err) {
+		return
+	}
+
+	h.respondGroupProperty(c, ID)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id}/property/{property_id} [delete]
+// @Summary Remove property from group property
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Param property_id path string true "property_id"
+// @Success 200 {object} models.GroupProperty
+func (h *handlerV1) RemoveGroupPropertyProperty(c *gin.Context) {
+	var (
+		ID         = c.Param("group_property_id")
+		propertyID = c.Param("property_id")
+		_, err     = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.RemoveProperty.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+
+	err = h.storage.GroupProperty().RemoveProperty(context.Background(), ID, propertyID)
+	if handleStorageError(c, "GroupProperty.RemoveProperty", err) {
+		return
+	}
+
+	h.respondGroupProperty(c, ID)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id}/property-order [put]
+// @Summary Reorder properties of group property
+// @Description API for reordering group properties, every property of the group must be listed
+// @Tags group_property
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Param properties body models.ReorderGroupPropertiesSwag true "properties"
+// @Success 200 {object} models.GroupProperty
+func (h *handlerV1) ReorderGroupPropertyProperties(c *gin.Context) {
+	var (
+		ID         = c.Param("group_property_id")
+		reorder    models.ReorderGroupPropertiesSwag
+		properties []*models.CreateProperties
+		_, err     = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.ReorderProperties.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&reorder); HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.ReorderProperties.BindingJson", err) {
+		return
+	}
+
+	for _, property := range reorder.Properties {
+		propertyObjectID, err := primitive.ObjectIDFromHex(property.PropertyID)
+		if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.ReorderProperties.ParsePropertyId", err) {
+			return
+		}
+		properties = append(properties, &models.CreateProperties{
+			PropertyID: propertyObjectID,
+			Order:      property.Order,
+		})
+	}
+
+	err = h.storage.GroupProperty().ReorderProperties(context.Background(), ID, properties)
+	if handleStorageError(c, "GroupProperty.ReorderProperties", err) {
+		return
+	}
+
+	h.respondGroupProperty(c, ID)
+}
+
+func (h *handlerV1) respondGroupProperty(c *gin.Context, id string) {
+	groupProperty, err := h.storage.GroupProperty().Get(context.Background(), id)
+	if HandleHTTPError(c, http.StatusBadRequest, "GroupProperty.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, groupProperty)
+}
+
+func (h *handlerV1) groupPropertyFromSwag(swag *models.UpdateGroupPropertySwag) (*models.CreateGroupProperty, error) {
+	groupProperty := &models.CreateGroupProperty{
+		Name:          swag.Name,
+		Step:          swag.Step,
+		Type:          swag.Type,
+		Description:   swag.Description,
+		Organization:  models.OrganizationCreate{Name: swag.Organization.Name},
+		Translations:  swag.Translations,
+		ReadStatuses:  []primitive.ObjectID{},
+		WriteStatuses: []primitive.ObjectID{},
+	}
+	if swag.Organization.ID != "" {
+		organizationID, err := primitive.ObjectIDFromHex(swag.Organization.ID)
+		if err != nil {
+			return nil, err
+		}
+		groupProperty.Organization.ID = organizationID
+	}
+
+	if swag.Translations != nil {
+		if err := validateTranslations(swag.Translations.Name, swag.Translations.Description); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[string]bool, len(swag.Properties))
+	for _, property := range swag.Properties {
+		if seen[property.PropertyID] {
+			return nil, repo.ErrPropertyAlreadyInGroup
+		}
+		seen[property.PropertyID] = true
+
+		propertyID, err := primitive.ObjectIDFromHex(property.PropertyID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := h.storage.Property().Get(context.Background(), property.PropertyID); err != nil {
+			return nil, err
+		}
+		groupProperty.Properties = append(groupProperty.Properties, &models.CreateProperties{
+			PropertyID: propertyID,
+			Order:      property.Order,
+		})
+	}
+	for _, status := range swag.ReadStatuses {
+		statusID, err := primitive.ObjectIDFromHex(status)
+		if err != nil {
+			return nil, err
+		}
+		groupProperty.ReadStatuses = append(groupProperty.ReadStatuses, statusID)
+	}
+	for _, status := range swag.WriteStatuses {
+		statusID, err := primitive.ObjectIDFromHex(status)
+		if err != nil {
+			return nil, err
+		}
+		groupProperty.WriteStatuses = append(groupProperty.WriteStatuses, statusID)
+	}
+
+	return groupProperty, nil
 }
@@ -11,10 +11,19 @@ import (
 
 	"github.com/e-space-uz/backend/config"
 	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/cms"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/geo"
 	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/pdf"
+	"github.com/e-space-uz/backend/pkg/scanner"
 	"github.com/e-space-uz/backend/pkg/security"
+	"github.com/e-space-uz/backend/pkg/signer"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
 	"github.com/e-space-uz/backend/storage"
+	"github.com/e-space-uz/backend/storage/repo"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -32,22 +41,48 @@ const (
 )
 
 type handlerV1 struct {
-	cfg     config.Config
-	log     logger.Logger
-	storage storage.StorageI
+	cfg          config.Config
+	log          logger.Logger
+	storage      storage.StorageI
+	fileStore    filestore.FileStore
+	scanner      scanner.Scanner
+	thumbnails   *thumbnail.Worker
+	documentFont pdf.Font
+	// signatureOptions are the trusted CAs of signed documents and how
+	// their certificates are checked
+	signatureOptions cms.VerifyOptions
+	decisionSigner   *signer.Signer
+	decisionKeys     *signer.Keyring
+	crs              *geo.Registry
 }
 
 type HandlerV1Options struct {
-	Cfg     config.Config
-	Log     logger.Logger
-	Storage storage.StorageI
+	Cfg              config.Config
+	Log              logger.Logger
+	Storage          storage.StorageI
+	FileStore        filestore.FileStore
+	Scanner          scanner.Scanner
+	Thumbnails       *thumbnail.Worker
+	DocumentFont     pdf.Font
+	SignatureOptions cms.VerifyOptions
+	DecisionSigner   *signer.Signer
+	DecisionKeys     *signer.Keyring
+	CRS              *geo.Registry
 }
 
 func New(options *HandlerV1Options) *handlerV1 {
 	return &handlerV1{
-		log:     options.Log,
-		cfg:     options.Cfg,
-		storage: options.Storage,
+		log:              options.Log,
+		cfg:              options.Cfg,
+		storage:          options.Storage,
+		fileStore:        options.FileStore,
+		scanner:          options.Scanner,
+		thumbnails:       options.Thumbnails,
+		documentFont:     options.DocumentFont,
+		signatureOptions: options.SignatureOptions,
+		decisionSigner:   options.DecisionSigner,
+		decisionKeys:     options.DecisionKeys,
+		crs:              options.CRS,
 	}
 }
 
@@ -131,6 +166,20 @@ func (h *handlerV1) UserInfo(c *gin.Context, required bool) (*models.LoginInfo,
 	}
 }
 
+// StaffInfo is UserInfo for endpoints which only staff may call
+func (h *handlerV1) StaffInfo(c *gin.Context) (*models.LoginInfo, error) {
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return nil, err
+	}
+	if userInfo.UserType != "staff" {
+		err = errors.New("forbidden")
+		HandleHTTPError(c, http.StatusForbidden, "only staff can access this resource", err)
+		return nil, err
+	}
+	return userInfo, nil
+}
+
 // This function is to parsing upcoming query params
 // please, make sure you write proper message error
 func ParseQueryParam(c *gin.Context, log logger.Logger, key string, defaultValue string) (int, error) {
@@ -178,6 +227,22 @@ func HandleHTTPError(c *gin.Context, code int, message string, err error) bool {
 			Error:   err,
 		})
 		return true
+	} else if err != nil && code == http.StatusForbidden {
+		log.Error(message+" --> Error: ", logger.Error(err))
+		c.JSON(http.StatusForbidden, models.FailureResponse{
+			Success: false,
+			Message: message,
+			Error:   err,
+		})
+		return true
+	} else if err != nil && code == http.StatusNotFound {
+		log.Error(message+" --> Error: ", logger.Error(err))
+		c.JSON(http.StatusNotFound, models.FailureResponse{
+			Success: false,
+			Message: message,
+			Error:   err,
+		})
+		return true
 	} else if err != nil && code == http.StatusConflict {
 		log.Error(message+" --> Error: ", logger.Error(err))
 		c.JSON(http.StatusConflict, models.FailureResponse{
@@ -189,6 +254,23 @@ func HandleHTTPError(c *gin.Context, code int, message string, err error) bool {
 	}
 	return false
 }
+
+// handleStorageError picks the HTTP status for errors returned by storage
+func handleStorageError(c *gin.Context, message string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, mongo.ErrNoDocuments), errors.Is(err, repo.ErrPropertyNotInGroup):
+		return HandleHTTPError(c, http.StatusNotFound, message, err)
+	case errors.Is(err, repo.ErrPropertyAlreadyInGroup), errors.Is(err, repo.ErrFileAlreadyAttached),
+		errors.Is(err, repo.ErrDecisionConflict), errors.Is(err, repo.ErrVersionConflict),
+		errors.Is(err, repo.ErrSoatoTaken), errors.Is(err, repo.ErrSoatoImportApplied):
+		return HandleHTTPError(c, http.StatusConflict, message, err)
+	default:
+		return HandleHTTPError(c, http.StatusBadRequest, message, err)
+	}
+}
+
 func RandomSixDigits() (uint64, error) {
 	max := big.NewInt(999999)
 	n, err := rand.Int(rand.Reader, max)
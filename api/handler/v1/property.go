@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/i18n"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -25,6 +26,9 @@ func (h *handlerV1) CreateProperty(c *gin.Context) {
 	if HandleHTTPError(c, http.StatusBadRequest, "DiscussionLogicService.Action.Create.BindingAction", err) {
 		return
 	}
+	if HandleHTTPError(c, http.StatusBadRequest, "error while validating translations", validatePropertyTranslations(&property)) {
+		return
+	}
 	property.ID = primitive.NewObjectID()
 
 	resp, err := h.storage.Property().Create(
@@ -55,7 +59,7 @@ func (h *handlerV1) UpdateProperty(c *gin.Context) {
 	)
 	propertyID := c.Param("property_id")
 
-	_, err := primitive.ObjectIDFromHex(propertyID)
+	objectID, err := primitive.ObjectIDFromHex(propertyID)
 	if HandleHTTPError(c, http.StatusBadRequest, "Error while parsing objectID, incorrect format", err) {
 		return
 	}
@@ -64,6 +68,10 @@ func (h *handlerV1) UpdateProperty(c *gin.Context) {
 	if HandleHTTPError(c, http.StatusBadRequest, "error while binding model to json", err) {
 		return
 	}
+	if HandleHTTPError(c, http.StatusBadRequest, "error while validating translations", validatePropertyTranslations(&property)) {
+		return
+	}
+	property.ID = objectID
 
 	err = h.storage.Property().Update(
 		context.Background(),
@@ -75,3 +83,136 @@ func (h *handlerV1) UpdateProperty(c *gin.Context) {
 
 	c.JSON(http.StatusOK, "resp")
 }
+
+// @Router /v1/property/{property_id} [get]
+// @Summary Get Property
+// @Tags property
+// @Accept json
+// @Produce json
+// @Param property_id path string  true "property_id"
+// @Success 200 {object} models.Property
+func (h *handlerV1) GetProperty(c *gin.Context) {
+	propertyID := c.Param("property_id")
+
+	_, err := primitive.ObjectIDFromHex(propertyID)
+	if HandleHTTPError(c, http.StatusBadRequest, "Error while parsing objectID, incorrect format", err) {
+		return
+	}
+
+	property, err := h.storage.Property().Get(context.Background(), propertyID)
+	if handleStorageError(c, "error while getting property", err) {
+		return
+	}
+	if lang := requestLanguage(c); lang != "" {
+		i18n.LocalizeProperty(property, lang)
+	}
+
+	c.JSON(http.StatusOK, property)
+}
+
+// @Router /v1/property [get]
+// @Summary Getting All Properties
+// @Description API for getting all properties
+// @Tags property
+// @Accept json
+// @Produce json
+// @Param search query string false "search"
+// @Param page query integer false "page"
+// @Param limit query integer false "limit"
+// @Success 200 {object} models.GetAllPropertiesResponse
+func (h *handlerV1) GetAllProperties(c *gin.Context) {
+	page, err := ParseQueryParam(c, h.log, "page", "1")
+	if err != nil {
+		return
+	}
+
+	limit, err := ParseQueryParam(c, h.log, "limit", "20")
+	if err != nil {
+		return
+	}
+
+	properties, count, err := h.storage.Property().GetAll(
+		context.Background(),
+		uint32(page),
+		uint32(limit),
+		c.Query("search"),
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "error while getting all properties", err) {
+		return
+	}
+	if lang := requestLanguage(c); lang != "" {
+		for _, property := range properties {
+			i18n.LocalizeProperty(property, lang)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.GetAllPropertiesResponse{
+		Properties: properties,
+		Count:      count,
+	})
+}
+
+// @Router /v1/property/{property_id}/usage [get]
+// @Summary Get Property usage
+// @Description API for counting entities, drafts and group properties referencing the property
+// @Tags property
+// @Accept json
+// @Produce json
+// @Param property_id path string  true "property_id"
+// @Success 200 {object} models.PropertyUsage
+func (h *handlerV1) GetPropertyUsage(c *gin.Context) {
+	propertyID := c.Param("property_id")
+
+	_, err := primitive.ObjectIDFromHex(propertyID)
+	if HandleHTTPError(c, http.StatusBadRequest, "Error while parsing objectID, incorrect format", err) {
+		return
+	}
+
+	usage, err := h.storage.Property().GetUsage(context.Background(), propertyID)
+	if HandleHTTPError(c, http.StatusBadRequest, "error while getting property usage", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/property/{property_id} [delete]
+// @Summary Delete Property
+// @Description API for deleting property, refused while entities, drafts or group properties reference it
+// @Tags property
+// @Accept json
+// @Produce json
+// @Param property_id path string  true "property_id"
+// @Success 204
+func (h *handlerV1) DeleteProperty(c *gin.Context) {
+	propertyID := c.Param("property_id")
+
+	_, err := primitive.ObjectIDFromHex(propertyID)
+	if HandleHTTPError(c, http.StatusBadRequest, "Error while parsing objectID, incorrect format", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+
+	usage, err := h.storage.Property().GetUsage(context.Background(), propertyID)
+	if HandleHTTPError(c, http.StatusBadRequest, "error while getting property usage", err) {
+		return
+	}
+	if usage.InUse {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "property is still in use",
+			"usage":   usage,
+		})
+		return
+	}
+
+	err = h.storage.Property().Delete(context.Background(), propertyID)
+	if handleStorageError(c, "error while deleting property", err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/soato"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -116,3 +119,196 @@ func (h *handlerV1) GetAllRegionsByCityID(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, response)
 }
+
+// @Security ApiKeyAuth
+// @Router /v1/region [post]
+// @Summary Create region
+// @Description API for adding a region to an active city, its soato starts with the soato of the city
+// @Tags region
+// @Accept json
+// @Produce json
+// @Param region body models.RegionSwag true "region"
+// @Success 201 {object} models.Region
+func (h *handlerV1) CreateRegion(c *gin.Context) {
+	var body models.RegionSwag
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&body); HandleHTTPError(c, http.StatusBadRequest, "SettingService.CreateRegion.BindingJson", err) {
+		return
+	}
+	region := &models.CreateUpdateRegion{
+		ID:         primitive.NewObjectID(),
+		Name:       body.Name,
+		RuName:     body.RuName,
+		Code:       body.Code,
+		ExternalID: body.ExternalID,
+		Soato:      body.Soato,
+		Active:     unitActive(true, body.Active),
+		UpdatedAt:  time.Now(),
+	}
+	if !h.setRegionCity(c, "SettingService.CreateRegion", region, body.CityID) {
+		return
+	}
+	regionID, err := h.storage.Region().Create(context.Background(), region)
+	if handleStorageError(c, "SettingService.CreateRegion.Create", err) {
+		return
+	}
+	response, err := h.storage.Region().Get(context.Background(), regionID)
+	if handleStorageError(c, "SettingService.CreateRegion.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/region/{region_id} [put]
+// @Summary Update region
+// @Description API for renaming, moving, abolishing or restoring a region. A region with active districts can not be abolished, its soato can not change while districts have codes of the old one. Districts move to the city of the region.
+// @Tags region
+// @Accept json
+// @Produce json
+// @Param region_id path string true "region_id"
+// @Param region body models.RegionSwag true "region"
+// @Success 200 {object} models.Region
+func (h *handlerV1) UpdateRegion(c *gin.Context) {
+	var body models.RegionSwag
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	regionID, ok := parseUnitID(c, "region_id", "SettingService.UpdateRegion.ParseRegionID")
+	if !ok {
+		return
+	}
+	if err := c.ShouldBindJSON(&body); HandleHTTPError(c, http.StatusBadRequest, "SettingService.UpdateRegion.BindingJson", err) {
+		return
+	}
+	current, err := h.storage.Region().Get(context.Background(), regionID.Hex())
+	if handleStorageError(c, "SettingService.UpdateRegion.Get", err) {
+		return
+	}
+	region := &models.CreateUpdateRegion{
+		ID:         regionID,
+		Name:       body.Name,
+		RuName:     body.RuName,
+		Code:       body.Code,
+		ExternalID: body.ExternalID,
+		Soato:      body.Soato,
+		Active:     unitActive(current.Active, body.Active),
+		UpdatedAt:  time.Now(),
+	}
+	if !h.setRegionCity(c, "SettingService.UpdateRegion", region, body.CityID) {
+		return
+	}
+	if current.Active && !region.Active && !h.checkRegionAbolishable(c, "SettingService.UpdateRegion", regionID.Hex()) {
+		return
+	}
+	if !checkChildCodes(c, "SettingService.UpdateRegion", models.AdminUnitRegion, current.Soato, region.Soato, func(from, to uint64) (int64, error) {
+		return h.storage.District().CountCodedOutside(context.Background(), regionID.Hex(), from, to)
+	}) {
+		return
+	}
+	err = h.storage.Region().Update(context.Background(), region)
+	if handleStorageError(c, "SettingService.UpdateRegion.Update", err) {
+		return
+	}
+	if current.Active && !region.Active {
+		h.dropAdminBoundary(models.AdminUnitRegion, regionID.Hex())
+	}
+	// districts keep the city of their region
+	if current.City.ID != body.CityID {
+		err = h.storage.District().MoveRegion(context.Background(), regionID.Hex(), body.CityID)
+		if HandleHTTPError(c, http.StatusInternalServerError, "SettingService.UpdateRegion.MoveDistricts", err) {
+			return
+		}
+	}
+	response, err := h.storage.Region().Get(context.Background(), regionID.Hex())
+	if handleStorageError(c, "SettingService.UpdateRegion.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/region/{region_id} [delete]
+// @Summary Abolish region
+// @Description API for abolishing a region. It is kept with active false, entities registered in it still point to it.
+// @Tags region
+// @Accept json
+// @Produce json
+// @Param region_id path string true "region_id"
+// @Success 204
+func (h *handlerV1) DeleteRegion(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	regionID, ok := parseUnitID(c, "region_id", "SettingService.DeleteRegion.ParseRegionID")
+	if !ok {
+		return
+	}
+	region, err := h.storage.Region().Get(context.Background(), regionID.Hex())
+	if handleStorageError(c, "SettingService.DeleteRegion.Get", err) {
+		return
+	}
+	if region.Active {
+		if !h.checkRegionAbolishable(c, "SettingService.DeleteRegion", regionID.Hex()) {
+			return
+		}
+		cityID, err := primitive.ObjectIDFromHex(region.City.ID)
+		if HandleHTTPError(c, http.StatusInternalServerError, "SettingService.DeleteRegion.ParseCityID", err) {
+			return
+		}
+		err = h.storage.Region().Update(context.Background(), &models.CreateUpdateRegion{
+			ID:         regionID,
+			CityID:     cityID,
+			Name:       region.Name,
+			RuName:     region.RuName,
+			Code:       region.Code,
+			ExternalID: region.ExternalID,
+			Soato:      region.Soato,
+			UpdatedAt:  time.Now(),
+		})
+		if handleStorageError(c, "SettingService.DeleteRegion.Update", err) {
+			return
+		}
+		h.dropAdminBoundary(models.AdminUnitRegion, regionID.Hex())
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setRegionCity checks that the city exists, is active when the region is,
+// and that the soato of the region continues its soato
+func (h *handlerV1) setRegionCity(c *gin.Context, message string, region *models.CreateUpdateRegion, cityID string) bool {
+	objectID, err := primitive.ObjectIDFromHex(cityID)
+	if HandleHTTPError(c, http.StatusBadRequest, message+".ParseCityID", err) {
+		return false
+	}
+	city, err := h.storage.City().Get(context.Background(), cityID)
+	if handleStorageError(c, message+".GetCity", err) {
+		return false
+	}
+	if region.Active && !city.Active {
+		HandleHTTPError(c, http.StatusConflict, message+".GetCity", errParentAbolished)
+		return false
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, message+".ValidateSoato", soato.ValidateCode(models.AdminUnitRegion, city.Soato, region.Soato)) {
+		return false
+	}
+	region.CityID = objectID
+	return true
+}
+
+func (h *handlerV1) checkRegionAbolishable(c *gin.Context, message, regionID string) bool {
+	count, err := h.storage.District().CountActiveByRegion(context.Background(), regionID)
+	if HandleHTTPError(c, http.StatusInternalServerError, message+".CountDistricts", err) {
+		return false
+	}
+	if count > 0 {
+		HandleHTTPError(c, http.StatusConflict, message+".CountDistricts", errActiveChildUnits)
+		return false
+	}
+	return true
+}
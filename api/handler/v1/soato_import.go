@@ -0,0 +1,166 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/soato"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errSoatoImportStale = errors.New("units have changed since the preview, upload the classifier again")
+
+// @Security ApiKeyAuth
+// @Router /v1/soato-import [post]
+// @Summary Upload SOATO classifier
+// @Description API for comparing the official SOATO classifier with the stored cities, regions and districts. The file is a CSV or XLSX with soato, name and ru_name columns, nothing is changed until the import is applied.
+// @Tags soato-import
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX"
+// @Success 201 {object} models.SoatoImport
+func (h *handlerV1) CreateSoatoImport(c *gin.Context) {
+	staffInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.SoatoImportMaxSize)
+	file, err := c.FormFile("file")
+	if HandleHTTPError(c, http.StatusBadRequest, "SoatoImport.Create.FormFile", err) {
+		return
+	}
+	uploaded, err := file.Open()
+	if HandleHTTPError(c, http.StatusBadRequest, "SoatoImport.Create.Open", err) {
+		return
+	}
+	defer uploaded.Close()
+	data, err := ioutil.ReadAll(uploaded)
+	if HandleHTTPError(c, http.StatusBadRequest, "SoatoImport.Create.Read", err) {
+		return
+	}
+	units, ruNames, err := soato.Parse(file.Filename, data)
+	if HandleHTTPError(c, http.StatusBadRequest, "SoatoImport.Create.Parse", err) {
+		return
+	}
+
+	stored, err := h.storage.SoatoImport().GetUnits(context.Background())
+	if HandleHTTPError(c, http.StatusInternalServerError, "SoatoImport.Create.GetUnits", err) {
+		return
+	}
+	soatoImport := &models.SoatoImport{
+		ID:        primitive.NewObjectID().Hex(),
+		FileName:  file.Filename,
+		Status:    models.SoatoImportPreview,
+		Units:     units,
+		RuNames:   ruNames,
+		Diff:      soato.Compare(stored, units, ruNames),
+		CreatedAt: time.Now(),
+		CreatedBy: staffInfo.ID,
+	}
+	err = h.storage.SoatoImport().Create(context.Background(), soatoImport)
+	if HandleHTTPError(c, http.StatusInternalServerError, "SoatoImport.Create", err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, soatoImport)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/soato-import/{import_id} [get]
+// @Summary Get SOATO import
+// @Description API for getting an uploaded classifier with the units it adds, renames, abolishes and restores
+// @Tags soato-import
+// @Accept json
+// @Produce json
+// @Param import_id path string true "import_id"
+// @Success 200 {object} models.SoatoImport
+func (h *handlerV1) GetSoatoImport(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	soatoImport, err := h.storage.SoatoImport().Get(context.Background(), c.Param("import_id"))
+	if handleStorageError(c, "SoatoImport.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, soatoImport)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/soato-import/{import_id}/apply [post]
+// @Summary Apply SOATO import
+// @Description API for writing the previewed changes to the units. Abolished units are kept with active false. The import is refused when the units changed after the preview.
+// @Tags soato-import
+// @Accept json
+// @Produce json
+// @Param import_id path string true "import_id"
+// @Success 200 {object} models.SoatoImport
+func (h *handlerV1) ApplySoatoImport(c *gin.Context) {
+	var importID = c.Param("import_id")
+	staffInfo, err := h.StaffInfo(c)
+	if err != nil {
+		return
+	}
+	soatoImport, err := h.storage.SoatoImport().Get(context.Background(), importID)
+	if handleStorageError(c, "SoatoImport.Apply.Get", err) {
+		return
+	}
+	if soatoImport.Status != models.SoatoImportPreview {
+		HandleHTTPError(c, http.StatusConflict, "SoatoImport.Apply.Status", errors.New("import is "+soatoImport.Status))
+		return
+	}
+
+	// the preview is what the staff agreed to, units edited since then make
+	// it another change
+	stored, err := h.storage.SoatoImport().GetUnits(context.Background())
+	if HandleHTTPError(c, http.StatusInternalServerError, "SoatoImport.Apply.GetUnits", err) {
+		return
+	}
+	if !sameSoatoDiff(soato.Compare(stored, soatoImport.Units, soatoImport.RuNames), soatoImport.Diff) {
+		HandleHTTPError(c, http.StatusConflict, "SoatoImport.Apply.Compare", errSoatoImportStale)
+		return
+	}
+
+	err = h.storage.SoatoImport().Claim(context.Background(), importID, staffInfo.ID)
+	if handleStorageError(c, "SoatoImport.Apply.Claim", err) {
+		return
+	}
+	applyErr := h.storage.SoatoImport().Apply(context.Background(), soatoImport)
+	err = h.storage.SoatoImport().Finish(context.Background(), importID, applyErr)
+	if HandleHTTPError(c, http.StatusInternalServerError, "SoatoImport.Apply", applyErr) ||
+		HandleHTTPError(c, http.StatusInternalServerError, "SoatoImport.Apply.Finish", err) {
+		return
+	}
+	soatoImport, err = h.storage.SoatoImport().Get(context.Background(), importID)
+	if handleStorageError(c, "SoatoImport.Apply.Get", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, soatoImport)
+}
+
+// sameSoatoDiff compares the changes one by one, empty lists may come back
+// from the database as nil
+func sameSoatoDiff(a, b *models.SoatoDiff) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	same := func(x, y []*models.SoatoChange) bool {
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if *x[i] != *y[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Unchanged == b.Unchanged && same(a.Added, b.Added) && same(a.Renamed, b.Renamed) &&
+		same(a.Abolished, b.Abolished) && same(a.Restored, b.Restored)
+}
@@ -0,0 +1,353 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/geo"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxOverlapCandidates caps the entities a boundary is compared with, a
+// boundary over more of them is wrong anyway. Topology tells when there were
+// more.
+const maxOverlapCandidates = 200
+
+var errNoBoundary = errors.New("boundary is not set")
+
+// @Security ApiKeyAuth
+// @Router /v1/entity/{entity_id}/topology [get]
+// @Summary Check entity topology
+// @Description API for checking the boundary of the entity again: its area, how far it is from the declared area and which entities it overlaps. Blocking issues are listed too, nothing is saved.
+// @Tags entity
+// @Accept json
+// @Produce json
+// @Param entity_id path string true "entity_id"
+// @Success 200 {object} models.Topology
+func (h *handlerV1) GetEntityTopology(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	entityID, err := primitive.ObjectIDFromHex(c.Param("entity_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Entity.GetTopology.ParseEntityId", err) {
+		return
+	}
+	entity, err := h.storage.Entity().Get(context.Background(), entityID.Hex())
+	if handleStorageError(c, "Entity.GetTopology.GetEntity", err) {
+		return
+	}
+	if entity.Boundary == nil || len(entity.Boundary.Polygons) == 0 {
+		HandleHTTPError(c, http.StatusNotFound, "Entity.GetTopology", errNoBoundary)
+		return
+	}
+
+	topology, err := h.entityTopology(context.Background(), entity.Boundary, entity)
+	if err != nil && !isTopologyError(err) {
+		HandleHTTPError(c, http.StatusInternalServerError, "Entity.GetTopology", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, topology)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-draft/{entity_draft_id}/topology [get]
+// @Summary Check entity draft topology
+// @Description API for checking the boundary of the draft again: its area, how far it is from the declared area and which entities it overlaps. Blocking issues are listed too, nothing is saved.
+// @Tags entity-draft
+// @Accept json
+// @Produce json
+// @Param entity_draft_id path string true "entity_draft_id"
+// @Success 200 {object} models.Topology
+func (h *handlerV1) GetEntityDraftTopology(c *gin.Context) {
+	if _, err := h.UserInfo(c, true); err != nil {
+		return
+	}
+	entityDraftID, err := primitive.ObjectIDFromHex(c.Param("entity_draft_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "EntityDraft.GetTopology.ParseEntityDraftId", err) {
+		return
+	}
+	draft, err := h.storage.EntityDraft().Get(context.Background(), entityDraftID.Hex())
+	if handleStorageError(c, "EntityDraft.GetTopology.GetEntityDraft", err) {
+		return
+	}
+	if draft.Boundary == nil || len(draft.Boundary.Polygons) == 0 {
+		HandleHTTPError(c, http.StatusNotFound, "EntityDraft.GetTopology", errNoBoundary)
+		return
+	}
+
+	topology, err := h.draftTopology(context.Background(), draft.Boundary, draft)
+	if err != nil && !isTopologyError(err) {
+		HandleHTTPError(c, http.StatusInternalServerError, "EntityDraft.GetTopology", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, topology)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/entity-overlaps [get]
+// @Summary Get entity overlap report
+// @Description API for listing every pair of entities of the district whose boundaries overlap by more than the tolerance, largest first
+// @Tags entity
+// @Accept json
+// @Produce json
+// @Param district_id query string true "district_id"
+// @Success 200 {object} models.OverlapReport
+func (h *handlerV1) GetEntityOverlapReport(c *gin.Context) {
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	districtID := c.Query("district_id")
+	if districtID == "" {
+		HandleHTTPError(c, http.StatusBadRequest, "Entity.GetOverlapReport", errors.New("district_id is required"))
+		return
+	}
+	entities, err := h.storage.Entity().GetDistrictBoundaries(context.Background(), districtID)
+	if handleStorageError(c, "Entity.GetOverlapReport.GetDistrictBoundaries", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.OverlapReport{
+		DistrictID: districtID,
+		Entities:   len(entities),
+		Overlaps:   h.overlapPairs(entities),
+		CreatedAt:  time.Now(),
+	})
+}
+
+// overlapPairs compares the entities sorted by their west edge, so each is
+// only compared with those whose bounds reach it
+func (h *handlerV1) overlapPairs(entities []*models.EntityBoundary) []*models.OverlapPair {
+	type measured struct {
+		entity *models.EntityBoundary
+		bounds [4]float64
+		area   float64
+	}
+	sorted := make([]*measured, 0, len(entities))
+	for _, entity := range entities {
+		if entity.Boundary == nil || len(entity.Boundary.Polygons) == 0 {
+			continue
+		}
+		sorted = append(sorted, &measured{
+			entity: entity,
+			bounds: geo.Bounds(entity.Boundary.Polygons),
+			area:   geo.Area(entity.Boundary.Polygons),
+		})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].bounds[0] < sorted[j].bounds[0]
+	})
+
+	pairs := []*models.OverlapPair{}
+	for i, a := range sorted {
+		for _, b := range sorted[i+1:] {
+			if b.bounds[0] > a.bounds[2] {
+				break
+			}
+			if b.bounds[1] > a.bounds[3] || a.bounds[1] > b.bounds[3] {
+				continue
+			}
+			area := geo.OverlapArea(a.entity.Boundary.Polygons, b.entity.Boundary.Polygons)
+			if area <= h.cfg.OverlapTolerance {
+				continue
+			}
+			percent := overlapPercent(area, a.area, b.area)
+			pairs = append(pairs, &models.OverlapPair{
+				EntityID:          a.entity.ID,
+				EntityNumber:      a.entity.EntityNumber,
+				OtherEntityID:     b.entity.ID,
+				OtherEntityNumber: b.entity.EntityNumber,
+				Area:              roundArea(area),
+				Percent:           roundArea(percent),
+				Blocking:          h.overlapBlocks(percent),
+			})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Area > pairs[j].Area
+	})
+	return pairs
+}
+
+// checkTopology measures the boundary and compares it with the declared area
+// and the boundaries of the other entities. When a rule blocks the boundary
+// the topology comes back with a *models.TopologyError.
+func (h *handlerV1) checkTopology(ctx context.Context, boundary *models.GeoBoundary, excludeID string, declaredArea *float64) (*models.Topology, error) {
+	area := geo.Area(boundary.Polygons)
+	topology := &models.Topology{
+		Area:      roundArea(area),
+		Overlaps:  []*models.EntityOverlap{},
+		Issues:    []*models.TopologyIssue{},
+		CheckedAt: time.Now(),
+	}
+
+	if declaredArea != nil {
+		declared := roundArea(*declaredArea)
+		topology.DeclaredArea = &declared
+		discrepancy := math.Abs(area-*declaredArea) / *declaredArea * 100
+		topology.AreaDiscrepancy = roundArea(discrepancy)
+		blocking := h.cfg.AreaBlockPercent > 0 && discrepancy > h.cfg.AreaBlockPercent
+		if blocking || (h.cfg.AreaWarnPercent > 0 && discrepancy > h.cfg.AreaWarnPercent) {
+			topology.Issues = append(topology.Issues, &models.TopologyIssue{
+				Code:     models.TopologyAreaDiscrepancy,
+				Blocking: blocking,
+				Message: fmt.Sprintf("boundary area %.1f m2 differs from the declared %.1f m2 by %.1f%%",
+					area, *declaredArea, discrepancy),
+			})
+		}
+	}
+
+	others, err := h.storage.Entity().GetOverlapping(ctx, boundary, excludeID, maxOverlapCandidates+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(others) > maxOverlapCandidates {
+		others = others[:maxOverlapCandidates]
+		topology.OverlapsTruncated = true
+		topology.Issues = append(topology.Issues, &models.TopologyIssue{
+			Code:    models.TopologyTooManyCandidates,
+			Message: fmt.Sprintf("boundary touches more than %d entities, only the first %d are checked for overlaps", maxOverlapCandidates, maxOverlapCandidates),
+		})
+	}
+	for _, other := range others {
+		if other.Boundary == nil {
+			continue
+		}
+		overlap := geo.OverlapArea(boundary.Polygons, other.Boundary.Polygons)
+		if overlap <= h.cfg.OverlapTolerance {
+			continue
+		}
+		percent := overlapPercent(overlap, area, geo.Area(other.Boundary.Polygons))
+		topology.Overlaps = append(topology.Overlaps, &models.EntityOverlap{
+			EntityID:     other.ID,
+			EntityNumber: other.EntityNumber,
+			Area:         roundArea(overlap),
+			Percent:      roundArea(percent),
+		})
+		topology.Issues = append(topology.Issues, &models.TopologyIssue{
+			Code:     models.TopologyOverlap,
+			Blocking: h.overlapBlocks(percent),
+			Message:  fmt.Sprintf("boundary overlaps entity %s by %.1f m2 (%.1f%%)", other.EntityNumber, overlap, percent),
+		})
+	}
+	sort.SliceStable(topology.Overlaps, func(i, j int) bool {
+		return topology.Overlaps[i].Area > topology.Overlaps[j].Area
+	})
+
+	for _, issue := range topology.Issues {
+		if issue.Blocking {
+			return topology, &models.TopologyError{Issues: topology.Issues}
+		}
+	}
+	return topology, nil
+}
+
+// entityTopology checks a boundary of the entity, which is not compared with
+// itself
+func (h *handlerV1) entityTopology(ctx context.Context, boundary *models.GeoBoundary, entity *models.Entity) (*models.Topology, error) {
+	return h.checkTopology(ctx, boundary, entity.ID, h.declaredArea(entity.EntityProperty))
+}
+
+// draftTopology checks a boundary of the draft, the entity the draft
+// changes is not compared with it
+func (h *handlerV1) draftTopology(ctx context.Context, boundary *models.GeoBoundary, draft *models.EntityDraft) (*models.Topology, error) {
+	excludeID := ""
+	if draft.Entity != nil {
+		excludeID = draft.Entity.ID
+	}
+	return h.checkTopology(ctx, boundary, excludeID, h.declaredArea(draft.EntityProperty))
+}
+
+func (h *handlerV1) overlapBlocks(percent float64) bool {
+	return h.cfg.OverlapBlockPercent > 0 && percent > h.cfg.OverlapBlockPercent
+}
+
+// declaredArea is the value of the first area property in square meters,
+// nil when there is none or it is not a positive number
+func (h *handlerV1) declaredArea(properties []*models.GetEntityProperty) *float64 {
+	for _, property := range properties {
+		if property == nil || property.Property == nil || !isAreaProperty(property.Property.Type) {
+			continue
+		}
+		return h.parseArea(property.Value)
+	}
+	return nil
+}
+
+// declaredAreaByID is declaredArea for properties given by id, as on create
+func (h *handlerV1) declaredAreaByID(ctx context.Context, properties []*models.CreateEntityProperty) (*float64, error) {
+	for _, property := range properties {
+		if property == nil {
+			continue
+		}
+		definition, err := h.storage.Property().Get(ctx, property.PropertyID.Hex())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isAreaProperty(definition.Type) {
+			return h.parseArea(property.Value), nil
+		}
+	}
+	return nil, nil
+}
+
+// parseArea takes a decimal comma too, as forms are filled in by hand. The
+// unit is checked at startup, without one there is no declared area.
+func (h *handlerV1) parseArea(value string) *float64 {
+	area, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(value), ",", ".", 1), 64)
+	if err != nil || !(area > 0) || math.IsInf(area, 0) {
+		return nil
+	}
+	unit, err := geo.AreaUnit(h.cfg.AreaPropertyUnit)
+	if err != nil {
+		return nil
+	}
+	area *= unit
+	return &area
+}
+
+func isAreaProperty(propertyType string) bool {
+	return strings.ToLower(strings.TrimSpace(propertyType)) == "area"
+}
+
+func overlapPercent(overlap, area, otherArea float64) float64 {
+	smaller := math.Min(area, otherArea)
+	if smaller <= 0 {
+		return 0
+	}
+	return math.Min(overlap/smaller*100, 100)
+}
+
+// roundArea keeps two decimals of areas and percents, more than surveys
+// measure
+func roundArea(area float64) float64 {
+	return math.Round(area*100) / 100
+}
+
+func isTopologyError(err error) bool {
+	var topologyError *models.TopologyError
+	return errors.As(err, &topologyError)
+}
+
+// handleTopologyError rejects a boundary with blocking issues with 409, the
+// error lists all of them
+func handleTopologyError(c *gin.Context, message string, err error) bool {
+	if isTopologyError(err) {
+		return HandleHTTPError(c, http.StatusConflict, message, err)
+	}
+	return HandleHTTPError(c, http.StatusInternalServerError, message, err)
+}
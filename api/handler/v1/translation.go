@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// requestLanguage negotiates the response language from Accept-Language. An
+// empty result means the client did not ask for one and the stored texts are
+// returned as they are, so admin forms do not save translated texts back.
+func requestLanguage(c *gin.Context) string {
+	lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+	if lang != "" {
+		c.Header("Content-Language", lang)
+		c.Header("Vary", "Accept-Language")
+	}
+	return lang
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/property/{property_id}/translations [put]
+// @Summary Update property translations
+// @Description API for adding, changing or removing (empty text) translations of the label, placeholder, description and option names of a property
+// @Tags translation
+// @Accept json
+// @Produce json
+// @Param property_id path string true "property_id"
+// @Param translations body models.UpdatePropertyTranslationsSwag true "translations"
+// @Success 200 {object} models.Property
+func (h *handlerV1) UpdatePropertyTranslations(c *gin.Context) {
+	var (
+		translations models.UpdatePropertyTranslationsSwag
+		propertyID   = c.Param("property_id")
+		_, err       = primitive.ObjectIDFromHex(propertyID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateProperty.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&translations); HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateProperty.BindingJson", err) {
+		return
+	}
+
+	err = validateTranslations(translations.Label, translations.Placeholder, translations.Description)
+	if HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateProperty.Validate", err) {
+		return
+	}
+
+	property, err := h.storage.Property().Get(context.Background(), propertyID)
+	if handleStorageError(c, "Translation.UpdateProperty.GetProperty", err) {
+		return
+	}
+	options := make(map[string]bool, len(property.PropertyOptions))
+	for _, option := range property.PropertyOptions {
+		options[option.Value] = true
+	}
+	for value, optionTranslations := range translations.Options {
+		if !options[value] {
+			HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateProperty.Validate", fmt.Errorf("property has no option with value %q", value))
+			return
+		}
+		err = validateTranslations(optionTranslations)
+		if HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateProperty.Validate", err) {
+			return
+		}
+	}
+
+	err = h.storage.Property().UpdateTranslations(context.Background(), propertyID, &translations)
+	if handleStorageError(c, "Translation.UpdateProperty", err) {
+		return
+	}
+
+	property, err = h.storage.Property().Get(context.Background(), propertyID)
+	if handleStorageError(c, "Translation.UpdateProperty.GetProperty", err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, property)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/group-property/{group_property_id}/translations [put]
+// @Summary Update group property translations
+// @Description API for adding, changing or removing (empty text) translations of the name and description of a group property
+// @Tags translation
+// @Accept json
+// @Produce json
+// @Param group_property_id path string true "group_property_id"
+// @Param translations body models.UpdateGroupPropertyTranslationsSwag true "translations"
+// @Success 200 {object} models.GroupProperty
+func (h *handlerV1) UpdateGroupPropertyTranslations(c *gin.Context) {
+	var (
+		translations models.UpdateGroupPropertyTranslationsSwag
+		ID           = c.Param("group_property_id")
+		_, err       = primitive.ObjectIDFromHex(ID)
+	)
+	if HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateGroupProperty.ParseId", err) {
+		return
+	}
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+	if err := c.ShouldBindJSON(&translations); HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateGroupProperty.BindingJson", err) {
+		return
+	}
+
+	err = validateTranslations(translations.Name, translations.Description)
+	if HandleHTTPError(c, http.StatusBadRequest, "Translation.UpdateGroupProperty.Validate", err) {
+		return
+	}
+
+	err = h.storage.GroupProperty().UpdateTranslations(context.Background(), ID, &translations)
+	if handleStorageError(c, "Translation.UpdateGroupProperty", err) {
+		return
+	}
+
+	h.respondGroupProperty(c, ID)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/translations/missing [get]
+// @Summary Missing translations report
+// @Description API for listing the property and group property texts that are not translated. lang is a comma separated list, all languages by default.
+// @Tags translation
+// @Accept json
+// @Produce json
+// @Param lang query string false "lang" example(ru,uz-Cyrl)
+// @Param kind query string false "property, property_option or group_property"
+// @Success 200 {object} models.MissingTranslationsResponse
+func (h *handlerV1) GetMissingTranslations(c *gin.Context) {
+	var (
+		languages = i18n.Languages
+		kind      = c.Query("kind")
+		response  = models.MissingTranslationsResponse{
+			MissingTranslations: []*models.MissingTranslation{},
+		}
+	)
+	if _, err := h.StaffInfo(c); err != nil {
+		return
+	}
+
+	if langQuery := c.Query("lang"); langQuery != "" {
+		languages = []string{}
+		for _, tag := range strings.Split(langQuery, ",") {
+			lang := i18n.Normalize(tag)
+			if lang == "" {
+				HandleHTTPError(c, http.StatusBadRequest, "Translation.GetMissing.ParseLang", fmt.Errorf("unsupported language %q", tag))
+				return
+			}
+			languages = append(languages, lang)
+		}
+	}
+	response.Languages = languages
+
+	properties, err := h.storage.Property().GetMissingTranslations(context.Background(), languages, i18n.Default)
+	if HandleHTTPError(c, http.StatusBadRequest, "Translation.GetMissing.Property", err) {
+		return
+	}
+	groupProperties, err := h.storage.GroupProperty().GetMissingTranslations(context.Background(), languages, i18n.Default)
+	if HandleHTTPError(c, http.StatusBadRequest, "Translation.GetMissing.GroupProperty", err) {
+		return
+	}
+
+	for _, missing := range append(properties, groupProperties...) {
+		if kind == "" || missing.Kind == kind {
+			response.MissingTranslations = append(response.MissingTranslations, missing)
+		}
+	}
+	response.Count = uint32(len(response.MissingTranslations))
+
+	c.JSON(http.StatusOK, response)
+}
+
+// validateTranslations only accepts the supported language tags as keys
+func validateTranslations(translations ...models.Translations) error {
+	var unsupported []string
+	for _, t := range translations {
+		for lang := range t {
+			if !i18n.IsSupported(lang) {
+				unsupported = append(unsupported, lang)
+			}
+		}
+	}
+	if len(unsupported) != 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("unsupported languages %s, use one of %s",
+			strings.Join(unsupported, ", "), strings.Join(i18n.Languages, ", "))
+	}
+	return nil
+}
+
+func validatePropertyTranslations(property *models.CreateUpdateProperty) error {
+	translations := []models.Translations{}
+	if property.Translations != nil {
+		translations = append(translations,
+			property.Translations.Label,
+			property.Translations.Placeholder,
+			property.Translations.Description)
+	}
+	for _, option := range property.PropertyOptions {
+		if option != nil {
+			translations = append(translations, option.Translations)
+		}
+	}
+	return validateTranslations(translations...)
+}
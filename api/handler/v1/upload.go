@@ -0,0 +1,414 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/tus"
+	"github.com/e-space-uz/backend/storage/repo"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// @Router /v1/uploads [options]
+// @Summary Resumable upload capabilities
+// @Description tus 1.0 discovery, lists the supported version, extensions and the largest upload
+// @Tags upload
+// @Success 204
+func (h *handlerV1) GetUploadOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tus.Version)
+	c.Header("Tus-Version", tus.Version)
+	c.Header("Tus-Extension", tus.Extensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.cfg.ResumableUploadMaxSize, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/uploads [post]
+// @Summary Create resumable upload
+// @Description tus 1.0 creation. Upload-Length is required, Upload-Metadata may carry filename, filetype and comment. The upload URL is returned in Location.
+// @Tags upload
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Length header integer true "size of the file in bytes"
+// @Param Upload-Metadata header string false "filename <base64>,filetype <base64>,comment <base64>"
+// @Success 201
+func (h *handlerV1) CreateUpload(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		HandleHTTPError(c, http.StatusBadRequest, "Upload.Create.UploadLength",
+			errors.New("Upload-Length must be a positive number, deferred length is not supported"))
+		return
+	}
+	if length > h.cfg.ResumableUploadMaxSize {
+		tusError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload must not exceed %d bytes", h.cfg.ResumableUploadMaxSize))
+		return
+	}
+	metadata, err := tus.ParseMetadata(c.GetHeader("Upload-Metadata"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Upload.Create.UploadMetadata", err) {
+		return
+	}
+	// the content is checked once the upload is complete, a declared type
+	// that is not allowed is refused before anything is sent
+	if metadata["filetype"] != "" {
+		_, err := h.allowedContentType(metadata["filetype"])
+		if HandleHTTPError(c, http.StatusBadRequest, "Upload.Create.ContentType", err) {
+			return
+		}
+	}
+
+	upload := &models.Upload{
+		ID:          primitive.NewObjectID(),
+		User:        userInfo.ID,
+		Length:      length,
+		FileName:    metadata["filename"],
+		ContentType: metadata["filetype"],
+		Comment:     metadata["comment"],
+		Parts:       []*models.UploadPart{},
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(h.cfg.ResumableUploadExpiry),
+	}
+	err = h.storage.Upload().Create(context.Background(), upload)
+	if HandleHTTPError(c, http.StatusBadRequest, "Upload.Create", err) {
+		return
+	}
+
+	c.Header("Location", "/v1/uploads/"+upload.ID.Hex())
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/uploads/{upload_id} [head]
+// @Summary Get resumable upload offset
+// @Description tus 1.0 HEAD, Upload-Offset tells where to resume
+// @Tags upload
+// @Param upload_id path string true "upload_id"
+// @Param Tus-Resumable header string true "1.0.0"
+// @Success 200
+func (h *handlerV1) GetUploadOffset(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	upload, ok := h.userUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if !upload.Completed {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	c.Status(http.StatusOK)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/uploads/{upload_id} [get]
+// @Summary Get resumable upload
+// @Description API for getting the state of an upload, entity_file_id is set once it is complete
+// @Tags upload
+// @Produce json
+// @Param upload_id path string true "upload_id"
+// @Success 200 {object} models.Upload
+func (h *handlerV1) GetUpload(c *gin.Context) {
+	upload, ok := h.userUpload(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, upload)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/uploads/{upload_id} [patch]
+// @Summary Upload a chunk
+// @Description tus 1.0 PATCH. Upload-Offset has to match the offset of the upload. When the last chunk arrives the file is checked and registered as an entity file.
+// @Tags upload
+// @Accept application/offset+octet-stream
+// @Param upload_id path string true "upload_id"
+// @Param Tus-Resumable header string true "1.0.0"
+// @Param Upload-Offset header integer true "offset of the chunk"
+// @Success 204
+func (h *handlerV1) PatchUpload(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	if c.ContentType() != tus.OffsetContentType {
+		tusError(c, http.StatusUnsupportedMediaType, "Content-Type must be "+tus.OffsetContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		HandleHTTPError(c, http.StatusBadRequest, "Upload.Patch.UploadOffset", errors.New("Upload-Offset must be a number"))
+		return
+	}
+	upload, ok := h.userUpload(c)
+	if !ok {
+		return
+	}
+	if offset != upload.Offset {
+		HandleHTTPError(c, http.StatusConflict, "Upload.Patch",
+			fmt.Errorf("Upload-Offset is %d, the upload is at %d", offset, upload.Offset))
+		return
+	}
+
+	if upload.Offset < upload.Length {
+		part, ok := h.storeUploadPart(c, upload)
+		if !ok {
+			return
+		}
+		if part != nil {
+			upload.Offset += part.Size
+			upload.Parts = append(upload.Parts, part)
+		}
+	}
+	// a failed completion is retried by sending an empty chunk at the end
+	if upload.Offset == upload.Length && !upload.Completed {
+		if !h.completeUpload(c, upload) {
+			return
+		}
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if !upload.Completed {
+		c.Header("Upload-Expires", time.Now().Add(h.cfg.ResumableUploadExpiry).UTC().Format(http.TimeFormat))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Security ApiKeyAuth
+// @Router /v1/uploads/{upload_id} [delete]
+// @Summary Terminate resumable upload
+// @Description tus 1.0 termination, the received chunks are removed. A completed upload keeps its entity file.
+// @Tags upload
+// @Param upload_id path string true "upload_id"
+// @Param Tus-Resumable header string true "1.0.0"
+// @Success 204
+func (h *handlerV1) TerminateUpload(c *gin.Context) {
+	if !tusRequest(c) {
+		return
+	}
+	upload, ok := h.userUpload(c)
+	if !ok {
+		return
+	}
+
+	err := tus.RemoveParts(context.Background(), h.fileStore, upload)
+	if HandleHTTPError(c, http.StatusInternalServerError, "Upload.Terminate.RemoveParts", err) {
+		return
+	}
+	err = h.storage.Upload().Delete(context.Background(), upload.ID.Hex())
+	if handleStorageError(c, "Upload.Terminate", err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// userUpload loads the upload of the path for its owner. Uploads of other
+// users are reported as missing. It returns false when the response has been
+// written already.
+func (h *handlerV1) userUpload(c *gin.Context) (*models.Upload, bool) {
+	userInfo, err := h.UserInfo(c, true)
+	if err != nil {
+		return nil, false
+	}
+	uploadID, err := primitive.ObjectIDFromHex(c.Param("upload_id"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Upload.ParseUploadId", err) {
+		return nil, false
+	}
+
+	upload, err := h.storage.Upload().Get(context.Background(), uploadID.Hex())
+	if err == nil && upload.User != userInfo.ID {
+		err = mongo.ErrNoDocuments
+	}
+	if handleStorageError(c, "Upload.Get", err) {
+		return nil, false
+	}
+	if !upload.Completed && time.Now().After(upload.ExpiresAt) {
+		tusError(c, http.StatusGone, "upload has expired")
+		return nil, false
+	}
+	return upload, true
+}
+
+// storeUploadPart keeps the body of a PATCH request as a chunk, an empty
+// body gives no chunk. The body is spooled to a temporary file first, so the
+// part received before a dropped connection is kept and the upload resumes
+// from there.
+func (h *handlerV1) storeUploadPart(c *gin.Context, upload *models.Upload) (*models.UploadPart, bool) {
+	remaining := upload.Length - upload.Offset
+
+	tmp, err := ioutil.TempFile("", "upload-*")
+	if HandleHTTPError(c, http.StatusInternalServerError, "Upload.Patch.TempFile", err) {
+		return nil, false
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	size, readErr := io.Copy(tmp, io.LimitReader(c.Request.Body, remaining+1))
+	if size > remaining {
+		HandleHTTPError(c, http.StatusBadRequest, "Upload.Patch", errors.New("chunk goes past Upload-Length"))
+		return nil, false
+	}
+	if readErr != nil {
+		h.log.Warn("upload chunk was cut", logger.String("upload", upload.ID.Hex()), logger.Error(readErr))
+	}
+	if size == 0 {
+		if HandleHTTPError(c, http.StatusBadRequest, "Upload.Patch.ReadBody", readErr) {
+			return nil, false
+		}
+		return nil, true
+	}
+
+	// every chunk is kept in the upload document, the count is bounded so
+	// it stays well inside the MongoDB document limit
+	if len(upload.Parts)+1 >= h.cfg.ResumableUploadMaxParts && size < remaining {
+		HandleHTTPError(c, http.StatusBadRequest, "Upload.Patch",
+			fmt.Errorf("upload has too many chunks, the remaining %d bytes have to be sent at once", remaining))
+		return nil, false
+	}
+
+	part := &models.UploadPart{
+		Name:   tus.PartName(upload.ID.Hex(), upload.Offset, primitive.NewObjectID().Hex()),
+		Offset: upload.Offset,
+		Size:   size,
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); HandleHTTPError(c, http.StatusInternalServerError, "Upload.Patch.Seek", err) {
+		return nil, false
+	}
+	err = h.fileStore.Put(context.Background(), part.Name, tmp, size, "application/octet-stream")
+	if HandleHTTPError(c, http.StatusInternalServerError, "Upload.Patch.PutPart", err) {
+		return nil, false
+	}
+
+	err = h.storage.Upload().AppendPart(context.Background(), upload.ID.Hex(), part, time.Now().Add(h.cfg.ResumableUploadExpiry))
+	if errors.Is(err, repo.ErrUploadOffsetConflict) {
+		// another request for the same offset won
+		_ = h.fileStore.Delete(context.Background(), part.Name)
+		HandleHTTPError(c, http.StatusConflict, "Upload.Patch.AppendPart", err)
+		return nil, false
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "Upload.Patch.AppendPart", err) {
+		return nil, false
+	}
+	if readErr != nil {
+		HandleHTTPError(c, http.StatusBadRequest, "Upload.Patch.ReadBody", readErr)
+		return nil, false
+	}
+	return part, true
+}
+
+// completeUpload joins the chunks into one file, checks it like any other
+// upload and registers it in EntityFilesCollection
+func (h *handlerV1) completeUpload(c *gin.Context, upload *models.Upload) bool {
+	objectName := upload.ID.Hex() + strings.ToLower(filepath.Ext(upload.FileName))
+	if !objectNamePattern.MatchString(objectName) {
+		objectName = upload.ID.Hex()
+	}
+
+	// an earlier attempt may have registered the file and failed afterwards
+	entityFile, err := h.storage.EntityFiles().GetByObjectName(context.Background(), objectName)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		HandleHTTPError(c, http.StatusBadRequest, "Upload.Complete.GetByObjectName", err)
+		return false
+	}
+	if entityFile == nil {
+		parts := make([]string, 0, len(upload.Parts))
+		for _, part := range upload.Parts {
+			if part.Size > 0 {
+				parts = append(parts, part.Name)
+			}
+		}
+		contentType := upload.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		content := tus.NewPartsReader(context.Background(), h.fileStore, parts)
+		err = h.fileStore.Put(context.Background(), objectName, content, upload.Length, contentType)
+		_ = content.Close()
+		if HandleHTTPError(c, http.StatusInternalServerError, "Upload.Complete.Put", err) {
+			return false
+		}
+		info, err := h.fileStore.Stat(context.Background(), objectName)
+		if HandleHTTPError(c, http.StatusInternalServerError, "Upload.Complete.Stat", err) {
+			return false
+		}
+
+		entityFiles := &models.CreateEntityFiles{
+			FileName: upload.FileName,
+			Comment:  upload.Comment,
+			User:     upload.User,
+		}
+		if !h.checkStoredFile(c, info, entityFiles, h.resumableUploadPolicy()) {
+			return false
+		}
+		id, err := h.storage.EntityFiles().Create(context.Background(), entityFiles)
+		if mongo.IsDuplicateKeyError(err) {
+			// a completion sent at the same time registered the file, it is
+			// the same upload so its file is taken
+			entityFile, err = h.storage.EntityFiles().GetByObjectName(context.Background(), objectName)
+		} else if err == nil {
+			entityFile, err = h.storage.EntityFiles().Get(context.Background(), id)
+		}
+		if HandleHTTPError(c, http.StatusBadRequest, "Upload.Complete.Create", err) {
+			return false
+		}
+	}
+
+	err = h.storage.Upload().Complete(context.Background(), upload.ID.Hex(), entityFile.ID)
+	if HandleHTTPError(c, http.StatusBadRequest, "Upload.Complete", err) {
+		return false
+	}
+	if err := tus.RemoveParts(context.Background(), h.fileStore, upload); err != nil {
+		h.log.Error("error while removing upload parts", logger.Error(err))
+	}
+	upload.Completed = true
+	upload.EntityFileID = entityFile.ID
+	return true
+}
+
+// tusRequest refuses requests of other protocol versions
+func tusRequest(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tus.Version)
+	if c.GetHeader("Tus-Resumable") != tus.Version {
+		c.Header("Tus-Version", tus.Version)
+		tusError(c, http.StatusPreconditionFailed, "Tus-Resumable must be "+tus.Version)
+		return false
+	}
+	return true
+}
+
+// tusError answers with the status codes of the tus protocol that
+// HandleHTTPError does not know
+func tusError(c *gin.Context, code int, message string) {
+	c.JSON(code, gin.H{
+		"success": false,
+		"message": message,
+	})
+}
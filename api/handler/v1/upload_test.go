@@ -0,0 +1,548 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/scanner"
+	"github.com/e-space-uz/backend/pkg/security"
+	"github.com/e-space-uz/backend/pkg/tus"
+	"github.com/e-space-uz/backend/storage"
+	"github.com/e-space-uz/backend/storage/repo"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const uploadTestSecret = "upload-test-secret"
+
+// uploadStorage keeps uploads and entity files in memory, the other
+// repositories are not used by the upload handlers
+type uploadStorage struct {
+	storage.StorageI
+	uploads *memoryUploads
+	files   *memoryEntityFiles
+}
+
+func (s *uploadStorage) Upload() repo.UploadI {
+	return s.uploads
+}
+
+func (s *uploadStorage) EntityFiles() repo.EntityFilesI {
+	return s.files
+}
+
+// memoryUploads behaves like the MongoDB repository: AppendPart only
+// matches the current offset of an unfinished upload
+type memoryUploads struct {
+	mu      sync.Mutex
+	uploads map[string]*models.Upload
+}
+
+func (m *memoryUploads) Create(ctx context.Context, req *models.Upload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploads[req.ID.Hex()] = copyUpload(req)
+	return nil
+}
+
+func (m *memoryUploads) Get(ctx context.Context, id string) (*models.Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, ok := m.uploads[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return copyUpload(upload), nil
+}
+
+func (m *memoryUploads) AppendPart(ctx context.Context, id string, part *models.UploadPart, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, ok := m.uploads[id]
+	if !ok || upload.Offset != part.Offset || upload.Completed {
+		return repo.ErrUploadOffsetConflict
+	}
+	partCopy := *part
+	upload.Parts = append(upload.Parts, &partCopy)
+	upload.Offset += part.Size
+	upload.ExpiresAt = expiresAt
+	return nil
+}
+
+func (m *memoryUploads) Complete(ctx context.Context, id, entityFileID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	upload, ok := m.uploads[id]
+	if !ok {
+		return mongo.ErrNoDocuments
+	}
+	upload.Completed = true
+	upload.EntityFileID = entityFileID
+	return nil
+}
+
+func (m *memoryUploads) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.uploads[id]; !ok {
+		return mongo.ErrNoDocuments
+	}
+	delete(m.uploads, id)
+	return nil
+}
+
+func (m *memoryUploads) GetExpired(ctx context.Context, now time.Time, limit int64) ([]*models.Upload, error) {
+	return nil, nil
+}
+
+func copyUpload(upload *models.Upload) *models.Upload {
+	result := *upload
+	result.Parts = make([]*models.UploadPart, 0, len(upload.Parts))
+	for _, part := range upload.Parts {
+		partCopy := *part
+		result.Parts = append(result.Parts, &partCopy)
+	}
+	return &result
+}
+
+type memoryEntityFiles struct {
+	repo.EntityFilesI
+	mu    sync.Mutex
+	files map[string]*models.EntityFiles
+}
+
+func (m *memoryEntityFiles) Create(ctx context.Context, req *models.CreateEntityFiles) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, file := range m.files {
+		if file.ObjectName == req.ObjectName {
+			return "", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}
+		}
+	}
+	m.files[req.ID.Hex()] = &models.EntityFiles{
+		ID:          req.ID.Hex(),
+		FileName:    req.FileName,
+		ObjectName:  req.ObjectName,
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		Comment:     req.Comment,
+		User:        req.User,
+	}
+	return req.ID.Hex(), nil
+}
+
+func (m *memoryEntityFiles) Get(ctx context.Context, id string) (*models.EntityFiles, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return file, nil
+}
+
+func (m *memoryEntityFiles) GetByObjectName(ctx context.Context, objectName string) (*models.EntityFiles, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, file := range m.files {
+		if file.ObjectName == objectName {
+			return file, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+type uploadTest struct {
+	t       *testing.T
+	router  *gin.Engine
+	store   filestore.FileStore
+	storage *uploadStorage
+	token   string
+}
+
+func newUploadTest(t *testing.T, maxParts int) *uploadTest {
+	gin.SetMode(gin.TestMode)
+	store, err := filestore.NewLocal(t.TempDir(), "http://localhost/files", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	strg := &uploadStorage{
+		uploads: &memoryUploads{uploads: map[string]*models.Upload{}},
+		files:   &memoryEntityFiles{files: map[string]*models.EntityFiles{}},
+	}
+	h := New(&HandlerV1Options{
+		Cfg: config.Config{
+			LoginSecretAccessKey:      uploadTestSecret,
+			AllowedUploadContentTypes: []string{"text/plain"},
+			ResumableUploadMaxSize:    1 << 20,
+			ResumableUploadMaxParts:   maxParts,
+			ResumableUploadExpiry:     time.Hour,
+		},
+		Log:       logger.New("ERROR", "upload_test"),
+		Storage:   strg,
+		FileStore: store,
+		Scanner:   scanner.NewNoop(),
+	})
+
+	router := gin.New()
+	router.POST("/v1/uploads", h.CreateUpload)
+	router.HEAD("/v1/uploads/:upload_id", h.GetUploadOffset)
+	router.PATCH("/v1/uploads/:upload_id", h.PatchUpload)
+
+	token, err := security.GenerateJWT(jwt.MapClaims{
+		"id":        "applicant-1",
+		"login":     "applicant",
+		"user_type": "applicant",
+		"full_name": "Applicant",
+	}, time.Hour, uploadTestSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &uploadTest{
+		t:       t,
+		router:  router,
+		store:   store,
+		storage: strg,
+		token:   token,
+	}
+}
+
+func (u *uploadTest) do(method, path string, header map[string]string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Authorization", u.token)
+	req.Header.Set("Tus-Resumable", tus.Version)
+	for key, value := range header {
+		if value == "" {
+			req.Header.Del(key)
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	u.router.ServeHTTP(w, req)
+	return w
+}
+
+// create starts an upload of length bytes and returns its path
+func (u *uploadTest) create(length int) string {
+	metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("notes.txt")) +
+		",filetype " + base64.StdEncoding.EncodeToString([]byte("text/plain"))
+	w := u.do(http.MethodPost, "/v1/uploads", map[string]string{
+		"Upload-Length":   strconv.Itoa(length),
+		"Upload-Metadata": metadata,
+	}, nil)
+	if w.Code != http.StatusCreated {
+		u.t.Fatalf("create: got status %d: %s", w.Code, w.Body.String())
+	}
+	return w.Header().Get("Location")
+}
+
+func (u *uploadTest) patch(path string, offset int, body io.Reader) *httptest.ResponseRecorder {
+	return u.do(http.MethodPatch, path, map[string]string{
+		"Content-Type":  tus.OffsetContentType,
+		"Upload-Offset": strconv.Itoa(offset),
+	}, body)
+}
+
+func (u *uploadTest) upload(path string) *models.Upload {
+	upload, err := u.storage.uploads.Get(context.Background(), strings.TrimPrefix(path, "/v1/uploads/"))
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	return upload
+}
+
+func TestCreateUpload(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		code   int
+	}{
+		{
+			name:   "no length",
+			header: map[string]string{},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "zero length",
+			header: map[string]string{"Upload-Length": "0"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "deferred length",
+			header: map[string]string{"Upload-Defer-Length": "1"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "too large",
+			header: map[string]string{"Upload-Length": strconv.Itoa(1<<20 + 1)},
+			code:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "invalid metadata",
+			header: map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename notes.txt"},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "type not allowed",
+			header: map[string]string{"Upload-Length": "10", "Upload-Metadata": "filetype " + base64.StdEncoding.EncodeToString([]byte("image/png"))},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "other protocol version",
+			header: map[string]string{"Upload-Length": "10", "Tus-Resumable": "0.2.2"},
+			code:   http.StatusPreconditionFailed,
+		},
+		{
+			name:   "no metadata",
+			header: map[string]string{"Upload-Length": "10"},
+			code:   http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUploadTest(t, 10)
+			w := u.do(http.MethodPost, "/v1/uploads", tt.header, nil)
+			if w.Code != tt.code {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if got := len(u.storage.uploads.uploads); (got == 1) != (tt.code == http.StatusCreated) {
+				t.Fatalf("got %d uploads", got)
+			}
+		})
+	}
+}
+
+// TestPatchUpload sends the steps in order to one upload of "hello world"
+func TestPatchUpload(t *testing.T) {
+	u := newUploadTest(t, 10)
+	path := u.create(11)
+
+	steps := []struct {
+		name   string
+		header map[string]string
+		offset int
+		body   io.Reader
+		code   int
+		// want is the offset of the upload after the step
+		want int
+	}{
+		{
+			name:   "offset ahead",
+			offset: 3,
+			body:   strings.NewReader("lo "),
+			code:   http.StatusConflict,
+			want:   0,
+		},
+		{
+			name:   "wrong content type",
+			header: map[string]string{"Content-Type": "application/octet-stream"},
+			body:   strings.NewReader("hello "),
+			code:   http.StatusUnsupportedMediaType,
+			want:   0,
+		},
+		{
+			name:   "negative offset",
+			header: map[string]string{"Upload-Offset": "-1"},
+			body:   strings.NewReader("hello "),
+			code:   http.StatusBadRequest,
+			want:   0,
+		},
+		{
+			name: "empty chunk",
+			body: strings.NewReader(""),
+			code: http.StatusNoContent,
+			want: 0,
+		},
+		{
+			name: "past the length",
+			body: strings.NewReader("hello world!"),
+			code: http.StatusBadRequest,
+			want: 0,
+		},
+		{
+			name: "cut connection keeps the received part",
+			body: io.MultiReader(strings.NewReader("hel"), iotest.ErrReader(errors.New("connection reset"))),
+			code: http.StatusBadRequest,
+			want: 3,
+		},
+		{
+			name:   "resend of the first chunk",
+			offset: 0,
+			body:   strings.NewReader("hel"),
+			code:   http.StatusConflict,
+			want:   3,
+		},
+		{
+			name:   "offset behind",
+			offset: 2,
+			body:   strings.NewReader("llo "),
+			code:   http.StatusConflict,
+			want:   3,
+		},
+		{
+			name:   "next chunk",
+			offset: 3,
+			body:   strings.NewReader("lo wor"),
+			code:   http.StatusNoContent,
+			want:   9,
+		},
+		{
+			name:   "short final chunk",
+			offset: 9,
+			body:   strings.NewReader("ld"),
+			code:   http.StatusNoContent,
+			want:   11,
+		},
+		{
+			name:   "empty chunk after completion",
+			offset: 11,
+			body:   strings.NewReader(""),
+			code:   http.StatusNoContent,
+			want:   11,
+		},
+	}
+	for _, step := range steps {
+		header := map[string]string{
+			"Content-Type":  tus.OffsetContentType,
+			"Upload-Offset": strconv.Itoa(step.offset),
+		}
+		for key, value := range step.header {
+			header[key] = value
+		}
+		w := u.do(http.MethodPatch, path, header, step.body)
+		if w.Code != step.code {
+			t.Fatalf("%s: got status %d, want %d: %s", step.name, w.Code, step.code, w.Body.String())
+		}
+		if w.Code == http.StatusNoContent && w.Header().Get("Upload-Offset") != strconv.Itoa(step.want) {
+			t.Fatalf("%s: got Upload-Offset %s, want %d", step.name, w.Header().Get("Upload-Offset"), step.want)
+		}
+		if got := u.upload(path).Offset; got != int64(step.want) {
+			t.Fatalf("%s: upload is at %d, want %d", step.name, got, step.want)
+		}
+	}
+
+	upload := u.upload(path)
+	if !upload.Completed || upload.EntityFileID == "" {
+		t.Fatalf("upload is not completed: %+v", upload)
+	}
+	entityFile, err := u.storage.files.Get(context.Background(), upload.EntityFileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entityFile.FileName != "notes.txt" || entityFile.ContentType != "text/plain" || entityFile.Size != 11 || entityFile.User != "applicant-1" {
+		t.Fatalf("unexpected entity file %+v", entityFile)
+	}
+	file, _, err := u.store.Get(context.Background(), entityFile.ObjectName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello world" {
+		t.Fatalf("got %q, want %q", content, "hello world")
+	}
+	for _, part := range upload.Parts {
+		if _, err := u.store.Stat(context.Background(), part.Name); !errors.Is(err, filestore.ErrNotFound) {
+			t.Fatalf("part %s was not removed: %v", part.Name, err)
+		}
+	}
+
+	w := u.do(http.MethodHead, path, nil, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "11" || w.Header().Get("Upload-Length") != "11" {
+		t.Fatalf("got status %d, headers %v", w.Code, w.Header())
+	}
+}
+
+func TestPatchUploadOtherUser(t *testing.T) {
+	u := newUploadTest(t, 10)
+	path := u.create(5)
+
+	token, err := security.GenerateJWT(jwt.MapClaims{
+		"id":        "applicant-2",
+		"login":     "other",
+		"user_type": "applicant",
+		"full_name": "Other",
+	}, time.Hour, uploadTestSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.token = token
+	if w := u.patch(path, 0, strings.NewReader("hello")); w.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := u.upload(path).Offset; got != 0 {
+		t.Fatalf("upload is at %d, want 0", got)
+	}
+}
+
+// TestPatchUploadMaxParts checks that the last chunk the cap allows has to
+// carry the rest of the upload
+func TestPatchUploadMaxParts(t *testing.T) {
+	u := newUploadTest(t, 3)
+	path := u.create(10)
+
+	steps := []struct {
+		offset int
+		body   string
+		code   int
+	}{
+		{offset: 0, body: "a", code: http.StatusNoContent},
+		{offset: 1, body: "b", code: http.StatusNoContent},
+		{offset: 2, body: "c", code: http.StatusBadRequest},
+		{offset: 2, body: "cdefghi", code: http.StatusBadRequest},
+		{offset: 2, body: "cdefghij", code: http.StatusNoContent},
+	}
+	for i, step := range steps {
+		if w := u.patch(path, step.offset, strings.NewReader(step.body)); w.Code != step.code {
+			t.Fatalf("step %d: got status %d, want %d: %s", i, w.Code, step.code, w.Body.String())
+		}
+	}
+
+	upload := u.upload(path)
+	if !upload.Completed || len(upload.Parts) != 3 {
+		t.Fatalf("got completed %v with %d parts, want a completed upload of 3 parts", upload.Completed, len(upload.Parts))
+	}
+	entityFile, err := u.storage.files.Get(context.Background(), upload.EntityFileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, _, err := u.store.Get(context.Background(), entityFile.ObjectName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if content, err := ioutil.ReadAll(file); err != nil || string(content) != "abcdefghij" {
+		t.Fatalf("got %q, %v", content, err)
+	}
+}
+
+func TestPatchUploadExpired(t *testing.T) {
+	u := newUploadTest(t, 10)
+	path := u.create(5)
+
+	u.storage.uploads.uploads[strings.TrimPrefix(path, "/v1/uploads/")].ExpiresAt = time.Now().Add(-time.Minute)
+	if w := u.patch(path, 0, strings.NewReader("hello")); w.Code != http.StatusGone {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusGone)
+	}
+}
@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/docgen"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// verifyUploadSlack is how much larger than the issued document an upload
+// may be, the form fields of the request come on top of the file
+const verifyUploadSlack = 1 << 20
+
+// @Router /v1/verify/{code} [get]
+// @Summary Verify document
+// @Description Public API for checking the verification code printed on an issued document
+// @Tags verify
+// @Produce json
+// @Param code path string true "verification code"
+// @Success 200 {object} models.VerifyDocumentResponse
+func (h *handlerV1) VerifyDocument(c *gin.Context) {
+	document, ok := h.issuedDocument(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, verifyDocumentResponse(document))
+}
+
+// @Router /v1/verify/{code} [post]
+// @Summary Verify document file
+// @Description Public API for checking that a PDF is byte for byte the document issued with the code
+// @Tags verify
+// @Accept multipart/form-data
+// @Produce json
+// @Param code path string true "verification code"
+// @Param file formData file true "file"
+// @Success 200 {object} models.VerifyDocumentUploadResponse
+func (h *handlerV1) VerifyDocumentUpload(c *gin.Context) {
+	document, ok := h.issuedDocument(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, document.Size+verifyUploadSlack)
+	file, err := c.FormFile("file")
+	if HandleHTTPError(c, http.StatusBadRequest, "Verify.Upload.FormFile", err) {
+		return
+	}
+	uploaded, err := file.Open()
+	if HandleHTTPError(c, http.StatusBadRequest, "Verify.Upload.Open", err) {
+		return
+	}
+	defer uploaded.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, uploaded)
+	if HandleHTTPError(c, http.StatusBadRequest, "Verify.Upload.Read", err) {
+		return
+	}
+	response := models.VerifyDocumentUploadResponse{
+		VerifyDocumentResponse: verifyDocumentResponse(document),
+		UploadedSHA256:         hex.EncodeToString(hash.Sum(nil)),
+		UploadedSize:           size,
+	}
+	response.Matches = size == document.Size && response.UploadedSHA256 == document.SHA256
+
+	// the hash is what was recorded at issue, the stored file is compared
+	// too in case the record and the file went apart
+	if response.Matches {
+		if _, err := uploaded.Seek(0, io.SeekStart); HandleHTTPError(c, http.StatusInternalServerError, "Verify.Upload.Seek", err) {
+			return
+		}
+		stored, _, err := h.fileStore.Get(context.Background(), document.ObjectName)
+		if errors.Is(err, filestore.ErrNotFound) {
+			HandleHTTPError(c, http.StatusNotFound, "Verify.Upload.GetStored", errors.New("issued file is not stored anymore"))
+			return
+		}
+		if HandleHTTPError(c, http.StatusInternalServerError, "Verify.Upload.GetStored", err) {
+			return
+		}
+		defer stored.Close()
+		response.Matches, err = sameContent(uploaded, stored)
+		if HandleHTTPError(c, http.StatusInternalServerError, "Verify.Upload.Compare", err) {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *handlerV1) issuedDocument(c *gin.Context) (*models.IssuedDocument, bool) {
+	code, err := docgen.NormalizeVerificationCode(c.Param("code"))
+	if HandleHTTPError(c, http.StatusBadRequest, "Verify.ParseCode", err) {
+		return nil, false
+	}
+	document, err := h.storage.IssuedDocument().Get(context.Background(), code)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		HandleHTTPError(c, http.StatusNotFound, "Verify.GetIssuedDocument", errors.New("no document was issued with this code"))
+		return nil, false
+	}
+	if HandleHTTPError(c, http.StatusBadRequest, "Verify.GetIssuedDocument", err) {
+		return nil, false
+	}
+	return document, true
+}
+
+func verifyDocumentResponse(document *models.IssuedDocument) models.VerifyDocumentResponse {
+	return models.VerifyDocumentResponse{
+		Code:         document.Code,
+		DocumentType: document.DocumentType,
+		EntityNumber: document.EntityNumber,
+		IssuedAt:     document.IssuedAt,
+		SHA256:       document.SHA256,
+		Size:         document.Size,
+	}
+}
+
+// sameContent compares two readers to the end
+func sameContent(a, b io.Reader) (bool, error) {
+	ra, rb := bufio.NewReader(a), bufio.NewReader(b)
+	for {
+		ca, errA := ra.ReadByte()
+		cb, errB := rb.ReadByte()
+		if errA == io.EOF || errB == io.EOF {
+			return errA == errB, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+		if ca != cb {
+			return false, nil
+		}
+	}
+}
@@ -8,7 +8,14 @@ import (
 	_ "github.com/e-space-uz/backend/api/docs"
 	v1 "github.com/e-space-uz/backend/api/handler/v1"
 	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/pkg/cms"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/geo"
 	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/pdf"
+	"github.com/e-space-uz/backend/pkg/scanner"
+	"github.com/e-space-uz/backend/pkg/signer"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
 	"github.com/e-space-uz/backend/storage"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -17,9 +24,24 @@ import (
 )
 
 type RouterOptions struct {
-	Log     logger.Logger
-	Cfg     config.Config
-	Storage storage.StorageI
+	Log        logger.Logger
+	Cfg        config.Config
+	Storage    storage.StorageI
+	FileStore  filestore.FileStore
+	Scanner    scanner.Scanner
+	Thumbnails *thumbnail.Worker
+	// DocumentFont is used for the PDF documents the service issues
+	DocumentFont pdf.Font
+	// SignatureOptions are the CAs trusted to issue signing certificates,
+	// the revocation checker and the verifier of E-IMZO signatures
+	SignatureOptions cms.VerifyOptions
+	// DecisionSigner signs the status decisions of staff
+	DecisionSigner *signer.Signer
+	// DecisionKeys verify decisions signed with the current and the earlier
+	// keys of the server
+	DecisionKeys *signer.Keyring
+	// CRS are the coordinate reference systems geometry can be sent in
+	CRS *geo.Registry
 }
 
 // @securityDefinitions.apikey ApiKeyAuth
@@ -35,13 +57,26 @@ func New(opt *RouterOptions) *gin.Engine {
 	corsConfig.AllowAllOrigins = true
 	corsConfig.AllowCredentials = true
 	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, "*")
+	// browsers hide response headers from scripts unless listed, tus clients
+	// read these
+	corsConfig.ExposeHeaders = append(corsConfig.ExposeHeaders,
+		"Location", "Upload-Offset", "Upload-Length", "Upload-Expires",
+		"Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size")
 
 	router.Use(cors.New(corsConfig))
 
 	handlerV1 := v1.New(&v1.HandlerV1Options{
-		Log:     opt.Log,
-		Cfg:     opt.Cfg,
-		Storage: opt.Storage,
+		Log:              opt.Log,
+		Cfg:              opt.Cfg,
+		Storage:          opt.Storage,
+		FileStore:        opt.FileStore,
+		Scanner:          opt.Scanner,
+		Thumbnails:       opt.Thumbnails,
+		DocumentFont:     opt.DocumentFont,
+		SignatureOptions: opt.SignatureOptions,
+		DecisionSigner:   opt.DecisionSigner,
+		DecisionKeys:     opt.DecisionKeys,
+		CRS:              opt.CRS,
 	})
 	routesV1 := router.Group("/v1")
 	routesV1.Use()
@@ -53,18 +88,59 @@ func New(opt *RouterOptions) *gin.Engine {
 			})
 		})
 
+		// presigned URLs of the local file store point here
+		if local, ok := opt.FileStore.(*filestore.Local); ok {
+			localFiles := gin.WrapH(http.StripPrefix("/v1/local-files", local))
+			routesV1.GET("/local-files/*name", localFiles)
+			routesV1.PUT("/local-files/*name", localFiles)
+		}
+
 		routesV1.POST("/image-upload", handlerV1.ImageUpload)
+		routesV1.POST("/files/upload-url", handlerV1.CreateUploadURL)
+		routesV1.POST("/files/confirm", handlerV1.ConfirmUpload)
+		routesV1.POST("/files/:file_id/signature", handlerV1.VerifyFileSignature)
+		routesV1.OPTIONS("/uploads", handlerV1.GetUploadOptions)
+		routesV1.POST("/uploads", handlerV1.CreateUpload)
+		routesV1.HEAD("/uploads/:upload_id", handlerV1.GetUploadOffset)
+		routesV1.GET("/uploads/:upload_id", handlerV1.GetUpload)
+		routesV1.PATCH("/uploads/:upload_id", handlerV1.PatchUpload)
+		routesV1.DELETE("/uploads/:upload_id", handlerV1.TerminateUpload)
+
+		// public, printed on issued documents
+		routesV1.GET("/verify/:code", handlerV1.VerifyDocument)
+		routesV1.POST("/verify/:code", handlerV1.VerifyDocumentUpload)
 
 		//City endpoints
 		routesV1.GET("/city/:city_id", handlerV1.GetCity)
 		routesV1.GET("/city", handlerV1.GetAllCities)
+		routesV1.POST("/city", handlerV1.CreateCity)
+		routesV1.PUT("/city/:city_id", handlerV1.UpdateCity)
+		routesV1.DELETE("/city/:city_id", handlerV1.DeleteCity)
 
 		routesV1.GET("/region/:region_id", handlerV1.GetRegion)
 		routesV1.GET("/region", handlerV1.GetAllRegions)
 		routesV1.GET("/regions/:city_id", handlerV1.GetAllRegionsByCityID)
+		routesV1.POST("/region", handlerV1.CreateRegion)
+		routesV1.PUT("/region/:region_id", handlerV1.UpdateRegion)
+		routesV1.DELETE("/region/:region_id", handlerV1.DeleteRegion)
 		//District Endpoints
 		routesV1.GET("/district/:district_id", handlerV1.GetDistrict)
 		routesV1.GET("/district", handlerV1.GetAllDistricts)
+		routesV1.POST("/district", handlerV1.CreateDistrict)
+		routesV1.PUT("/district/:district_id", handlerV1.UpdateDistrict)
+		routesV1.DELETE("/district/:district_id", handlerV1.DeleteDistrict)
+
+		//SOATO classifier import
+		routesV1.POST("/soato-import", handlerV1.CreateSoatoImport)
+		routesV1.GET("/soato-import/:import_id", handlerV1.GetSoatoImport)
+		routesV1.POST("/soato-import/:import_id/apply", handlerV1.ApplySoatoImport)
+
+		//Admin boundary endpoints
+		routesV1.PUT("/admin-boundary/:level/:unit_id", handlerV1.UpdateAdminBoundary)
+		routesV1.GET("/admin-boundary/:level/:unit_id", handlerV1.GetAdminBoundary)
+		routesV1.DELETE("/admin-boundary/:level/:unit_id", handlerV1.DeleteAdminBoundary)
+		routesV1.GET("/admin-unit", handlerV1.LookupAdminUnits)
+		routesV1.GET("/crs", handlerV1.GetAllCRS)
 
 		routesV1.POST("/login", handlerV1.Login)
 		routesV1.POST("/login-exists", handlerV1.LoginExist)
@@ -85,19 +161,72 @@ func New(opt *RouterOptions) *gin.Engine {
 		//Entity endpoints
 		routesV1.POST("/entity", handlerV1.CreateEntity)
 		routesV1.GET("/entity/:entity_id", handlerV1.GetEntity)
+		routesV1.POST("/entity/:entity_id/document", handlerV1.AttachEntityDocument)
+		routesV1.GET("/entity/:entity_id/document", handlerV1.GetEntityDocuments)
+		routesV1.DELETE("/entity/:entity_id/document/:file_id", handlerV1.DetachEntityDocument)
+		routesV1.PUT("/entity/:entity_id/location", handlerV1.UpdateEntityLocation)
+		routesV1.POST("/entity/:entity_id/boundary-import", handlerV1.ImportEntityBoundary)
+		routesV1.GET("/entity/:entity_id/topology", handlerV1.GetEntityTopology)
+		routesV1.PUT("/entity/:entity_id/inspection", handlerV1.UpdateEntityInspection)
+		routesV1.GET("/entity/:entity_id/bundle.zip", handlerV1.GetEntityBundle)
+		routesV1.POST("/entity/:entity_id/extract", handlerV1.CreateEntityExtract)
+		routesV1.POST("/entity/:entity_id/decision", handlerV1.CreateEntityDecision)
+		routesV1.GET("/entity/:entity_id/decision", handlerV1.GetEntityDecisions)
+		routesV1.GET("/entity/:entity_id/decision-payload", handlerV1.GetEntityDecisionPayload)
 		routesV1.GET("/entity-properties", handlerV1.GetAllEntitiesWithProperties)
+		routesV1.GET("/entity-overlaps", handlerV1.GetEntityOverlapReport)
+		routesV1.GET("/entity-export", handlerV1.ExportEntities)
 
 		//Entity Draft endpoints
 		routesV1.POST("/entity-draft", handlerV1.CreateEntityDraft)
 		routesV1.GET("/entity-draft/:entity_draft_id", handlerV1.GetEntityDraft)
+		routesV1.GET("/entity-draft/:entity_draft_id/steps", handlerV1.GetEntityDraftSteps)
+		routesV1.PUT("/entity-draft/:entity_draft_id/step/:step", handlerV1.SaveEntityDraftStep)
+		routesV1.POST("/entity-draft/:entity_draft_id/submit", handlerV1.SubmitEntityDraft)
+		routesV1.PUT("/entity-draft/:entity_draft_id/location", handlerV1.UpdateEntityDraftLocation)
+		routesV1.POST("/entity-draft/:entity_draft_id/boundary-import", handlerV1.ImportEntityDraftBoundary)
+		routesV1.GET("/entity-draft/:entity_draft_id/topology", handlerV1.GetEntityDraftTopology)
+		routesV1.POST("/entity-draft/:entity_draft_id/document", handlerV1.AttachEntityDraftDocument)
+		routesV1.GET("/entity-draft/:entity_draft_id/document", handlerV1.GetEntityDraftDocuments)
+		routesV1.DELETE("/entity-draft/:entity_draft_id/document/:file_id", handlerV1.DetachEntityDraftDocument)
+		routesV1.POST("/entity-draft/:entity_draft_id/decision", handlerV1.CreateEntityDraftDecision)
+		routesV1.GET("/entity-draft/:entity_draft_id/decision", handlerV1.GetEntityDraftDecisions)
+		routesV1.GET("/entity-draft/:entity_draft_id/decision-payload", handlerV1.GetEntityDraftDecisionPayload)
+		// public, for auditors
+		routesV1.GET("/decision-key", handlerV1.GetDecisionKey)
 
 		//Property endpoints
 		routesV1.POST("/property", handlerV1.CreateProperty)
+		routesV1.GET("/property", handlerV1.GetAllProperties)
+		routesV1.GET("/property/:property_id", handlerV1.GetProperty)
 		routesV1.PUT("/property/:property_id", handlerV1.UpdateProperty)
+		routesV1.DELETE("/property/:property_id", handlerV1.DeleteProperty)
+		routesV1.GET("/property/:property_id/usage", handlerV1.GetPropertyUsage)
+		routesV1.PUT("/property/:property_id/translations", handlerV1.UpdatePropertyTranslations)
 
 		// Group property endpoints
+		routesV1.POST("/group-property", handlerV1.CreateGroupProperty)
 		routesV1.GET("/group-property", handlerV1.GetAllGroupProperties)
+		routesV1.GET("/group-property/:group_property_id", handlerV1.GetGroupProperty)
+		routesV1.PUT("/group-property/:group_property_id", handlerV1.UpdateGroupProperty)
+		routesV1.DELETE("/group-property/:group_property_id", handlerV1.DeleteGroupProperty)
+		routesV1.PUT("/group-property/:group_property_id/status", handlerV1.UpdateGroupPropertyStatus)
+		routesV1.POST("/group-property/:group_property_id/clone", handlerV1.CloneGroupProperty)
+		routesV1.POST("/group-property/:group_property_id/property", handlerV1.AddGroupPropertyProperty)
+		routesV1.DELETE("/group-property/:group_property_id/property/:property_id", handlerV1.RemoveGroupPropertyProperty)
+		routesV1.PUT("/group-property/:group_property_id/property-order", handlerV1.ReorderGroupPropertyProperties)
+		routesV1.PUT("/group-property/:group_property_id/translations", handlerV1.UpdateGroupPropertyTranslations)
 		routesV1.GET("/group-property-type", handlerV1.GetAllGroupPropertiesByType)
+
+		routesV1.GET("/form-schema", handlerV1.GetFormSchema)
+
+		// Translation endpoints
+		routesV1.GET("/translations/missing", handlerV1.GetMissingTranslations)
+
+		// Form schema version endpoints
+		routesV1.POST("/form-schema-version", handlerV1.PublishFormSchemaVersion)
+		routesV1.GET("/form-schema-version", handlerV1.GetAllFormSchemaVersions)
+		routesV1.GET("/form-schema-version/:form_schema_version_id", handlerV1.GetFormSchemaVersion)
 	}
 
 	// swagger
@@ -2,11 +2,25 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/e-space-uz/backend/api"
 	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/pkg/cms"
+	"github.com/e-space-uz/backend/pkg/filegc"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/geo"
 	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/pdf"
+	"github.com/e-space-uz/backend/pkg/scanner"
+	"github.com/e-space-uz/backend/pkg/signer"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
+	"github.com/e-space-uz/backend/pkg/tus"
 	"github.com/e-space-uz/backend/storage"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -35,11 +49,225 @@ func main() {
 	log.Info("Connected to MongoDB", logger.Any("database: ", connDB.Name()))
 
 	strg := storage.NewStorageMongo(connDB)
+	if err := strg.Entity().CreateIndexes(context.Background()); err != nil {
+		log.Error("Cannot create entity indexes error ->", logger.Error(err))
+		panic(err)
+	}
+	if err := strg.EntityDraft().CreateIndexes(context.Background()); err != nil {
+		log.Error("Cannot create entity draft indexes error ->", logger.Error(err))
+		panic(err)
+	}
+	if err := strg.AdminBoundary().CreateIndexes(context.Background()); err != nil {
+		log.Error("Cannot create admin boundary indexes error ->", logger.Error(err))
+		panic(err)
+	}
+	if err := strg.EntityFiles().CreateIndexes(context.Background()); err != nil {
+		log.Error("Cannot create entity file indexes error ->", logger.Error(err))
+		panic(err)
+	}
+	if err := strg.FormSchemaVersion().CreateIndexes(context.Background()); err != nil {
+		log.Error("Cannot create form schema version indexes error ->", logger.Error(err))
+		panic(err)
+	}
+	if err := strg.City().Migrate(context.Background()); err != nil {
+		log.Error("Cannot migrate cities error ->", logger.Error(err))
+		panic(err)
+	}
+	if err := strg.Region().Migrate(context.Background()); err != nil {
+		log.Error("Cannot migrate regions error ->", logger.Error(err))
+		panic(err)
+	}
+	if err := strg.District().Migrate(context.Background()); err != nil {
+		log.Error("Cannot migrate districts error ->", logger.Error(err))
+		panic(err)
+	}
+
+	fileStore, err := newFileStore(cfg)
+	if err != nil {
+		log.Error("Cannot create file store error ->", logger.Error(err))
+		panic(err)
+	}
+	log.Info("File store is ready", logger.String("file_store", cfg.FileStore))
+
+	// backend file-gc [-enforce] [-grace 168h] collects orphaned files once
+	if len(os.Args) > 1 && os.Args[1] == "file-gc" {
+		if err := runFileGC(fileStore, strg, log, cfg, os.Args[2:]); err != nil {
+			log.Error("error while collecting orphaned files", logger.Error(err))
+		}
+		return
+	}
+
+	fileScanner, err := newScanner(cfg)
+	if err != nil {
+		log.Error("Cannot create scanner error ->", logger.Error(err))
+		panic(err)
+	}
+
+	documentFont, err := newDocumentFont(cfg)
+	if err != nil {
+		log.Error("Cannot load document font error ->", logger.Error(err))
+		panic(err)
+	}
+
+	signatureOptions, err := newSignatureOptions(cfg)
+	if err != nil {
+		log.Error("Cannot load trusted signature CAs error ->", logger.Error(err))
+		panic(err)
+	}
+
+	decisionSigner, decisionKeys, err := newDecisionSigner(cfg)
+	if err != nil {
+		log.Error("Cannot load decision signing key error ->", logger.Error(err))
+		panic(err)
+	}
+
+	crs, err := newCRSRegistry(cfg)
+	if err != nil {
+		log.Error("Cannot load coordinate reference systems error ->", logger.Error(err))
+		panic(err)
+	}
+
+	if _, err := geo.AreaUnit(cfg.AreaPropertyUnit); err != nil {
+		log.Error("Cannot use area property unit error ->", logger.Error(err))
+		panic(err)
+	}
+
+	if cfg.FileGCInterval > 0 {
+		fileGC := filegc.New(fileStore, strg.FileGC(), log, fileGCOptions(cfg))
+		go fileGC.Schedule(context.Background(), cfg.FileGCInterval)
+	}
+
+	if cfg.ResumableUploadExpireEach > 0 {
+		expirer := tus.NewExpirer(fileStore, strg.Upload(), log)
+		go expirer.Schedule(context.Background(), cfg.ResumableUploadExpireEach)
+	}
+
+	thumbnails := thumbnail.NewWorker(fileStore, log, cfg.ThumbnailWorkers, cfg.ThumbnailQueueSize, cfg.ThumbnailTimeout)
 
 	server := api.New(&api.RouterOptions{
-		Log:     log,
-		Cfg:     cfg,
-		Storage: strg,
+		Log:              log,
+		Cfg:              cfg,
+		Storage:          strg,
+		FileStore:        fileStore,
+		Scanner:          fileScanner,
+		Thumbnails:       thumbnails,
+		DocumentFont:     documentFont,
+		SignatureOptions: signatureOptions,
+		DecisionSigner:   decisionSigner,
+		DecisionKeys:     decisionKeys,
+		CRS:              crs,
 	})
 	server.Run(cfg.HttpPort)
 }
+
+func newFileStore(cfg config.Config) (filestore.FileStore, error) {
+	switch cfg.FileStore {
+	case "minio":
+		return filestore.NewMinio(context.Background(), filestore.MinioOptions{
+			Endpoint:        cfg.MinioDomain,
+			AccessKeyID:     cfg.MinioAccessKeyID,
+			SecretAccessKey: cfg.MinioSecretAccesKey,
+			Bucket:          cfg.BucketName,
+			Region:          cfg.MinioRegion,
+			UseSSL:          cfg.MinioUseSSL,
+		})
+	case "local":
+		return filestore.NewLocal(cfg.LocalFileStorePath, cfg.LocalFileStoreURL, cfg.LocalFileStoreSigningKey)
+	}
+	return nil, fmt.Errorf("unknown file store %q, use minio or local", cfg.FileStore)
+}
+
+func runFileGC(fileStore filestore.FileStore, strg storage.StorageI, log logger.Logger, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("file-gc", flag.ContinueOnError)
+	enforce := flags.Bool("enforce", cfg.FileGCEnforce, "delete orphaned files, otherwise only report them")
+	grace := flags.Duration("grace", cfg.FileGCGracePeriod, "files modified within this period are kept")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := fileGCOptions(cfg)
+	opts.Enforce = *enforce
+	opts.GracePeriod = *grace
+	run, err := filegc.New(fileStore, strg.FileGC(), log, opts).Run(context.Background(), "cli")
+	if err != nil {
+		return err
+	}
+	for _, orphan := range run.Orphans {
+		fmt.Printf("%s\t%d\t%s\t%t\n", orphan.Name, orphan.Size, orphan.LastModified.Format(time.RFC3339), orphan.Deleted)
+	}
+	fmt.Printf("mode %s: %d scanned, %d orphans (%d bytes), %d deleted, %d failed, audit record %s\n",
+		run.Mode, run.Scanned, run.OrphanCount, run.OrphanBytes, run.Deleted, run.Failed, run.ID.Hex())
+	return nil
+}
+
+func fileGCOptions(cfg config.Config) filegc.Options {
+	return filegc.Options{
+		GracePeriod:  cfg.FileGCGracePeriod,
+		Enforce:      cfg.FileGCEnforce,
+		KeepPrefixes: []string{filestore.QuarantinePrefix, tus.Prefix},
+	}
+}
+
+func newDocumentFont(cfg config.Config) (pdf.Font, error) {
+	if cfg.DocumentFontPath == "" {
+		return pdf.Helvetica, nil
+	}
+	return pdf.LoadTrueType(cfg.DocumentFontPath)
+}
+
+// newSignatureOptions trusts an empty pool without a bundle, signatures are
+// verified then but none is trusted
+func newSignatureOptions(cfg config.Config) (cms.VerifyOptions, error) {
+	opts := cms.VerifyOptions{Roots: x509.NewCertPool()}
+	if cfg.SignatureTrustedCAPath != "" {
+		roots, err := cms.LoadCertPool(cfg.SignatureTrustedCAPath)
+		if err != nil {
+			return opts, err
+		}
+		opts.Roots = roots
+	}
+	if cfg.SignatureRevocationCheck {
+		opts.Revocation = cms.NewOnlineRevocation(cfg.SignatureRevocationTimeout)
+	}
+	if cfg.EIMZOServerURL != "" {
+		opts.External = cms.NewEIMZOServer(cfg.EIMZOServerURL, cfg.EIMZOServerTimeout)
+	}
+	return opts, nil
+}
+
+// newDecisionSigner refuses to run without a key, decisions signed with a
+// temporary one could not be verified after a restart
+func newDecisionSigner(cfg config.Config) (*signer.Signer, *signer.Keyring, error) {
+	if cfg.DecisionSigningKeyPath == "" {
+		return nil, nil, errors.New("DECISION_SIGNING_KEY_PATH is not set")
+	}
+	decisionSigner, err := signer.Load(cfg.DecisionSigningKeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := signer.NewKeyring(decisionSigner)
+	if cfg.DecisionPublicKeysPath != "" {
+		if err := keys.LoadFile(cfg.DecisionPublicKeysPath); err != nil {
+			return nil, nil, err
+		}
+	}
+	return decisionSigner, keys, nil
+}
+
+func newCRSRegistry(cfg config.Config) (*geo.Registry, error) {
+	crs := geo.NewRegistry()
+	if cfg.CRSDefinitionsPath == "" {
+		return crs, nil
+	}
+	return crs, crs.LoadFile(cfg.CRSDefinitionsPath)
+}
+
+func newScanner(cfg config.Config) (scanner.Scanner, error) {
+	switch cfg.Scanner {
+	case "none":
+		return scanner.NewNoop(), nil
+	case "clamav":
+		return scanner.NewClamAV(cfg.ClamAVAddress, cfg.ClamAVTimeout)
+	}
+	return nil, fmt.Errorf("unknown scanner %q, use none or clamav", cfg.Scanner)
+}
@@ -2,23 +2,32 @@ package config
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cast"
 )
 
 const (
-	EntityCollection        = "EntityCollection"
-	EntityDraftCollection   = "EntityDraftCollection"
-	EntityFilesCollection   = "EntityFilesCollection"
-	PropertyCollection      = "PropertyCollection"
-	GroupPropertyCollection = "GroupPropertyCollection"
-	ApplicantCollection     = "ApplicantCollection"
-	StaffCollection         = "StaffCollection"
-	CityCollection          = "CityCollection"
-	RegionCollection        = "RegionCollection"
-	DistrictCollection      = "DistrictCollection"
-	TimeLayout              = "2006-01-02"
+	EntityCollection            = "EntityCollection"
+	EntityDraftCollection       = "EntityDraftCollection"
+	EntityFilesCollection       = "EntityFilesCollection"
+	PropertyCollection          = "PropertyCollection"
+	GroupPropertyCollection     = "GroupPropertyCollection"
+	ApplicantCollection         = "ApplicantCollection"
+	StaffCollection             = "StaffCollection"
+	CityCollection              = "CityCollection"
+	RegionCollection            = "RegionCollection"
+	DistrictCollection          = "DistrictCollection"
+	FormSchemaVersionCollection = "FormSchemaVersionCollection"
+	PhotoMetadataCollection     = "PhotoMetadataCollection"
+	FileGCRunCollection         = "FileGCRunCollection"
+	UploadCollection            = "UploadCollection"
+	IssuedDocumentCollection    = "IssuedDocumentCollection"
+	DecisionCollection          = "DecisionCollection"
+	AdminBoundaryCollection     = "AdminBoundaryCollection"
+	SoatoImportCollection       = "SoatoImportCollection"
+	TimeLayout                  = "2006-01-02"
 
 	// Access token expire time duration
 	AccessTokenExpireDuration time.Duration = 2 * 24 * time.Hour
@@ -40,10 +49,116 @@ type Config struct {
 	LoginSecretAccessKey  string
 	LoginSecretRefreshKey string
 
+	// FileStore is either minio or local
+	FileStore string
+
 	BucketName          string
 	MinioDomain         string
 	MinioAccessKeyID    string
 	MinioSecretAccesKey string
+	MinioRegion         string
+	MinioUseSSL         bool
+
+	LocalFileStorePath string
+	LocalFileStoreURL  string
+	// LocalFileStoreSigningKey has no default, the local file store does not
+	// start without one
+	LocalFileStoreSigningKey string
+
+	// FileURLExpiry is how long presigned download URLs stay valid
+	FileURLExpiry time.Duration
+	// UploadURLExpiry is how long presigned upload URLs stay valid
+	UploadURLExpiry           time.Duration
+	MaxUploadSize             int64
+	AllowedUploadContentTypes []string
+
+	// ImageUpload* limit /v1/image-upload, MaxImagePixels applies to every
+	// decoded image
+	ImageUploadMaxSize      int64
+	ImageUploadContentTypes []string
+	MaxImagePixels          int
+
+	ThumbnailWorkers   int
+	ThumbnailQueueSize int
+	ThumbnailTimeout   time.Duration
+
+	// PhotoMaxDistance is how far in meters from the entity gallery photos
+	// may be taken. PhotoUTCOffset is the time zone of cameras that do not
+	// record one.
+	PhotoMaxDistance float64
+	PhotoUTCOffset   time.Duration
+
+	// FileGCInterval is how often orphaned files are collected, zero turns
+	// the schedule off. It is off by default, set it on one replica only.
+	// Without FileGCEnforce orphans are only reported.
+	FileGCInterval    time.Duration
+	FileGCGracePeriod time.Duration
+	FileGCEnforce     bool
+
+	// ResumableUpload* limit tus uploads under /v1/uploads. Unfinished
+	// uploads are removed ResumableUploadExpiry after their last chunk.
+	ResumableUploadMaxSize    int64
+	ResumableUploadMaxParts   int
+	ResumableUploadExpiry     time.Duration
+	ResumableUploadExpireEach time.Duration
+
+	// DocumentFontPath is a TrueType font embedded into issued documents.
+	// Without it Helvetica is used, which has no Cyrillic letters.
+	DocumentFontPath string
+	// DocumentVerifyURL is put into the QR code of issued documents with the
+	// verification code appended
+	DocumentVerifyURL string
+
+	// SignatureTrustedCAPath is a PEM bundle of the CAs whose certificates
+	// sign documents, without it no signature is trusted. Signed files up to
+	// SignatureMaxSize are read into memory to be verified.
+	SignatureTrustedCAPath string
+	SignatureMaxSize       int64
+	// SignatureRevocationCheck asks the OCSP responders and CRLs of the
+	// certificates, waiting up to SignatureRevocationTimeout for each
+	SignatureRevocationCheck   bool
+	SignatureRevocationTimeout time.Duration
+	// EIMZOServerURL is an E-IMZO-SERVER that verifies the O'zDSt 1092
+	// signatures of E-IMZO keys, without it they are reported as unsupported
+	EIMZOServerURL     string
+	EIMZOServerTimeout time.Duration
+
+	// DecisionSigningKeyPath is the PEM private key status decisions are
+	// signed with, the service does not start without it.
+	// DecisionPublicKeysPath is a PEM file of the public keys decisions were
+	// signed with before the key was rotated.
+	DecisionSigningKeyPath string
+	DecisionPublicKeysPath string
+	// EntityStatuses are the statuses decisions may give entities
+	EntityStatuses []string
+
+	// CRSDefinitionsPath is a JSON file of coordinate reference systems
+	// geometry is accepted and returned in besides the built in WGS84 and
+	// SK-42 ones, such as the local MSK systems
+	CRSDefinitionsPath string
+	// BoundaryImportMaxSize limits the DXF, Shapefile and KML uploads, which
+	// are read into memory
+	BoundaryImportMaxSize int64
+	// SoatoImportMaxSize limits the classifier uploads
+	SoatoImportMaxSize int64
+
+	// OverlapTolerance is how many square meters two boundaries may share
+	// before it counts as an overlap, OverlapBlockPercent the share of the
+	// smaller of them above which the boundary is rejected instead of
+	// warned about. Zero never rejects.
+	OverlapTolerance    float64
+	OverlapBlockPercent float64
+	// Area*Percent are how far in percent the boundary may be from the
+	// declared area of the area property before a warning or a rejection,
+	// zero turns the rule off. AreaPropertyUnit is m2, ha or sotix.
+	AreaWarnPercent  float64
+	AreaBlockPercent float64
+	AreaPropertyUnit string
+
+	// Scanner is either none or clamav
+	Scanner       string
+	ClamAVAddress string
+	ClamAVTimeout time.Duration
 }
 
 func Load() Config {
@@ -60,6 +175,81 @@ func Load() Config {
 	cfg.MongoPassword = cast.ToString(getOrReturnDefault("MONGO_PASSWORD", "mongodb"))
 	cfg.MongoDatabase = cast.ToString(getOrReturnDefault("MONGO_DATABASE", "espace"))
 
+	cfg.FileStore = cast.ToString(getOrReturnDefault("FILE_STORE", "minio"))
+
+	cfg.BucketName = cast.ToString(getOrReturnDefault("MINIO_BUCKET_NAME", "espace"))
+	cfg.MinioDomain = cast.ToString(getOrReturnDefault("MINIO_DOMAIN", "localhost:9000"))
+	cfg.MinioAccessKeyID = cast.ToString(getOrReturnDefault("MINIO_ACCESS_KEY_ID", ""))
+	cfg.MinioSecretAccesKey = cast.ToString(getOrReturnDefault("MINIO_SECRET_ACCESS_KEY", ""))
+	cfg.MinioRegion = cast.ToString(getOrReturnDefault("MINIO_REGION", ""))
+	cfg.MinioUseSSL = cast.ToBool(getOrReturnDefault("MINIO_USE_SSL", true))
+
+	cfg.LocalFileStorePath = cast.ToString(getOrReturnDefault("LOCAL_FILE_STORE_PATH", "./uploads"))
+	cfg.LocalFileStoreURL = cast.ToString(getOrReturnDefault("LOCAL_FILE_STORE_URL", "http://localhost:8000/v1/local-files"))
+	cfg.LocalFileStoreSigningKey = cast.ToString(getOrReturnDefault("LOCAL_FILE_STORE_SIGNING_KEY", ""))
+
+	cfg.FileURLExpiry = cast.ToDuration(getOrReturnDefault("FILE_URL_EXPIRY", "1h"))
+	cfg.UploadURLExpiry = cast.ToDuration(getOrReturnDefault("UPLOAD_URL_EXPIRY", "30m"))
+	cfg.MaxUploadSize = cast.ToInt64(getOrReturnDefault("MAX_UPLOAD_SIZE", 250<<20))
+	cfg.AllowedUploadContentTypes = strings.Split(cast.ToString(getOrReturnDefault(
+		"ALLOWED_UPLOAD_CONTENT_TYPES",
+		"application/pdf,image/jpeg,image/png,image/tiff,application/zip,application/pkcs7-mime,application/pkcs7-signature",
+	)), ",")
+
+	cfg.ImageUploadMaxSize = cast.ToInt64(getOrReturnDefault("IMAGE_UPLOAD_MAX_SIZE", 20<<20))
+	cfg.ImageUploadContentTypes = strings.Split(cast.ToString(getOrReturnDefault(
+		"IMAGE_UPLOAD_CONTENT_TYPES",
+		"image/jpeg,image/png,image/gif,image/webp",
+	)), ",")
+	cfg.MaxImagePixels = cast.ToInt(getOrReturnDefault("MAX_IMAGE_PIXELS", 50_000_000))
+
+	cfg.ThumbnailWorkers = cast.ToInt(getOrReturnDefault("THUMBNAIL_WORKERS", 2))
+	cfg.ThumbnailQueueSize = cast.ToInt(getOrReturnDefault("THUMBNAIL_QUEUE_SIZE", 1000))
+	cfg.ThumbnailTimeout = cast.ToDuration(getOrReturnDefault("THUMBNAIL_TIMEOUT", "1m"))
+
+	cfg.PhotoMaxDistance = cast.ToFloat64(getOrReturnDefault("PHOTO_MAX_DISTANCE", 300))
+	cfg.PhotoUTCOffset = cast.ToDuration(getOrReturnDefault("PHOTO_UTC_OFFSET", "5h"))
+
+	cfg.FileGCInterval = cast.ToDuration(getOrReturnDefault("FILE_GC_INTERVAL", "0"))
+	cfg.FileGCGracePeriod = cast.ToDuration(getOrReturnDefault("FILE_GC_GRACE_PERIOD", "168h"))
+	cfg.FileGCEnforce = cast.ToBool(getOrReturnDefault("FILE_GC_ENFORCE", false))
+
+	cfg.ResumableUploadMaxSize = cast.ToInt64(getOrReturnDefault("RESUMABLE_UPLOAD_MAX_SIZE", 2<<30))
+	cfg.ResumableUploadMaxParts = cast.ToInt(getOrReturnDefault("RESUMABLE_UPLOAD_MAX_PARTS", 10000))
+	cfg.ResumableUploadExpiry = cast.ToDuration(getOrReturnDefault("RESUMABLE_UPLOAD_EXPIRY", "24h"))
+	cfg.ResumableUploadExpireEach = cast.ToDuration(getOrReturnDefault("RESUMABLE_UPLOAD_EXPIRE_EACH", "1h"))
+
+	cfg.DocumentFontPath = cast.ToString(getOrReturnDefault("DOCUMENT_FONT_PATH", ""))
+	cfg.DocumentVerifyURL = cast.ToString(getOrReturnDefault("DOCUMENT_VERIFY_URL", "http://localhost:8000/v1/verify/"))
+
+	cfg.SignatureTrustedCAPath = cast.ToString(getOrReturnDefault("SIGNATURE_TRUSTED_CA_PATH", ""))
+	cfg.SignatureMaxSize = cast.ToInt64(getOrReturnDefault("SIGNATURE_MAX_SIZE", 50<<20))
+	cfg.SignatureRevocationCheck = cast.ToBool(getOrReturnDefault("SIGNATURE_REVOCATION_CHECK", true))
+	cfg.SignatureRevocationTimeout = cast.ToDuration(getOrReturnDefault("SIGNATURE_REVOCATION_TIMEOUT", "10s"))
+	cfg.EIMZOServerURL = cast.ToString(getOrReturnDefault("EIMZO_SERVER_URL", ""))
+	cfg.EIMZOServerTimeout = cast.ToDuration(getOrReturnDefault("EIMZO_SERVER_TIMEOUT", "30s"))
+
+	cfg.DecisionSigningKeyPath = cast.ToString(getOrReturnDefault("DECISION_SIGNING_KEY_PATH", ""))
+	cfg.DecisionPublicKeysPath = cast.ToString(getOrReturnDefault("DECISION_PUBLIC_KEYS_PATH", ""))
+	cfg.EntityStatuses = strings.Split(cast.ToString(getOrReturnDefault(
+		"ENTITY_STATUSES",
+		"new,in_review,approved,rejected,archived",
+	)), ",")
+
+	cfg.CRSDefinitionsPath = cast.ToString(getOrReturnDefault("CRS_DEFINITIONS_PATH", ""))
+	cfg.BoundaryImportMaxSize = cast.ToInt64(getOrReturnDefault("BOUNDARY_IMPORT_MAX_SIZE", 20<<20))
+	cfg.SoatoImportMaxSize = cast.ToInt64(getOrReturnDefault("SOATO_IMPORT_MAX_SIZE", 10<<20))
+
+	cfg.OverlapTolerance = cast.ToFloat64(getOrReturnDefault("OVERLAP_TOLERANCE", 1))
+	cfg.OverlapBlockPercent = cast.ToFloat64(getOrReturnDefault("OVERLAP_BLOCK_PERCENT", 5))
+	cfg.AreaWarnPercent = cast.ToFloat64(getOrReturnDefault("AREA_WARN_PERCENT", 5))
+	cfg.AreaBlockPercent = cast.ToFloat64(getOrReturnDefault("AREA_BLOCK_PERCENT", 20))
+	cfg.AreaPropertyUnit = cast.ToString(getOrReturnDefault("AREA_PROPERTY_UNIT", "m2"))
+
+	cfg.Scanner = cast.ToString(getOrReturnDefault("SCANNER", "none"))
+	cfg.ClamAVAddress = cast.ToString(getOrReturnDefault("CLAMAV_ADDRESS", "tcp://localhost:3310"))
+	cfg.ClamAVTimeout = cast.ToDuration(getOrReturnDefault("CLAMAV_TIMEOUT", "2m"))
+
 	return cfg
 }
 func getOrReturnDefault(key string, defaultValue interface{}) interface{} {
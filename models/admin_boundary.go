@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Administrative unit levels, a city holds regions and a region districts
+const (
+	AdminUnitCity     = "city"
+	AdminUnitRegion   = "region"
+	AdminUnitDistrict = "district"
+)
+
+// AdminBoundary is the territory of a city, region or district. It is kept
+// apart from the units, which are copied into every entity.
+type AdminBoundary struct {
+	// ID is the level and the unit id, a unit has one boundary
+	ID        string       `json:"id" bson:"_id"`
+	Level     string       `json:"level" bson:"level"`
+	UnitID    string       `json:"unit_id" bson:"unit_id"`
+	Soato     uint32       `json:"soato" bson:"soato"`
+	Boundary  *GeoBoundary `json:"boundary" bson:"boundary"`
+	UpdatedAt time.Time    `json:"updated_at" bson:"updated_at"`
+	UpdatedBy string       `json:"updated_by" bson:"updated_by"`
+}
+
+func AdminBoundaryID(level, unitID string) string {
+	return level + "-" + unitID
+}
+
+// AdminUnits are the units whose boundaries contain a point, a level without
+// a loaded boundary is left empty
+type AdminUnits struct {
+	City        *City     `json:"city"`
+	Region      *Region   `json:"region"`
+	District    *District `json:"district"`
+	EntitySoato string    `json:"entity_soato"`
+}
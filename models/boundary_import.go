@@ -0,0 +1,24 @@
+package models
+
+// BoundaryCandidate is one polygon of an imported survey file
+type BoundaryCandidate struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Type  string `json:"type" example:"Polygon"`
+	// BBox is in WGS84, it and the preview are left out when the polygon
+	// does not convert or validate and Error tells why
+	BBox    []float64 `json:"bbox,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Preview string    `json:"preview,omitempty"`
+}
+
+type BoundaryImportResponse struct {
+	// Attached is false when the file has several polygons and none was
+	// chosen with the feature parameter
+	Attached   bool                 `json:"attached"`
+	Selected   *int                 `json:"selected,omitempty"`
+	Boundary   *GeoBoundary         `json:"boundary,omitempty"`
+	Preview    string               `json:"preview,omitempty"`
+	Topology   *Topology            `json:"topology,omitempty"`
+	Candidates []*BoundaryCandidate `json:"candidates"`
+}
@@ -1,9 +1,38 @@
 package models
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// City is the top administrative unit. Abolished units are kept with Active
+// unset, entities that were registered in them still point to them.
 type City struct {
 	ID     string `json:"id" bson:"_id"`
 	RuName string `json:"ru_name" bson:"ru_name"`
 	Name   string `json:"name" bson:"name"`
 	Soato  uint32 `json:"soato" bson:"soato"`
 	Code   uint32 `json:"code" bson:"code"`
+	Active bool   `json:"active" bson:"active"`
+}
+
+type CreateUpdateCity struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Name      string             `bson:"name"`
+	RuName    string             `bson:"ru_name"`
+	Soato     uint32             `bson:"soato"`
+	Code      uint32             `bson:"code"`
+	Active    bool               `bson:"active"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+type CitySwag struct {
+	Name   string `json:"name" binding:"required"`
+	RuName string `json:"ru_name"`
+	Soato  uint32 `json:"soato" binding:"required" example:"1726"`
+	Code   uint32 `json:"code"`
+	// Active abolishes or restores the unit on update, it is left as it is
+	// when not sent
+	Active *bool `json:"active"`
 }
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Decision signature types. Server decisions are signed with the key of the
+// service, cms ones by the staff member with E-IMZO.
+const (
+	DecisionSignatureServer = "server"
+	DecisionSignatureCMS    = "cms"
+)
+
+// Entity draft statuses staff decide on
+const (
+	EntityDraftStatusApproved = "approved"
+	EntityDraftStatusRejected = "rejected"
+)
+
+// Decision is one status change in the audit trail of an entity or draft.
+// The decisions of one subject form a chain, each carries the hash of the
+// one before.
+type Decision struct {
+	// ID is the subject id and the sequence, two decisions can not take the
+	// same place in the chain
+	ID            string    `json:"id" bson:"_id"`
+	EntityID      string    `json:"entity_id,omitempty" bson:"entity_id,omitempty"`
+	EntityDraftID string    `json:"entity_draft_id,omitempty" bson:"entity_draft_id,omitempty"`
+	Sequence      uint64    `json:"sequence" bson:"sequence"`
+	Version       uint64    `json:"version" bson:"version"`
+	Status        string    `json:"status" bson:"status"`
+	Comment       string    `json:"comment,omitempty" bson:"comment,omitempty"`
+	Actor         string    `json:"actor" bson:"actor"`
+	ActorLogin    string    `json:"actor_login" bson:"actor_login"`
+	Timestamp     time.Time `json:"timestamp" bson:"timestamp"`
+	Previous      string    `json:"previous" bson:"previous"`
+	// Hash is the SHA-256 of the payload followed by the signature
+	Hash          string        `json:"hash" bson:"hash"`
+	SignatureType string        `json:"signature_type" bson:"signature_type"`
+	KeyID         string        `json:"key_id,omitempty" bson:"key_id,omitempty"`
+	Signature     []byte        `json:"signature" bson:"signature"`
+	Signers       []*FileSigner `json:"signers,omitempty" bson:"signers,omitempty"`
+}
+
+// DecisionPayload is what is signed, marshalled to JSON with the fields in
+// this order and the timestamp in UTC with milliseconds
+type DecisionPayload struct {
+	EntityID      string `json:"entity_id,omitempty"`
+	EntityDraftID string `json:"entity_draft_id,omitempty"`
+	Sequence      uint64 `json:"sequence"`
+	Version       uint64 `json:"version"`
+	Status        string `json:"status"`
+	Comment       string `json:"comment,omitempty"`
+	Actor         string `json:"actor"`
+	Timestamp     string `json:"timestamp" example:"2024-05-01T09:30:00.000Z"`
+	Previous      string `json:"previous"`
+}
+
+type DecisionSwag struct {
+	Status  string `json:"status" binding:"required" example:"approved"`
+	Comment string `json:"comment"`
+	// Timestamp and Signature are for decisions signed with E-IMZO, the
+	// signature is the base64 CMS of the payload from /decision-payload
+	Timestamp string `json:"timestamp" example:"2024-05-01T09:30:00.000Z"`
+	Signature string `json:"signature"`
+}
+
+type DecisionPayloadResponse struct {
+	Payload DecisionPayload `json:"payload"`
+	// Canonical is the exact text to sign
+	Canonical string `json:"canonical"`
+}
+
+type GetDecisionsRequest struct {
+	EntityID      string
+	EntityDraftID string
+}
+
+type DecisionVerification struct {
+	*Decision
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+type DecisionChainResponse struct {
+	Valid     bool                    `json:"valid"`
+	Error     string                  `json:"error,omitempty"`
+	Decisions []*DecisionVerification `json:"decisions"`
+	Count     int                     `json:"count"`
+}
+
+type DecisionKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+// DecisionKeyResponse is the key decisions are signed with now, Previous are
+// the keys of earlier decisions
+type DecisionKeyResponse struct {
+	DecisionKey
+	Previous []DecisionKey `json:"previous"`
+}
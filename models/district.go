@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 type District struct {
 	ID         string `json:"id" bson:"_id"`
 	Name       string `json:"name" bson:"name"`
@@ -7,6 +13,31 @@ type District struct {
 	Code       uint32 `json:"code" bson:"code"`
 	ExternalID uint32 `json:"external_id" bson:"external_id"`
 	Soato      uint32 `json:"soato" bson:"soato"`
+	Active     bool   `json:"active" bson:"active"`
 	City       City   `json:"city" bson:"city"`
 	Region     Region `json:"region" bson:"region"`
 }
+
+type CreateUpdateDistrict struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	CityID     primitive.ObjectID `bson:"city_id"`
+	RegionID   primitive.ObjectID `bson:"region_id"`
+	Name       string             `bson:"name"`
+	RuName     string             `bson:"ru_name"`
+	Code       uint32             `bson:"code"`
+	ExternalID uint32             `bson:"external_id"`
+	Soato      uint32             `bson:"soato"`
+	Active     bool               `bson:"active"`
+	UpdatedAt  time.Time          `bson:"updated_at"`
+}
+
+type DistrictSwag struct {
+	CityID     string `json:"city_id" binding:"required"`
+	RegionID   string `json:"region_id" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	RuName     string `json:"ru_name"`
+	Code       uint32 `json:"code"`
+	ExternalID uint32 `json:"external_id"`
+	Soato      uint32 `json:"soato" binding:"required" example:"1726269501"`
+	Active     *bool  `json:"active"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+type CreateEntityExtractSwag struct {
+	// Owner adds the data of the applicant who filed the latest draft of
+	// the entity
+	Owner bool `json:"owner"`
+	// Lang is uz-Latn, uz-Cyrl, ru or en. Accept-Language is used when it
+	// is empty.
+	Lang string `json:"lang" example:"uz-Latn"`
+}
+
+// IssuedDocument is what the service remembers about a generated document,
+// so printouts can be checked with its verification code
+type IssuedDocument struct {
+	Code         string    `json:"code" bson:"_id"`
+	DocumentType string    `json:"document_type" bson:"document_type"`
+	EntityID     string    `json:"entity_id" bson:"entity_id"`
+	EntityNumber string    `json:"entity_number" bson:"entity_number"`
+	FileID       string    `json:"file_id" bson:"file_id"`
+	ObjectName   string    `json:"object_name" bson:"object_name"`
+	SHA256       string    `json:"sha256" bson:"sha256"`
+	Size         int64     `json:"size" bson:"size"`
+	IssuedBy     string    `json:"issued_by" bson:"issued_by"`
+	IssuedAt     time.Time `json:"issued_at" bson:"issued_at"`
+}
+
+// VerifyDocumentResponse is shown to anyone who has the code, it leaves out
+// internal ids
+type VerifyDocumentResponse struct {
+	Code         string    `json:"code" example:"7KQM-2XRA-9D4F"`
+	DocumentType string    `json:"document_type" example:"cadastral_extract"`
+	EntityNumber string    `json:"entity_number"`
+	IssuedAt     time.Time `json:"issued_at"`
+	SHA256       string    `json:"sha256"`
+	Size         int64     `json:"size"`
+}
+
+type VerifyDocumentUploadResponse struct {
+	VerifyDocumentResponse
+	// Matches is true when the uploaded file is byte for byte the issued one
+	Matches        bool   `json:"matches"`
+	UploadedSHA256 string `json:"uploaded_sha256"`
+	UploadedSize   int64  `json:"uploaded_size"`
+}
@@ -7,20 +7,32 @@ import (
 )
 
 type Entity struct {
-	ID             string          `json:"id" bson:"_id"`
-	EntitySoato    string          `json:"entity_soato" bson:"entity_soato"`
-	Address        string          `json:"address" bson:"address"`
-	RevertComment  string          `json:"revert_comment" bson:"revert_comment"`
-	EntityNumber   string          `json:"entity_number" bson:"entity_number"`
-	EntityTypeCode uint64          `json:"entity_type_code" bson:"entity_type_code"`
-	Version        uint64          `json:"version" bson:"version"`
-	Organizations  map[string]bool `json:"organizations" bson:"organizations"`
-	Status         string          `json:"status" bson:"status"`
-	City           *City           `json:"city" bson:"city"`
-	Region         *Region         `json:"region" bson:"region"`
-	District       *District       `json:"district" bson:"district"`
-	StaffIds       []string        `json:"staff_ids" bson:"staff_ids"`
-	EntityGallery  []string        `json:"entity_gallery" bson:"entity_gallery"`
+	ID                string          `json:"id" bson:"_id"`
+	EntitySoato       string          `json:"entity_soato" bson:"entity_soato"`
+	Address           string          `json:"address" bson:"address"`
+	RevertComment     string          `json:"revert_comment" bson:"revert_comment"`
+	EntityNumber      string          `json:"entity_number" bson:"entity_number"`
+	EntityTypeCode    uint64          `json:"entity_type_code" bson:"entity_type_code"`
+	Version           uint64          `json:"version" bson:"version"`
+	Organizations     map[string]bool `json:"organizations" bson:"organizations"`
+	Status            string          `json:"status" bson:"status"`
+	City              *City           `json:"city" bson:"city"`
+	Region            *Region         `json:"region" bson:"region"`
+	District          *District       `json:"district" bson:"district"`
+	StaffIds          []string        `json:"staff_ids" bson:"staff_ids"`
+	EntityGallery     []string        `json:"entity_gallery" bson:"entity_gallery"`
+	EntityGalleryURLs []string        `json:"entity_gallery_urls" bson:"-"`
+	// EntityGalleryPreviewURLs are the 1024 px copies of the gallery
+	EntityGalleryPreviewURLs []string       `json:"entity_gallery_preview_urls" bson:"-"`
+	ThumbnailURL             string         `json:"thumbnail_url" bson:"-"`
+	FormVersions             []*StepVersion `json:"form_versions" bson:"form_versions"`
+	// Location is where the entity is, Inspection when it is photographed.
+	// Gallery photos are checked against both.
+	Location            *GeoPoint         `json:"location" bson:"location"`
+	Boundary            *GeoBoundary      `json:"boundary" bson:"boundary,omitempty"`
+	Topology            *Topology         `json:"topology" bson:"topology,omitempty"`
+	Inspection          *EntityInspection `json:"inspection" bson:"inspection"`
+	EntityGalleryPhotos []*GalleryPhoto   `json:"entity_gallery_photos" bson:"-"`
 	// EntityDrafts   []*GetAllEntityDraft `json:"entity_drafts" bson:"entity_drafts"`
 	EntityFiles    []*EntityFiles       `json:"entity_files" bson:"entity_files"`
 	EntityProperty []*GetEntityProperty `json:"entity_properties" bson:"entity_properties"`
@@ -42,6 +54,10 @@ type GetAllEntities struct {
 	District         *District          `json:"district" bson:"district"`
 	EntityFiles      []string           `json:"entity_files" bson:"entity_files"`
 	EntityGallery    []string           `json:"entity_gallery" bson:"entity_gallery"`
+	ThumbnailURL     string             `json:"thumbnail_url" bson:"-"`
+	FormVersions     []*StepVersion     `json:"form_versions" bson:"form_versions"`
+	Location         *GeoPoint          `json:"location" bson:"location"`
+	Boundary         *GeoBoundary       `json:"boundary" bson:"boundary,omitempty"`
 	CreatedAt        primitive.DateTime `json:"created_at" bson:"created_at"`
 	UpdatedAt        primitive.DateTime `json:"updated_at" bson:"updated_at"`
 }
@@ -62,6 +78,10 @@ type CreateUpdateEntity struct {
 	EntityDrafts       []primitive.ObjectID    `bson:"entity_drafts"`
 	EntityGallery      []string                `bson:"entity_gallery"`
 	EntityProperties   []*CreateEntityProperty `bson:"entity_properties"`
+	FormVersions       []*StepVersion          `bson:"form_versions"`
+	Location           *GeoPoint               `bson:"location,omitempty"`
+	Boundary           *GeoBoundary            `bson:"boundary,omitempty"`
+	Topology           *Topology               `bson:"topology,omitempty"`
 	CreatedAt          time.Time               `bson:"created_at"`
 	UpdatedAt          time.Time               `bson:"updated_at"`
 	EntityStatusUpdate time.Time               `bson:"entity_status_update"`
@@ -94,6 +114,8 @@ type CreateUpdateEntitySwag struct {
 	EntityFiles      []string          `json:"entity_files" binding:"required"`
 	EntityGallery    []string          `json:"entity_gallery" binding:"required"`
 	EntityProperties []*EntityProperty `json:"entity_properties" binding:"required"`
+	Location         *GeoPoint         `json:"location"`
+	Boundary         *GeoBoundary      `json:"boundary"`
 }
 type UpdateWithActionIDEntitySwag struct {
 	ActionDescription string            `json:"action_description" binding:"required"`
@@ -121,7 +143,15 @@ type GetAllEntitiesRequest struct {
 	EntitySoato  string `json:"entity_soato"`
 	CityID       string `json:"city_id"`
 	RegionID     string `json:"region_id"`
+	DistrictID   string `json:"district_id"`
 	EntityNumber string `json:"entity_number"`
 	Page         uint32 `json:"page"`
 	Limit        uint32 `json:"limit"`
+	// BBox is [min longitude, min latitude, max longitude, max latitude],
+	// Within a polygon. Entities match with their location or boundary.
+	BBox   []float64    `json:"bbox"`
+	Within *GeoBoundary `json:"within"`
+	// Near matches entities located up to Radius meters from the point
+	Near   *GeoPoint `json:"near"`
+	Radius float64   `json:"radius"`
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// EntityBundleManifest is manifest.json of an entity bundle. Files lists
+// every other entry of the archive with its SHA-256.
+type EntityBundleManifest struct {
+	EntityID     string                 `json:"entity_id"`
+	EntityNumber string                 `json:"entity_number"`
+	GeneratedAt  time.Time              `json:"generated_at"`
+	GeneratedBy  string                 `json:"generated_by"`
+	Files        []*EntityBundleFile    `json:"files"`
+	Skipped      []*EntityBundleSkipped `json:"skipped"`
+}
+
+type EntityBundleFile struct {
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+	// Source is the file store name or the entity file id
+	Source string `json:"source"`
+}
+
+// EntityBundleSkipped is a file that could not be put into the bundle, such
+// as files kept outside the file store by older clients
+type EntityBundleSkipped struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// EntityBundleProperty is a row of properties.json and properties.csv
+type EntityBundleProperty struct {
+	PropertyID string `json:"property_id"`
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Value      string `json:"value"`
+}
@@ -28,9 +28,19 @@ type EntityDraft struct {
 	Region            *Region              `json:"region" bson:"region"`
 	District          *District            `json:"district" bson:"district"`
 	Status            string               `json:"status" bson:"status"`
+	ApplicantID       string               `json:"applicant_id" bson:"applicant_id"`
+	Version           uint64               `json:"version" bson:"version"`
 	Entity            *DraftEntity         `json:"entity" bson:"entity"`
 	EntityGallery     []string             `json:"entity_gallery" bson:"entity_gallery"`
+	EntityGalleryURLs []string             `json:"entity_gallery_urls" bson:"-"`
+	EntityFiles       []*EntityFiles       `json:"entity_files" bson:"-"`
 	EntityProperty    []*GetEntityProperty `json:"entity_properties" bson:"entity_properties"`
+	EntityTypeCode    uint64               `json:"entity_type_code" bson:"entity_type_code"`
+	FormVersions      []*StepVersion       `json:"form_versions" bson:"form_versions"`
+	CompletedSteps    []uint32             `json:"completed_steps" bson:"completed_steps"`
+	Location          *GeoPoint            `json:"location" bson:"location,omitempty"`
+	Boundary          *GeoBoundary         `json:"boundary" bson:"boundary,omitempty"`
+	Topology          *Topology            `json:"topology" bson:"topology,omitempty"`
 	CreatedAt         primitive.DateTime   `json:"created_at" bson:"created_at"`
 	UpdatedAt         primitive.DateTime   `json:"updated_at" bson:"updated_at"`
 }
@@ -45,6 +55,9 @@ type GetAllEntityDrafts struct {
 	District          *District          `json:"district" bson:"district"`
 	Status            string             `json:"status" bson:"status"`
 	EntityProperty    []*EntityProperty  `json:"entity_properties" bson:"entity_properties"`
+	EntityTypeCode    uint64             `json:"entity_type_code" bson:"entity_type_code"`
+	FormVersions      []*StepVersion     `json:"form_versions" bson:"form_versions"`
+	CompletedSteps    []uint32           `json:"completed_steps" bson:"completed_steps"`
 	CreatedAt         primitive.DateTime `json:"created_at" bson:"created_at"`
 	UpdatedAt         primitive.DateTime `json:"updated_at" bson:"updated_at"`
 }
@@ -55,16 +68,24 @@ type UpdateEntityDraftStatus struct {
 
 type CreateEntityDraft struct {
 	ID                primitive.ObjectID      `bson:"_id"`
-	EntityID          primitive.ObjectID      `bson:"entity_id"`
+	EntityID          primitive.ObjectID      `json:"entity_id" bson:"entity_id"`
 	EntityDraftNumber string                  `bson:"entity_draft_number" example:"T123"`
 	EntityDraftSoato  string                  `bson:"entity_draft_soato"`
 	Comment           string                  `bson:"comment"`
 	Status            string                  `bson:"status"`
+	ApplicantID       string                  `json:"-" bson:"applicant_id"`
 	City              City                    `bson:"city"`
 	Region            Region                  `bson:"region"`
 	District          District                `bson:"district"`
 	EntityGallery     []string                `bson:"entity_gallery"`
 	EntityProperties  []*CreateEntityProperty `bson:"entity_properties"`
+	EntityTypeCode    uint64                  `json:"entity_type_code" bson:"entity_type_code"`
+	FormVersions      []*StepVersion          `bson:"form_versions"`
+	CompletedSteps    []uint32                `bson:"completed_steps"`
+	Location          *GeoPoint               `bson:"location,omitempty"`
+	Boundary          *GeoBoundary            `bson:"boundary,omitempty"`
+	Topology          *Topology               `bson:"topology,omitempty"`
+	Wizard            bool                    `json:"wizard" bson:"-"`
 	CreatedAt         time.Time               `bson:"created_at"`
 	UpdatedAt         time.Time               `bson:"updated_at"`
 	DeletedAt         time.Time               `bson:"deleted_at"`
@@ -79,6 +100,11 @@ type EntityDraftSwag struct {
 	EntityGallery    []string          `json:"entity_gallery"`
 	EntityProperties []*EntityProperty `json:"entity_properties"`
 	EntityID         string            `json:"entity_id"`
+	EntityTypeCode   uint64            `json:"entity_type_code" example:"1"`
+	Location         *GeoPoint         `json:"location"`
+	Boundary         *GeoBoundary      `json:"boundary"`
+	// Wizard drafts are filled step by step and stay in_progress until submitted
+	Wizard bool `json:"wizard" example:"false"`
 }
 
 type ConfirmEntityDraftSwag struct {
@@ -98,3 +124,41 @@ type GetAllEntityDraftsRequest struct {
 type UpdateEntityDraftPropertySwag struct {
 	EntityProperty []EntityProperty `json:"entity_properties" binding:"required"`
 }
+
+// Entity draft statuses of the wizard. A submitted draft becomes new, the
+// status drafts created in one request start with.
+const (
+	EntityDraftStatusInProgress = "in_progress"
+	EntityDraftStatusNew        = "new"
+)
+
+type SaveEntityDraftStepSwag struct {
+	EntityProperties []*EntityProperty `json:"entity_properties" binding:"required"`
+}
+
+type UpdateEntityDraftStep struct {
+	ID               primitive.ObjectID
+	Version          uint64
+	EntityProperties []*CreateEntityProperty
+	CompletedSteps   []uint32
+}
+
+type EntityDraftPropertyError struct {
+	PropertyID string `json:"property_id"`
+	Label      string `json:"label"`
+	Message    string `json:"message" example:"required"`
+}
+
+type EntityDraftStep struct {
+	Step   uint32                      `json:"step"`
+	Valid  bool                        `json:"valid"`
+	Errors []*EntityDraftPropertyError `json:"errors"`
+}
+
+type EntityDraftStepsResponse struct {
+	EntityDraftID  string             `json:"entity_draft_id"`
+	Status         string             `json:"status"`
+	Steps          []*EntityDraftStep `json:"steps"`
+	CompletedSteps []uint32           `json:"completed_steps"`
+	CanSubmit      bool               `json:"can_submit"`
+}
@@ -7,23 +7,111 @@ import (
 )
 
 type EntityFiles struct {
-	ID       string `json:"id" bson:"_id"`
-	FileName string `json:"name" bson:"name"`
-	Url      string `json:"url" bson:"url"`
-	Comment  string `json:"comment" bson:"comment"`
-	User     string `json:"user" bson:"user"`
+	ID          string `json:"id" bson:"_id"`
+	FileName    string `json:"name" bson:"name"`
+	Url         string `json:"url" bson:"url"`
+	Comment     string `json:"comment" bson:"comment"`
+	User        string `json:"user" bson:"user"`
+	ObjectName  string `json:"object_name" bson:"object_name"`
+	ContentType string `json:"content_type" bson:"content_type"`
+	Size        int64  `json:"size" bson:"size"`
+
+	EntityID      string     `json:"entity_id,omitempty" bson:"entity_id,omitempty"`
+	EntityDraftID string     `json:"entity_draft_id,omitempty" bson:"entity_draft_id,omitempty"`
+	DocumentType  string     `json:"document_type,omitempty" bson:"document_type,omitempty"`
+	Version       uint32     `json:"version,omitempty" bson:"version,omitempty"`
+	Current       bool       `json:"current" bson:"current"`
+	AttachedAt    *time.Time `json:"attached_at,omitempty" bson:"attached_at,omitempty"`
+	DetachedAt    *time.Time `json:"detached_at,omitempty" bson:"detached_at,omitempty"`
+
+	Signature *FileSignature `json:"signature,omitempty" bson:"signature,omitempty"`
 }
 
 type CreateEntityFiles struct {
-	ID        primitive.ObjectID `bson:"_id"`
-	FileName  string             `bson:"name"`
-	Url       string             `bson:"url"`
-	Comment   string             `bson:"comment"`
-	User      string             `bson:"user"`
-	CreatedAt time.Time          `bson:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at"`
+	ID          primitive.ObjectID `bson:"_id"`
+	FileName    string             `bson:"name"`
+	Url         string             `bson:"url"`
+	Comment     string             `bson:"comment"`
+	User        string             `bson:"user"`
+	ObjectName  string             `bson:"object_name"`
+	ContentType string             `bson:"content_type"`
+	Size        int64              `bson:"size"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at"`
+	Signature   *FileSignature     `bson:"signature,omitempty"`
 }
 
 type EntityFilesSwag struct {
 	Comment string `json:"comment"`
 }
+
+type UploadURLSwag struct {
+	FileName    string `json:"file_name" binding:"required" example:"survey_plan.pdf"`
+	ContentType string `json:"content_type" binding:"required" example:"application/pdf"`
+	Size        int64  `json:"size" binding:"required" example:"52428800"`
+}
+
+type UploadURLResponse struct {
+	ObjectName string `json:"object_name"`
+	UploadURL  string `json:"upload_url"`
+	Method     string `json:"method" example:"PUT"`
+	// UploadToken has to be sent with the confirmation, it ties the object
+	// name to the user it was given to
+	UploadToken string `json:"upload_token"`
+	// Headers have to be sent with the upload exactly as given
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type ConfirmUploadSwag struct {
+	ObjectName  string `json:"object_name" binding:"required"`
+	UploadToken string `json:"upload_token" binding:"required"`
+	FileName    string `json:"file_name" binding:"required" example:"survey_plan.pdf"`
+	Comment     string `json:"comment"`
+}
+
+// Document types of the files attached to entities and drafts
+const (
+	DocumentTypeTitleDeed    = "title_deed"
+	DocumentTypeSurveyPlan   = "survey_plan"
+	DocumentTypePassportCopy = "passport_copy"
+)
+
+var DocumentTypes = []string{
+	DocumentTypeTitleDeed,
+	DocumentTypeSurveyPlan,
+	DocumentTypePassportCopy,
+}
+
+// DocumentTypeCadastralExtract is generated by the service and can not be
+// attached by hand
+const DocumentTypeCadastralExtract = "cadastral_extract"
+
+type AttachDocumentSwag struct {
+	FileID       string `json:"file_id" binding:"required"`
+	DocumentType string `json:"document_type" binding:"required" example:"survey_plan"`
+	Comment      string `json:"comment"`
+}
+
+// AttachEntityFile links a registered file to either an entity or a draft
+type AttachEntityFile struct {
+	FileID        primitive.ObjectID
+	EntityID      primitive.ObjectID
+	EntityDraftID primitive.ObjectID
+	DocumentType  string
+	Comment       string
+	User          string
+}
+
+type GetEntityDocumentsRequest struct {
+	EntityID      string
+	EntityDraftID string
+	DocumentType  string
+	// AllVersions includes the superseded and detached versions
+	AllVersions bool
+}
+
+type GetEntityDocumentsResponse struct {
+	Documents []*EntityFiles `json:"documents"`
+	Count     uint32         `json:"count"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	FileGCModeDryRun  = "dry_run"
+	FileGCModeEnforce = "enforce"
+)
+
+// FileGCRun is the audit record of one orphaned file collection. Orphans is
+// cut at a limit, the counters cover every orphan.
+type FileGCRun struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	Mode        string             `json:"mode" bson:"mode"`
+	TriggeredBy string             `json:"triggered_by" bson:"triggered_by"`
+	GracePeriod string             `json:"grace_period" bson:"grace_period"`
+	StartedAt   time.Time          `json:"started_at" bson:"started_at"`
+	FinishedAt  time.Time          `json:"finished_at" bson:"finished_at"`
+	Scanned     uint64             `json:"scanned" bson:"scanned"`
+	OrphanCount uint64             `json:"orphan_count" bson:"orphan_count"`
+	OrphanBytes int64              `json:"orphan_bytes" bson:"orphan_bytes"`
+	Deleted     uint64             `json:"deleted" bson:"deleted"`
+	Failed      uint64             `json:"failed" bson:"failed"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	Orphans     []*FileGCOrphan    `json:"orphans" bson:"orphans"`
+}
+
+type FileGCOrphan struct {
+	Name         string    `json:"name" bson:"name"`
+	Size         int64     `json:"size" bson:"size"`
+	LastModified time.Time `json:"last_modified" bson:"last_modified"`
+	Deleted      bool      `json:"deleted" bson:"deleted"`
+	Error        string    `json:"error,omitempty" bson:"error,omitempty"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FormSchemaVersion is an immutable snapshot of the group properties of one
+// (type, step) pair, taken at the moment the form was published.
+type FormSchemaVersion struct {
+	ID              string             `json:"id" bson:"_id"`
+	Type            uint32             `json:"type" bson:"type"`
+	Step            uint32             `json:"step" bson:"step"`
+	Version         uint32             `json:"version" bson:"version"`
+	Comment         string             `json:"comment" bson:"comment"`
+	PublishedBy     string             `json:"published_by" bson:"published_by"`
+	GroupProperties []*GroupProperty   `json:"group_properties" bson:"group_properties"`
+	CreatedAt       primitive.DateTime `json:"created_at" bson:"created_at"`
+}
+
+type CreateFormSchemaVersion struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	Type            uint32             `bson:"type"`
+	Step            uint32             `bson:"step"`
+	Version         uint32             `bson:"version"`
+	Comment         string             `bson:"comment"`
+	PublishedBy     string             `bson:"published_by"`
+	GroupProperties []*GroupProperty   `bson:"group_properties"`
+	CreatedAt       time.Time          `bson:"created_at"`
+}
+
+// StepVersion records which published form version was used for a step
+type StepVersion struct {
+	Step    uint32 `json:"step" bson:"step"`
+	Version uint32 `json:"version" bson:"version"`
+}
+
+// GetAllFormSchemaVersionsRequest lists the versions of every step when Step
+// is nil
+type GetAllFormSchemaVersionsRequest struct {
+	Type  uint32  `json:"type"`
+	Step  *uint32 `json:"step"`
+	Page  uint32  `json:"page"`
+	Limit uint32  `json:"limit"`
+}
+
+type GetAllFormSchemaVersionsResponse struct {
+	FormSchemaVersions []*FormSchemaVersion `json:"form_schema_versions"`
+	Count              uint32               `json:"count"`
+}
+
+// PublishFormSchemaVersionSwag takes Step as a pointer, so step 0 can be
+// published while a request without a step is rejected
+type PublishFormSchemaVersionSwag struct {
+	Type    uint32  `json:"type" binding:"required" example:"1"`
+	Step    *uint32 `json:"step" binding:"required" example:"1"`
+	Comment string  `json:"comment"`
+}
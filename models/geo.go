@@ -0,0 +1,212 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/e-space-uz/backend/pkg/geo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const GeoJSONPoint = "Point"
+
+// GeoPoint is a GeoJSON point, coordinates are [longitude, latitude]
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type" example:"Point"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates" example:"69.2401,41.2995"`
+}
+
+func NewGeoPoint(latitude, longitude float64) *GeoPoint {
+	return &GeoPoint{
+		Type:        GeoJSONPoint,
+		Coordinates: []float64{longitude, latitude},
+	}
+}
+
+func (p *GeoPoint) Longitude() float64 {
+	return p.Coordinates[0]
+}
+
+func (p *GeoPoint) Latitude() float64 {
+	return p.Coordinates[1]
+}
+
+func (p *GeoPoint) Validate() error {
+	if p.Type != GeoJSONPoint {
+		return errors.New("type must be Point")
+	}
+	if len(p.Coordinates) != 2 {
+		return errors.New("coordinates must be [longitude, latitude]")
+	}
+	if math.IsNaN(p.Longitude()) || math.Abs(p.Longitude()) > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	if math.IsNaN(p.Latitude()) || math.Abs(p.Latitude()) > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	return nil
+}
+
+const (
+	GeoJSONPolygon      = "Polygon"
+	GeoJSONMultiPolygon = "MultiPolygon"
+)
+
+// GeoBoundary is a GeoJSON Polygon or MultiPolygon. A Polygon is kept as a
+// MultiPolygon of one, so code reading it does not care which it was sent
+// as, and written back in the type it came in.
+type GeoBoundary struct {
+	Type     string          `json:"type" example:"Polygon"`
+	Polygons [][][][]float64 `json:"coordinates" swaggertype:"array,number"`
+}
+
+type geoPolygon struct {
+	Type        string        `json:"type" bson:"type"`
+	Coordinates [][][]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+type geoMultiPolygon struct {
+	Type        string          `json:"type" bson:"type"`
+	Coordinates [][][][]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+func NewGeoBoundary(polygons [][][][]float64) *GeoBoundary {
+	if len(polygons) == 1 {
+		return &GeoBoundary{Type: GeoJSONPolygon, Polygons: polygons}
+	}
+	return &GeoBoundary{Type: GeoJSONMultiPolygon, Polygons: polygons}
+}
+
+func (b *GeoBoundary) Validate() error {
+	return b.ValidateLimit(geo.MaxRingPositions)
+}
+
+// ValidateLimit is Validate with another limit of positions per ring, for
+// boundaries larger than a parcel
+func (b *GeoBoundary) ValidateLimit(maxRingPositions int) error {
+	if b.Type != GeoJSONPolygon && b.Type != GeoJSONMultiPolygon {
+		return errors.New("type must be Polygon or MultiPolygon")
+	}
+	if len(b.Polygons) == 0 || (b.Type == GeoJSONPolygon && len(b.Polygons) != 1) {
+		return errors.New("coordinates must have a polygon")
+	}
+	for i, polygon := range b.Polygons {
+		if err := geo.ValidatePolygon(polygon, maxRingPositions); err != nil {
+			if b.Type == GeoJSONMultiPolygon {
+				return fmt.Errorf("polygon %d: %w", i, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Contains tells whether the point is inside one of the polygons
+func (b *GeoBoundary) Contains(p *GeoPoint) bool {
+	for _, polygon := range b.Polygons {
+		if geo.PointInPolygon(p.Longitude(), p.Latitude(), polygon) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b GeoBoundary) geoJSON() interface{} {
+	if b.Type == GeoJSONPolygon && len(b.Polygons) == 1 {
+		return geoPolygon{Type: b.Type, Coordinates: b.Polygons[0]}
+	}
+	return geoMultiPolygon{Type: b.Type, Coordinates: b.Polygons}
+}
+
+func (b GeoBoundary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.geoJSON())
+}
+
+func (b *GeoBoundary) UnmarshalJSON(data []byte) error {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return err
+	}
+	return b.set(geometry.Type, func(v interface{}) error {
+		return json.Unmarshal(geometry.Coordinates, v)
+	})
+}
+
+func (b GeoBoundary) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(b.geoJSON())
+}
+
+// UnmarshalBSON leaves a null boundary empty
+func (b *GeoBoundary) UnmarshalBSON(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var geometry struct {
+		Type        string        `bson:"type"`
+		Coordinates bson.RawValue `bson:"coordinates"`
+	}
+	if err := bson.Unmarshal(data, &geometry); err != nil {
+		return err
+	}
+	return b.set(geometry.Type, geometry.Coordinates.Unmarshal)
+}
+
+func (b *GeoBoundary) set(geometryType string, coordinates func(interface{}) error) error {
+	b.Type = geometryType
+	switch geometryType {
+	case GeoJSONPolygon:
+		var polygon [][][]float64
+		if err := coordinates(&polygon); err != nil {
+			return err
+		}
+		b.Polygons = [][][][]float64{polygon}
+	case GeoJSONMultiPolygon:
+		b.Polygons = nil
+		return coordinates(&b.Polygons)
+	default:
+		return fmt.Errorf("unsupported boundary type %q", geometryType)
+	}
+	return nil
+}
+
+// Transform returns a copy of the point with converted coordinates
+func (p *GeoPoint) Transform(convert func(x, y float64) (float64, float64)) *GeoPoint {
+	return &GeoPoint{Type: p.Type, Coordinates: transformPosition(p.Coordinates, convert)}
+}
+
+// Transform returns a copy of the boundary with converted coordinates
+func (b *GeoBoundary) Transform(convert func(x, y float64) (float64, float64)) *GeoBoundary {
+	polygons := make([][][][]float64, len(b.Polygons))
+	for i, polygon := range b.Polygons {
+		polygons[i] = make([][][]float64, len(polygon))
+		for j, ring := range polygon {
+			polygons[i][j] = make([][]float64, len(ring))
+			for k, position := range ring {
+				polygons[i][j][k] = transformPosition(position, convert)
+			}
+		}
+	}
+	return &GeoBoundary{Type: b.Type, Polygons: polygons}
+}
+
+// transformPosition keeps the altitude, positions too short are left for
+// validation to reject
+func transformPosition(position []float64, convert func(x, y float64) (float64, float64)) []float64 {
+	converted := append([]float64(nil), position...)
+	if len(converted) >= 2 {
+		converted[0], converted[1] = convert(position[0], position[1])
+	}
+	return converted
+}
+
+type CRS struct {
+	Code       string `json:"code" example:"EPSG:28412"`
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+	Projected  bool   `json:"projected"`
+}
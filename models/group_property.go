@@ -7,24 +7,26 @@ import (
 )
 
 type GroupProperty struct {
-	ID          string      `json:"id" bson:"_id"`
-	Name        string      `json:"name" bson:"name" example:"Doe"`
-	Step        uint32      `json:"step" bson:"step"`
-	Type        uint32      `json:"type" bson:"type"`
-	Description string      `json:"description" bson:"description"`
-	Status      bool        `json:"status" bson:"status"`
-	Properties  []*Property `json:"properties" bson:"properties"`
+	ID           string                     `json:"id" bson:"_id"`
+	Name         string                     `json:"name" bson:"name" example:"Doe"`
+	Step         uint32                     `json:"step" bson:"step"`
+	Type         uint32                     `json:"type" bson:"type"`
+	Description  string                     `json:"description" bson:"description"`
+	Status       bool                       `json:"status" bson:"status"`
+	Properties   []*Property                `json:"properties" bson:"properties"`
+	Translations *GroupPropertyTranslations `json:"translations,omitempty" bson:"translations,omitempty"`
 }
 type GetAllGroupProperty struct {
-	ID            string           `json:"id" bson:"_id"`
-	Name          string           `json:"name" bson:"name" example:"Doe"`
-	Step          uint32           `json:"step" bson:"step"`
-	Type          uint32           `json:"type" bson:"type"`
-	Status        bool             `json:"status" bson:"status"`
-	Description   string           `json:"description" bson:"description"`
-	ReadStatuses  []string         `json:"read_statuses" bson:"read_statuses,omitempty"`
-	WriteStatuses []string         `json:"write_statuses" bson:"write_statuses,omitempty"`
-	Properties    []*GetProperties `json:"properties" bson:"properties"`
+	ID            string                     `json:"id" bson:"_id"`
+	Name          string                     `json:"name" bson:"name" example:"Doe"`
+	Step          uint32                     `json:"step" bson:"step"`
+	Type          uint32                     `json:"type" bson:"type"`
+	Status        bool                       `json:"status" bson:"status"`
+	Description   string                     `json:"description" bson:"description"`
+	ReadStatuses  []string                   `json:"read_statuses" bson:"read_statuses,omitempty"`
+	WriteStatuses []string                   `json:"write_statuses" bson:"write_statuses,omitempty"`
+	Properties    []*GetProperties           `json:"properties" bson:"properties"`
+	Translations  *GroupPropertyTranslations `json:"translations,omitempty" bson:"translations,omitempty"`
 }
 
 type Properties struct {
@@ -61,18 +63,19 @@ type GetGroupPropertyByStatusID struct {
 	Organization OrganizationGet `json:"organization" bson:"organization"`
 }
 type CreateGroupProperty struct {
-	ID            primitive.ObjectID   `bson:"_id"`
-	Step          uint32               `bson:"step"`
-	Name          string               `bson:"name"`
-	Type          uint32               `bson:"type"`
-	Status        bool                 `bson:"status"`
-	Description   string               `bson:"description"`
-	Organization  OrganizationCreate   `json:"organization" bson:"organization"`
-	CreatedAt     time.Time            `bson:"created_at"`
-	UpdatedAt     time.Time            `bson:"updated_at"`
-	Properties    []*CreateProperties  `bson:"properties"`
-	ReadStatuses  []primitive.ObjectID `bson:"read_statuses"`
-	WriteStatuses []primitive.ObjectID `bson:"write_statuses"`
+	ID            primitive.ObjectID         `bson:"_id"`
+	Step          uint32                     `bson:"step"`
+	Name          string                     `bson:"name"`
+	Type          uint32                     `bson:"type"`
+	Status        bool                       `bson:"status"`
+	Description   string                     `bson:"description"`
+	Organization  OrganizationCreate         `json:"organization" bson:"organization"`
+	CreatedAt     time.Time                  `bson:"created_at"`
+	UpdatedAt     time.Time                  `bson:"updated_at"`
+	Properties    []*CreateProperties        `bson:"properties"`
+	ReadStatuses  []primitive.ObjectID       `bson:"read_statuses"`
+	WriteStatuses []primitive.ObjectID       `bson:"write_statuses"`
+	Translations  *GroupPropertyTranslations `bson:"translations,omitempty"`
 }
 
 type CreateProperties struct {
@@ -81,15 +84,22 @@ type CreateProperties struct {
 }
 
 type GroupPropertySwag struct {
-	Name          string           `json:"name" binding:"required"`
-	Step          uint32           `json:"step" binding:"required"`
-	Type          uint32           `json:"type" binding:"required"`
-	Status        bool             `json:"status" binding:"required"`
-	Organization  OrganizationGet  `json:"organization" bson:"organization"`
-	Description   string           `json:"description" binding:"required" example:"I have no idea"`
-	Properties    []*GetProperties `json:"properties" binding:"required"`
-	ReadStatuses  []string         `json:"read_statuses" binding:"required" example:"60dd9c0a4472a2aaa970304e"`
-	WriteStatuses []string         `json:"write_statuses" binding:"required" example:"60dd9c1a729317449b1ada03"`
+	UpdateGroupPropertySwag
+	// Status is true when it is not sent, afterwards it only changes through
+	// /status
+	Status *bool `json:"status" example:"true"`
+}
+
+type UpdateGroupPropertySwag struct {
+	Name          string                     `json:"name" binding:"required"`
+	Step          uint32                     `json:"step"`
+	Type          uint32                     `json:"type" binding:"required"`
+	Organization  OrganizationGet            `json:"organization" bson:"organization"`
+	Description   string                     `json:"description" binding:"required" example:"I have no idea"`
+	Properties    []*GetProperties           `json:"properties" binding:"required"`
+	ReadStatuses  []string                   `json:"read_statuses" binding:"required" example:"60dd9c0a4472a2aaa970304e"`
+	WriteStatuses []string                   `json:"write_statuses" binding:"required" example:"60dd9c1a729317449b1ada03"`
+	Translations  *GroupPropertyTranslations `json:"translations"`
 }
 
 type OrganizationGet struct {
@@ -101,3 +111,15 @@ type OrganizationCreate struct {
 	Name string             `json:"name"`
 	ID   primitive.ObjectID `json:"id"`
 }
+
+type ReorderGroupPropertiesSwag struct {
+	Properties []*GetProperties `json:"properties" binding:"required"`
+}
+
+type UpdateGroupPropertyStatusSwag struct {
+	Status bool `json:"status" example:"true"`
+}
+
+type CloneGroupPropertySwag struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	PhotoFlagNoExif                  = "no_exif"
+	PhotoFlagNoLocation              = "no_location"
+	PhotoFlagTooFar                  = "too_far"
+	PhotoFlagNoCaptureTime           = "no_capture_time"
+	PhotoFlagOutsideInspectionWindow = "outside_inspection_window"
+	// PhotoFlagUnverified is for gallery images whose metadata was never
+	// read, such as files registered with /v1/files/confirm or named by the
+	// client
+	PhotoFlagUnverified = "unverified"
+)
+
+// PhotoMetadata is read from the EXIF of a gallery image when it is uploaded
+type PhotoMetadata struct {
+	ObjectName string     `json:"object_name" bson:"_id"`
+	HasExif    bool       `json:"has_exif" bson:"has_exif"`
+	Location   *GeoPoint  `json:"location" bson:"location,omitempty"`
+	Altitude   *float64   `json:"altitude" bson:"altitude,omitempty"`
+	CapturedAt *time.Time `json:"captured_at" bson:"captured_at,omitempty"`
+	Make       string     `json:"make" bson:"make"`
+	Model      string     `json:"model" bson:"model"`
+	Software   string     `json:"software" bson:"software"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// GalleryPhoto is a gallery image with its metadata checked against the
+// entity. Images without metadata are flagged as unverified.
+type GalleryPhoto struct {
+	Name           string         `json:"name"`
+	URL            string         `json:"url"`
+	Metadata       *PhotoMetadata `json:"metadata"`
+	DistanceMeters *float64       `json:"distance_meters,omitempty"`
+	Flags          []string       `json:"flags"`
+}
+
+// EntityInspection is when inspectors are expected to photograph the entity
+type EntityInspection struct {
+	StartsAt  time.Time `json:"starts_at" bson:"starts_at"`
+	EndsAt    time.Time `json:"ends_at" bson:"ends_at"`
+	UpdatedBy string    `json:"updated_by" bson:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type UpdateEntityInspectionSwag struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+}
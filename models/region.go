@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 type Region struct {
 	ID         string `json:"id" bson:"_id"`
 	RuName     string `json:"ru_name" bson:"ru_name"`
@@ -7,5 +13,28 @@ type Region struct {
 	Code       uint32 `json:"code" bson:"code"`
 	ExternalID uint32 `json:"external_id" bson:"external_id"`
 	Soato      uint32 `json:"soato" bson:"soato"`
+	Active     bool   `json:"active" bson:"active"`
 	City       City   `json:"city" bson:"city"`
 }
+
+type CreateUpdateRegion struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	CityID     primitive.ObjectID `bson:"city_id"`
+	Name       string             `bson:"name"`
+	RuName     string             `bson:"ru_name"`
+	Code       uint32             `bson:"code"`
+	ExternalID uint32             `bson:"external_id"`
+	Soato      uint32             `bson:"soato"`
+	Active     bool               `bson:"active"`
+	UpdatedAt  time.Time          `bson:"updated_at"`
+}
+
+type RegionSwag struct {
+	CityID     string `json:"city_id" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	RuName     string `json:"ru_name"`
+	Code       uint32 `json:"code"`
+	ExternalID uint32 `json:"external_id"`
+	Soato      uint32 `json:"soato" binding:"required" example:"1726269"`
+	Active     *bool  `json:"active"`
+}
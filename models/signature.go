@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Signature statuses, a signature is valid only when every signer is
+const (
+	SignatureStatusValid       = "valid"
+	SignatureStatusInvalid     = "invalid"
+	SignatureStatusExpired     = "expired"
+	SignatureStatusRevoked     = "revoked"
+	SignatureStatusUntrusted   = "untrusted"
+	SignatureStatusUnsupported = "unsupported"
+)
+
+// FileSignature is the result of verifying the CMS signature of a file,
+// either the file itself or a detached signature sent for it
+type FileSignature struct {
+	Status     string        `json:"status" bson:"status"`
+	Detached   bool          `json:"detached" bson:"detached"`
+	Error      string        `json:"error,omitempty" bson:"error,omitempty"`
+	Signers    []*FileSigner `json:"signers" bson:"signers"`
+	VerifiedAt time.Time     `json:"verified_at" bson:"verified_at"`
+	VerifiedBy string        `json:"verified_by,omitempty" bson:"verified_by,omitempty"`
+	// Data is the detached signature as sent
+	Data []byte `json:"-" bson:"data,omitempty"`
+}
+
+type FileSigner struct {
+	Name         string     `json:"name" bson:"name"`
+	Organization string     `json:"organization,omitempty" bson:"organization,omitempty"`
+	Pinfl        string     `json:"pinfl,omitempty" bson:"pinfl,omitempty"`
+	Inn          string     `json:"inn,omitempty" bson:"inn,omitempty"`
+	SerialNumber string     `json:"serial_number,omitempty" bson:"serial_number,omitempty"`
+	Issuer       string     `json:"issuer,omitempty" bson:"issuer,omitempty"`
+	ValidFrom    *time.Time `json:"valid_from,omitempty" bson:"valid_from,omitempty"`
+	ValidTo      *time.Time `json:"valid_to,omitempty" bson:"valid_to,omitempty"`
+	SigningTime  *time.Time `json:"signing_time,omitempty" bson:"signing_time,omitempty"`
+	// Timestamp is the time of a trusted timestamp, the certificate was
+	// checked at it
+	Timestamp *time.Time `json:"timestamp,omitempty" bson:"timestamp,omitempty"`
+	Status    string     `json:"status" bson:"status"`
+	Error     string     `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+type FileSignatureSwag struct {
+	// Signature is the detached CMS signature in base64, as E-IMZO returns it
+	Signature string `json:"signature" binding:"required"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Statuses of a SOATO import
+const (
+	SoatoImportPreview  = "preview"
+	SoatoImportApplying = "applying"
+	SoatoImportApplied  = "applied"
+	SoatoImportFailed   = "failed"
+)
+
+// SoatoUnit is a city, region or district as the classifier lists it.
+// ParentSoato is the code of the unit above, zero for cities. ID and Active
+// are set for the stored units only.
+type SoatoUnit struct {
+	ID          string `json:"id,omitempty" bson:"id,omitempty"`
+	Level       string `json:"level" bson:"level" example:"region"`
+	Soato       uint32 `json:"soato" bson:"soato"`
+	ParentSoato uint32 `json:"parent_soato" bson:"parent_soato"`
+	Name        string `json:"name" bson:"name"`
+	RuName      string `json:"ru_name" bson:"ru_name"`
+	Active      bool   `json:"active" bson:"active"`
+}
+
+// SoatoChange is one unit the import adds, renames, abolishes or restores,
+// the old names are those stored
+type SoatoChange struct {
+	Level       string `json:"level" bson:"level" example:"district"`
+	Soato       uint32 `json:"soato" bson:"soato"`
+	ParentSoato uint32 `json:"parent_soato" bson:"parent_soato"`
+	UnitID      string `json:"unit_id,omitempty" bson:"unit_id,omitempty"`
+	Name        string `json:"name" bson:"name"`
+	RuName      string `json:"ru_name" bson:"ru_name"`
+	OldName     string `json:"old_name,omitempty" bson:"old_name,omitempty"`
+	OldRuName   string `json:"old_ru_name,omitempty" bson:"old_ru_name,omitempty"`
+}
+
+// SoatoDiff is what applying the classifier changes. Units missing from the
+// file are abolished, abolished units listed again are restored.
+type SoatoDiff struct {
+	Added     []*SoatoChange `json:"added" bson:"added"`
+	Renamed   []*SoatoChange `json:"renamed" bson:"renamed"`
+	Abolished []*SoatoChange `json:"abolished" bson:"abolished"`
+	Restored  []*SoatoChange `json:"restored" bson:"restored"`
+	Unchanged int            `json:"unchanged" bson:"unchanged"`
+}
+
+// SoatoImport is an uploaded classifier with the changes it makes, it is
+// applied after the diff is reviewed
+type SoatoImport struct {
+	ID       string       `json:"id" bson:"_id"`
+	FileName string       `json:"file_name" bson:"file_name"`
+	Status   string       `json:"status" bson:"status" example:"preview"`
+	Units    []*SoatoUnit `json:"-" bson:"units"`
+	// RuNames tells whether the file has Russian names, without them the
+	// stored ones are kept
+	RuNames   bool       `json:"ru_names" bson:"ru_names"`
+	Diff      *SoatoDiff `json:"diff" bson:"diff"`
+	Error     string     `json:"error,omitempty" bson:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	CreatedBy string     `json:"created_by" bson:"created_by"`
+	AppliedAt *time.Time `json:"applied_at,omitempty" bson:"applied_at,omitempty"`
+	AppliedBy string     `json:"applied_by,omitempty" bson:"applied_by,omitempty"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Topology issue codes
+const (
+	TopologyOverlap           = "overlap"
+	TopologyAreaDiscrepancy   = "area_discrepancy"
+	TopologyTooManyCandidates = "too_many_candidates"
+)
+
+// Topology is what the check of a boundary found, it is kept with the entity
+// or draft so reviewers see the warnings. Areas are in square meters.
+type Topology struct {
+	Area float64 `json:"area" bson:"area"`
+	// DeclaredArea is the value of the area property, AreaDiscrepancy how
+	// far in percent the boundary is from it
+	DeclaredArea    *float64         `json:"declared_area" bson:"declared_area"`
+	AreaDiscrepancy float64          `json:"area_discrepancy" bson:"area_discrepancy"`
+	Overlaps        []*EntityOverlap `json:"overlaps" bson:"overlaps"`
+	// OverlapsTruncated is set when the boundary touches more entities than
+	// are compared with it, the overlaps of the rest are not listed
+	OverlapsTruncated bool             `json:"overlaps_truncated" bson:"overlaps_truncated"`
+	Issues            []*TopologyIssue `json:"issues" bson:"issues"`
+	CheckedAt         time.Time        `json:"checked_at" bson:"checked_at"`
+}
+
+// EntityOverlap is the part of the boundary another entity covers too,
+// Percent is of the smaller of the two
+type EntityOverlap struct {
+	EntityID     string  `json:"entity_id" bson:"entity_id"`
+	EntityNumber string  `json:"entity_number" bson:"entity_number"`
+	Area         float64 `json:"area" bson:"area"`
+	Percent      float64 `json:"percent" bson:"percent"`
+}
+
+type TopologyIssue struct {
+	Code     string `json:"code" bson:"code" example:"overlap"`
+	Blocking bool   `json:"blocking" bson:"blocking"`
+	Message  string `json:"message" bson:"message"`
+}
+
+// TopologyError rejects a boundary with blocking issues
+type TopologyError struct {
+	Issues []*TopologyIssue `json:"issues"`
+}
+
+func (e *TopologyError) Error() string {
+	messages := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		if issue.Blocking {
+			messages = append(messages, issue.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// EntityBoundary is what the overlap checks read of an entity
+type EntityBoundary struct {
+	ID           string       `json:"id" bson:"_id"`
+	EntityNumber string       `json:"entity_number" bson:"entity_number"`
+	Boundary     *GeoBoundary `json:"boundary" bson:"boundary"`
+}
+
+// OverlapReport lists the entities of a district whose boundaries overlap,
+// largest overlaps first
+type OverlapReport struct {
+	DistrictID string `json:"district_id"`
+	// Entities is how many entities of the district have a boundary
+	Entities  int            `json:"entities"`
+	Overlaps  []*OverlapPair `json:"overlaps"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// OverlapPair is two entities covering the same land, Percent is of the
+// smaller of the two
+type OverlapPair struct {
+	EntityID          string  `json:"entity_id"`
+	EntityNumber      string  `json:"entity_number"`
+	OtherEntityID     string  `json:"other_entity_id"`
+	OtherEntityNumber string  `json:"other_entity_number"`
+	Area              float64 `json:"area"`
+	Percent           float64 `json:"percent"`
+	Blocking          bool    `json:"blocking"`
+}
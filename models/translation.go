@@ -0,0 +1,72 @@
+package models
+
+// Translations maps a language tag (uz-Latn, uz-Cyrl, ru, en) to the text in
+// that language. The untranslated field keeps the text in the default language.
+type Translations map[string]string
+
+// Get returns the text for the language, or fallback when there is none
+func (t Translations) Get(lang, fallback string) string {
+	if text := t[lang]; text != "" {
+		return text
+	}
+	return fallback
+}
+
+// Missing returns the languages that have no text, skipping the default
+// language which is covered by the untranslated field
+func (t Translations) Missing(languages []string, defaultLanguage string) []string {
+	missing := []string{}
+	for _, lang := range languages {
+		if lang == defaultLanguage {
+			continue
+		}
+		if t[lang] == "" {
+			missing = append(missing, lang)
+		}
+	}
+	return missing
+}
+
+type PropertyTranslations struct {
+	Label       Translations `json:"label,omitempty" bson:"label,omitempty"`
+	Placeholder Translations `json:"placeholder,omitempty" bson:"placeholder,omitempty"`
+	Description Translations `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+type GroupPropertyTranslations struct {
+	Name        Translations `json:"name,omitempty" bson:"name,omitempty"`
+	Description Translations `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+// UpdatePropertyTranslationsSwag merges the given texts into the stored
+// translations. An empty text removes the translation.
+type UpdatePropertyTranslationsSwag struct {
+	Label       Translations `json:"label"`
+	Placeholder Translations `json:"placeholder"`
+	Description Translations `json:"description"`
+	// Options are keyed by PropertyOption.Value
+	Options map[string]Translations `json:"options"`
+}
+
+// UpdateGroupPropertyTranslationsSwag merges the given texts into the stored
+// translations. An empty text removes the translation.
+type UpdateGroupPropertyTranslationsSwag struct {
+	Name        Translations `json:"name"`
+	Description Translations `json:"description"`
+}
+
+type MissingTranslation struct {
+	Kind    string   `json:"kind" example:"property"`
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Field   string   `json:"field" example:"label"`
+	Option  string   `json:"option,omitempty"`
+	Text    string   `json:"text"`
+	Missing []string `json:"missing" example:"ru"`
+}
+
+type MissingTranslationsResponse struct {
+	Languages           []string              `json:"languages"`
+	MissingTranslations []*MissingTranslation `json:"missing_translations"`
+	Count               uint32                `json:"count"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Upload is a tus resumable upload. Its chunks are kept in the file store
+// until Offset reaches Length, then they are joined into one file that is
+// registered in EntityFilesCollection.
+type Upload struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id"`
+	User         string             `json:"user" bson:"user"`
+	Length       int64              `json:"length" bson:"length"`
+	Offset       int64              `json:"offset" bson:"offset"`
+	FileName     string             `json:"file_name" bson:"file_name"`
+	ContentType  string             `json:"content_type" bson:"content_type"`
+	Comment      string             `json:"comment" bson:"comment"`
+	Parts        []*UploadPart      `json:"parts" bson:"parts"`
+	Completed    bool               `json:"completed" bson:"completed"`
+	EntityFileID string             `json:"entity_file_id" bson:"entity_file_id"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+	ExpiresAt    time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+type UploadPart struct {
+	Name   string `json:"name" bson:"name"`
+	Offset int64  `json:"offset" bson:"offset"`
+	Size   int64  `json:"size" bson:"size"`
+}
@@ -0,0 +1,108 @@
+// Package bundle writes ZIP archives entry by entry straight to a stream and
+// hashes every entry on the way, so nothing is kept in memory
+package bundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Entry describes what was written
+type Entry struct {
+	Path   string
+	Size   int64
+	SHA256 string
+}
+
+type Writer struct {
+	zw   *zip.Writer
+	used map[string]bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		zw:   zip.NewWriter(w),
+		used: map[string]bool{},
+	}
+}
+
+// Add copies r into the archive under name. A name that is taken gets a
+// number before its extension.
+func (w *Writer) Add(name string, r io.Reader, contentType string, modified time.Time) (*Entry, error) {
+	name = w.unique(name)
+	method := zip.Deflate
+	if compressed(contentType) {
+		method = zip.Store
+	}
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   method,
+		Modified: modified,
+	}
+	entry, err := w.zw.CreateHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(entry, hash), r)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{
+		Path:   name,
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Close writes the central directory, it does not close the underlying writer
+func (w *Writer) Close() error {
+	return w.zw.Close()
+}
+
+func (w *Writer) unique(name string) string {
+	if !w.used[name] {
+		w.used[name] = true
+		return name
+	}
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if !w.used[candidate] {
+			w.used[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// SafeName keeps a client supplied file name from adding directories to the
+// archive
+func SafeName(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = path.Base(name)
+	if name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// compressed formats gain nothing from deflate
+func compressed(contentType string) bool {
+	switch {
+	case strings.HasPrefix(contentType, "image/") && contentType != "image/bmp" && contentType != "image/tiff",
+		strings.HasPrefix(contentType, "video/"),
+		contentType == "application/zip",
+		contentType == "application/gzip",
+		strings.HasPrefix(contentType, "application/vnd.openxmlformats"):
+		return true
+	}
+	return false
+}
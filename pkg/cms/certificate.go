@@ -0,0 +1,76 @@
+package cms
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// Subject attributes of certificates issued in Uzbekistan
+var (
+	OIDInn   = asn1.ObjectIdentifier{1, 2, 860, 3, 16, 1, 1}
+	OIDPinfl = asn1.ObjectIdentifier{1, 2, 860, 3, 16, 1, 2}
+
+	oidUID = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}
+)
+
+var ErrNoCertificates = errors.New("no certificates in the bundle")
+
+// LoadCertPool reads a bundle of PEM encoded CA certificates
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	bundle, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("%w: %s", ErrNoCertificates, path)
+	}
+	return pool, nil
+}
+
+// Pinfl returns the personal number of the certificate holder, empty for
+// certificates of organizations
+func Pinfl(certificate *x509.Certificate) string {
+	if pinfl := subjectValue(certificate, OIDPinfl); pinfl != "" {
+		return pinfl
+	}
+	if serial := certificate.Subject.SerialNumber; len(serial) == 14 && digits(serial) {
+		return serial
+	}
+	return ""
+}
+
+// Inn returns the tax number of the certificate holder. Older certificates
+// keep it in the UID attribute.
+func Inn(certificate *x509.Certificate) string {
+	if inn := subjectValue(certificate, OIDInn); inn != "" {
+		return inn
+	}
+	if uid := subjectValue(certificate, oidUID); len(uid) == 9 && digits(uid) {
+		return uid
+	}
+	return ""
+}
+
+func subjectValue(certificate *x509.Certificate, oid asn1.ObjectIdentifier) string {
+	for _, name := range certificate.Subject.Names {
+		if name.Type.Equal(oid) {
+			if value, ok := name.Value.(string); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
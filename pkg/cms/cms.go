@@ -0,0 +1,524 @@
+// Package cms parses and verifies CMS (PKCS#7) SignedData, the format E-IMZO
+// and most e-signature tools produce. Signatures with RSA or ECDSA keys are
+// verified here. Others, such as the O'zDSt 1092:2009 signatures of E-IMZO,
+// are handed to an ExternalVerifier when one is given and are reported as
+// unsupported otherwise.
+//
+// Certificates are checked at the time of verification, or at the time of a
+// RFC 3161 timestamp by a trusted authority. The signing time attribute is
+// set by the signer and is only reported.
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"time"
+
+	// hash functions the digest algorithms below refer to
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	ErrNotSignedData        = errors.New("content is not CMS signed data")
+	ErrNoContent            = errors.New("detached signature needs the signed content")
+	ErrUnsupportedAlgorithm = errors.New("signature algorithm is not supported")
+	ErrCertificateNotFound  = errors.New("signer certificate is not in the signature")
+	ErrDigestMismatch       = errors.New("content does not match the signed digest")
+	ErrBadSignature         = errors.New("signature does not match the certificate")
+	ErrExpired              = errors.New("certificate is not valid at the time of verification")
+	ErrUntrusted            = errors.New("certificate is not issued by a trusted authority")
+	ErrRevoked              = errors.New("certificate is revoked")
+	ErrRevocationUnknown    = errors.New("revocation status of the certificate is unknown")
+	ErrBadTimestamp         = errors.New("timestamp does not verify")
+)
+
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidTimestampAttr = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA1WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSA         = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSAWithSHA1 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidECDSAWithSHA2 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3}
+)
+
+var digestAlgorithms = map[string]crypto.Hash{
+	oidSHA1.String():   crypto.SHA1,
+	oidSHA256.String(): crypto.SHA256,
+	oidSHA384.String(): crypto.SHA384,
+	oidSHA512.String(): crypto.SHA512,
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// SignedData is a parsed signature
+type SignedData struct {
+	// Content is nil for detached signatures
+	Content      []byte
+	Certificates []*x509.Certificate
+	contentType  asn1.ObjectIdentifier
+	signers      []signerInfo
+	raw          signedData
+}
+
+// Parse accepts DER, PEM and base64 encoded signatures. E-IMZO hands out
+// base64.
+func Parse(data []byte) (*SignedData, error) {
+	der := decode(data)
+
+	var info contentInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotSignedData, err)
+	}
+	if len(bytes.TrimRight(rest, "\x00")) > 0 || !info.ContentType.Equal(oidSignedData) {
+		return nil, ErrNotSignedData
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotSignedData, err)
+	}
+
+	result := &SignedData{
+		contentType: sd.EncapContentInfo.EContentType,
+		signers:     sd.SignerInfos,
+		raw:         sd,
+	}
+	if content := sd.EncapContentInfo.EContent; len(content.FullBytes) > 0 {
+		result.Content, err = octetString(content.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotSignedData, err)
+		}
+	}
+	if len(sd.Certificates.Bytes) > 0 {
+		result.Certificates, err = x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotSignedData, err)
+		}
+	}
+	return result, nil
+}
+
+// IsSignedData tells CMS signatures from other files by their first bytes
+func IsSignedData(head []byte) bool {
+	// a SEQUENCE, its length in up to four bytes, then the content type
+	if len(head) < 2 || head[0] != 0x30 {
+		return false
+	}
+	offset := 2
+	if head[1]&0x80 != 0 {
+		offset += int(head[1] & 0x7f)
+	}
+	oid, err := asn1.Marshal(oidSignedData)
+	if err != nil || offset > len(head) {
+		return false
+	}
+	return bytes.HasPrefix(head[offset:], oid)
+}
+
+// Detached reports whether the signed content has to be given to Verify
+func (sd *SignedData) Detached() bool {
+	return sd.Content == nil
+}
+
+// VerifyOptions are the trusted roots and, for detached signatures, the
+// signed content
+type VerifyOptions struct {
+	Roots   *x509.CertPool
+	Content io.Reader
+	// CurrentTime is when certificates of signers without a trusted
+	// timestamp have to be valid, now when zero
+	CurrentTime time.Time
+	// Revocation checks the certificates of the chain, revocation is not
+	// checked without it
+	Revocation RevocationChecker
+	// External verifies signers whose algorithms are not implemented here
+	External ExternalVerifier
+	// keyUsage is what the certificates have to be for, any when zero
+	keyUsage x509.ExtKeyUsage
+}
+
+// Signer is the result for one signature of the data
+type Signer struct {
+	// Certificate is nil when it is not in the signature
+	Certificate *x509.Certificate
+	// SigningTime is what the signer claims, it is not trusted
+	SigningTime time.Time
+	// Timestamp is the time of a verified timestamp token, certificates are
+	// checked at it
+	Timestamp time.Time
+	// Err is nil for a valid signature by a trusted certificate
+	Err error
+}
+
+// Verify checks every signer. The error is for problems with the content,
+// problems of single signers are in their Err.
+func (sd *SignedData) Verify(opts VerifyOptions) ([]*Signer, error) {
+	content := io.Reader(bytes.NewReader(sd.Content))
+	if sd.Detached() {
+		if opts.Content == nil {
+			return nil, ErrNoContent
+		}
+		content = opts.Content
+	}
+	if opts.CurrentTime.IsZero() {
+		opts.CurrentTime = time.Now()
+	}
+
+	// the content is read once for all digest algorithms, and kept for the
+	// external verifier when it is needed
+	var (
+		hashes   = map[crypto.Hash]hash.Hash{}
+		writers  []io.Writer
+		external = opts.External != nil && sd.hasExternalSigner()
+		kept     bytes.Buffer
+	)
+	if external && sd.Detached() {
+		writers = append(writers, &kept)
+	}
+	for _, signer := range sd.signers {
+		algorithm, ok := digestAlgorithms[signer.DigestAlgorithm.Algorithm.String()]
+		if !ok || hashes[algorithm] != nil {
+			continue
+		}
+		hashes[algorithm] = algorithm.New()
+		writers = append(writers, hashes[algorithm])
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), content); err != nil {
+		return nil, err
+	}
+
+	signers := make([]*Signer, len(sd.signers))
+	for i, info := range sd.signers {
+		signers[i] = sd.verifySigner(info, hashes, opts)
+	}
+	if external {
+		content := sd.Content
+		if sd.Detached() {
+			content = kept.Bytes()
+		}
+		if err := sd.verifyExternal(signers, content, opts.External); err != nil {
+			return nil, err
+		}
+	}
+	return signers, nil
+}
+
+// hasExternalSigner tells whether a signer uses a key this package can not
+// verify
+func (sd *SignedData) hasExternalSigner() bool {
+	for _, info := range sd.signers {
+		if certificate := sd.certificate(info.SID); certificate != nil && !supportedKey(certificate) {
+			return true
+		}
+	}
+	return false
+}
+
+func supportedKey(certificate *x509.Certificate) bool {
+	switch certificate.PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return true
+	}
+	return false
+}
+
+func (sd *SignedData) verifySigner(info signerInfo, hashes map[crypto.Hash]hash.Hash, opts VerifyOptions) *Signer {
+	signer := &Signer{}
+	signer.Certificate = sd.certificate(info.SID)
+	if signer.Certificate == nil {
+		signer.Err = ErrCertificateNotFound
+		return signer
+	}
+	algorithm, ok := digestAlgorithms[info.DigestAlgorithm.Algorithm.String()]
+	if !ok {
+		signer.Err = fmt.Errorf("%w: digest %s", ErrUnsupportedAlgorithm, info.DigestAlgorithm.Algorithm)
+		return signer
+	}
+	digest := hashes[algorithm].Sum(nil)
+
+	// with signed attributes the signature is over them and they carry the
+	// digest of the content
+	if len(info.SignedAttrs.FullBytes) > 0 {
+		attributes, err := parseAttributes(info.SignedAttrs.Bytes)
+		if err != nil {
+			signer.Err = fmt.Errorf("%w: %s", ErrBadSignature, err)
+			return signer
+		}
+		var messageDigest []byte
+		if values, ok := attributes[oidMessageDigest.String()]; !ok {
+			signer.Err = fmt.Errorf("%w: no message digest", ErrBadSignature)
+			return signer
+		} else if _, err := asn1.Unmarshal(values, &messageDigest); err != nil {
+			signer.Err = fmt.Errorf("%w: %s", ErrBadSignature, err)
+			return signer
+		}
+		var contentType asn1.ObjectIdentifier
+		if values, ok := attributes[oidContentType.String()]; ok {
+			if _, err := asn1.Unmarshal(values, &contentType); err != nil || !contentType.Equal(sd.contentType) {
+				signer.Err = fmt.Errorf("%w: content type attribute does not match", ErrBadSignature)
+				return signer
+			}
+		}
+		if values, ok := attributes[oidSigningTime.String()]; ok {
+			_, _ = asn1.Unmarshal(values, &signer.SigningTime)
+		}
+		if !bytes.Equal(messageDigest, digest) {
+			signer.Err = ErrDigestMismatch
+			return signer
+		}
+
+		// the attributes are signed as a SET, not with their implicit tag
+		signed := append([]byte{0x31}, info.SignedAttrs.FullBytes[1:]...)
+		h := algorithm.New()
+		h.Write(signed)
+		digest = h.Sum(nil)
+	}
+
+	if err := checkSignature(signer.Certificate, info.SignatureAlgorithm.Algorithm, algorithm, digest, info.Signature); err != nil {
+		signer.Err = err
+		return signer
+	}
+
+	at := opts.CurrentTime
+	if token, ok := unsignedAttribute(info, oidTimestampAttr); ok {
+		timestamp, err := verifyTimestamp(token, info.Signature, opts)
+		if err != nil {
+			signer.Err = fmt.Errorf("%w: %s", ErrBadTimestamp, err)
+			return signer
+		}
+		signer.Timestamp, at = timestamp, timestamp
+	}
+	signer.Err = verifyCertificate(signer.Certificate, sd.Certificates, at, opts)
+	return signer
+}
+
+// verifyCertificate checks the validity, the chain to a trusted root and,
+// with a revocation checker, the revocation of the chain at the time
+func verifyCertificate(certificate *x509.Certificate, pool []*x509.Certificate, at time.Time, opts VerifyOptions) error {
+	if at.Before(certificate.NotBefore) || at.After(certificate.NotAfter) {
+		return ErrExpired
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range pool {
+		intermediates.AddCert(c)
+	}
+	roots := opts.Roots
+	if roots == nil {
+		roots = x509.NewCertPool()
+	}
+	chains, err := certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{opts.keyUsage},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUntrusted, err)
+	}
+	if opts.Revocation == nil {
+		return nil
+	}
+	// the root is trusted as it is, the others are checked against their
+	// issuer
+	chain := chains[0]
+	for i := 0; i < len(chain)-1; i++ {
+		if err := opts.Revocation.Check(chain[i], chain[i+1], at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsignedAttribute returns the DER of the first value of the attribute
+func unsignedAttribute(info signerInfo, oid asn1.ObjectIdentifier) ([]byte, bool) {
+	if len(info.UnsignedAttrs.FullBytes) == 0 {
+		return nil, false
+	}
+	attributes, err := parseAttributes(info.UnsignedAttrs.Bytes)
+	if err != nil {
+		return nil, false
+	}
+	value, ok := attributes[oid.String()]
+	return value, ok
+}
+
+// certificate finds the signer by issuer and serial number or by subject key
+// identifier
+func (sd *SignedData) certificate(sid asn1.RawValue) *x509.Certificate {
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, certificate := range sd.Certificates {
+			if bytes.Equal(certificate.SubjectKeyId, sid.Bytes) {
+				return certificate
+			}
+		}
+		return nil
+	}
+	var id issuerAndSerialNumber
+	if _, err := asn1.Unmarshal(sid.FullBytes, &id); err != nil {
+		return nil
+	}
+	for _, certificate := range sd.Certificates {
+		if certificate.SerialNumber.Cmp(id.SerialNumber) == 0 && bytes.Equal(certificate.RawIssuer, id.Issuer.FullBytes) {
+			return certificate
+		}
+	}
+	return nil
+}
+
+func checkSignature(certificate *x509.Certificate, signatureAlgorithm asn1.ObjectIdentifier, digestAlgorithm crypto.Hash, digest, signature []byte) error {
+	switch key := certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if !oneOf(signatureAlgorithm, oidRSA, oidSHA1WithRSA, oidSHA256WithRSA, oidSHA384WithRSA, oidSHA512WithRSA) {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(key, digestAlgorithm, digest, signature); err != nil {
+			return ErrBadSignature
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !oneOf(signatureAlgorithm, oidECDSA, oidECDSAWithSHA1) && !hasPrefix(signatureAlgorithm, oidECDSAWithSHA2) {
+			break
+		}
+		if !ecdsa.VerifyASN1(key, digest, signature) {
+			return ErrBadSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, signatureAlgorithm)
+}
+
+// parseAttributes returns the DER of the first value of every attribute
+func parseAttributes(raw []byte) (map[string][]byte, error) {
+	attributes := map[string][]byte{}
+	for len(raw) > 0 {
+		var a attribute
+		var err error
+		raw, err = asn1.Unmarshal(raw, &a)
+		if err != nil {
+			return nil, err
+		}
+		var value asn1.RawValue
+		if _, err := asn1.Unmarshal(a.Values.Bytes, &value); err != nil {
+			return nil, err
+		}
+		attributes[a.Type.String()] = value.FullBytes
+	}
+	return attributes, nil
+}
+
+// octetString reads the encapsulated content, which some tools split into
+// several OCTET STRINGs
+func octetString(raw []byte) ([]byte, error) {
+	var value asn1.RawValue
+	if _, err := asn1.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	if value.Tag != asn1.TagOctetString {
+		return nil, errors.New("content is not an octet string")
+	}
+	if !value.IsCompound {
+		return value.Bytes, nil
+	}
+	var content []byte
+	rest := value.Bytes
+	for len(rest) > 0 {
+		var part []byte
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &part); err != nil {
+			return nil, err
+		}
+		content = append(content, part...)
+	}
+	return content, nil
+}
+
+func decode(data []byte) []byte {
+	if block, _ := pem.Decode(data); block != nil {
+		return block.Bytes
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != 0x30 {
+		if decoded, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(trimmed), nil))); err == nil {
+			return decoded
+		}
+	}
+	return data
+}
+
+func oneOf(oid asn1.ObjectIdentifier, oids ...asn1.ObjectIdentifier) bool {
+	for _, o := range oids {
+		if oid.Equal(o) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(oid, prefix asn1.ObjectIdentifier) bool {
+	return len(oid) > len(prefix) && oid[:len(prefix)].Equal(prefix)
+}
@@ -0,0 +1,742 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+var (
+	now     = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	oidData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	// an algorithm the package does not implement, as the O'zDSt ones
+	oidUnknownKey    = asn1.ObjectIdentifier{1, 2, 860, 3, 15, 1, 1, 2, 1}
+	oidUnknownDigest = asn1.ObjectIdentifier{1, 2, 860, 3, 15, 1, 1, 1, 1}
+)
+
+type testCA struct {
+	certificate *x509.Certificate
+	key         *rsa.PrivateKey
+}
+
+type testSigner struct {
+	certificate *x509.Certificate
+	key         crypto.Signer
+}
+
+var serial int64
+
+func newSerial() *big.Int {
+	serial++
+	return big.NewInt(serial)
+}
+
+func newCA(t *testing.T, name string) *testCA {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          newSerial(),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.AddDate(-10, 0, 0),
+		NotAfter:              now.AddDate(10, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{certificate: certificate, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	return pool
+}
+
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate, key crypto.Signer) *testSigner {
+	if template.SerialNumber == nil {
+		template.SerialNumber = newSerial()
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore, template.NotAfter = now.AddDate(-1, 0, 0), now.AddDate(1, 0, 0)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{certificate: certificate, key: key}
+}
+
+func rsaKey(t *testing.T) crypto.Signer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func ecdsaKey(t *testing.T) crypto.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// withUnknownKey reissues the certificate with a public key algorithm the
+// package does not know
+func (ca *testCA) withUnknownKey(t *testing.T, signer *testSigner) *x509.Certificate {
+	var certificate struct {
+		TBS                asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(signer.certificate.Raw, &certificate); err != nil {
+		t.Fatal(err)
+	}
+	spki, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidUnknownKey},
+		PublicKey: asn1.BitString{Bytes: []byte{1, 2, 3, 4}, BitLength: 32},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the length of the TBS changes with the key, it is encoded again
+	tbs, err := asn1.Marshal(asn1.RawValue{
+		Tag: asn1.TagSequence, IsCompound: true,
+		Bytes: bytes.Replace(certificate.TBS.Bytes, signer.certificate.RawSubjectPublicKeyInfo, spki, 1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(tbs)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ca.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate.TBS = asn1.RawValue{FullBytes: tbs}
+	certificate.Signature = asn1.BitString{Bytes: signature, BitLength: len(signature) * 8}
+	der, err := asn1.Marshal(certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+type signOptions struct {
+	contentType asn1.ObjectIdentifier
+	detached    bool
+	// noAttributes signs the content digest itself
+	noAttributes bool
+	signingTime  time.Time
+	certificates []*x509.Certificate
+	// timestamp returns a timestamp token for the signature value
+	timestamp func(signature []byte) []byte
+	// digest and signature replace the real ones when set
+	digestAlgorithm asn1.ObjectIdentifier
+	signature       []byte
+}
+
+func attributeDER(t *testing.T, oid asn1.ObjectIdentifier, value interface{}) []byte {
+	valueDER, err := asn1.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(attribute{
+		Type:   oid,
+		Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: valueDER},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func sign(t *testing.T, signer *testSigner, content []byte, opts signOptions) []byte {
+	if opts.contentType == nil {
+		opts.contentType = oidData
+	}
+	if opts.certificates == nil {
+		opts.certificates = []*x509.Certificate{signer.certificate}
+	}
+	digest := sha256.Sum256(content)
+	toSign := digest[:]
+
+	info := signerInfo{
+		Version:         1,
+		DigestAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+	}
+	sid, err := asn1.Marshal(issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: signer.certificate.RawIssuer},
+		SerialNumber: signer.certificate.SerialNumber,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info.SID = asn1.RawValue{FullBytes: sid}
+
+	if !opts.noAttributes {
+		attributes := append(attributeDER(t, oidContentType, opts.contentType), attributeDER(t, oidMessageDigest, digest[:])...)
+		if !opts.signingTime.IsZero() {
+			attributes = append(attributes, attributeDER(t, oidSigningTime, opts.signingTime)...)
+		}
+		info.SignedAttrs = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attributes}
+		set, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attributes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(set)
+		toSign = sum[:]
+	}
+
+	switch key := signer.key.(type) {
+	case *rsa.PrivateKey:
+		info.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidSHA256WithRSA}
+		info.Signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, toSign)
+	case *ecdsa.PrivateKey:
+		info.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: append(oidECDSAWithSHA2[:len(oidECDSAWithSHA2):len(oidECDSAWithSHA2)], 2)}
+		info.Signature, err = ecdsa.SignASN1(rand.Reader, key, toSign)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.digestAlgorithm != nil {
+		info.DigestAlgorithm = pkix.AlgorithmIdentifier{Algorithm: opts.digestAlgorithm}
+		info.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidUnknownKey}
+	}
+	if opts.signature != nil {
+		info.Signature = opts.signature
+	}
+	if opts.timestamp != nil {
+		info.UnsignedAttrs = asn1.RawValue{
+			Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true,
+			Bytes: attributeDER(t, oidTimestampAttr, asn1.RawValue{FullBytes: opts.timestamp(info.Signature)}),
+		}
+	}
+
+	var certificates []byte
+	for _, certificate := range opts.certificates {
+		certificates = append(certificates, certificate.Raw...)
+	}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{info.DigestAlgorithm},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: opts.contentType},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certificates},
+		SignerInfos:      []signerInfo{info},
+	}
+	if !opts.detached {
+		der, err := (&SignedData{raw: sd}).attached(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	signed := mustMarshal(t, sd)
+	return mustMarshal(t, contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+}
+
+// timestamper returns tokens of the authority for the time
+func timestamper(t *testing.T, tsa *testSigner, at time.Time) func([]byte) []byte {
+	return func(signature []byte) []byte {
+		sum := sha256.Sum256(signature)
+		info, err := asn1.Marshal(tstInfo{
+			Version: 1,
+			Policy:  asn1.ObjectIdentifier{1, 2, 3},
+			MessageImprint: messageImprint{
+				HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+				HashedMessage: sum[:],
+			},
+			SerialNumber: big.NewInt(7),
+			GenTime:      at,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sign(t, tsa, info, signOptions{contentType: oidTSTInfo})
+	}
+}
+
+func verifyOne(t *testing.T, der []byte, opts VerifyOptions) (*Signer, error) {
+	t.Helper()
+	sd, err := Parse(der)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CurrentTime.IsZero() {
+		opts.CurrentTime = now
+	}
+	signers, err := sd.Verify(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(signers) != 1 {
+		t.Fatalf("got %d signers", len(signers))
+	}
+	return signers[0], nil
+}
+
+func TestVerify(t *testing.T) {
+	var (
+		ca      = newCA(t, "Root")
+		other   = newCA(t, "Other root")
+		content = []byte("kadastr ko'chirmasi")
+		rsaLeaf = ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "RSA signer"}}, rsaKey(t))
+		ecLeaf  = ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ECDSA signer"}}, ecdsaKey(t))
+		expired = ca.issue(t, &x509.Certificate{
+			Subject:   pkix.Name{CommonName: "Expired signer"},
+			NotBefore: now.AddDate(-3, 0, 0),
+			NotAfter:  now.AddDate(-2, 0, 0),
+		}, rsaKey(t))
+		tsa = ca.issue(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "TSA"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		}, rsaKey(t))
+		untrustedTSA = other.issue(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "Untrusted TSA"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		}, rsaKey(t))
+		notTSA = ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Not a TSA"}}, rsaKey(t))
+	)
+
+	tests := []struct {
+		name     string
+		der      []byte
+		content  []byte
+		roots    *x509.CertPool
+		err      error
+		stamped  bool
+		verifyAt time.Time
+	}{
+		{name: "attached RSA", der: sign(t, rsaLeaf, content, signOptions{}), roots: ca.pool()},
+		{name: "attached ECDSA", der: sign(t, ecLeaf, content, signOptions{}), roots: ca.pool()},
+		{name: "without signed attributes", der: sign(t, rsaLeaf, content, signOptions{noAttributes: true}), roots: ca.pool()},
+		{name: "detached", der: sign(t, rsaLeaf, content, signOptions{detached: true}), content: content, roots: ca.pool()},
+		{name: "detached other content", der: sign(t, rsaLeaf, content, signOptions{detached: true}), content: []byte("other"), roots: ca.pool(), err: ErrDigestMismatch},
+		{name: "detached no content", der: sign(t, rsaLeaf, content, signOptions{detached: true}), roots: ca.pool(), err: ErrNoContent},
+		{name: "tampered signature", der: sign(t, rsaLeaf, content, signOptions{signature: make([]byte, 256)}), roots: ca.pool(), err: ErrBadSignature},
+		{name: "untrusted root", der: sign(t, rsaLeaf, content, signOptions{}), roots: other.pool(), err: ErrUntrusted},
+		{name: "no roots", der: sign(t, rsaLeaf, content, signOptions{}), err: ErrUntrusted},
+		{name: "signer not included", der: sign(t, rsaLeaf, content, signOptions{certificates: []*x509.Certificate{ecLeaf.certificate}}), roots: ca.pool(), err: ErrCertificateNotFound},
+		{name: "unknown digest", der: sign(t, rsaLeaf, content, signOptions{digestAlgorithm: oidUnknownDigest}), roots: ca.pool(), err: ErrUnsupportedAlgorithm},
+		{
+			// the signing time is the signer's word, it does not make an
+			// expired certificate valid
+			name:  "expired with claimed signing time",
+			der:   sign(t, expired, content, signOptions{signingTime: now.AddDate(-2, -6, 0)}),
+			roots: ca.pool(), err: ErrExpired,
+		},
+		{
+			name:  "expired with trusted timestamp",
+			der:   sign(t, expired, content, signOptions{timestamp: timestamper(t, tsa, now.AddDate(-2, -6, 0))}),
+			roots: ca.pool(), stamped: true,
+		},
+		{
+			name:  "timestamp after expiry",
+			der:   sign(t, expired, content, signOptions{timestamp: timestamper(t, tsa, now.AddDate(-1, 0, 0))}),
+			roots: ca.pool(), stamped: true, err: ErrExpired,
+		},
+		{
+			name:  "timestamp by untrusted authority",
+			der:   sign(t, expired, content, signOptions{timestamp: timestamper(t, untrustedTSA, now.AddDate(-2, -6, 0))}),
+			roots: ca.pool(), err: ErrBadTimestamp,
+		},
+		{
+			name:  "timestamp by certificate not for timestamping",
+			der:   sign(t, expired, content, signOptions{timestamp: timestamper(t, notTSA, now.AddDate(-2, -6, 0))}),
+			roots: ca.pool(), err: ErrBadTimestamp,
+		},
+		{
+			name: "timestamp of another signature",
+			der: sign(t, rsaLeaf, content, signOptions{timestamp: func([]byte) []byte {
+				return timestamper(t, tsa, now)([]byte("other signature"))
+			}}),
+			roots: ca.pool(), err: ErrBadTimestamp,
+		},
+		{name: "not yet valid", der: sign(t, rsaLeaf, content, signOptions{}), roots: ca.pool(), verifyAt: now.AddDate(-2, 0, 0), err: ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := VerifyOptions{Roots: tt.roots, CurrentTime: tt.verifyAt}
+			if tt.content != nil {
+				opts.Content = bytes.NewReader(tt.content)
+			}
+			signer, err := verifyOne(t, tt.der, opts)
+			if err == nil {
+				err = signer.Err
+			}
+			if tt.err == nil && err != nil || !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if signer != nil && tt.stamped == signer.Timestamp.IsZero() {
+				t.Fatalf("timestamp %v, want one %t", signer.Timestamp, tt.stamped)
+			}
+		})
+	}
+}
+
+func TestVerifyOpenSSL(t *testing.T) {
+	roots, err := LoadCertPool("testdata/ca.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile("testdata/content.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fixtures were made in October 2026 and are valid for a century
+	at := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, name := range []string{"attached.p7m", "detached.p7s"} {
+		t.Run(name, func(t *testing.T) {
+			der, err := ioutil.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sd, err := Parse([]byte(base64.StdEncoding.EncodeToString(der)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := VerifyOptions{Roots: roots, CurrentTime: at}
+			if sd.Detached() {
+				opts.Content = bytes.NewReader(content)
+			} else if !bytes.Equal(sd.Content, content) {
+				t.Fatalf("content %q", sd.Content)
+			}
+			signers, err := sd.Verify(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(signers) != 1 || signers[0].Err != nil {
+				t.Fatalf("signers %+v", signers[0])
+			}
+			if cn := signers[0].Certificate.Subject.CommonName; cn != "Aliyev Vali" {
+				t.Fatalf("signer %q", cn)
+			}
+			if pinfl := Pinfl(signers[0].Certificate); pinfl != "12345678901234" {
+				t.Fatalf("pinfl %q", pinfl)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	valid, err := ioutil.ReadFile("testdata/attached.p7m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string][]byte{
+		"empty":        nil,
+		"text":         []byte("not a signature"),
+		"truncated":    valid[:len(valid)/2],
+		"trailing":     append(append([]byte{}, valid...), 1, 2, 3),
+		"certificate":  mustRead(t, "testdata/signer.pem"),
+		"other type":   mustMarshal(t, contentInfo{ContentType: oidData}),
+		"garbage body": mustMarshal(t, contentInfo{ContentType: oidSignedData, Content: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: []byte{0x30, 0x01, 0x00}}}),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(data); !errors.Is(err, ErrNotSignedData) {
+				t.Fatalf("got %v", err)
+			}
+		})
+	}
+	if !IsSignedData(valid[:32]) || IsSignedData([]byte("%PDF-1.7")) {
+		t.Fatal("IsSignedData")
+	}
+}
+
+func mustRead(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func mustMarshal(t *testing.T, value interface{}) []byte {
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+type fakeExternal struct {
+	attached []byte
+	results  []*ExternalSigner
+}
+
+func (f *fakeExternal) Verify(attached []byte) ([]*ExternalSigner, error) {
+	f.attached = attached
+	return f.results, nil
+}
+
+func TestVerifyExternal(t *testing.T) {
+	var (
+		ca      = newCA(t, "Root")
+		content = []byte("signed with an E-IMZO key")
+		leaf    = ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "E-IMZO signer"}}, rsaKey(t))
+	)
+	leaf.certificate = ca.withUnknownKey(t, leaf)
+	der := sign(t, leaf, content, signOptions{detached: true, digestAlgorithm: oidUnknownDigest})
+
+	signer, err := verifyOne(t, der, VerifyOptions{Roots: ca.pool(), Content: bytes.NewReader(content)})
+	if err != nil || !errors.Is(signer.Err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("without external verifier got %v %v", err, signer.Err)
+	}
+
+	tests := []struct {
+		name    string
+		results []*ExternalSigner
+		err     error
+	}{
+		{name: "valid", results: []*ExternalSigner{{SerialNumber: leaf.certificate.SerialNumber}}},
+		{name: "rejected", results: []*ExternalSigner{{SerialNumber: leaf.certificate.SerialNumber, Err: ErrUntrusted}}, err: ErrUntrusted},
+		{name: "other signer", results: []*ExternalSigner{{SerialNumber: big.NewInt(999999)}}, err: ErrBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			external := &fakeExternal{results: tt.results}
+			signer, err := verifyOne(t, der, VerifyOptions{Roots: ca.pool(), Content: bytes.NewReader(content), External: external})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.err == nil && signer.Err != nil || !errors.Is(signer.Err, tt.err) {
+				t.Fatalf("got %v, want %v", signer.Err, tt.err)
+			}
+			// the verifier gets the detached signature with the content
+			sd, err := Parse(external.attached)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(sd.Content, content) {
+				t.Fatalf("attached content %q", sd.Content)
+			}
+		})
+	}
+}
+
+func TestEIMZOServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		status   int
+		err      error
+		failed   bool
+	}{
+		{
+			name:     "verified",
+			response: `{"status":1,"pkcs7Info":{"signers":[{"certificate":[{"serialNumber":"7b"}],"verified":true,"certificateVerified":true,"certificateValidAtSigningTime":true}]}}`,
+		},
+		{
+			name:     "bad signature",
+			response: `{"status":1,"pkcs7Info":{"signers":[{"certificate":[{"serialNumber":"7b"}],"verified":false,"certificateVerified":true}]}}`,
+			err:      ErrBadSignature,
+		},
+		{
+			name:     "untrusted",
+			response: `{"status":1,"pkcs7Info":{"signers":[{"certificate":[{"serialNumber":"7b"}],"verified":true,"certificateVerified":false}]}}`,
+			err:      ErrUntrusted,
+		},
+		{
+			name:     "expired",
+			response: `{"status":1,"pkcs7Info":{"signers":[{"certificate":[{"serialNumber":"7b"}],"verified":true,"certificateVerified":true,"certificateValidAtSigningTime":false}]}}`,
+			err:      ErrExpired,
+		},
+		{name: "server error", response: `{"status":-10,"message":"bad pkcs7"}`, failed: true},
+		{name: "http error", response: "oops", status: http.StatusBadGateway, failed: true},
+		{name: "no signers", response: `{"status":1,"pkcs7Info":{"signers":[]}}`, failed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				if r.URL.Path != "/backend/pkcs7/verify/attached" || string(body) != base64.StdEncoding.EncodeToString([]byte("pkcs7")) {
+					http.Error(w, "unexpected request", http.StatusBadRequest)
+					return
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			signers, err := NewEIMZOServer(server.URL+"/", time.Second).Verify([]byte("pkcs7"))
+			if tt.failed {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(signers) != 1 || signers[0].SerialNumber.Int64() != 123 {
+				t.Fatalf("signers %+v", signers)
+			}
+			if tt.err == nil && signers[0].Err != nil || !errors.Is(signers[0].Err, tt.err) {
+				t.Fatalf("got %v, want %v", signers[0].Err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	var (
+		ca        = newCA(t, "Root")
+		content   = []byte("revocation")
+		revokedAt = now.AddDate(0, -1, 0)
+		crlURL    string
+		ocspURL   string
+		ocspState = ocsp.Good
+	)
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	crlURL, ocspURL = server.URL+"/root.crl", server.URL+"/ocsp"
+
+	crlLeaf := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "CRL signer"}, CRLDistributionPoints: []string{crlURL}}, rsaKey(t))
+	goodLeaf := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Good signer"}, CRLDistributionPoints: []string{crlURL}}, rsaKey(t))
+	ocspLeaf := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "OCSP signer"}, OCSPServer: []string{ocspURL}}, rsaKey(t))
+	unreachable := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Unreachable"}, OCSPServer: []string{server.URL + "/missing"}}, rsaKey(t))
+	plain := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "No revocation info"}}, rsaKey(t))
+	tsa := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "TSA"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, rsaKey(t))
+
+	crl, err := ca.certificate.CreateCRL(rand.Reader, ca.key, []pkix.RevokedCertificate{
+		{SerialNumber: crlLeaf.certificate.SerialNumber, RevocationTime: revokedAt},
+	}, time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crlFetches := 0
+	mux.HandleFunc("/root.crl", func(w http.ResponseWriter, r *http.Request) {
+		crlFetches++
+		_, _ = w.Write(crl)
+	})
+	mux.HandleFunc("/ocsp", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response, err := ocsp.CreateResponse(ca.certificate, ca.certificate, ocsp.Response{
+			Status:       ocspState,
+			SerialNumber: request.SerialNumber,
+			ThisUpdate:   time.Now(),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    revokedAt,
+		}, ca.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(response)
+	})
+
+	revocation := NewOnlineRevocation(time.Second)
+	tests := []struct {
+		name  string
+		der   []byte
+		state int
+		err   error
+	}{
+		{name: "revoked by CRL", der: sign(t, crlLeaf, content, signOptions{}), err: ErrRevoked},
+		{name: "signed before revocation", der: sign(t, crlLeaf, content, signOptions{timestamp: timestamper(t, tsa, revokedAt.AddDate(0, 0, -1))})},
+		{name: "not in CRL", der: sign(t, goodLeaf, content, signOptions{})},
+		{name: "good by OCSP", der: sign(t, ocspLeaf, content, signOptions{}), state: ocsp.Good},
+		{name: "revoked by OCSP", der: sign(t, ocspLeaf, content, signOptions{}), state: ocsp.Revoked, err: ErrRevoked},
+		{name: "unknown to OCSP", der: sign(t, ocspLeaf, content, signOptions{}), state: ocsp.Unknown, err: ErrRevocationUnknown},
+		{name: "responder unreachable", der: sign(t, unreachable, content, signOptions{}), err: ErrRevocationUnknown},
+		{name: "nothing published", der: sign(t, plain, content, signOptions{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocspState = tt.state
+			signer, err := verifyOne(t, tt.der, VerifyOptions{Roots: ca.pool(), Revocation: revocation})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.err == nil && signer.Err != nil || !errors.Is(signer.Err, tt.err) {
+				t.Fatalf("got %v, want %v", signer.Err, tt.err)
+			}
+		})
+	}
+	if crlFetches != 1 {
+		t.Fatalf("CRL fetched %d times, it is kept until its next update", crlFetches)
+	}
+}
+
+func TestSubjectNumbers(t *testing.T) {
+	tests := []struct {
+		name       string
+		subject    pkix.Name
+		pinfl, inn string
+	}{
+		{name: "pinfl attribute", subject: pkix.Name{ExtraNames: []pkix.AttributeTypeAndValue{{Type: OIDPinfl, Value: "31234567890123"}}}, pinfl: "31234567890123"},
+		{name: "pinfl in serial number", subject: pkix.Name{SerialNumber: "31234567890123"}, pinfl: "31234567890123"},
+		{name: "serial number not a pinfl", subject: pkix.Name{SerialNumber: "AB12"}},
+		{name: "inn attribute", subject: pkix.Name{ExtraNames: []pkix.AttributeTypeAndValue{{Type: OIDInn, Value: "301234567"}}}, inn: "301234567"},
+		{name: "inn in uid", subject: pkix.Name{ExtraNames: []pkix.AttributeTypeAndValue{{Type: oidUID, Value: "301234567"}}}, inn: "301234567"},
+		{name: "uid not an inn", subject: pkix.Name{ExtraNames: []pkix.AttributeTypeAndValue{{Type: oidUID, Value: "user"}}}},
+	}
+	ca := newCA(t, "Root")
+	key := rsaKey(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.subject.CommonName = "holder"
+			certificate := ca.issue(t, &x509.Certificate{Subject: tt.subject}, key).certificate
+			if pinfl := Pinfl(certificate); pinfl != tt.pinfl {
+				t.Fatalf("pinfl %q, want %q", pinfl, tt.pinfl)
+			}
+			if inn := Inn(certificate); inn != tt.inn {
+				t.Fatalf("inn %q, want %q", inn, tt.inn)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package cms
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ExternalVerifier verifies signatures by algorithms this package does not
+// implement. It is given the signature with its content attached.
+type ExternalVerifier interface {
+	Verify(attached []byte) ([]*ExternalSigner, error)
+}
+
+// ExternalSigner is the result for the signer with the serial number, Err
+// wraps the errors of the package
+type ExternalSigner struct {
+	SerialNumber *big.Int
+	Err          error
+}
+
+// verifyExternal replaces the results of the signers with keys the package
+// does not know by those of the external verifier
+func (sd *SignedData) verifyExternal(signers []*Signer, content []byte, verifier ExternalVerifier) error {
+	attached, err := sd.attached(content)
+	if err != nil {
+		return err
+	}
+	results, err := verifier.Verify(attached)
+	if err != nil {
+		return err
+	}
+	for _, signer := range signers {
+		if signer.Certificate == nil || supportedKey(signer.Certificate) {
+			continue
+		}
+		signer.Err = fmt.Errorf("%w: the external verifier did not report the signer", ErrBadSignature)
+		for _, result := range results {
+			if result.SerialNumber != nil && result.SerialNumber.Cmp(signer.Certificate.SerialNumber) == 0 {
+				signer.Err = result.Err
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// attached encodes the signature with the content inside. Signatures are
+// over the digest of the content, where the content is kept does not change
+// them.
+func (sd *SignedData) attached(content []byte) ([]byte, error) {
+	raw := sd.raw
+	if sd.Detached() {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		raw.EncapContentInfo.EContent = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: octets}
+		raw.EncapContentInfo.EContent.FullBytes, err = asn1.Marshal(raw.EncapContentInfo.EContent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	signed, err := asn1.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+}
+
+// EIMZOServer verifies signatures with the pkcs7 verify endpoint of an
+// E-IMZO-SERVER, which implements the O'zDSt 1092:2009 and O'zDSt 1106:2009
+// algorithms of E-IMZO keys. The server checks the chain against its own
+// trust store and the revocation by OCSP.
+type EIMZOServer struct {
+	url    string
+	client *http.Client
+}
+
+// NewEIMZOServer takes the address of the server, such as
+// http://127.0.0.1:8080
+func NewEIMZOServer(address string, timeout time.Duration) *EIMZOServer {
+	return &EIMZOServer{
+		url:    strings.TrimRight(address, "/") + "/backend/pkcs7/verify/attached",
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type eimzoResponse struct {
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	Pkcs7Info struct {
+		Signers []struct {
+			Certificate []struct {
+				SerialNumber string `json:"serialNumber"`
+			} `json:"certificate"`
+			Verified                      bool  `json:"verified"`
+			CertificateVerified           bool  `json:"certificateVerified"`
+			CertificateValidAtSigningTime *bool `json:"certificateValidAtSigningTime"`
+		} `json:"signers"`
+	} `json:"pkcs7Info"`
+}
+
+func (s *EIMZOServer) Verify(attached []byte) ([]*ExternalSigner, error) {
+	body := base64.StdEncoding.EncodeToString(attached)
+	response, err := s.client.Post(s.url, "text/plain", strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("E-IMZO server answered %s: %s", response.Status, bytes.TrimSpace(data))
+	}
+	var result eimzoResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("E-IMZO server answered: %w", err)
+	}
+	if result.Status != 1 {
+		return nil, fmt.Errorf("E-IMZO server answered status %d: %s", result.Status, result.Message)
+	}
+
+	signers := make([]*ExternalSigner, 0, len(result.Pkcs7Info.Signers))
+	for _, info := range result.Pkcs7Info.Signers {
+		signer := &ExternalSigner{}
+		if len(info.Certificate) > 0 {
+			signer.SerialNumber, _ = new(big.Int).SetString(info.Certificate[0].SerialNumber, 16)
+		}
+		switch {
+		case !info.Verified:
+			signer.Err = ErrBadSignature
+		case info.CertificateValidAtSigningTime != nil && !*info.CertificateValidAtSigningTime:
+			signer.Err = ErrExpired
+		case !info.CertificateVerified:
+			signer.Err = ErrUntrusted
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("E-IMZO server found no signers")
+	}
+	return signers, nil
+}
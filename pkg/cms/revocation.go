@@ -0,0 +1,166 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxRevocationResponse bounds what is read of an OCSP response or a CRL,
+// CRLs of national CAs run to a few megabytes
+const maxRevocationResponse = 32 << 20
+
+// RevocationChecker tells whether the certificate was revoked at the time
+type RevocationChecker interface {
+	// Check returns ErrRevoked when the certificate was revoked at or before
+	// the time and ErrRevocationUnknown when its status can not be found out
+	Check(certificate, issuer *x509.Certificate, at time.Time) error
+}
+
+// OnlineRevocation asks the OCSP responders of the certificate and falls back
+// to its CRL distribution points. CRLs are kept until their next update.
+// Certificates that publish neither are not checked.
+type OnlineRevocation struct {
+	client *http.Client
+
+	mu   sync.Mutex
+	crls map[string]*pkix.CertificateList
+}
+
+func NewOnlineRevocation(timeout time.Duration) *OnlineRevocation {
+	return &OnlineRevocation{
+		client: &http.Client{Timeout: timeout},
+		crls:   map[string]*pkix.CertificateList{},
+	}
+}
+
+func (r *OnlineRevocation) Check(certificate, issuer *x509.Certificate, at time.Time) error {
+	if len(certificate.OCSPServer) == 0 && len(certificate.CRLDistributionPoints) == 0 {
+		return nil
+	}
+	var errs []string
+	for _, server := range certificate.OCSPServer {
+		err := r.checkOCSP(server, certificate, issuer, at)
+		if err == nil || errors.Is(err, ErrRevoked) {
+			return err
+		}
+		errs = append(errs, err.Error())
+	}
+	for _, point := range certificate.CRLDistributionPoints {
+		err := r.checkCRL(point, certificate, issuer, at)
+		if err == nil || errors.Is(err, ErrRevoked) {
+			return err
+		}
+		errs = append(errs, err.Error())
+	}
+	return fmt.Errorf("%w: %s", ErrRevocationUnknown, strings.Join(errs, "; "))
+}
+
+func (r *OnlineRevocation) checkOCSP(server string, certificate, issuer *x509.Certificate, at time.Time) error {
+	request, err := ocsp.CreateRequest(certificate, issuer, nil)
+	if err != nil {
+		return err
+	}
+	body, err := r.fetch(http.MethodPost, server, request)
+	if err != nil {
+		return err
+	}
+	response, err := ocsp.ParseResponseForCert(body, certificate, issuer)
+	if err != nil {
+		return err
+	}
+	switch response.Status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return revokedAt(response.RevokedAt, at)
+	}
+	return fmt.Errorf("%s does not know the certificate", server)
+}
+
+func (r *OnlineRevocation) checkCRL(point string, certificate, issuer *x509.Certificate, at time.Time) error {
+	crl, err := r.crl(point, issuer)
+	if err != nil {
+		return err
+	}
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(certificate.SerialNumber) == 0 {
+			return revokedAt(revoked.RevocationTime, at)
+		}
+	}
+	return nil
+}
+
+// crl returns the list of the distribution point, fetching it again after
+// its next update
+func (r *OnlineRevocation) crl(point string, issuer *x509.Certificate) (*pkix.CertificateList, error) {
+	r.mu.Lock()
+	crl := r.crls[point]
+	r.mu.Unlock()
+	if crl != nil && time.Now().Before(crl.TBSCertList.NextUpdate) {
+		return crl, nil
+	}
+
+	if !strings.HasPrefix(point, "http://") && !strings.HasPrefix(point, "https://") {
+		return nil, fmt.Errorf("%s is not fetched over http", point)
+	}
+	body, err := r.fetch(http.MethodGet, point, nil)
+	if err != nil {
+		return nil, err
+	}
+	crl, err = x509.ParseCRL(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.crls[point] = crl
+	r.mu.Unlock()
+	return crl, nil
+}
+
+func (r *OnlineRevocation) fetch(method, url string, body []byte) ([]byte, error) {
+	request, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/ocsp-request")
+	}
+	response, err := r.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", url, response.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, maxRevocationResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRevocationResponse {
+		return nil, fmt.Errorf("%s answered more than %d bytes", url, maxRevocationResponse)
+	}
+	return data, nil
+}
+
+// revokedAt lets signatures made before the revocation stand
+func revokedAt(revocation, at time.Time) error {
+	if revocation.After(at) {
+		return nil
+	}
+	return fmt.Errorf("%w since %s", ErrRevoked, revocation.Format(time.RFC3339))
+}
@@ -0,0 +1,76 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// tstInfo is the content of a RFC 3161 timestamp token, the optional fields
+// after the time are not needed
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// verifyTimestamp checks that the token is over the signature value and is
+// signed by a trusted timestamping authority, and returns its time
+func verifyTimestamp(token, signature []byte, opts VerifyOptions) (time.Time, error) {
+	sd, err := Parse(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !sd.contentType.Equal(oidTSTInfo) || sd.Detached() {
+		return time.Time{}, errors.New("token is not a timestamp")
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.Content, &info); err != nil {
+		return time.Time{}, err
+	}
+	algorithm, ok := digestAlgorithms[info.MessageImprint.HashAlgorithm.Algorithm.String()]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: digest %s", ErrUnsupportedAlgorithm, info.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	h := algorithm.New()
+	h.Write(signature)
+	if !bytes.Equal(h.Sum(nil), info.MessageImprint.HashedMessage) {
+		return time.Time{}, errors.New("timestamp is not over the signature")
+	}
+
+	// the authority is checked now, its token vouches for the time of the
+	// signature
+	signers, err := sd.Verify(VerifyOptions{
+		Roots:       opts.Roots,
+		CurrentTime: opts.CurrentTime,
+		Revocation:  opts.Revocation,
+		keyUsage:    x509.ExtKeyUsageTimeStamping,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(signers) != 1 {
+		return time.Time{}, errors.New("timestamp has to have one signer")
+	}
+	if signers[0].Err != nil {
+		return time.Time{}, signers[0].Err
+	}
+	// RFC 3161 has the authority certificate name timestamping as its only
+	// usage, certificates without usages would pass the chain check
+	if usages := signers[0].Certificate.ExtKeyUsage; len(usages) != 1 || usages[0] != x509.ExtKeyUsageTimeStamping {
+		return time.Time{}, errors.New("certificate is not for timestamping")
+	}
+	return info.GenTime, nil
+}
@@ -0,0 +1,139 @@
+package docgen
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/e-space-uz/backend/pkg/pdf"
+)
+
+const (
+	thumbnailWidth  = 200.0
+	thumbnailHeight = 150.0
+)
+
+// Property is a value of the entity with its label in the document language
+type Property struct {
+	Label string
+	Value string
+}
+
+type Owner struct {
+	FullName       string
+	Pin            string
+	Inn            string
+	PassportNumber string
+	Address        string
+}
+
+// Place is a city, region or district with its SOATO code
+type Place struct {
+	Name  string
+	Soato uint32
+}
+
+// CadastralExtract holds what is printed on an extract. Texts are expected in
+// Lang already, only the fixed labels are translated here.
+type CadastralExtract struct {
+	Lang         string
+	EntityNumber string
+	Address      string
+	Soato        string
+	City         Place
+	Region       Place
+	District     Place
+	Properties   []Property
+	// Owner is left out when nil
+	Owner *Owner
+	// Thumbnail is a JPEG of the entity, left out when empty
+	Thumbnail []byte
+	IssuedAt  time.Time
+	IssuedBy  string
+	// VerificationCode is printed with a QR code of VerificationURL, both
+	// are left out when the code is empty
+	VerificationCode string
+	VerificationURL  string
+}
+
+// Render writes the extract as PDF. A thumbnail that can not be embedded is
+// left out instead of failing the document.
+func (e *CadastralExtract) Render(w io.Writer, font pdf.Font) error {
+	title := label(e.Lang, "extract_title")
+	doc := pdf.New(font)
+	doc.SetInfo(title+" "+e.EntityNumber, e.IssuedBy, e.IssuedAt)
+
+	l := newLayout(doc)
+	l.heading(title, 15)
+	l.heading(e.EntityNumber, 13)
+
+	l.section(label(e.Lang, "location"))
+	l.row(label(e.Lang, "entity_number"), e.EntityNumber)
+	l.row(label(e.Lang, "address"), e.Address)
+	l.row(label(e.Lang, "soato"), e.Soato)
+	l.row(label(e.Lang, "city"), e.City.String())
+	l.row(label(e.Lang, "region"), e.Region.String())
+	l.row(label(e.Lang, "district"), e.District.String())
+	if len(e.Thumbnail) > 0 {
+		if img, err := pdf.NewJPEG(e.Thumbnail); err == nil {
+			l.image(img)
+		}
+	}
+
+	l.section(label(e.Lang, "properties"))
+	if len(e.Properties) == 0 {
+		l.paragraph(label(e.Lang, "no_properties"), 10)
+	}
+	for _, property := range e.Properties {
+		l.row(property.Label, property.Value)
+	}
+
+	if e.Owner != nil {
+		l.section(label(e.Lang, "owner"))
+		l.row(label(e.Lang, "full_name"), e.Owner.FullName)
+		l.row(label(e.Lang, "pin"), e.Owner.Pin)
+		if e.Owner.Inn != "" {
+			l.row(label(e.Lang, "inn"), e.Owner.Inn)
+		}
+		l.row(label(e.Lang, "passport"), e.Owner.PassportNumber)
+		l.row(label(e.Lang, "owner_address"), e.Owner.Address)
+	}
+
+	l.section(label(e.Lang, "issued_at"))
+	l.row(label(e.Lang, "issued_at"), e.IssuedAt.Format("02.01.2006 15:04"))
+	if e.IssuedBy != "" {
+		l.row(label(e.Lang, "issued_by"), e.IssuedBy)
+	}
+	l.y += 10
+	l.paragraph(label(e.Lang, "extract_notice"), 8)
+	if e.VerificationCode != "" {
+		l.verification(e.Lang, e.VerificationCode, e.VerificationURL)
+	}
+
+	footers(doc.Pages(), e.EntityNumber+" · "+e.IssuedAt.Format("02.01.2006"), label(e.Lang, "page"))
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+func (p Place) String() string {
+	if p.Soato == 0 {
+		return p.Name
+	}
+	return p.Name + " (" + strconv.FormatUint(uint64(p.Soato), 10) + ")"
+}
+
+// image fits img into the thumbnail box keeping its aspect ratio
+func (l *layout) image(img *pdf.Image) {
+	size := img.Size()
+	if size.X == 0 || size.Y == 0 {
+		return
+	}
+	width, height := thumbnailWidth, thumbnailWidth*float64(size.Y)/float64(size.X)
+	if height > thumbnailHeight {
+		width, height = thumbnailHeight*float64(size.X)/float64(size.Y), thumbnailHeight
+	}
+	l.reserve(height + 10)
+	l.y += 10
+	l.page.Image(img, margin+labelWidth, l.y, width, height)
+	l.y += height
+}
@@ -0,0 +1,219 @@
+// Package docgen renders the documents the service issues, such as the
+// cadastral extract, as PDF. Everything is drawn by pkg/pdf on the server,
+// no external renderer is involved.
+package docgen
+
+import (
+	"strconv"
+
+	"github.com/e-space-uz/backend/pkg/i18n"
+	"github.com/e-space-uz/backend/pkg/pdf"
+)
+
+const (
+	margin      = 50.0
+	footerSpace = 40.0
+	labelWidth  = 170.0
+	lineHeight  = 1.35
+)
+
+// labels are the fixed texts of the documents. Languages without a text fall
+// back to i18n.Default.
+var labels = map[string]map[string]string{
+	i18n.UzLatn: {
+		"extract_title":     "Ko‘chmas mulk obyekti bo‘yicha kadastr ko‘chirmasi",
+		"entity_number":     "Kadastr raqami",
+		"address":           "Manzil",
+		"soato":             "MHOBT (SOATO)",
+		"city":              "Shahar",
+		"region":            "Viloyat",
+		"district":          "Tuman",
+		"location":          "Obyekt",
+		"properties":        "Obyekt ma’lumotlari",
+		"owner":             "Mulkdor",
+		"full_name":         "F.I.Sh.",
+		"pin":               "JShShIR",
+		"inn":               "STIR",
+		"passport":          "Pasport",
+		"owner_address":     "Doimiy manzil",
+		"issued_at":         "Berilgan sana",
+		"issued_by":         "Bergan xodim",
+		"page":              "Sahifa",
+		"no_properties":     "Ma’lumotlar kiritilmagan",
+		"extract_notice":    "Ko‘chirma ko‘chmas mulk kadastri ma’lumotlari asosida avtomatik tarzda shakllantirildi.",
+		"verification_code": "Tekshirish kodi",
+		"verification_hint": "Hujjat haqiqiyligini QR kodni skanerlab yoki kodni quyidagi manzilda kiritib tekshiring.",
+	},
+	i18n.UzCyrl: {
+		"extract_title":     "Кўчмас мулк объекти бўйича кадастр кўчирмаси",
+		"entity_number":     "Кадастр рақами",
+		"address":           "Манзил",
+		"soato":             "МҲОБТ (СОАТО)",
+		"city":              "Шаҳар",
+		"region":            "Вилоят",
+		"district":          "Туман",
+		"location":          "Объект",
+		"properties":        "Объект маълумотлари",
+		"owner":             "Мулкдор",
+		"full_name":         "Ф.И.Ш.",
+		"pin":               "ЖШШИР",
+		"inn":               "СТИР",
+		"passport":          "Паспорт",
+		"owner_address":     "Доимий манзил",
+		"issued_at":         "Берилган сана",
+		"issued_by":         "Берган ходим",
+		"page":              "Саҳифа",
+		"no_properties":     "Маълумотлар киритилмаган",
+		"extract_notice":    "Кўчирма кўчмас мулк кадастри маълумотлари асосида автоматик тарзда шакллантирилди.",
+		"verification_code": "Текшириш коди",
+		"verification_hint": "Ҳужжат ҳақиқийлигини QR кодни сканерлаб ёки кодни қуйидаги манзилда киритиб текширинг.",
+	},
+	i18n.Ru: {
+		"extract_title":     "Кадастровая выписка об объекте недвижимости",
+		"entity_number":     "Кадастровый номер",
+		"address":           "Адрес",
+		"soato":             "СОАТО",
+		"city":              "Город",
+		"region":            "Область",
+		"district":          "Район",
+		"location":          "Объект",
+		"properties":        "Сведения об объекте",
+		"owner":             "Правообладатель",
+		"full_name":         "Ф.И.О.",
+		"pin":               "ПИНФЛ",
+		"inn":               "ИНН",
+		"passport":          "Паспорт",
+		"owner_address":     "Постоянный адрес",
+		"issued_at":         "Дата выдачи",
+		"issued_by":         "Выдал",
+		"page":              "Страница",
+		"no_properties":     "Сведения не внесены",
+		"extract_notice":    "Выписка сформирована автоматически на основании сведений кадастра недвижимости.",
+		"verification_code": "Код проверки",
+		"verification_hint": "Подлинность документа можно проверить, отсканировав QR-код или введя код по адресу ниже.",
+	},
+	i18n.En: {
+		"extract_title":     "Cadastral extract for a real estate object",
+		"entity_number":     "Cadastral number",
+		"address":           "Address",
+		"soato":             "SOATO",
+		"city":              "City",
+		"region":            "Region",
+		"district":          "District",
+		"location":          "Object",
+		"properties":        "Object details",
+		"owner":             "Owner",
+		"full_name":         "Full name",
+		"pin":               "PINFL",
+		"inn":               "TIN",
+		"passport":          "Passport",
+		"owner_address":     "Permanent address",
+		"issued_at":         "Issue date",
+		"issued_by":         "Issued by",
+		"page":              "Page",
+		"no_properties":     "No details entered",
+		"extract_notice":    "The extract was generated automatically from the real estate cadastre.",
+		"verification_code": "Verification code",
+		"verification_hint": "Check that the document is genuine by scanning the QR code or entering the code at the address below.",
+	},
+}
+
+func label(lang, key string) string {
+	if text, ok := labels[lang][key]; ok {
+		return text
+	}
+	return labels[i18n.Default][key]
+}
+
+// layout places blocks of text from the top of the page down and starts a
+// new page when the next block does not fit
+type layout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+func newLayout(doc *pdf.Document) *layout {
+	l := &layout{doc: doc}
+	l.newPage()
+	return l
+}
+
+func (l *layout) newPage() {
+	l.page = l.doc.AddPage()
+	l.y = margin
+}
+
+func (l *layout) width() float64 {
+	return l.page.Width() - 2*margin
+}
+
+// reserve starts a new page unless height fits on the current one
+func (l *layout) reserve(height float64) {
+	if l.y+height > l.page.Height()-margin-footerSpace {
+		l.newPage()
+	}
+}
+
+// heading writes a centered line
+func (l *layout) heading(text string, size float64) {
+	for _, line := range l.doc.Wrap(text, size, l.width()) {
+		l.reserve(size * lineHeight)
+		l.y += size * lineHeight
+		x := (l.page.Width() - l.doc.TextWidth(line, size)) / 2
+		l.page.Text(x, l.y, size, line)
+	}
+}
+
+// section writes a title with a rule under it
+func (l *layout) section(title string) {
+	const size = 12.0
+	l.reserve(3 * size * lineHeight)
+	l.y += size
+	l.y += size * lineHeight
+	l.page.Text(margin, l.y, size, title)
+	l.y += 4
+	l.page.Line(margin, l.y, l.page.Width()-margin, l.y, 0.75)
+}
+
+// row writes a label and its value, both wrapped in their columns
+func (l *layout) row(name, value string) {
+	const size = 10.0
+	nameLines := l.doc.Wrap(name, size, labelWidth-8)
+	valueLines := l.doc.Wrap(value, size, l.width()-labelWidth)
+	lines := len(nameLines)
+	if len(valueLines) > lines {
+		lines = len(valueLines)
+	}
+	l.reserve(float64(lines)*size*lineHeight + 4)
+	l.y += 4
+	for i := 0; i < lines; i++ {
+		l.y += size * lineHeight
+		if i < len(nameLines) {
+			l.page.Text(margin, l.y, size, nameLines[i])
+		}
+		if i < len(valueLines) {
+			l.page.Text(margin+labelWidth, l.y, size, valueLines[i])
+		}
+	}
+}
+
+// paragraph writes wrapped text across the whole width
+func (l *layout) paragraph(text string, size float64) {
+	for _, line := range l.doc.Wrap(text, size, l.width()) {
+		l.reserve(size * lineHeight)
+		l.y += size * lineHeight
+		l.page.Text(margin, l.y, size, line)
+	}
+}
+
+// footers writes the same line and the page number at the bottom of every page
+func footers(pages []*pdf.Page, text, pageLabel string) {
+	const size = 8.0
+	for i, page := range pages {
+		y := page.Height() - margin + size
+		page.Line(margin, y-2*size, page.Width()-margin, y-2*size, 0.5)
+		page.Text(margin, y, size, text)
+		page.TextRight(page.Width()-margin, y, size, pageLabel+" "+strconv.Itoa(i+1)+"/"+strconv.Itoa(len(pages)))
+	}
+}
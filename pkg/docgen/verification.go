@@ -0,0 +1,96 @@
+package docgen
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+
+	"github.com/e-space-uz/backend/pkg/pdf"
+	"github.com/e-space-uz/backend/pkg/qr"
+)
+
+// codeAlphabet is Crockford's base32, it has no I, L, O and U so codes read
+// out over the phone are not mistaken
+const codeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// codeLength gives 60 random bits, too many to guess codes one by one
+const codeLength = 12
+
+var ErrInvalidCode = errors.New("invalid verification code")
+
+// NewVerificationCode returns a random code such as 7KQM-2XRA-9D4F
+func NewVerificationCode() (string, error) {
+	random := make([]byte, codeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	code := make([]byte, codeLength)
+	for i, b := range random {
+		code[i] = codeAlphabet[b&31]
+	}
+	return format(string(code)), nil
+}
+
+// NormalizeVerificationCode accepts codes typed in lower case, without
+// dashes or with the letters Crockford's base32 reads as digits
+func NormalizeVerificationCode(code string) (string, error) {
+	code = strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		case 'O', 'o':
+			return '0'
+		case 'I', 'i', 'L', 'l':
+			return '1'
+		}
+		return r
+	}, strings.ToUpper(code))
+	if len(code) != codeLength || strings.Trim(code, codeAlphabet) != "" {
+		return "", ErrInvalidCode
+	}
+	return format(code), nil
+}
+
+func format(code string) string {
+	return code[:4] + "-" + code[4:8] + "-" + code[8:]
+}
+
+const qrSize = 80.0
+
+// verification draws the QR code of url with the code next to it
+func (l *layout) verification(lang, code, url string) {
+	// without a QR code, for a URL too long for one, the code is typed in
+	qrCode, _ := qr.Encode([]byte(url))
+	l.reserve(qrSize + 14)
+	l.y += 14
+	textX := margin
+	if qrCode != nil {
+		drawQR(l.page, qrCode, margin, l.y, qrSize)
+		textX += qrSize + 12
+	}
+	l.page.Text(textX, l.y+20, 10, label(lang, "verification_code")+": "+code)
+	for i, line := range l.doc.Wrap(label(lang, "verification_hint"), 8, l.page.Width()-margin-textX) {
+		l.page.Text(textX, l.y+36+float64(i)*8*lineHeight, 8, line)
+	}
+	l.page.Text(textX, l.y+qrSize-8, 8, url)
+	l.y += qrSize
+}
+
+// drawQR fills the dark modules, neighbouring modules of a row are one
+// rectangle to keep the page small
+func drawQR(page *pdf.Page, code *qr.Code, x, y, size float64) {
+	module := size / float64(code.Size)
+	for row := 0; row < code.Size; row++ {
+		for col := 0; col < code.Size; {
+			if !code.Dark(col, row) {
+				col++
+				continue
+			}
+			start := col
+			for col < code.Size && code.Dark(col, row) {
+				col++
+			}
+			page.FillRect(x+float64(start)*module, y+float64(row)*module, float64(col-start)*module, module)
+		}
+	}
+}
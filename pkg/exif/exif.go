@@ -0,0 +1,319 @@
+// Package exif reads the few EXIF tags the backend needs from JPEG files:
+// orientation for thumbnails, and GPS, capture time and device for gallery
+// photos
+package exif
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+var ErrNoExif = errors.New("no exif data")
+
+const (
+	tagMake        = 0x010f
+	tagModel       = 0x0110
+	tagOrientation = 0x0112
+	tagSoftware    = 0x0131
+	tagDateTime    = 0x0132
+	tagExifIFD     = 0x8769
+	tagGPSIFD      = 0x8825
+
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	tagGPSLatitudeRef  = 0x01
+	tagGPSLatitude     = 0x02
+	tagGPSLongitudeRef = 0x03
+	tagGPSLongitude    = 0x04
+	tagGPSAltitudeRef  = 0x05
+	tagGPSAltitude     = 0x06
+
+	typeASCII    = 2
+	typeShort    = 3
+	typeRational = 5
+	typeByte     = 1
+)
+
+// Data holds the tags that were found. Orientation is 1 when the tag is
+// missing. DateTime and OffsetTime are kept as written by the camera, see
+// CapturedAt.
+type Data struct {
+	Orientation int
+
+	HasGPS      bool
+	Latitude    float64
+	Longitude   float64
+	HasAltitude bool
+	Altitude    float64
+
+	DateTime   string
+	OffsetTime string
+
+	Make     string
+	Model    string
+	Software string
+}
+
+// CapturedAt parses DateTime. Cameras that do not write OffsetTime store the
+// local time of the device, loc is used for them.
+func (d *Data) CapturedAt(loc *time.Location) (time.Time, bool) {
+	if d.DateTime == "" {
+		return time.Time{}, false
+	}
+	if d.OffsetTime != "" {
+		t, err := time.Parse("2006:01:02 15:04:05-07:00", d.DateTime+d.OffsetTime)
+		if err == nil {
+			return t, true
+		}
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", d.DateTime, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Read looks for the APP1 Exif segment of a JPEG. Other formats return
+// ErrNoExif.
+func Read(r io.Reader) (*Data, error) {
+	segment, err := exifSegment(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	tiff, err := newTIFF(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &Data{Orientation: 1}
+	ifd0, err := tiff.ifd(tiff.firstIFD)
+	if err != nil {
+		return nil, err
+	}
+	if entry, ok := ifd0[tagOrientation]; ok {
+		if o := int(tiff.short(entry)); o >= 1 && o <= 8 {
+			data.Orientation = o
+		}
+	}
+	data.Make = tiff.ascii(ifd0[tagMake])
+	data.Model = tiff.ascii(ifd0[tagModel])
+	data.Software = tiff.ascii(ifd0[tagSoftware])
+	data.DateTime = tiff.ascii(ifd0[tagDateTime])
+
+	// a broken sub IFD leaves the tags of IFD0 usable
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		if exifIFD, err := tiff.ifd(tiff.long(entry)); err == nil {
+			if original := tiff.ascii(exifIFD[tagDateTimeOriginal]); original != "" {
+				data.DateTime = original
+				data.OffsetTime = tiff.ascii(exifIFD[tagOffsetTimeOriginal])
+			}
+		}
+	}
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := tiff.ifd(tiff.long(entry)); err == nil {
+			tiff.readGPS(gps, data)
+		}
+	}
+	return data, nil
+}
+
+func (t *tiff) readGPS(gps map[uint16]entry, data *Data) {
+	latitude, latOK := t.degrees(gps[tagGPSLatitude])
+	longitude, lonOK := t.degrees(gps[tagGPSLongitude])
+	if !latOK || !lonOK {
+		return
+	}
+	if t.ascii(gps[tagGPSLatitudeRef]) == "S" {
+		latitude = -latitude
+	}
+	if t.ascii(gps[tagGPSLongitudeRef]) == "W" {
+		longitude = -longitude
+	}
+	if math.Abs(latitude) > 90 || math.Abs(longitude) > 180 {
+		return
+	}
+	// phones without a fix write zeros
+	if latitude == 0 && longitude == 0 {
+		return
+	}
+	data.HasGPS = true
+	data.Latitude = latitude
+	data.Longitude = longitude
+
+	if altitude := t.rationals(gps[tagGPSAltitude]); len(altitude) == 1 {
+		data.HasAltitude = true
+		data.Altitude = altitude[0]
+		if ref, ok := gps[tagGPSAltitudeRef]; ok && ref.kind == typeByte && ref.value[0] == 1 {
+			data.Altitude = -data.Altitude
+		}
+	}
+}
+
+// degrees turns degrees, minutes and seconds into decimal degrees
+func (t *tiff) degrees(e entry) (float64, bool) {
+	dms := t.rationals(e)
+	if len(dms) != 3 {
+		return 0, false
+	}
+	return dms[0] + dms[1]/60 + dms[2]/3600, true
+}
+
+// exifSegment walks the JPEG markers until the Exif APP1 segment and returns
+// its TIFF payload
+func exifSegment(r *bufio.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return nil, ErrNoExif
+	}
+	for {
+		marker, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrNoExif
+		}
+		if marker != 0xff {
+			return nil, ErrNoExif
+		}
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrNoExif
+		}
+		// padding and markers without a length
+		if kind == 0xff {
+			_ = r.UnreadByte()
+			continue
+		}
+		if kind == 0x01 || (kind >= 0xd0 && kind <= 0xd7) {
+			continue
+		}
+		// start of scan, the metadata is over
+		if kind == 0xda || kind == 0xd9 {
+			return nil, ErrNoExif
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil || length < 2 {
+			return nil, ErrNoExif
+		}
+		payload := make([]byte, length-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, ErrNoExif
+		}
+		if kind == 0xe1 && len(payload) > 6 && string(payload[:6]) == "Exif\x00\x00" {
+			return payload[6:], nil
+		}
+	}
+}
+
+type tiff struct {
+	data     []byte
+	order    binary.ByteOrder
+	firstIFD uint32
+}
+
+type entry struct {
+	kind  uint16
+	count uint32
+	// value holds the 4 value bytes, an offset when the value is larger
+	value []byte
+}
+
+func newTIFF(data []byte) (*tiff, error) {
+	if len(data) < 8 {
+		return nil, ErrNoExif
+	}
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, ErrNoExif
+	}
+	if t.order.Uint16(data[2:]) != 42 {
+		return nil, ErrNoExif
+	}
+	t.firstIFD = t.order.Uint32(data[4:])
+	return t, nil
+}
+
+func (t *tiff) ifd(offset uint32) (map[uint16]entry, error) {
+	if int64(offset)+2 > int64(len(t.data)) {
+		return nil, ErrNoExif
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(t.data) {
+		return nil, ErrNoExif
+	}
+
+	entries := make(map[uint16]entry, count)
+	for i := 0; i < count; i++ {
+		raw := t.data[start+i*12:]
+		entries[t.order.Uint16(raw)] = entry{
+			kind:  t.order.Uint16(raw[2:]),
+			count: t.order.Uint32(raw[4:]),
+			value: raw[8:12],
+		}
+	}
+	return entries, nil
+}
+
+func (t *tiff) short(e entry) uint16 {
+	return t.order.Uint16(e.value)
+}
+
+func (t *tiff) long(e entry) uint32 {
+	if e.kind == typeShort {
+		return uint32(t.short(e))
+	}
+	return t.order.Uint32(e.value)
+}
+
+// bytes returns the value of an entry, following the offset when it does not
+// fit in the entry itself
+func (t *tiff) bytes(e entry, size uint32) []byte {
+	if e.value == nil || e.count == 0 || e.count > uint32(len(t.data)) {
+		return nil
+	}
+	length := e.count * size
+	if length <= 4 {
+		return e.value[:length]
+	}
+	offset := t.order.Uint32(e.value)
+	if uint64(offset)+uint64(length) > uint64(len(t.data)) {
+		return nil
+	}
+	return t.data[offset : offset+length]
+}
+
+func (t *tiff) ascii(e entry) string {
+	if e.kind != typeASCII {
+		return ""
+	}
+	value := t.bytes(e, 1)
+	return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+}
+
+func (t *tiff) rationals(e entry) []float64 {
+	if e.kind != typeRational {
+		return nil
+	}
+	value := t.bytes(e, 8)
+	rationals := make([]float64, 0, len(value)/8)
+	for i := 0; i+8 <= len(value); i += 8 {
+		numerator := t.order.Uint32(value[i:])
+		denominator := t.order.Uint32(value[i+4:])
+		if denominator == 0 {
+			return nil
+		}
+		rationals = append(rationals, float64(numerator)/float64(denominator))
+	}
+	return rationals
+}
@@ -0,0 +1,308 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testTag struct {
+	id    uint16
+	kind  uint16
+	count uint32
+	value []byte
+}
+
+func asciiTag(id uint16, s string) testTag {
+	return testTag{id: id, kind: typeASCII, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func rationalTag(order binary.ByteOrder, id uint16, values ...[2]uint32) testTag {
+	value := make([]byte, 8*len(values))
+	for i, v := range values {
+		order.PutUint32(value[8*i:], v[0])
+		order.PutUint32(value[8*i+4:], v[1])
+	}
+	return testTag{id: id, kind: typeRational, count: uint32(len(values)), value: value}
+}
+
+func shortTag(order binary.ByteOrder, id, v uint16) testTag {
+	value := make([]byte, 4)
+	order.PutUint16(value, v)
+	return testTag{id: id, kind: typeShort, count: 1, value: value}
+}
+
+func longTag(order binary.ByteOrder, id uint16, v uint32) testTag {
+	value := make([]byte, 4)
+	order.PutUint32(value, v)
+	return testTag{id: id, kind: 4, count: 1, value: value}
+}
+
+// encodeIFD writes the entries, the next IFD offset and the values that do
+// not fit into the entries, for an IFD starting at offset
+func encodeIFD(order binary.ByteOrder, offset int, tags []testTag) []byte {
+	ifd := make([]byte, 2+12*len(tags)+4)
+	order.PutUint16(ifd, uint16(len(tags)))
+	var values []byte
+	for i, tag := range tags {
+		raw := ifd[2+12*i:]
+		order.PutUint16(raw, tag.id)
+		order.PutUint16(raw[2:], tag.kind)
+		order.PutUint32(raw[4:], tag.count)
+		if len(tag.value) <= 4 {
+			copy(raw[8:12], tag.value)
+			continue
+		}
+		order.PutUint32(raw[8:], uint32(offset+len(ifd)+len(values)))
+		values = append(values, tag.value...)
+	}
+	return append(ifd, values...)
+}
+
+// buildTIFF lays out IFD0 with pointers to the Exif and GPS IFDs when they
+// have tags
+func buildTIFF(order binary.ByteOrder, ifd0, exifIFD, gps []testTag) []byte {
+	header := make([]byte, 8)
+	if order == binary.LittleEndian {
+		copy(header, "II")
+	} else {
+		copy(header, "MM")
+	}
+	order.PutUint16(header[2:], 42)
+	order.PutUint32(header[4:], 8)
+
+	// the size of IFD0 does not depend on where the sub IFDs are
+	pointers := func(exifOffset, gpsOffset int) []testTag {
+		tags := append([]testTag(nil), ifd0...)
+		if exifIFD != nil {
+			tags = append(tags, longTag(order, tagExifIFD, uint32(exifOffset)))
+		}
+		if gps != nil {
+			tags = append(tags, longTag(order, tagGPSIFD, uint32(gpsOffset)))
+		}
+		return tags
+	}
+	exifOffset := 8 + len(encodeIFD(order, 8, pointers(0, 0)))
+	gpsOffset := exifOffset + len(encodeIFD(order, exifOffset, exifIFD))
+
+	data := append(header, encodeIFD(order, 8, pointers(exifOffset, gpsOffset))...)
+	data = append(data, encodeIFD(order, exifOffset, exifIFD)...)
+	return append(data, encodeIFD(order, gpsOffset, gps)...)
+}
+
+func segment(kind byte, payload []byte) []byte {
+	header := []byte{0xff, kind, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(payload)+2))
+	return append(header, payload...)
+}
+
+// jpeg wraps the TIFF into an APP1 segment after a JFIF one, followed by the
+// start of scan
+func jpeg(tiff []byte) []byte {
+	data := []byte{0xff, 0xd8}
+	data = append(data, segment(0xe0, []byte("JFIF\x00\x01\x01"))...)
+	data = append(data, segment(0xe1, append([]byte("Exif\x00\x00"), tiff...))...)
+	return append(data, segment(0xda, []byte{0, 0, 0})...)
+}
+
+func sampleTIFF(order binary.ByteOrder) []byte {
+	return buildTIFF(order,
+		[]testTag{
+			asciiTag(tagMake, "Apple"),
+			asciiTag(tagModel, "iPhone 12"),
+			shortTag(order, tagOrientation, 6),
+			asciiTag(tagSoftware, "15.4"),
+			asciiTag(tagDateTime, "2024:05:01 10:00:00"),
+		},
+		[]testTag{
+			asciiTag(tagDateTimeOriginal, "2024:05:01 09:30:00"),
+			asciiTag(tagOffsetTimeOriginal, "+05:00"),
+		},
+		[]testTag{
+			asciiTag(tagGPSLatitudeRef, "N"),
+			rationalTag(order, tagGPSLatitude, [2]uint32{41, 1}, [2]uint32{18, 1}, [2]uint32{0, 1}),
+			asciiTag(tagGPSLongitudeRef, "E"),
+			rationalTag(order, tagGPSLongitude, [2]uint32{69, 1}, [2]uint32{15, 1}, [2]uint32{0, 1}),
+			{id: tagGPSAltitudeRef, kind: typeByte, count: 1, value: []byte{0, 0, 0, 0}},
+			rationalTag(order, tagGPSAltitude, [2]uint32{4555, 10}),
+		})
+}
+
+var sampleData = &Data{
+	Orientation: 6,
+	HasGPS:      true,
+	Latitude:    41.3,
+	Longitude:   69.25,
+	HasAltitude: true,
+	Altitude:    455.5,
+	DateTime:    "2024:05:01 09:30:00",
+	OffsetTime:  "+05:00",
+	Make:        "Apple",
+	Model:       "iPhone 12",
+	Software:    "15.4",
+}
+
+func TestRead(t *testing.T) {
+	order := binary.LittleEndian
+	southWest := buildTIFF(order, nil, nil, []testTag{
+		asciiTag(tagGPSLatitudeRef, "S"),
+		rationalTag(order, tagGPSLatitude, [2]uint32{41, 1}, [2]uint32{18, 1}, [2]uint32{0, 1}),
+		asciiTag(tagGPSLongitudeRef, "W"),
+		rationalTag(order, tagGPSLongitude, [2]uint32{69, 1}, [2]uint32{15, 1}, [2]uint32{0, 1}),
+		{id: tagGPSAltitudeRef, kind: typeByte, count: 1, value: []byte{1, 0, 0, 0}},
+		rationalTag(order, tagGPSAltitude, [2]uint32{12, 1}),
+	})
+	noFix := buildTIFF(order, nil, nil, []testTag{
+		rationalTag(order, tagGPSLatitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+		rationalTag(order, tagGPSLongitude, [2]uint32{0, 1}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+	})
+	// the Make value points past the end of the data
+	makeOutside := sampleTIFF(order)
+	order.PutUint32(makeOutside[8+2+8:], uint32(len(makeOutside)))
+
+	tests := []struct {
+		name string
+		data []byte
+		want *Data
+	}{
+		{name: "little endian", data: jpeg(sampleTIFF(binary.LittleEndian)), want: sampleData},
+		{name: "big endian", data: jpeg(sampleTIFF(binary.BigEndian)), want: sampleData},
+		{
+			name: "padding and markers without length",
+			data: append([]byte{0xff, 0xd8, 0xff, 0xff, 0xff, 0xd0}, jpeg(sampleTIFF(order))[2:]...),
+			want: sampleData,
+		},
+		{
+			name: "south west below sea level",
+			data: jpeg(southWest),
+			want: &Data{Orientation: 1, HasGPS: true, Latitude: -41.3, Longitude: -69.25, HasAltitude: true, Altitude: -12},
+		},
+		{name: "gps without a fix", data: jpeg(noFix), want: &Data{Orientation: 1}},
+		{name: "empty ifd", data: jpeg(buildTIFF(order, nil, nil, nil)), want: &Data{Orientation: 1}},
+		{
+			name: "value outside the data",
+			data: jpeg(makeOutside),
+			want: func() *Data { d := *sampleData; d.Make = ""; return &d }(),
+		},
+		{
+			name: "orientation out of range",
+			data: jpeg(buildTIFF(order, []testTag{shortTag(order, tagOrientation, 9)}, nil, nil)),
+			want: &Data{Orientation: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMalformed(t *testing.T) {
+	order := binary.LittleEndian
+	valid := jpeg(sampleTIFF(order))
+	exifStart := 2 + 4 + 7 + 4 + 6
+
+	badOrder := append([]byte(nil), valid...)
+	copy(badOrder[exifStart:], "XX")
+	badMagic := append([]byte(nil), valid...)
+	order.PutUint16(badMagic[exifStart+2:], 43)
+	ifdOutside := append([]byte(nil), valid...)
+	order.PutUint32(ifdOutside[exifStart+4:], 1<<31)
+	tooManyEntries := append([]byte(nil), valid...)
+	order.PutUint16(tooManyEntries[exifStart+8:], 0xffff)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty"},
+		{name: "png", data: []byte("\x89PNG\r\n\x1a\n")},
+		{name: "only soi", data: []byte{0xff, 0xd8}},
+		{name: "no marker", data: []byte{0xff, 0xd8, 0x00, 0xe1}},
+		{name: "start of scan first", data: append([]byte{0xff, 0xd8}, segment(0xda, nil)...)},
+		{name: "end of image first", data: []byte{0xff, 0xd8, 0xff, 0xd9}},
+		{name: "length below two", data: []byte{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x01}},
+		{name: "length cut", data: []byte{0xff, 0xd8, 0xff, 0xe1, 0x00}},
+		{name: "segment cut", data: valid[:exifStart+10]},
+		{name: "app1 that is not exif", data: append([]byte{0xff, 0xd8}, segment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00"))...)},
+		{name: "tiff header cut", data: append([]byte{0xff, 0xd8}, segment(0xe1, []byte("Exif\x00\x00II*\x00"))...)},
+		{name: "byte order", data: badOrder},
+		{name: "magic", data: badMagic},
+		{name: "ifd outside", data: ifdOutside},
+		{name: "ifd entries outside", data: tooManyEntries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(tt.data)); !errors.Is(err, ErrNoExif) {
+				t.Fatalf("got %v, want %v", err, ErrNoExif)
+			}
+		})
+	}
+}
+
+// TestReadMutated feeds damaged copies of a valid photo, whatever is in them
+// has to come back as data or an error
+func TestReadMutated(t *testing.T) {
+	samples := [][]byte{jpeg(sampleTIFF(binary.LittleEndian)), jpeg(sampleTIFF(binary.BigEndian))}
+	random := rand.New(rand.NewSource(1))
+	for _, sample := range samples {
+		for n := 0; n < 5000; n++ {
+			data := append([]byte(nil), sample...)
+			for i := random.Intn(4); i >= 0; i-- {
+				switch random.Intn(3) {
+				case 0:
+					data[random.Intn(len(data))] = byte(random.Intn(256))
+				case 1:
+					data = data[:random.Intn(len(data))+1]
+				case 2:
+					// offsets and counts are 16 and 32 bit, large values
+					// reach for the end of the data
+					at := random.Intn(len(data))
+					copy(data[at:], []byte{0xff, 0xff, 0xff, 0xff})
+				}
+			}
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("%x: %v", data, r)
+					}
+				}()
+				_, _ = Read(bytes.NewReader(data))
+			}()
+		}
+	}
+}
+
+func TestCapturedAt(t *testing.T) {
+	tashkent := time.FixedZone("", 5*3600)
+	tests := []struct {
+		name string
+		data Data
+		want time.Time
+		ok   bool
+	}{
+		{name: "offset", data: Data{DateTime: "2024:05:01 09:30:00", OffsetTime: "+03:00"}, want: time.Date(2024, 5, 1, 6, 30, 0, 0, time.UTC), ok: true},
+		{name: "device time", data: Data{DateTime: "2024:05:01 09:30:00"}, want: time.Date(2024, 5, 1, 4, 30, 0, 0, time.UTC), ok: true},
+		{name: "broken offset", data: Data{DateTime: "2024:05:01 09:30:00", OffsetTime: "local"}, want: time.Date(2024, 5, 1, 4, 30, 0, 0, time.UTC), ok: true},
+		{name: "none"},
+		{name: "broken", data: Data{DateTime: "    :  :     :  :  "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.data.CapturedAt(tashkent)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Fatalf("got %v %v, want %v %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
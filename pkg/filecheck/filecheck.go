@@ -0,0 +1,168 @@
+// Package filecheck decides what an uploaded file really is. The content type
+// sent by the client is only a hint, the first bytes of the file decide.
+package filecheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/e-space-uz/backend/pkg/cms"
+
+	// decoders for the image formats that are checked
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+var (
+	ErrTooLarge       = errors.New("file is too large")
+	ErrTypeNotAllowed = errors.New("file type is not allowed")
+	ErrTypeMismatch   = errors.New("file content does not match its content type")
+	ErrBadImage       = errors.New("image can not be decoded")
+)
+
+// sniffLen is how many bytes http.DetectContentType looks at
+const sniffLen = 512
+
+// Policy is what one upload endpoint accepts
+type Policy struct {
+	ContentTypes []string
+	MaxSize      int64
+	// MaxImagePixels limits width*height of decoded images, zero turns
+	// decoding off
+	MaxImagePixels int
+}
+
+// zipBased are the formats that are zip archives inside, their first bytes
+// can not tell them apart from a plain zip
+var zipBased = map[string]bool{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       true,
+	"application/vnd.oasis.opendocument.text":                                 true,
+	"application/vnd.oasis.opendocument.spreadsheet":                          true,
+	"application/vnd.google-earth.kmz":                                        true,
+}
+
+// decodable are the image types registered with the image package above
+var decodable = map[string]bool{
+	"image/gif":  true,
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+var signatures = []struct {
+	prefix      []byte
+	contentType string
+}{
+	{[]byte("MZ"), "application/x-msdownload"},
+	{[]byte("\x7fELF"), "application/x-executable"},
+	{[]byte("\xca\xfe\xba\xbe"), "application/x-mach-binary"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("II*\x00"), "image/tiff"},
+	{[]byte("MM\x00*"), "image/tiff"},
+}
+
+// Sniff returns the media type of the content that starts with head, without
+// parameters
+func Sniff(head []byte) string {
+	// signed documents, .p7m with the document inside and .p7s without
+	if cms.IsSignedData(head) {
+		return "application/pkcs7-mime"
+	}
+	for _, s := range signatures {
+		if bytes.HasPrefix(head, s.prefix) {
+			return s.contentType
+		}
+	}
+	contentType := http.DetectContentType(head)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// Check sniffs the content type of r, compares it with the declared one and
+// the policy, and decodes images. It returns the content type the file
+// should be stored with and leaves r at the start.
+func Check(r io.ReadSeeker, size int64, declared string, policy Policy) (string, error) {
+	if policy.MaxSize > 0 && size > policy.MaxSize {
+		return "", fmt.Errorf("%w: %d bytes, at most %d are allowed", ErrTooLarge, size, policy.MaxSize)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	sniffed := Sniff(head[:n])
+
+	contentType := sniffed
+	if declared != "" {
+		declaredType, _, err := mime.ParseMediaType(declared)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrTypeMismatch, err)
+		}
+		switch {
+		case declaredType == sniffed:
+		case zipBased[declaredType] && sniffed == "application/zip":
+			contentType = declaredType
+		case declaredType == "application/pkcs7-signature" && sniffed == "application/pkcs7-mime":
+			contentType = declaredType
+		case declaredType == "application/octet-stream":
+		case strings.HasPrefix(declaredType, "text/") && sniffed == "text/plain":
+			contentType = declaredType
+		default:
+			return "", fmt.Errorf("%w: declared %s, content is %s", ErrTypeMismatch, declaredType, sniffed)
+		}
+	}
+	if !allowed(policy.ContentTypes, contentType) {
+		return "", fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	if policy.MaxImagePixels > 0 && decodable[contentType] {
+		if err := checkImage(r, policy.MaxImagePixels); err != nil {
+			return "", err
+		}
+	}
+
+	_, err = r.Seek(0, io.SeekStart)
+	return contentType, err
+}
+
+// checkImage reads the dimensions first, so a small file that claims a huge
+// image is rejected before it is decoded
+func checkImage(r io.ReadSeeker, maxPixels int) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBadImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return fmt.Errorf("%w: %dx%d pixels, at most %d are allowed", ErrBadImage, config.Width, config.Height, maxPixels)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, _, err := image.Decode(r); err != nil {
+		return fmt.Errorf("%w: %s", ErrBadImage, err)
+	}
+	return nil
+}
+
+func allowed(contentTypes []string, contentType string) bool {
+	for _, t := range contentTypes {
+		if strings.EqualFold(strings.TrimSpace(t), contentType) {
+			return true
+		}
+	}
+	return false
+}
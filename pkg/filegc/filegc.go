@@ -0,0 +1,153 @@
+// Package filegc deletes files nobody references: uploads that were never
+// attached to an entity or a draft
+package filegc
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/logger"
+	"github.com/e-space-uz/backend/pkg/thumbnail"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxRecordedOrphans keeps the audit record far below the document size limit
+const maxRecordedOrphans = 10000
+
+// Storage looks up references and keeps the audit records
+type Storage interface {
+	ReferencedObjects(ctx context.Context) (map[string]bool, error)
+	CreateRun(ctx context.Context, req *models.FileGCRun) error
+}
+
+type Options struct {
+	// GracePeriod protects uploads that are not attached yet
+	GracePeriod time.Duration
+	// Enforce deletes orphans, otherwise they are only reported
+	Enforce bool
+	// KeepPrefixes are never collected, such as the quarantine
+	KeepPrefixes []string
+}
+
+type Collector struct {
+	store   filestore.FileStore
+	storage Storage
+	log     logger.Logger
+	opts    Options
+}
+
+func New(store filestore.FileStore, storage Storage, log logger.Logger, opts Options) *Collector {
+	return &Collector{
+		store:   store,
+		storage: storage,
+		log:     log,
+		opts:    opts,
+	}
+}
+
+// Run collects once and records the run, also when it fails halfway
+func (c *Collector) Run(ctx context.Context, triggeredBy string) (*models.FileGCRun, error) {
+	run := &models.FileGCRun{
+		ID:          primitive.NewObjectID(),
+		Mode:        models.FileGCModeDryRun,
+		TriggeredBy: triggeredBy,
+		GracePeriod: c.opts.GracePeriod.String(),
+		StartedAt:   time.Now(),
+		Orphans:     []*models.FileGCOrphan{},
+	}
+	if c.opts.Enforce {
+		run.Mode = models.FileGCModeEnforce
+	}
+
+	err := c.collect(ctx, run)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	run.FinishedAt = time.Now()
+
+	if createErr := c.storage.CreateRun(context.Background(), run); createErr != nil && err == nil {
+		err = createErr
+	}
+	c.log.Info("file gc finished",
+		logger.String("mode", run.Mode),
+		logger.Any("scanned", run.Scanned),
+		logger.Any("orphans", run.OrphanCount),
+		logger.Any("orphan_bytes", run.OrphanBytes),
+		logger.Any("deleted", run.Deleted),
+		logger.Any("failed", run.Failed))
+	return run, err
+}
+
+// Schedule runs the collector every interval until ctx is done
+func (c *Collector) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Run(ctx, "scheduler"); err != nil {
+				c.log.Error("error while collecting orphaned files", logger.Error(err))
+			}
+		}
+	}
+}
+
+func (c *Collector) collect(ctx context.Context, run *models.FileGCRun) error {
+	// references are read before the listing, a file attached meanwhile was
+	// uploaded within the grace period
+	referenced, err := c.storage.ReferencedObjects(ctx)
+	if err != nil {
+		return err
+	}
+	bases := make(map[string]bool, len(referenced))
+	for name := range referenced {
+		bases[strings.TrimSuffix(name, path.Ext(name))] = true
+	}
+	cutoff := run.StartedAt.Add(-c.opts.GracePeriod)
+
+	return c.store.Walk(ctx, "", func(info *filestore.ObjectInfo) error {
+		run.Scanned++
+		if c.keep(info.Name) || info.LastModified.After(cutoff) || referenced[info.Name] {
+			return nil
+		}
+		if original, ok := thumbnail.Original(info.Name); ok && bases[original] {
+			return nil
+		}
+
+		run.OrphanCount++
+		run.OrphanBytes += info.Size
+		orphan := &models.FileGCOrphan{
+			Name:         info.Name,
+			Size:         info.Size,
+			LastModified: info.LastModified,
+		}
+		if c.opts.Enforce {
+			if err := c.store.Delete(ctx, info.Name); err != nil {
+				run.Failed++
+				orphan.Error = err.Error()
+			} else {
+				run.Deleted++
+				orphan.Deleted = true
+			}
+		}
+		if len(run.Orphans) < maxRecordedOrphans {
+			run.Orphans = append(run.Orphans, orphan)
+		}
+		return nil
+	})
+}
+
+func (c *Collector) keep(name string) bool {
+	for _, prefix := range c.opts.KeepPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
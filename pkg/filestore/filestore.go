@@ -0,0 +1,50 @@
+// Package filestore hides where uploaded files are kept. The MinIO store is
+// used in deployments, the local store keeps files on disk so uploads work on
+// laptops and in CI without an object storage.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// QuarantinePrefix is where infected uploads are kept for inspection. Names
+// under it are never given to clients.
+const QuarantinePrefix = "quarantine/"
+
+var (
+	ErrNotFound    = errors.New("file not found")
+	ErrInvalidName = errors.New("invalid file name")
+)
+
+// ObjectInfo describes a stored file
+type ObjectInfo struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// FileStore keeps files under flat names such as "<object id>.pdf"
+type FileStore interface {
+	Put(ctx context.Context, name string, reader io.Reader, size int64, contentType string) error
+	// Get returns the content of the file, the caller closes it
+	Get(ctx context.Context, name string) (io.ReadCloser, *ObjectInfo, error)
+	Delete(ctx context.Context, name string) error
+	// Copy writes the content of src under dst, later writes to src do not
+	// change dst
+	Copy(ctx context.Context, src, dst string) error
+	Stat(ctx context.Context, name string) (*ObjectInfo, error)
+	// PresignGet returns a URL the file can be downloaded from until it expires
+	PresignGet(ctx context.Context, name string, expires time.Duration) (string, error)
+	// PresignPut returns a URL the file can be uploaded to with a PUT request
+	// until it expires. A non empty contentType and a positive size have to
+	// be sent exactly as given.
+	PresignPut(ctx context.Context, name string, expires time.Duration, contentType string, size int64) (string, error)
+	// Walk calls fn for every file whose name starts with prefix, in no
+	// particular order. An error from fn stops the walk and is returned.
+	Walk(ctx context.Context, prefix string, fn func(*ObjectInfo) error) error
+}
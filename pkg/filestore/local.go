@@ -0,0 +1,305 @@
+package filestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const metaDir = ".meta"
+
+// Local keeps files in a directory. Presigned URLs point to BaseURL, where
+// the API serves Local as an http.Handler, and are signed with an HMAC key
+// instead of storage credentials.
+type Local struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+// NewLocal creates root when it does not exist
+func NewLocal(root, baseURL, signingKey string) (*Local, error) {
+	if signingKey == "" {
+		return nil, errors.New("local file store needs a signing key")
+	}
+	if err := os.MkdirAll(filepath.Join(root, metaDir), 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{
+		root:       root,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+func (l *Local) Put(ctx context.Context, name string, reader io.Reader, size int64, contentType string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	filePath := l.filePath(name)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	// write next to the destination and rename, so readers never see half a file
+	tmp, err := ioutil.TempFile(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("file size is %d bytes, expected %d", written, size)
+	}
+
+	meta, err := json.Marshal(&ObjectInfo{
+		Name:         name,
+		Size:         written,
+		ContentType:  contentType,
+		ETag:         strconv.FormatInt(time.Now().UnixNano(), 16),
+		LastModified: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	metaPath := l.metaPath(name)
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(metaPath, meta, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func (l *Local) Get(ctx context.Context, name string) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := l.Stat(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(l.filePath(name))
+	if err != nil {
+		return nil, nil, localError(err)
+	}
+	return file, info, nil
+}
+
+func (l *Local) Delete(ctx context.Context, name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(l.filePath(name)); err != nil {
+		return localError(err)
+	}
+	_ = os.Remove(l.metaPath(name))
+	return nil
+}
+
+// Copy reads src from an open file, a PUT replacing src meanwhile does not
+// mix into dst
+func (l *Local) Copy(ctx context.Context, src, dst string) error {
+	file, info, err := l.Get(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return l.Put(ctx, dst, file, -1, info.ContentType)
+}
+
+func (l *Local) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(l.filePath(name))
+	if err != nil {
+		return nil, localError(err)
+	}
+
+	info := &ObjectInfo{
+		Name:         name,
+		Size:         stat.Size(),
+		LastModified: stat.ModTime().UTC(),
+	}
+	// files copied into the directory by hand have no metadata
+	if meta, err := ioutil.ReadFile(l.metaPath(name)); err == nil {
+		_ = json.Unmarshal(meta, info)
+		info.Size = stat.Size()
+	}
+	if info.ContentType == "" {
+		info.ContentType = "application/octet-stream"
+	}
+	return info, nil
+}
+
+func (l *Local) PresignGet(ctx context.Context, name string, expires time.Duration) (string, error) {
+	return l.presign(http.MethodGet, name, expires, "", 0)
+}
+
+func (l *Local) PresignPut(ctx context.Context, name string, expires time.Duration, contentType string, size int64) (string, error) {
+	return l.presign(http.MethodPut, name, expires, contentType, size)
+}
+
+// Walk skips the metadata directory and unfinished uploads
+func (l *Local) Walk(ctx context.Context, prefix string, fn func(*ObjectInfo) error) error {
+	return filepath.Walk(l.root, func(filePath string, stat os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.root, filePath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if stat.IsDir() {
+			if name == metaDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(stat.Name(), ".upload-") || !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		info, err := l.Stat(ctx, name)
+		if err != nil {
+			return err
+		}
+		return fn(info)
+	})
+}
+
+// ServeHTTP serves GET and PUT requests to presigned URLs. The request path
+// is the file name, mount it with http.StripPrefix under BaseURL's path.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		name        = strings.TrimPrefix(r.URL.Path, "/")
+		query       = r.URL.Query()
+		contentType = query.Get("content_type")
+		size, _     = strconv.ParseInt(query.Get("size"), 10, 64)
+		expires, _  = strconv.ParseInt(query.Get("expires"), 10, 64)
+	)
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if validateName(name) != nil {
+		http.Error(w, ErrInvalidName.Error(), http.StatusBadRequest)
+		return
+	}
+	if time.Now().Unix() > expires {
+		http.Error(w, "url has expired", http.StatusForbidden)
+		return
+	}
+	expected := l.signature(r.Method, name, expires, contentType, size)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		http.Error(w, "signature does not match", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		file, info, err := l.Get(r.Context(), name)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		w.Header().Set("Content-Type", info.ContentType)
+		http.ServeContent(w, r, path.Base(name), info.LastModified, file.(io.ReadSeeker))
+		return
+	}
+
+	if contentType != "" && r.Header.Get("Content-Type") != contentType {
+		http.Error(w, "content type does not match the signed one", http.StatusForbidden)
+		return
+	}
+	if size > 0 && r.ContentLength != size {
+		http.Error(w, "content length does not match the signed one", http.StatusForbidden)
+		return
+	}
+	if contentType == "" {
+		contentType = r.Header.Get("Content-Type")
+	}
+	if err := l.Put(r.Context(), name, r.Body, r.ContentLength, contentType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (l *Local) presign(method, name string, expires time.Duration, contentType string, size int64) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(expires).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	if contentType != "" {
+		query.Set("content_type", contentType)
+	}
+	if size > 0 {
+		query.Set("size", strconv.FormatInt(size, 10))
+	}
+	query.Set("signature", l.signature(method, name, expiresAt, contentType, size))
+
+	return l.baseURL + "/" + (&url.URL{Path: name}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+func (l *Local) signature(method, name string, expires int64, contentType string, size int64) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%s\n%d", method, name, expires, contentType, size)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *Local) filePath(name string) string {
+	return filepath.Join(l.root, filepath.FromSlash(name))
+}
+
+func (l *Local) metaPath(name string) string {
+	return filepath.Join(l.root, metaDir, filepath.FromSlash(name)+".json")
+}
+
+// validateName accepts relative slash separated names that stay inside the store
+func validateName(name string) error {
+	if name == "" || name == "." || strings.Contains(name, `\`) || strings.HasPrefix(name, "/") ||
+		path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") ||
+		strings.HasPrefix(name, metaDir+"/") || name == metaDir {
+		return ErrInvalidName
+	}
+	return nil
+}
+
+func localError(err error) error {
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
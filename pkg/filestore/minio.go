@@ -0,0 +1,182 @@
+package filestore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type MinioOptions struct {
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	Bucket          string
+	Region          string
+	UseSSL          bool
+}
+
+type minioStore struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewMinio connects to MinIO and creates the bucket when it does not exist.
+// It is meant to be called once at startup.
+func NewMinio(ctx context.Context, opts MinioOptions) (FileStore, error) {
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKeyID, opts.SecretAccessKey, ""),
+		Secure: opts.UseSSL,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, opts.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		err = client.MakeBucket(ctx, opts.Bucket, minio.MakeBucketOptions{Region: opts.Region})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &minioStore{
+		client: client,
+		bucket: opts.Bucket,
+	}, nil
+}
+
+func (ms *minioStore) Put(ctx context.Context, name string, reader io.Reader, size int64, contentType string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	_, err := ms.client.PutObject(ctx, ms.bucket, name, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+func (ms *minioStore) Get(ctx context.Context, name string) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := ms.Stat(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	object, err := ms.client.GetObject(ctx, ms.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, minioError(err)
+	}
+	return object, info, nil
+}
+
+func (ms *minioStore) Delete(ctx context.Context, name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	return minioError(ms.client.RemoveObject(ctx, ms.bucket, name, minio.RemoveObjectOptions{}))
+}
+
+func (ms *minioStore) Copy(ctx context.Context, src, dst string) error {
+	if err := validateName(src); err != nil {
+		return err
+	}
+	if err := validateName(dst); err != nil {
+		return err
+	}
+	_, err := ms.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: ms.bucket, Object: dst},
+		minio.CopySrcOptions{Bucket: ms.bucket, Object: src})
+	return minioError(err)
+}
+
+func (ms *minioStore) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	info, err := ms.client.StatObject(ctx, ms.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return &ObjectInfo{
+		Name:         name,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}, nil
+}
+
+func (ms *minioStore) PresignGet(ctx context.Context, name string, expires time.Duration) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+	u, err := ms.client.PresignedGetObject(ctx, ms.bucket, name, expires, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// PresignPut signs Content-Type and Content-Length into the URL, so MinIO
+// rejects uploads that do not send the same values
+func (ms *minioStore) PresignPut(ctx context.Context, name string, expires time.Duration, contentType string, size int64) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+	headers := http.Header{}
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+	if size > 0 {
+		headers.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	u, err := ms.client.PresignHeader(ctx, http.MethodPut, ms.bucket, name, expires, nil, headers)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (ms *minioStore) Walk(ctx context.Context, prefix string, fn func(*ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objects := ms.client.ListObjects(ctx, ms.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	})
+	for object := range objects {
+		if object.Err != nil {
+			return object.Err
+		}
+		err := fn(&ObjectInfo{
+			Name:         object.Key,
+			Size:         object.Size,
+			ContentType:  object.ContentType,
+			ETag:         object.ETag,
+			LastModified: object.LastModified,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func minioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return ErrNotFound
+	}
+	return err
+}
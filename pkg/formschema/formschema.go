@@ -0,0 +1,269 @@
+// Package formschema turns the dynamic entity forms (group properties and
+// their properties) into a JSON Schema (draft 2020-12) document and a UI
+// schema with rendering hints, so clients do not have to interpret
+// GroupProperty themselves.
+package formschema
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema keywords the forms need
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+	Const                *string            `json:"const,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// Document is the response of the form schema endpoint
+type Document struct {
+	Schema   *Schema                `json:"schema"`
+	UISchema map[string]interface{} `json:"ui_schema"`
+}
+
+// UIGroup keeps the grouping and ordering of GroupProperty for renderers
+type UIGroup struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Step        uint32   `json:"step"`
+	Properties  []string `json:"properties"`
+}
+
+// Generate builds the document. Fields are keyed by property id, the same key
+// entity_properties uses, so a submission can be checked against the schema
+// after turning the property list into an object.
+func Generate(id, title string, groups []*models.GroupProperty) *Document {
+	var (
+		additional = false
+		schema     = &Schema{
+			Schema:               Draft,
+			ID:                   id,
+			Title:                title,
+			Type:                 "object",
+			Properties:           map[string]*Schema{},
+			Required:             []string{},
+			AdditionalProperties: &additional,
+		}
+		uiSchema = map[string]interface{}{}
+		uiOrder  = []string{}
+		uiGroups = []*UIGroup{}
+	)
+
+	for _, group := range groups {
+		uiGroup := &UIGroup{
+			ID:          group.ID,
+			Title:       group.Name,
+			Description: group.Description,
+			Step:        group.Step,
+			Properties:  []string{},
+		}
+		for _, property := range group.Properties {
+			if property == nil || property.ID == "" {
+				continue
+			}
+			if _, ok := schema.Properties[property.ID]; ok {
+				continue
+			}
+
+			fieldSchema, uiField := propertySchema(property)
+			schema.Properties[property.ID] = fieldSchema
+			uiSchema[property.ID] = uiField
+			if property.IsRequired {
+				schema.Required = append(schema.Required, property.ID)
+			}
+			uiOrder = append(uiOrder, property.ID)
+			uiGroup.Properties = append(uiGroup.Properties, property.ID)
+		}
+		uiGroups = append(uiGroups, uiGroup)
+	}
+
+	uiSchema["ui:order"] = uiOrder
+	uiSchema["ui:groups"] = uiGroups
+
+	return &Document{
+		Schema:   schema,
+		UISchema: uiSchema,
+	}
+}
+
+func propertySchema(property *models.Property) (*Schema, map[string]interface{}) {
+	var (
+		schema = &Schema{
+			Title:       property.Label,
+			Description: property.Description,
+		}
+		uiField = map[string]interface{}{}
+		options = optionSchemas(property.PropertyOptions)
+		kind    = strings.ToLower(strings.TrimSpace(property.Type))
+		widget  string
+	)
+
+	switch kind {
+	case "number", "float", "decimal", "area":
+		schema.Type = "number"
+	case "integer", "int":
+		schema.Type = "integer"
+	case "boolean", "bool", "switch":
+		schema.Type = "boolean"
+	case "checkbox":
+		if len(options) == 0 {
+			schema.Type = "boolean"
+			break
+		}
+		schema.Type = "array"
+		schema.UniqueItems = true
+		schema.Items = &Schema{Type: "string", OneOf: options}
+		widget = "checkboxes"
+	case "multiselect", "multi-select":
+		schema.Type = "array"
+		schema.UniqueItems = true
+		schema.Items = &Schema{Type: "string", OneOf: options}
+		widget = "select"
+	case "radio", "select", "dropdown":
+		schema.Type = "string"
+		schema.OneOf = options
+		widget = kind
+	case "date":
+		schema.Type = "string"
+		schema.Format = "date"
+	case "datetime", "date-time":
+		schema.Type = "string"
+		schema.Format = "date-time"
+	case "time":
+		schema.Type = "string"
+		schema.Format = "time"
+	case "email":
+		schema.Type = "string"
+		schema.Format = "email"
+	case "url", "link":
+		schema.Type = "string"
+		schema.Format = "uri"
+	case "textarea":
+		schema.Type = "string"
+		widget = "textarea"
+	case "file", "image", "document":
+		// uploaded objects are referenced by their file store name
+		schema.Type = "string"
+		widget = "file"
+	default:
+		schema.Type = "string"
+		if len(options) != 0 {
+			schema.OneOf = options
+		}
+	}
+
+	if message := applyValidation(schema, property.Validation); message != "" {
+		uiField["ui:help"] = message
+	}
+	if property.IsRequired && schema.Type == "string" && schema.MinLength == nil && len(schema.OneOf) == 0 {
+		minLength := 1
+		schema.MinLength = &minLength
+	}
+
+	if widget != "" {
+		uiField["ui:widget"] = widget
+	}
+	if property.Placeholder != "" {
+		uiField["ui:placeholder"] = property.Placeholder
+	}
+	if property.Name != "" {
+		uiField["ui:name"] = property.Name
+	}
+
+	return schema, uiField
+}
+
+func optionSchemas(options []*models.PropertyOption) []*Schema {
+	var schemas []*Schema
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		value := option.Value
+		schemas = append(schemas, &Schema{
+			Const: &value,
+			Title: option.Name,
+		})
+	}
+	return schemas
+}
+
+// applyValidation understands Property.Validation written either as a JSON
+// object of schema keywords ({"minLength": 3}) or as a regular expression
+// anchored with ^. Anything else is treated as a message for the user and
+// returned so it can be shown as help text.
+func applyValidation(schema *Schema, validation string) string {
+	validation = strings.TrimSpace(validation)
+	if validation == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(validation, "{") {
+		var keywords struct {
+			Pattern   string   `json:"pattern"`
+			Format    string   `json:"format"`
+			MinLength *int     `json:"minLength"`
+			MaxLength *int     `json:"maxLength"`
+			Minimum   *float64 `json:"minimum"`
+			Maximum   *float64 `json:"maximum"`
+			MinItems  *int     `json:"minItems"`
+			MaxItems  *int     `json:"maxItems"`
+		}
+		if err := json.Unmarshal([]byte(validation), &keywords); err == nil {
+			if keywords.Pattern != "" {
+				schema.Pattern = keywords.Pattern
+			}
+			if keywords.Format != "" {
+				schema.Format = keywords.Format
+			}
+			schema.MinLength = pickInt(keywords.MinLength, schema.MinLength)
+			schema.MaxLength = pickInt(keywords.MaxLength, schema.MaxLength)
+			schema.MinItems = pickInt(keywords.MinItems, schema.MinItems)
+			schema.MaxItems = pickInt(keywords.MaxItems, schema.MaxItems)
+			if keywords.Minimum != nil {
+				schema.Minimum = keywords.Minimum
+			}
+			if keywords.Maximum != nil {
+				schema.Maximum = keywords.Maximum
+			}
+			return ""
+		}
+	}
+
+	if strings.HasPrefix(validation, "^") {
+		schema.Pattern = validation
+		return ""
+	}
+
+	return validation
+}
+
+func pickInt(value, fallback *int) *int {
+	if value != nil {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,147 @@
+package formschema
+
+import (
+	"encoding/json"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+// Validate checks a value, kept as a string like entity properties keep it,
+// against the schema Generate gives the property, so what is saved is held
+// to the form clients render. Arrays are JSON arrays or comma separated. It
+// returns what is wrong with the value, or "" when it is valid.
+func Validate(property *models.Property, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if property.IsRequired {
+			return "required"
+		}
+		return ""
+	}
+	schema, _ := propertySchema(property)
+	return schema.validate(value)
+}
+
+func (s *Schema) validate(value string) string {
+	switch s.Type {
+	case "number", "integer":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return "must be a number"
+		}
+		if s.Type == "integer" && number != math.Trunc(number) {
+			return "must be a whole number"
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			return "must be at least " + strconv.FormatFloat(*s.Minimum, 'f', -1, 64)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			return "must be at most " + strconv.FormatFloat(*s.Maximum, 'f', -1, 64)
+		}
+	case "boolean":
+		if value != "true" && value != "false" {
+			return "must be true or false"
+		}
+	case "array":
+		items := arrayItems(value)
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			return "must have at least " + strconv.Itoa(*s.MinItems) + " items"
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			return "must have at most " + strconv.Itoa(*s.MaxItems) + " items"
+		}
+		seen := map[string]bool{}
+		for _, item := range items {
+			if s.UniqueItems && seen[item] {
+				return "must not repeat an item"
+			}
+			seen[item] = true
+			if s.Items != nil {
+				if message := s.Items.validate(item); message != "" {
+					return message
+				}
+			}
+		}
+	default:
+		return s.validateString(value)
+	}
+	return ""
+}
+
+func (s *Schema) validateString(value string) string {
+	if len(s.OneOf) != 0 {
+		for _, option := range s.OneOf {
+			if option.Const != nil && *option.Const == value {
+				return ""
+			}
+		}
+		return "must be one of the options"
+	}
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		return "must be at least " + strconv.Itoa(*s.MinLength) + " characters"
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return "must be at most " + strconv.Itoa(*s.MaxLength) + " characters"
+	}
+	if s.Pattern != "" {
+		// a pattern that does not compile can not be met by anyone, it is
+		// left out like clients leave it out
+		pattern, err := regexp.Compile(s.Pattern)
+		if err == nil && !pattern.MatchString(value) {
+			return "does not match the expected format"
+		}
+	}
+	if !validFormat(s.Format, value) {
+		return "does not match the expected format"
+	}
+	return ""
+}
+
+func validFormat(format, value string) bool {
+	var err error
+	switch format {
+	case "date":
+		_, err = time.Parse("2006-01-02", value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "time":
+		if _, err = time.Parse("15:04:05Z07:00", value); err != nil {
+			_, err = time.Parse("15:04:05", value)
+		}
+	case "email":
+		var address *mail.Address
+		address, err = mail.ParseAddress(value)
+		if err == nil && address.Address != value {
+			return false
+		}
+	case "uri":
+		var uri *url.URL
+		uri, err = url.Parse(value)
+		if err == nil && (uri.Scheme == "" || uri.Host == "") {
+			return false
+		}
+	}
+	return err == nil
+}
+
+func arrayItems(value string) []string {
+	var items []string
+	if strings.HasPrefix(value, "[") && json.Unmarshal([]byte(value), &items) == nil {
+		return items
+	}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
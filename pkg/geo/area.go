@@ -0,0 +1,117 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// areaUnits are square meters in a unit of area, a sotix is a hundred square
+// meters
+var areaUnits = map[string]float64{
+	"m2":    1,
+	"ha":    10000,
+	"sotix": 100,
+}
+
+// AreaUnit returns the square meters in a unit: m2, ha or sotix
+func AreaUnit(unit string) (float64, error) {
+	meters, ok := areaUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown area unit %q, use m2, ha or sotix", unit)
+	}
+	return meters, nil
+}
+
+// wgs84Authalic is the sphere with the surface area of the WGS84 ellipsoid
+var wgs84Authalic = newAuthalic(WGS84Ellipsoid)
+
+// authalic maps latitudes of an ellipsoid to the sphere of the same surface
+// area, areas measured on the sphere are areas on the ellipsoid
+type authalic struct {
+	e      float64
+	qp     float64
+	radius float64
+}
+
+func newAuthalic(ellipsoid Ellipsoid) authalic {
+	a := authalic{e: math.Sqrt(ellipsoid.eccentricitySquared())}
+	a.qp = a.q(1)
+	a.radius = ellipsoid.A * math.Sqrt(a.qp/2)
+	return a
+}
+
+func (a authalic) q(sinPhi float64) float64 {
+	es := a.e * sinPhi
+	return (1 - a.e*a.e) * (sinPhi/(1-es*es) - math.Log((1-es)/(1+es))/(2*a.e))
+}
+
+// sinBeta is the sine of the authalic latitude
+func (a authalic) sinBeta(lat float64) float64 {
+	return a.q(math.Sin(lat*math.Pi/180)) / a.qp
+}
+
+// equalArea is a cylindrical equal area map of the authalic sphere touching
+// it along the parallel through the middle of the bounds. Square meters on it
+// are square meters on the ellipsoid and shapes near that parallel keep their
+// proportions, which is all parcels and districts need.
+type equalArea struct {
+	lon0     float64
+	sinBeta0 float64
+	cosBeta0 float64
+}
+
+func newEqualArea(bounds [4]float64) equalArea {
+	sinBeta0 := wgs84Authalic.sinBeta((bounds[1] + bounds[3]) / 2)
+	return equalArea{
+		lon0:     (bounds[0] + bounds[2]) / 2,
+		sinBeta0: sinBeta0,
+		cosBeta0: math.Sqrt(1 - sinBeta0*sinBeta0),
+	}
+}
+
+func (p equalArea) forward(lon, lat float64) (float64, float64) {
+	// longitudes are taken the short way round, so rings across the
+	// antimeridian stay in one piece
+	dLon := math.Remainder(lon-p.lon0, 360) * math.Pi / 180
+	x := wgs84Authalic.radius * dLon * p.cosBeta0
+	y := wgs84Authalic.radius * (wgs84Authalic.sinBeta(lat) - p.sinBeta0) / p.cosBeta0
+	return x, y
+}
+
+func (p equalArea) project(polygons [][][][]float64) [][][][]float64 {
+	projected := make([][][][]float64, len(polygons))
+	for i, polygon := range polygons {
+		projected[i] = make([][][]float64, len(polygon))
+		for j, ring := range polygon {
+			projected[i][j] = make([][]float64, len(ring))
+			for k, position := range ring {
+				x, y := p.forward(position[0], position[1])
+				projected[i][j][k] = []float64{x, y}
+			}
+		}
+	}
+	return projected
+}
+
+// Area returns the area in square meters of WGS84 polygons on the ellipsoid,
+// holes are taken out
+func Area(polygons [][][][]float64) float64 {
+	if len(polygons) == 0 {
+		return 0
+	}
+	return planarArea(newEqualArea(Bounds(polygons)).project(polygons))
+}
+
+func planarArea(polygons [][][][]float64) float64 {
+	area := 0.0
+	for _, polygon := range polygons {
+		for i, ring := range polygon {
+			if i == 0 {
+				area += math.Abs(RingArea(ring))
+			} else {
+				area -= math.Abs(RingArea(ring))
+			}
+		}
+	}
+	return area
+}
@@ -0,0 +1,230 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WGS84 is the code of the reference system everything is stored in
+const WGS84 = "EPSG:4326"
+
+var (
+	ErrUnknownCRS    = errors.New("coordinate reference system is not configured")
+	ErrBadDefinition = errors.New("coordinate reference system definition is not supported")
+)
+
+// CRS is a geographic or Transverse Mercator coordinate reference system.
+// Projected coordinates are easting and northing, geographic ones longitude
+// and latitude.
+type CRS struct {
+	Code       string
+	Name       string
+	Definition string
+
+	datum      *Datum
+	projection *TransverseMercator
+}
+
+// ToWGS84 converts coordinates of the system to WGS84 longitude and latitude
+func (c *CRS) ToWGS84(x, y float64) (float64, float64) {
+	if c.projection != nil {
+		x, y = c.projection.Inverse(x, y)
+	}
+	return c.datum.toWGS84(x, y)
+}
+
+// FromWGS84 converts WGS84 longitude and latitude to coordinates of the
+// system
+func (c *CRS) FromWGS84(lon, lat float64) (float64, float64) {
+	lon, lat = c.datum.fromWGS84(lon, lat)
+	if c.projection != nil {
+		return c.projection.Forward(lon, lat)
+	}
+	return lon, lat
+}
+
+func (c *CRS) Projected() bool {
+	return c.projection != nil
+}
+
+// Registry holds the systems coordinates are accepted and returned in, by
+// upper case code
+type Registry struct {
+	systems map[string]*CRS
+}
+
+// NewRegistry has WGS84, Pulkovo 1942 and its Gauss-Krüger zones 10 to 14,
+// which cover Uzbekistan
+func NewRegistry() *Registry {
+	r := &Registry{systems: map[string]*CRS{}}
+	builtin := []struct{ code, name, definition string }{
+		{WGS84, "WGS 84", "+proj=longlat +datum=WGS84"},
+		{"EPSG:4284", "Pulkovo 1942", "+proj=longlat +ellps=krass +towgs84=23.92,-141.27,-80.9,0,0.35,0.82,-0.12"},
+	}
+	for zone := 10; zone <= 14; zone++ {
+		builtin = append(builtin, struct{ code, name, definition string }{
+			fmt.Sprintf("EPSG:284%02d", zone),
+			fmt.Sprintf("Pulkovo 1942 / Gauss-Kruger zone %d", zone),
+			fmt.Sprintf("+proj=tmerc +lat_0=0 +lon_0=%d +k=1 +x_0=%d +y_0=0 +ellps=krass +towgs84=23.92,-141.27,-80.9,0,0.35,0.82,-0.12",
+				6*zone-3, zone*1000000+500000),
+		})
+	}
+	for _, system := range builtin {
+		if err := r.Add(system.code, system.name, system.definition); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// LoadFile adds the systems of a JSON file, an array of objects with code,
+// name and a proj definition. Local MSK systems are configured this way.
+func (r *Registry) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var systems []struct {
+		Code       string `json:"code"`
+		Name       string `json:"name"`
+		Definition string `json:"definition"`
+	}
+	if err := json.Unmarshal(data, &systems); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, system := range systems {
+		if err := r.Add(system.Code, system.Name, system.Definition); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Add parses a proj definition, it takes +proj=longlat or +proj=tmerc with
+// lat_0, lon_0, k, x_0, y_0, ellps or datum and towgs84
+func (r *Registry) Add(code, name, definition string) error {
+	crs, err := ParseProj(definition)
+	if err != nil {
+		return fmt.Errorf("%s: %w", code, err)
+	}
+	crs.Code = strings.ToUpper(strings.TrimSpace(code))
+	crs.Name = name
+	if crs.Code == "" {
+		return fmt.Errorf("%w: code is required", ErrBadDefinition)
+	}
+	r.systems[crs.Code] = crs
+	return nil
+}
+
+// Get returns WGS84 for an empty code
+func (r *Registry) Get(code string) (*CRS, error) {
+	if code == "" {
+		code = WGS84
+	}
+	crs, ok := r.systems[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCRS, code)
+	}
+	return crs, nil
+}
+
+func (r *Registry) All() []*CRS {
+	systems := make([]*CRS, 0, len(r.systems))
+	for _, crs := range r.systems {
+		systems = append(systems, crs)
+	}
+	sort.Slice(systems, func(i, j int) bool {
+		return systems[i].Code < systems[j].Code
+	})
+	return systems
+}
+
+func ParseProj(definition string) (*CRS, error) {
+	params := map[string]string{}
+	for _, field := range strings.Fields(definition) {
+		field = strings.TrimPrefix(field, "+")
+		key, value := field, ""
+		if i := strings.IndexByte(field, '='); i >= 0 {
+			key, value = field[:i], field[i+1:]
+		}
+		params[key] = value
+	}
+
+	datum := &Datum{Ellipsoid: WGS84Ellipsoid}
+	if name, ok := params["ellps"]; ok {
+		ellipsoid, ok := ellipsoidsByProjName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: ellipsoid %s", ErrBadDefinition, name)
+		}
+		datum.Ellipsoid = ellipsoid
+	}
+	if name, ok := params["datum"]; ok && name != "WGS84" {
+		return nil, fmt.Errorf("%w: datum %s, use ellps and towgs84", ErrBadDefinition, name)
+	}
+	if value, ok := params["towgs84"]; ok {
+		parts := strings.Split(value, ",")
+		if len(parts) != 3 && len(parts) != 7 {
+			return nil, fmt.Errorf("%w: towgs84 takes 3 or 7 numbers", ErrBadDefinition)
+		}
+		for i, part := range parts {
+			number, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: towgs84: %s", ErrBadDefinition, err)
+			}
+			datum.ToWGS84[i] = number
+		}
+	}
+	if units, ok := params["units"]; ok && units != "m" {
+		return nil, fmt.Errorf("%w: units %s", ErrBadDefinition, units)
+	}
+
+	crs := &CRS{Definition: strings.TrimSpace(definition), datum: datum}
+	switch params["proj"] {
+	case "longlat", "latlong":
+	case "tmerc", "utm":
+		number := func(key string, fallback float64) (float64, error) {
+			value, ok := params[key]
+			if !ok {
+				return fallback, nil
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0, fmt.Errorf("%w: %s: %s", ErrBadDefinition, key, err)
+			}
+			return parsed, nil
+		}
+		var values [5]float64
+		defaults := [5]float64{0, 0, 1, 0, 0}
+		if params["proj"] == "utm" {
+			zone, err := number("zone", 0)
+			if err != nil || zone < 1 || zone > 60 {
+				return nil, fmt.Errorf("%w: utm needs a zone", ErrBadDefinition)
+			}
+			defaults = [5]float64{0, 6*zone - 183, 0.9996, 500000, 0}
+			if _, south := params["south"]; south {
+				defaults[4] = 10000000
+			}
+		}
+		for i, key := range []string{"lat_0", "lon_0", "k", "x_0", "y_0"} {
+			if key == "k" {
+				if _, ok := params["k"]; !ok {
+					key = "k_0"
+				}
+			}
+			value, err := number(key, defaults[i])
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		crs.projection = NewTransverseMercator(datum.Ellipsoid, values[0], values[1], values[2], values[3], values[4])
+	default:
+		return nil, fmt.Errorf("%w: proj %s", ErrBadDefinition, params["proj"])
+	}
+	return crs, nil
+}
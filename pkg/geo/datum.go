@@ -0,0 +1,123 @@
+package geo
+
+import "math"
+
+// Ellipsoid is given by the semi-major axis in meters and the inverse
+// flattening
+type Ellipsoid struct {
+	A    float64
+	InvF float64
+}
+
+var (
+	WGS84Ellipsoid       = Ellipsoid{A: 6378137, InvF: 298.257223563}
+	GRS80Ellipsoid       = Ellipsoid{A: 6378137, InvF: 298.257222101}
+	KrassowskyEllipsoid  = Ellipsoid{A: 6378245, InvF: 298.3}
+	ellipsoidsByProjName = map[string]Ellipsoid{
+		"WGS84": WGS84Ellipsoid,
+		"GRS80": GRS80Ellipsoid,
+		"krass": KrassowskyEllipsoid,
+	}
+)
+
+func (e Ellipsoid) flattening() float64 {
+	return 1 / e.InvF
+}
+
+func (e Ellipsoid) eccentricitySquared() float64 {
+	f := e.flattening()
+	return f * (2 - f)
+}
+
+// Datum is an ellipsoid and the Helmert transformation from it to WGS84, in
+// the position vector convention of the proj towgs84 parameter: shifts in
+// meters, rotations in arc seconds and scale in parts per million.
+type Datum struct {
+	Ellipsoid Ellipsoid
+	ToWGS84   [7]float64
+}
+
+var (
+	WGS84Datum = &Datum{Ellipsoid: WGS84Ellipsoid}
+	// Pulkovo1942Datum uses the GOST R 51794-2001 parameters, which are what
+	// the SK-42 Gauss-Krüger zones are usually converted with
+	Pulkovo1942Datum = &Datum{
+		Ellipsoid: KrassowskyEllipsoid,
+		ToWGS84:   [7]float64{23.92, -141.27, -80.9, 0, 0.35, 0.82, -0.12},
+	}
+)
+
+func (d *Datum) isWGS84() bool {
+	return d.Ellipsoid == WGS84Ellipsoid && d.ToWGS84 == [7]float64{}
+}
+
+// toWGS84 converts geographic coordinates in degrees on the datum to WGS84,
+// heights are taken as zero
+func (d *Datum) toWGS84(lon, lat float64) (float64, float64) {
+	if d.isWGS84() {
+		return lon, lat
+	}
+	x, y, z := geodeticToCartesian(d.Ellipsoid, lon, lat, 0)
+	x, y, z = helmert(d.ToWGS84, x, y, z, 1)
+	lon, lat, _ = cartesianToGeodetic(WGS84Ellipsoid, x, y, z)
+	return lon, lat
+}
+
+// fromWGS84 reverses the Helmert transformation by negating its parameters,
+// which is exact to well under a millimeter for rotations of a few seconds
+func (d *Datum) fromWGS84(lon, lat float64) (float64, float64) {
+	if d.isWGS84() {
+		return lon, lat
+	}
+	x, y, z := geodeticToCartesian(WGS84Ellipsoid, lon, lat, 0)
+	x, y, z = helmert(d.ToWGS84, x, y, z, -1)
+	lon, lat, _ = cartesianToGeodetic(d.Ellipsoid, x, y, z)
+	return lon, lat
+}
+
+func helmert(p [7]float64, x, y, z, sign float64) (float64, float64, float64) {
+	const arcSecond = math.Pi / (180 * 3600)
+	var (
+		tx, ty, tz = sign * p[0], sign * p[1], sign * p[2]
+		rx, ry, rz = sign * p[3] * arcSecond, sign * p[4] * arcSecond, sign * p[5] * arcSecond
+		m          = 1 + sign*p[6]*1e-6
+	)
+	return tx + m*(x-rz*y+ry*z),
+		ty + m*(rz*x+y-rx*z),
+		tz + m*(-ry*x+rx*y+z)
+}
+
+func geodeticToCartesian(e Ellipsoid, lon, lat, h float64) (float64, float64, float64) {
+	var (
+		phi, lambda = lat * math.Pi / 180, lon * math.Pi / 180
+		e2          = e.eccentricitySquared()
+		sinPhi      = math.Sin(phi)
+		nu          = e.A / math.Sqrt(1-e2*sinPhi*sinPhi)
+	)
+	return (nu + h) * math.Cos(phi) * math.Cos(lambda),
+		(nu + h) * math.Cos(phi) * math.Sin(lambda),
+		((1-e2)*nu + h) * sinPhi
+}
+
+// cartesianToGeodetic iterates the latitude, a few rounds reach a fraction
+// of a millimeter near the surface
+func cartesianToGeodetic(e Ellipsoid, x, y, z float64) (float64, float64, float64) {
+	var (
+		e2  = e.eccentricitySquared()
+		p   = math.Hypot(x, y)
+		phi = math.Atan2(z, p*(1-e2))
+		nu  float64
+	)
+	for i := 0; i < 10; i++ {
+		sinPhi := math.Sin(phi)
+		nu = e.A / math.Sqrt(1-e2*sinPhi*sinPhi)
+		next := math.Atan2(z+e2*nu*sinPhi, p)
+		if math.Abs(next-phi) < 1e-12 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+	h := p/math.Cos(phi) - nu
+	return math.Atan2(y, x) * 180 / math.Pi, phi * 180 / math.Pi, h
+}
@@ -0,0 +1,186 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// overlapEpsilon is how close in meters a point has to be to an edge to be
+// on it. Neighbouring parcels drawn from the same survey share their edges.
+const overlapEpsilon = 1e-6
+
+// OverlapArea returns the area in square meters that two sets of WGS84
+// polygons have in common. Polygons that only touch or share an edge do not
+// overlap.
+//
+// The common part is bounded by the edges of each set that run inside the
+// other, so its area is their sum by the shoelace formula. Edges lying on
+// each other count once when both sets are on the same side of them and
+// not at all otherwise.
+func OverlapArea(a, b [][][][]float64) float64 {
+	boundsA, boundsB := Bounds(a), Bounds(b)
+	if !boundsIntersect(boundsA, boundsB) {
+		return 0
+	}
+	projection := newEqualArea([4]float64{
+		math.Min(boundsA[0], boundsB[0]),
+		math.Min(boundsA[1], boundsB[1]),
+		math.Max(boundsA[2], boundsB[2]),
+		math.Max(boundsA[3], boundsB[3]),
+	})
+	var (
+		planarA = orient(projection.project(a))
+		planarB = orient(projection.project(b))
+		area    = boundaryInside(planarA, planarB, true) + boundaryInside(planarB, planarA, false)
+	)
+	// rounding may leave a tiny negative area for parcels that only touch
+	return math.Max(area, 0)
+}
+
+func boundsIntersect(a, b [4]float64) bool {
+	return a[0] <= b[2] && b[0] <= a[2] && a[1] <= b[3] && b[1] <= a[3]
+}
+
+// orient turns outer rings counterclockwise and holes clockwise, so the
+// polygon is always on the left of its edges
+func orient(polygons [][][][]float64) [][][][]float64 {
+	for _, polygon := range polygons {
+		for i, ring := range polygon {
+			if (i == 0) != (RingArea(ring) > 0) {
+				for l, r := 0, len(ring)-1; l < r; l, r = l+1, r-1 {
+					ring[l], ring[r] = ring[r], ring[l]
+				}
+			}
+		}
+	}
+	return polygons
+}
+
+// boundaryInside sums the shoelace terms of the pieces of the edges of a
+// that are inside b, shared edges running the same way are taken when
+// shared is set
+func boundaryInside(a, b [][][][]float64, shared bool) float64 {
+	var (
+		area    = 0.0
+		boundsB = Bounds(b)
+	)
+	for _, polygon := range a {
+		for _, ring := range polygon {
+			for i := 0; i+1 < len(ring); i++ {
+				p, q := ring[i], ring[i+1]
+				edgeBounds := [4]float64{math.Min(p[0], q[0]), math.Min(p[1], q[1]), math.Max(p[0], q[0]), math.Max(p[1], q[1])}
+				if !boundsIntersect(edgeBounds, boundsB) {
+					continue
+				}
+				cuts := edgeCuts(p, q, b)
+				for j := 0; j+1 < len(cuts); j++ {
+					if cuts[j+1]-cuts[j] < 1e-12 {
+						continue
+					}
+					start := pointAt(p, q, cuts[j])
+					end := pointAt(p, q, cuts[j+1])
+					middle := pointAt(p, q, (cuts[j]+cuts[j+1])/2)
+					switch edgeSide(middle, q[0]-p[0], q[1]-p[1], b) {
+					case 1:
+						if !shared {
+							continue
+						}
+					case 0:
+						if !pointInPolygons(middle, b) {
+							continue
+						}
+					default:
+						continue
+					}
+					area += (start[0]*end[1] - end[0]*start[1]) / 2
+				}
+			}
+		}
+	}
+	return area
+}
+
+// edgeCuts returns where along p to q, from 0 to 1, the edges of b cross or
+// touch it
+func edgeCuts(p, q []float64, b [][][][]float64) []float64 {
+	var (
+		cuts   = []float64{0, 1}
+		dx, dy = q[0] - p[0], q[1] - p[1]
+		length = dx*dx + dy*dy
+	)
+	if length == 0 {
+		return nil
+	}
+	for _, polygon := range b {
+		for _, ring := range polygon {
+			for i := 0; i+1 < len(ring); i++ {
+				r, s := ring[i], ring[i+1]
+				ex, ey := s[0]-r[0], s[1]-r[1]
+				rx, ry := r[0]-p[0], r[1]-p[1]
+				denominator := dx*ey - dy*ex
+				if math.Abs(denominator) > 1e-12*math.Sqrt(length*(ex*ex+ey*ey)) {
+					t := (rx*ey - ry*ex) / denominator
+					u := (rx*dy - ry*dx) / denominator
+					if t > 0 && t < 1 && u >= 0 && u <= 1 {
+						cuts = append(cuts, t)
+					}
+					continue
+				}
+				// parallel edges cut each other only when they are on one
+				// line, where the ends of one split the other
+				if math.Abs(rx*dy-ry*dx)/math.Sqrt(length) > overlapEpsilon {
+					continue
+				}
+				for _, end := range [][]float64{r, s} {
+					t := ((end[0]-p[0])*dx + (end[1]-p[1])*dy) / length
+					if t > 0 && t < 1 {
+						cuts = append(cuts, t)
+					}
+				}
+			}
+		}
+	}
+	sort.Float64s(cuts)
+	return cuts
+}
+
+// edgeSide tells whether the point is on an edge of b running the same way
+// as the direction, 1, the other way, -1, or on none of them, 0
+func edgeSide(point []float64, dx, dy float64, b [][][][]float64) int {
+	for _, polygon := range b {
+		for _, ring := range polygon {
+			for i := 0; i+1 < len(ring); i++ {
+				if distanceToSegment(point, ring[i], ring[i+1]) > overlapEpsilon {
+					continue
+				}
+				if dx*(ring[i+1][0]-ring[i][0])+dy*(ring[i+1][1]-ring[i][1]) > 0 {
+					return 1
+				}
+				return -1
+			}
+		}
+	}
+	return 0
+}
+
+func distanceToSegment(point, p, q []float64) float64 {
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((point[0]-p[0])*dx+(point[1]-p[1])*dy)/length))
+	}
+	return math.Hypot(point[0]-p[0]-t*dx, point[1]-p[1]-t*dy)
+}
+
+func pointAt(p, q []float64, t float64) []float64 {
+	return []float64{p[0] + (q[0]-p[0])*t, p[1] + (q[1]-p[1])*t}
+}
+
+func pointInPolygons(point []float64, polygons [][][][]float64) bool {
+	for _, polygon := range polygons {
+		if PointInPolygon(point[0], point[1], polygon) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Polygons are GeoJSON coordinates: rings of [longitude, latitude]
+// positions, the first ring is the outer one and the others are holes.
+// Positions may carry an altitude, which is ignored.
+
+// MaxRingPositions is plenty for a parcel, MaxAdminRingPositions lets the
+// boundary of a district be as detailed as surveys draw it while keeping it
+// well inside the 16 MB MongoDB document limit
+const (
+	MaxRingPositions      = 10000
+	MaxAdminRingPositions = 250000
+)
+
+var (
+	ErrBadPosition      = errors.New("position must be [longitude, latitude] within range")
+	ErrRingTooShort     = errors.New("ring must have at least four positions")
+	ErrRingTooLong      = errors.New("ring has too many positions")
+	ErrRingNotClosed    = errors.New("ring must end with its first position")
+	ErrDuplicatePoint   = errors.New("ring has the same position twice in a row")
+	ErrSelfIntersection = errors.New("ring intersects itself")
+	ErrRingsIntersect   = errors.New("rings of the polygon intersect")
+	ErrHoleOutside      = errors.New("hole is not inside the outer ring")
+	ErrNoRings          = errors.New("polygon must have an outer ring")
+)
+
+// ValidatePosition checks a [longitude, latitude] position
+func ValidatePosition(position []float64) error {
+	if len(position) < 2 || len(position) > 3 {
+		return ErrBadPosition
+	}
+	lon, lat := position[0], position[1]
+	if math.IsNaN(lon) || math.IsNaN(lat) || math.Abs(lon) > 180 || math.Abs(lat) > 90 {
+		return ErrBadPosition
+	}
+	return nil
+}
+
+// ValidatePolygon checks what MongoDB needs for a 2dsphere index: closed
+// rings of at most maxRingPositions without repeated positions that do not
+// cross themselves or each other, and holes inside the outer ring
+func ValidatePolygon(polygon [][][]float64, maxRingPositions int) error {
+	if len(polygon) == 0 {
+		return ErrNoRings
+	}
+	for i, ring := range polygon {
+		if err := validateRing(ring, maxRingPositions); err != nil {
+			return fmt.Errorf("ring %d: %w", i, err)
+		}
+	}
+	if a, b, found := findCrossing(polygon); found {
+		if a == b {
+			return fmt.Errorf("ring %d: %w", a, ErrSelfIntersection)
+		}
+		if a > b {
+			a, b = b, a
+		}
+		return fmt.Errorf("rings %d and %d: %w", a, b, ErrRingsIntersect)
+	}
+	for i := 1; i < len(polygon); i++ {
+		if !PointInRing(polygon[i][0][0], polygon[i][0][1], polygon[0]) {
+			return fmt.Errorf("ring %d: %w", i, ErrHoleOutside)
+		}
+	}
+	return nil
+}
+
+func validateRing(ring [][]float64, maxPositions int) error {
+	if len(ring) < 4 {
+		return ErrRingTooShort
+	}
+	if len(ring) > maxPositions {
+		return fmt.Errorf("%w, at most %d", ErrRingTooLong, maxPositions)
+	}
+	for _, position := range ring {
+		if err := ValidatePosition(position); err != nil {
+			return err
+		}
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		return ErrRingNotClosed
+	}
+	for i := 1; i < len(ring); i++ {
+		if ring[i][0] == ring[i-1][0] && ring[i][1] == ring[i-1][1] {
+			return ErrDuplicatePoint
+		}
+	}
+	if RingArea(ring) == 0 {
+		return ErrSelfIntersection
+	}
+	return nil
+}
+
+// PointInRing tells whether the point is inside the closed ring by the even
+// odd rule, points on the edge may fall either way
+func PointInRing(lon, lat float64, ring [][]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// PointInPolygon is inside the outer ring and outside every hole
+func PointInPolygon(lon, lat float64, polygon [][][]float64) bool {
+	if len(polygon) == 0 || !PointInRing(lon, lat, polygon[0]) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if PointInRing(lon, lat, hole) {
+			return false
+		}
+	}
+	return true
+}
+
+// RingArea is the signed planar area in square degrees, positive for
+// counterclockwise rings
+func RingArea(ring [][]float64) float64 {
+	area := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+// Bounds returns the [min longitude, min latitude, max longitude, max
+// latitude] of the polygons
+func Bounds(polygons [][][][]float64) [4]float64 {
+	bounds := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			for _, position := range ring {
+				bounds[0] = math.Min(bounds[0], position[0])
+				bounds[1] = math.Min(bounds[1], position[1])
+				bounds[2] = math.Max(bounds[2], position[0])
+				bounds[3] = math.Max(bounds[3], position[1])
+			}
+		}
+	}
+	return bounds
+}
+
+// segmentsIntersect includes touching and collinear overlapping segments
+func segmentsIntersect(p1, p2, q1, q2 []float64) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// overlapping tells whether two edges that share a position run back over
+// each other
+func overlapping(p1, p2, q1, q2 []float64) bool {
+	if orientation(p1, p2, q1) != 0 || orientation(p1, p2, q2) != 0 {
+		return false
+	}
+	shared, pOther, qOther := p2, p1, q2
+	switch {
+	case same(p1, q1):
+		shared, pOther, qOther = p1, p2, q2
+	case same(p1, q2):
+		shared, pOther, qOther = p1, p2, q1
+	case same(p2, q2):
+		shared, pOther, qOther = p2, p1, q1
+	}
+	// on the same line the edges overlap when they leave the shared
+	// position in the same direction
+	return (pOther[0]-shared[0])*(qOther[0]-shared[0])+(pOther[1]-shared[1])*(qOther[1]-shared[1]) > 0
+}
+
+func orientation(a, b, c []float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func onSegment(a, b, p []float64) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
+
+func same(a, b []float64) bool {
+	return a[0] == b[0] && a[1] == b[1]
+}
@@ -0,0 +1,152 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestValidatePolygon(t *testing.T) {
+	square := [][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	tests := []struct {
+		name    string
+		polygon [][][]float64
+		err     error
+	}{
+		{name: "square", polygon: [][][]float64{square}},
+		{name: "clockwise", polygon: [][][]float64{{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}}},
+		{name: "triangle", polygon: [][][]float64{{{0, 0}, {1, 0}, {0, 1}, {0, 0}}}},
+		{name: "with altitude", polygon: [][][]float64{{{0, 0, 5}, {1, 0, 5}, {1, 1, 5}, {0, 0, 5}}}},
+		{
+			name:    "hole",
+			polygon: [][][]float64{square, {{0.2, 0.2}, {0.2, 0.8}, {0.8, 0.8}, {0.8, 0.2}, {0.2, 0.2}}},
+		},
+		{
+			name:    "vertical and horizontal edges",
+			polygon: [][][]float64{{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}, {0, 0}}},
+		},
+		{name: "no rings", err: ErrNoRings},
+		{name: "too short", polygon: [][][]float64{{{0, 0}, {1, 0}, {0, 0}}}, err: ErrRingTooShort},
+		{name: "not closed", polygon: [][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}}}, err: ErrRingNotClosed},
+		{name: "out of range", polygon: [][][]float64{{{0, 0}, {181, 0}, {1, 1}, {0, 0}}}, err: ErrBadPosition},
+		{name: "not a number", polygon: [][][]float64{{{0, 0}, {math.NaN(), 0}, {1, 1}, {0, 0}}}, err: ErrBadPosition},
+		{name: "one coordinate", polygon: [][][]float64{{{0, 0}, {1}, {1, 1}, {0, 0}}}, err: ErrBadPosition},
+		{name: "repeated position", polygon: [][][]float64{{{0, 0}, {1, 0}, {1, 0}, {1, 1}, {0, 0}}}, err: ErrDuplicatePoint},
+		{name: "bow tie", polygon: [][][]float64{{{0, 0}, {1, 1}, {1, 0}, {0, 1}, {0, 0}}}, err: ErrSelfIntersection},
+		{name: "spike back over an edge", polygon: [][][]float64{{{0, 0}, {2, 0}, {1, 0}, {1, 1}, {0, 0}}}, err: ErrSelfIntersection},
+		{name: "flat", polygon: [][][]float64{{{0, 0}, {1, 0}, {2, 0}, {0, 0}}}, err: ErrSelfIntersection},
+		{
+			name:    "touches itself",
+			polygon: [][][]float64{{{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 1}, {0, 0}}},
+			err:     ErrSelfIntersection,
+		},
+		{
+			name:    "vertex on a vertical edge",
+			polygon: [][][]float64{{{0, 0}, {2, 0}, {2, 2}, {1, 2}, {2, 1}, {1, 0.5}, {0, 2}, {0, 0}}},
+			err:     ErrSelfIntersection,
+		},
+		{
+			name:    "hole crossing the outer ring",
+			polygon: [][][]float64{square, {{0.5, 0.5}, {1.5, 0.5}, {1.5, 0.8}, {0.5, 0.5}}},
+			err:     ErrRingsIntersect,
+		},
+		{
+			name:    "hole outside",
+			polygon: [][][]float64{square, {{2, 2}, {3, 2}, {3, 3}, {2, 2}}},
+			err:     ErrHoleOutside,
+		},
+		{
+			name:    "holes crossing",
+			polygon: [][][]float64{square, {{0.1, 0.1}, {0.6, 0.1}, {0.6, 0.6}, {0.1, 0.1}}, {{0.3, 0.2}, {0.9, 0.2}, {0.9, 0.9}, {0.3, 0.2}}},
+			err:     ErrRingsIntersect,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePolygon(tt.polygon, MaxRingPositions)
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidatePolygonLimit(t *testing.T) {
+	ring := circle(0, 0, 1, 20000)
+	if err := ValidatePolygon([][][]float64{ring}, MaxRingPositions); !errors.Is(err, ErrRingTooLong) {
+		t.Fatalf("got %v, want %v", err, ErrRingTooLong)
+	}
+	if err := ValidatePolygon([][][]float64{ring}, MaxAdminRingPositions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestValidatePolygonLarge is the size of a detailed district boundary, the
+// sweep has to check it well within a request
+func TestValidatePolygonLarge(t *testing.T) {
+	ring := circle(69.2, 41.3, 0.5, MaxAdminRingPositions-1)
+	start := time.Now()
+	if err := ValidatePolygon([][][]float64{ring}, MaxAdminRingPositions); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("took %s", elapsed)
+	}
+
+	// a position moved across the ring makes it cross itself
+	ring[len(ring)/2] = []float64{69.2 + 0.6, 41.3}
+	if err := ValidatePolygon([][][]float64{ring}, MaxAdminRingPositions); !errors.Is(err, ErrSelfIntersection) {
+		t.Fatalf("got %v, want %v", err, ErrSelfIntersection)
+	}
+}
+
+// TestFindCrossing compares the sweep with comparing every edge with every
+// other on random rings, most of which cross themselves
+func TestFindCrossing(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for n := 0; n < 3000; n++ {
+		positions := 3 + random.Intn(8)
+		ring := make([][]float64, 0, positions+1)
+		for i := 0; i < positions; i++ {
+			// a small grid gives touching and collinear edges
+			ring = append(ring, []float64{float64(random.Intn(5)), float64(random.Intn(5))})
+		}
+		ring = append(ring, ring[0])
+		if validateRing(ring, MaxRingPositions) == ErrDuplicatePoint {
+			continue
+		}
+		_, _, got := findCrossing([][][]float64{ring})
+		if want := crossesPairwise(ring); got != want {
+			t.Fatalf("%v: sweep %t, pairwise %t", ring, got, want)
+		}
+	}
+}
+
+func crossesPairwise(ring [][]float64) bool {
+	edges := len(ring) - 1
+	for i := 0; i < edges; i++ {
+		for j := i + 1; j < edges; j++ {
+			if j == i+1 || (i == 0 && j == edges-1) {
+				if edges > 3 && overlapping(ring[i], ring[i+1], ring[j], ring[j+1]) {
+					return true
+				}
+				continue
+			}
+			if segmentsIntersect(ring[i], ring[i+1], ring[j], ring[j+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func circle(lon, lat, radius float64, positions int) [][]float64 {
+	ring := make([][]float64, 0, positions+1)
+	for i := 0; i < positions; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(positions)
+		ring = append(ring, []float64{lon + radius*math.Cos(angle), lat + radius*math.Sin(angle)})
+	}
+	return append(ring, ring[0])
+}
@@ -0,0 +1,47 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SVG draws WGS84 polygons into a square image of the given size for a
+// preview. Longitudes are shortened by the cosine of the latitude so shapes
+// keep their proportions, holes are left empty.
+func SVG(polygons [][][][]float64, size int) string {
+	bounds := Bounds(polygons)
+	for _, value := range bounds {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return ""
+		}
+	}
+	var (
+		xScale = math.Cos((bounds[1] + bounds[3]) / 2 * math.Pi / 180)
+		width  = (bounds[2] - bounds[0]) * xScale
+		height = bounds[3] - bounds[1]
+		// a margin of one pixel keeps the stroke inside
+		inner = float64(size - 2)
+		scale = inner / math.Max(math.Max(width, height), 1e-12)
+		left  = 1 + (inner-width*scale)/2
+		top   = 1 + (inner-height*scale)/2
+		path  strings.Builder
+	)
+	for _, polygon := range polygons {
+		for _, ring := range polygon {
+			for i, position := range ring {
+				command := "L"
+				if i == 0 {
+					command = "M"
+				}
+				x := left + (position[0]-bounds[0])*xScale*scale
+				y := top + (bounds[3]-position[1])*scale
+				fmt.Fprintf(&path, "%s%.1f %.1f", command, x, y)
+			}
+			path.WriteString("Z")
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+
+		`<path d="%s" fill="#3b82f6" fill-opacity="0.3" fill-rule="evenodd" stroke="#1d4ed8" stroke-width="1"/></svg>`,
+		size, size, size, size, path.String())
+}
@@ -0,0 +1,127 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// sweepEdge is an edge of a polygon ring with its west end first
+type sweepEdge struct {
+	west, east []float64
+	ring       int
+	index      int
+	ringEdges  int
+}
+
+type sweepEvent struct {
+	point []float64
+	edge  *sweepEdge
+	start bool
+}
+
+// findCrossing sweeps the edges of all rings of the polygon from west to
+// east (Shamos and Hoey), comparing each edge only with its neighbours on
+// the sweep line, so a polygon of n positions is checked in O(n log n)
+// rather than by comparing every edge with every other. Edges of a ring that
+// follow each other share a position and only count when they run back over
+// each other. It returns the rings of the first crossing found.
+func findCrossing(polygon [][][]float64) (ringA, ringB int, found bool) {
+	var events []sweepEvent
+	for r, ring := range polygon {
+		edges := len(ring) - 1
+		for i := 0; i < edges; i++ {
+			edge := &sweepEdge{west: ring[i], east: ring[i+1], ring: r, index: i, ringEdges: edges}
+			if less(edge.east, edge.west) {
+				edge.west, edge.east = edge.east, edge.west
+			}
+			events = append(events,
+				sweepEvent{point: edge.west, edge: edge, start: true},
+				sweepEvent{point: edge.east, edge: edge})
+		}
+	}
+	// edges starting at a position are added before the ones ending there
+	// are removed, so edges touching at a position meet on the sweep line
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.point[0] != b.point[0] || a.point[1] != b.point[1] {
+			return less(a.point, b.point)
+		}
+		return a.start && !b.start
+	})
+
+	var status []*sweepEdge
+	for _, event := range events {
+		if event.start {
+			edge := event.edge
+			i := sort.Search(len(status), func(i int) bool { return below(edge, status[i]) })
+			status = append(status, nil)
+			copy(status[i+1:], status[i:])
+			status[i] = edge
+			if i > 0 && crosses(status[i-1], edge) {
+				return status[i-1].ring, edge.ring, true
+			}
+			if i+1 < len(status) && crosses(edge, status[i+1]) {
+				return edge.ring, status[i+1].ring, true
+			}
+			continue
+		}
+		i := 0
+		for i < len(status) && status[i] != event.edge {
+			i++
+		}
+		if i == len(status) {
+			continue
+		}
+		status = append(status[:i], status[i+1:]...)
+		if i > 0 && i < len(status) && crosses(status[i-1], status[i]) {
+			return status[i-1].ring, status[i].ring, true
+		}
+	}
+	return 0, 0, false
+}
+
+// below orders a starting edge against one on the sweep line by where they
+// are at the longitude it starts, then by which way they go from there
+func below(edge, other *sweepEdge) bool {
+	y := yAt(other, edge.west[0])
+	if edge.west[1] != y {
+		return edge.west[1] < y
+	}
+	return slope(edge) < slope(other)
+}
+
+func yAt(edge *sweepEdge, x float64) float64 {
+	if edge.west[0] == edge.east[0] {
+		return edge.west[1]
+	}
+	return edge.west[1] + (edge.east[1]-edge.west[1])*(x-edge.west[0])/(edge.east[0]-edge.west[0])
+}
+
+func slope(edge *sweepEdge) float64 {
+	dx := edge.east[0] - edge.west[0]
+	if dx == 0 {
+		return math.Inf(1)
+	}
+	return (edge.east[1] - edge.west[1]) / dx
+}
+
+func crosses(a, b *sweepEdge) bool {
+	if a.ring == b.ring && adjacent(a, b) {
+		return a.ringEdges > 3 && overlapping(a.west, a.east, b.west, b.east)
+	}
+	return segmentsIntersect(a.west, a.east, b.west, b.east)
+}
+
+// adjacent edges follow each other, the last edge ends where the first
+// starts
+func adjacent(a, b *sweepEdge) bool {
+	i, j := a.index, b.index
+	if i > j {
+		i, j = j, i
+	}
+	return j == i+1 || (i == 0 && j == a.ringEdges-1)
+}
+
+func less(a, b []float64) bool {
+	return a[0] < b[0] || (a[0] == b[0] && a[1] < b[1])
+}
@@ -0,0 +1,91 @@
+package geo
+
+import "math"
+
+// TransverseMercator is the projection of Gauss-Krüger zones, UTM and most
+// local MSK systems. Angles are in degrees, distances in meters.
+type TransverseMercator struct {
+	LatOrigin       float64
+	CentralMeridian float64
+	ScaleFactor     float64
+	FalseEasting    float64
+	FalseNorthing   float64
+
+	// Krüger series to the third order of n, good to a millimeter within a
+	// few thousand kilometers of the central meridian
+	alpha, beta, delta                   [3]float64
+	eccentricity, radius, northingOrigin float64
+}
+
+func NewTransverseMercator(ellipsoid Ellipsoid, latOrigin, centralMeridian, scaleFactor, falseEasting, falseNorthing float64) *TransverseMercator {
+	var (
+		f  = ellipsoid.flattening()
+		n  = f / (2 - f)
+		n2 = n * n
+		n3 = n2 * n
+	)
+	tm := &TransverseMercator{
+		LatOrigin:       latOrigin,
+		CentralMeridian: centralMeridian,
+		ScaleFactor:     scaleFactor,
+		FalseEasting:    falseEasting,
+		FalseNorthing:   falseNorthing,
+		alpha:           [3]float64{n/2 - 2*n2/3 + 5*n3/16, 13*n2/48 - 3*n3/5, 61 * n3 / 240},
+		beta:            [3]float64{n/2 - 2*n2/3 + 37*n3/96, n2/48 + n3/15, 17 * n3 / 480},
+		delta:           [3]float64{2*n - 2*n2/3 - 2*n3, 7*n2/3 - 8*n3/5, 56 * n3 / 15},
+		radius:          ellipsoid.A / (1 + n) * (1 + n2/4 + n2*n2/64),
+	}
+	tm.eccentricity = 2 * math.Sqrt(n) / (1 + n)
+	// the northing of the origin latitude on the central meridian
+	_, tm.northingOrigin = tm.project(centralMeridian, latOrigin)
+	return tm
+}
+
+// Forward projects longitude and latitude to easting and northing
+func (tm *TransverseMercator) Forward(lon, lat float64) (float64, float64) {
+	x, y := tm.project(lon, lat)
+	return tm.FalseEasting + x, tm.FalseNorthing + y - tm.northingOrigin
+}
+
+func (tm *TransverseMercator) project(lon, lat float64) (float64, float64) {
+	var (
+		phi    = lat * math.Pi / 180
+		lambda = (lon - tm.CentralMeridian) * math.Pi / 180
+		e      = tm.eccentricity
+		sinPhi = math.Sin(phi)
+		t      = math.Sinh(math.Atanh(sinPhi) - e*math.Atanh(e*sinPhi))
+		xi     = math.Atan2(t, math.Cos(lambda))
+		eta    = math.Atanh(math.Sin(lambda) / math.Sqrt(1+t*t))
+		x, y   = eta, xi
+	)
+	for j, alpha := range tm.alpha {
+		k := 2 * float64(j+1)
+		x += alpha * math.Cos(k*xi) * math.Sinh(k*eta)
+		y += alpha * math.Sin(k*xi) * math.Cosh(k*eta)
+	}
+	scale := tm.ScaleFactor * tm.radius
+	return scale * x, scale * y
+}
+
+// Inverse returns the longitude and latitude of easting and northing
+func (tm *TransverseMercator) Inverse(easting, northing float64) (float64, float64) {
+	var (
+		scale = tm.ScaleFactor * tm.radius
+		xi    = (northing - tm.FalseNorthing + tm.northingOrigin) / scale
+		eta   = (easting - tm.FalseEasting) / scale
+		xi1   = xi
+		eta1  = eta
+	)
+	for j, beta := range tm.beta {
+		k := 2 * float64(j+1)
+		xi1 -= beta * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta1 -= beta * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	chi := math.Asin(math.Sin(xi1) / math.Cosh(eta1))
+	phi := chi
+	for j, delta := range tm.delta {
+		phi += delta * math.Sin(2*float64(j+1)*chi)
+	}
+	lambda := math.Atan2(math.Sinh(eta1), math.Cos(xi1))
+	return tm.CentralMeridian + lambda*180/math.Pi, phi * 180 / math.Pi
+}
@@ -0,0 +1,94 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// The examples are from IOGP Guidance Note 7-2, Coordinate Conversions and
+// Transformations including Formulas
+
+func TestTransverseMercator(t *testing.T) {
+	// British National Grid on Airy 1830
+	airy := Ellipsoid{A: 6377563.396, InvF: 299.3249646}
+	tm := NewTransverseMercator(airy, 49, -2, 0.9996012717, 400000, -100000)
+	lon, lat := dms(0, 30, 0), dms(50, 30, 0)
+
+	easting, northing := tm.Forward(lon, lat)
+	if math.Abs(easting-577274.99) > 0.01 || math.Abs(northing-69740.50) > 0.01 {
+		t.Fatalf("got %.3f %.3f, want 577274.99 69740.50", easting, northing)
+	}
+	gotLon, gotLat := tm.Inverse(577274.99, 69740.50)
+	if math.Abs(gotLon-lon) > 1e-7 || math.Abs(gotLat-lat) > 1e-7 {
+		t.Fatalf("got %.9f %.9f, want %.9f %.9f", gotLon, gotLat, lon, lat)
+	}
+}
+
+func TestMeridianQuadrant(t *testing.T) {
+	// the meridian distance from the equator to the pole of WGS84
+	tm := NewTransverseMercator(WGS84Ellipsoid, 0, 0, 1, 0, 0)
+	if _, northing := tm.Forward(0, 90); math.Abs(northing-10001965.729) > 0.001 {
+		t.Fatalf("got %.4f, want 10001965.729", northing)
+	}
+}
+
+func TestGeodeticToCartesian(t *testing.T) {
+	lon, lat := dms(2, 7, 46.38), dms(53, 48, 33.82)
+	x, y, z := geodeticToCartesian(WGS84Ellipsoid, lon, lat, 73)
+	if math.Abs(x-3771793.968) > 0.001 || math.Abs(y-140253.342) > 0.001 || math.Abs(z-5124304.349) > 0.001 {
+		t.Fatalf("got %.3f %.3f %.3f", x, y, z)
+	}
+	gotLon, gotLat, h := cartesianToGeodetic(WGS84Ellipsoid, x, y, z)
+	if math.Abs(gotLon-lon) > 1e-9 || math.Abs(gotLat-lat) > 1e-9 || math.Abs(h-73) > 0.001 {
+		t.Fatalf("got %.10f %.10f %.4f", gotLon, gotLat, h)
+	}
+}
+
+func TestHelmert(t *testing.T) {
+	// WGS 72 to WGS 84 in the position vector convention
+	p := [7]float64{0, 0, 4.5, 0, 0, 0.554, 0.219}
+	x, y, z := helmert(p, 3657660.66, 255768.55, 5201382.11, 1)
+	if math.Abs(x-3657660.78) > 0.01 || math.Abs(y-255778.43) > 0.01 || math.Abs(z-5201387.75) > 0.01 {
+		t.Fatalf("got %.3f %.3f %.3f", x, y, z)
+	}
+}
+
+// TestGaussKruger checks the zones of NewRegistry: the central meridian gets
+// the zone number in front of 500 km, and points of Uzbekistan come back
+// where they were
+func TestGaussKruger(t *testing.T) {
+	registry := NewRegistry()
+	points := [][2]float64{
+		{69.2401, 41.2995}, // Tashkent
+		{66.9597, 39.6542}, // Samarkand
+		{59.6103, 42.4531}, // Nukus
+		{71.7843, 40.3834}, // Fergana
+	}
+	for zone := 10; zone <= 14; zone++ {
+		code := fmt.Sprintf("EPSG:284%02d", zone)
+		crs, err := registry.Get(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		centralMeridian := float64(6*zone - 3)
+		easting, northing := crs.projection.Forward(centralMeridian, 0)
+		if want := float64(zone)*1e6 + 500000; math.Abs(easting-want) > 1e-6 || math.Abs(northing) > 1e-6 {
+			t.Errorf("%s: origin at %.6f %.6f, want %.0f 0", code, easting, northing, want)
+		}
+
+		for _, point := range points {
+			x, y := crs.FromWGS84(point[0], point[1])
+			lon, lat := crs.ToWGS84(x, y)
+			// a millimeter is about 1e-8 degrees
+			if math.Abs(lon-point[0]) > 1e-8 || math.Abs(lat-point[1]) > 1e-8 {
+				t.Errorf("%s: %v came back as %.10f %.10f", code, point, lon, lat)
+			}
+		}
+	}
+}
+
+func dms(degrees, minutes, seconds float64) float64 {
+	return degrees + minutes/60 + seconds/3600
+}
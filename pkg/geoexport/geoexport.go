@@ -0,0 +1,97 @@
+// Package geoexport writes features for GIS tools: GeoJSON, Google Earth KML
+// and OGC GeoPackage. Features are written one by one as they are read, so
+// exports of any size take little memory. Coordinates are WGS84.
+package geoexport
+
+import (
+	"errors"
+	"math"
+)
+
+// Formats the export is available in
+const (
+	FormatGeoJSON    = "geojson"
+	FormatKML        = "kml"
+	FormatGeoPackage = "gpkg"
+)
+
+// Geometry types of features
+const (
+	Point        = "Point"
+	Polygon      = "Polygon"
+	MultiPolygon = "MultiPolygon"
+)
+
+var ErrUnsupportedFormat = errors.New("format must be geojson, kml or gpkg")
+
+// Feature is one row of the export. Type is empty for features without a
+// geometry, Polygons are GeoJSON MultiPolygon coordinates for both polygon
+// types. Properties are the values of the fields of the writer in order.
+type Feature struct {
+	Name       string
+	Type       string
+	Point      []float64
+	Polygons   [][][][]float64
+	Properties []string
+}
+
+// Writer writes features, Close ends the document. Nothing is written
+// before the first feature or Close, so an export failing early can still
+// answer with an error.
+type Writer interface {
+	Write(feature *Feature) error
+	Close() error
+}
+
+// ContentType and Extension tell how a format is served
+func ContentType(format string) string {
+	switch format {
+	case FormatGeoJSON:
+		return "application/geo+json"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	case FormatGeoPackage:
+		return "application/geopackage+sqlite3"
+	}
+	return ""
+}
+
+func Extension(format string) string {
+	switch format {
+	case FormatGeoJSON:
+		return ".geojson"
+	case FormatKML:
+		return ".kml"
+	case FormatGeoPackage:
+		return ".gpkg"
+	}
+	return ""
+}
+
+// bounds returns the [min x, min y, max x, max y] of the geometry, NaN
+// without one
+func (f *Feature) bounds() [4]float64 {
+	bounds := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	extend := func(position []float64) {
+		bounds[0] = math.Min(bounds[0], position[0])
+		bounds[1] = math.Min(bounds[1], position[1])
+		bounds[2] = math.Max(bounds[2], position[0])
+		bounds[3] = math.Max(bounds[3], position[1])
+	}
+	switch f.Type {
+	case Point:
+		extend(f.Point)
+	case Polygon, MultiPolygon:
+		for _, polygon := range f.Polygons {
+			for _, ring := range polygon {
+				for _, position := range ring {
+					extend(position)
+				}
+			}
+		}
+	}
+	if bounds[0] > bounds[2] {
+		return [4]float64{math.NaN(), math.NaN(), math.NaN(), math.NaN()}
+	}
+	return bounds
+}
@@ -0,0 +1,99 @@
+package geoexport
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type geoJSONWriter struct {
+	w       io.Writer
+	fields  []string
+	started bool
+}
+
+// NewGeoJSON writes a FeatureCollection, the properties of a feature keep
+// the order of the fields
+func NewGeoJSON(w io.Writer, fields []string) Writer {
+	return &geoJSONWriter{w: w, fields: fields}
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func (g *geoJSONWriter) Write(feature *Feature) error {
+	var buf []byte
+	if !g.started {
+		buf = append(buf, `{"type":"FeatureCollection","features":[`...)
+		g.started = true
+	} else {
+		buf = append(buf, ',')
+	}
+
+	id, err := json.Marshal(feature.Name)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, `{"type":"Feature","id":`...)
+	buf = append(buf, id...)
+	buf = append(buf, `,"geometry":`...)
+	geometry, err := json.Marshal(geoJSONGeometryOf(feature))
+	if err != nil {
+		return err
+	}
+	buf = append(buf, geometry...)
+
+	buf = append(buf, `,"properties":{`...)
+	for i, field := range g.fields {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, key...)
+		buf = append(buf, ':')
+		var value []byte
+		if i < len(feature.Properties) {
+			value, err = json.Marshal(feature.Properties[i])
+		} else {
+			value, err = json.Marshal(nil)
+		}
+		if err != nil {
+			return err
+		}
+		buf = append(buf, value...)
+	}
+	buf = append(buf, "}}\n"...)
+
+	_, err = g.w.Write(buf)
+	return err
+}
+
+func (g *geoJSONWriter) Close() error {
+	if !g.started {
+		_, err := io.WriteString(g.w, `{"type":"FeatureCollection","features":[]}`)
+		return err
+	}
+	_, err := io.WriteString(g.w, "]}")
+	return err
+}
+
+// geoJSONGeometryOf is nil for features without a geometry, which GeoJSON
+// writes as null
+func geoJSONGeometryOf(feature *Feature) *geoJSONGeometry {
+	switch feature.Type {
+	case Point:
+		return &geoJSONGeometry{Type: Point, Coordinates: feature.Point}
+	case Polygon:
+		if len(feature.Polygons) == 1 {
+			return &geoJSONGeometry{Type: Polygon, Coordinates: feature.Polygons[0]}
+		}
+		return &geoJSONGeometry{Type: MultiPolygon, Coordinates: feature.Polygons}
+	case MultiPolygon:
+		return &geoJSONGeometry{Type: MultiPolygon, Coordinates: feature.Polygons}
+	}
+	return nil
+}
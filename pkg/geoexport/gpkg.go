@@ -0,0 +1,231 @@
+package geoexport
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// GeoPackage 1.2, https://www.geopackage.org/spec120/
+const (
+	gpkgApplicationID = 0x47504B47 // GPKG
+	gpkgUserVersion   = 10200
+	gpkgSRS           = 4326
+	gpkgGeometry      = "geom"
+)
+
+const wgs84WKT = `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]]`
+
+// the tables every GeoPackage has, as the specification creates them
+const (
+	gpkgSpatialRefSysSQL   = `CREATE TABLE gpkg_spatial_ref_sys (srs_name TEXT NOT NULL, srs_id INTEGER NOT NULL PRIMARY KEY, organization TEXT NOT NULL, organization_coordsys_id INTEGER NOT NULL, definition  TEXT NOT NULL, description TEXT)`
+	gpkgContentsSQL        = `CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT UNIQUE, description TEXT DEFAULT '', last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')), min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER, CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id))`
+	gpkgGeometryColumnsSQL = `CREATE TABLE gpkg_geometry_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, geometry_type_name TEXT NOT NULL, srs_id INTEGER NOT NULL, z TINYINT NOT NULL, m TINYINT NOT NULL, CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name), CONSTRAINT uk_gc_table_name UNIQUE (table_name), CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name), CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id))`
+)
+
+type geoPackageWriter struct {
+	db       *sqliteWriter
+	table    string
+	fields   []string
+	features *sqliteTable
+	rows     int64
+	bounds   [4]float64
+}
+
+// NewGeoPackage writes a GeoPackage with one feature table. The file is
+// written out of order, so it cannot be streamed and has to be sent once
+// Close returns.
+func NewGeoPackage(file io.WriterAt, table string, fields []string) Writer {
+	db := newSQLiteWriter(file)
+	db.applicationID = gpkgApplicationID
+	db.userVersion = gpkgUserVersion
+	return &geoPackageWriter{
+		db:       db,
+		table:    table,
+		fields:   fields,
+		features: db.newTable(),
+		bounds:   [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)},
+	}
+}
+
+func (g *geoPackageWriter) Write(feature *Feature) error {
+	// the fid column is the rowid, which records keep as null
+	values := make([]interface{}, 0, len(g.fields)+2)
+	values = append(values, nil)
+	if geometry := gpkgGeometryBlob(feature); geometry != nil {
+		values = append(values, geometry)
+		bounds := feature.bounds()
+		g.bounds[0] = math.Min(g.bounds[0], bounds[0])
+		g.bounds[1] = math.Min(g.bounds[1], bounds[1])
+		g.bounds[2] = math.Max(g.bounds[2], bounds[2])
+		g.bounds[3] = math.Max(g.bounds[3], bounds[3])
+	} else {
+		values = append(values, nil)
+	}
+	for i := range g.fields {
+		if i < len(feature.Properties) {
+			values = append(values, feature.Properties[i])
+		} else {
+			values = append(values, nil)
+		}
+	}
+	g.rows++
+	return g.features.insert(g.rows, sqliteRecord(values...))
+}
+
+func (g *geoPackageWriter) Close() error {
+	featuresRoot, err := g.features.finish(0)
+	if err != nil {
+		return err
+	}
+
+	srs := g.db.newTable()
+	for _, row := range []struct {
+		id                int64
+		name, org, wkt, d string
+	}{
+		{-1, "Undefined cartesian SRS", "NONE", "undefined", "undefined cartesian coordinate reference system"},
+		{0, "Undefined geographic SRS", "NONE", "undefined", "undefined geographic coordinate reference system"},
+		{gpkgSRS, "WGS 84 geodetic", "EPSG", wgs84WKT, "longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid"},
+	} {
+		if err := srs.insert(row.id, sqliteRecord(row.name, nil, row.org, row.id, row.wkt, row.d)); err != nil {
+			return err
+		}
+	}
+	srsRoot, err := srs.finish(0)
+	if err != nil {
+		return err
+	}
+
+	// an empty export has no extent
+	bounds := []interface{}{nil, nil, nil, nil}
+	if g.bounds[0] <= g.bounds[2] {
+		bounds = []interface{}{g.bounds[0], g.bounds[1], g.bounds[2], g.bounds[3]}
+	}
+	contents := g.db.newTable()
+	lastChange := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	err = contents.insert(1, sqliteRecord(g.table, "features", g.table, "", lastChange,
+		bounds[0], bounds[1], bounds[2], bounds[3], int64(gpkgSRS)))
+	if err != nil {
+		return err
+	}
+	contentsRoot, err := contents.finish(0)
+	if err != nil {
+		return err
+	}
+	contentsTableIndex, err := g.db.writeIndex(sqliteRecord(g.table, int64(1)))
+	if err != nil {
+		return err
+	}
+	contentsIdentifierIndex, err := g.db.writeIndex(sqliteRecord(g.table, int64(1)))
+	if err != nil {
+		return err
+	}
+
+	columns := g.db.newTable()
+	err = columns.insert(1, sqliteRecord(g.table, gpkgGeometry, "GEOMETRY", int64(gpkgSRS), int64(0), int64(0)))
+	if err != nil {
+		return err
+	}
+	columnsRoot, err := columns.finish(0)
+	if err != nil {
+		return err
+	}
+	columnsKeyIndex, err := g.db.writeIndex(sqliteRecord(g.table, gpkgGeometry, int64(1)))
+	if err != nil {
+		return err
+	}
+	columnsTableIndex, err := g.db.writeIndex(sqliteRecord(g.table, int64(1)))
+	if err != nil {
+		return err
+	}
+
+	g.db.addSchema("table", "gpkg_spatial_ref_sys", "gpkg_spatial_ref_sys", srsRoot, gpkgSpatialRefSysSQL)
+	g.db.addSchema("table", "gpkg_contents", "gpkg_contents", contentsRoot, gpkgContentsSQL)
+	g.db.addSchema("index", "sqlite_autoindex_gpkg_contents_1", "gpkg_contents", contentsTableIndex, "")
+	g.db.addSchema("index", "sqlite_autoindex_gpkg_contents_2", "gpkg_contents", contentsIdentifierIndex, "")
+	g.db.addSchema("table", "gpkg_geometry_columns", "gpkg_geometry_columns", columnsRoot, gpkgGeometryColumnsSQL)
+	g.db.addSchema("index", "sqlite_autoindex_gpkg_geometry_columns_1", "gpkg_geometry_columns", columnsKeyIndex, "")
+	g.db.addSchema("index", "sqlite_autoindex_gpkg_geometry_columns_2", "gpkg_geometry_columns", columnsTableIndex, "")
+	g.db.addSchema("table", g.table, g.table, featuresRoot, g.featuresSQL())
+	return g.db.finish()
+}
+
+func (g *geoPackageWriter) featuresSQL() string {
+	var sql strings.Builder
+	sql.WriteString("CREATE TABLE " + quoteIdentifier(g.table) + " (\"fid\" INTEGER PRIMARY KEY, " +
+		quoteIdentifier(gpkgGeometry) + " GEOMETRY")
+	for _, field := range g.fields {
+		sql.WriteString(", " + quoteIdentifier(field) + " TEXT")
+	}
+	sql.WriteString(")")
+	return sql.String()
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// gpkgGeometryBlob is the GeoPackage binary header with the envelope
+// followed by little endian WKB, nil without a geometry
+func gpkgGeometryBlob(feature *Feature) []byte {
+	if feature.Type == "" {
+		return nil
+	}
+	bounds := feature.bounds()
+	if math.IsNaN(bounds[0]) {
+		return nil
+	}
+	blob := []byte{'G', 'P', 0, 0x03} // version 1, little endian, xy envelope
+	blob = appendUint32(blob, gpkgSRS)
+	for _, value := range []float64{bounds[0], bounds[2], bounds[1], bounds[3]} {
+		blob = appendFloat64(blob, value)
+	}
+
+	switch feature.Type {
+	case Point:
+		blob = append(blob, 1)
+		blob = appendUint32(blob, 1)
+		blob = appendFloat64(blob, feature.Point[0])
+		blob = appendFloat64(blob, feature.Point[1])
+	default:
+		if len(feature.Polygons) == 1 {
+			return appendWKBPolygon(blob, feature.Polygons[0])
+		}
+		blob = append(blob, 1)
+		blob = appendUint32(blob, 6)
+		blob = appendUint32(blob, uint32(len(feature.Polygons)))
+		for _, polygon := range feature.Polygons {
+			blob = appendWKBPolygon(blob, polygon)
+		}
+	}
+	return blob
+}
+
+func appendWKBPolygon(blob []byte, polygon [][][]float64) []byte {
+	blob = append(blob, 1)
+	blob = appendUint32(blob, 3)
+	blob = appendUint32(blob, uint32(len(polygon)))
+	for _, ring := range polygon {
+		blob = appendUint32(blob, uint32(len(ring)))
+		for _, position := range ring {
+			blob = appendFloat64(blob, position[0])
+			blob = appendFloat64(blob, position[1])
+		}
+	}
+	return blob
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendFloat64(buf []byte, v float64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,417 @@
+package geoexport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGeoPackage reads the file back the way SQLite does: every b-tree from
+// the roots in sqlite_master, the overflow chains, and every page of the file
+// used by exactly one of them
+func TestGeoPackage(t *testing.T) {
+	tests := []struct {
+		name     string
+		features int
+		// noteSize makes records overflow their page
+		noteSize int
+	}{
+		{name: "empty", features: 0},
+		{name: "one page", features: 3},
+		{name: "overflow", features: 40, noteSize: 9000},
+		{name: "two interior levels", features: 700, noteSize: 3000},
+		{name: "many rows", features: 20000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var file fileBuffer
+			writer := NewGeoPackage(&file, "entities", []string{"name", "note"})
+			features := make([]*Feature, tt.features)
+			for i := range features {
+				features[i] = testFeature(i, tt.noteSize)
+				if err := writer.Write(features[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			db := readSQLite(t, file)
+			master := db.table(1)
+			tables := map[string]uint32{}
+			for _, row := range master {
+				if row.values[0] == "table" {
+					tables[row.values[1].(string)] = uint32(row.values[3].(int64))
+				} else {
+					db.index(uint32(row.values[3].(int64)))
+				}
+			}
+			for _, name := range []string{"gpkg_spatial_ref_sys", "gpkg_contents", "gpkg_geometry_columns", "entities"} {
+				if tables[name] == 0 {
+					t.Fatalf("table %s is missing", name)
+				}
+			}
+
+			srs := db.table(tables["gpkg_spatial_ref_sys"])
+			if len(srs) != 3 || srs[2].rowid != gpkgSRS || srs[2].values[2] != "EPSG" {
+				t.Fatalf("gpkg_spatial_ref_sys is %v", srs)
+			}
+			columns := db.table(tables["gpkg_geometry_columns"])
+			if want := []interface{}{"entities", "geom", "GEOMETRY", int64(gpkgSRS), int64(0), int64(0)}; len(columns) != 1 || !reflect.DeepEqual(columns[0].values, want) {
+				t.Fatalf("gpkg_geometry_columns is %v", columns)
+			}
+			contents := db.table(tables["gpkg_contents"])
+			if len(contents) != 1 || contents[0].values[0] != "entities" || contents[0].values[1] != "features" {
+				t.Fatalf("gpkg_contents is %v", contents)
+			}
+			extent := []interface{}{nil, nil, nil, nil}
+			for _, feature := range features {
+				if feature.Type == "" {
+					continue
+				}
+				bounds := feature.bounds()
+				if extent[0] == nil {
+					extent = []interface{}{bounds[0], bounds[1], bounds[2], bounds[3]}
+				}
+				extent[0] = math.Min(extent[0].(float64), bounds[0])
+				extent[1] = math.Min(extent[1].(float64), bounds[1])
+				extent[2] = math.Max(extent[2].(float64), bounds[2])
+				extent[3] = math.Max(extent[3].(float64), bounds[3])
+			}
+			if !reflect.DeepEqual(contents[0].values[5:9], extent) {
+				t.Fatalf("extent is %v, want %v", contents[0].values[5:9], extent)
+			}
+
+			rows := db.table(tables["entities"])
+			if len(rows) != len(features) {
+				t.Fatalf("%d features, want %d", len(rows), len(features))
+			}
+			for i, row := range rows {
+				feature := features[i]
+				geometry, _ := row.values[1].([]byte)
+				want := []interface{}{nil, geometry, feature.Properties[0], feature.Properties[1]}
+				if feature.Type == "" {
+					want[1] = nil
+				}
+				if row.rowid != int64(i+1) || !reflect.DeepEqual(row.values, want) {
+					t.Fatalf("row %d is %v", i+1, row.values)
+				}
+				if feature.Type != "" {
+					checkGeometry(t, geometry, feature)
+				}
+			}
+
+			db.checkPages()
+		})
+	}
+}
+
+func TestLockPage(t *testing.T) {
+	w := newSQLiteWriter(&fileBuffer{})
+	w.pages = sqliteLockPage - 1
+	if page := w.allocate(); page != sqliteLockPage+1 {
+		t.Fatalf("got page %d, want %d", page, sqliteLockPage+1)
+	}
+}
+
+func testFeature(i, noteSize int) *Feature {
+	feature := &Feature{Properties: []string{fmt.Sprintf("#%d", i), strings.Repeat("x", noteSize+i%7)}}
+	y := 41 + float64(i+1)*1e-5
+	switch i % 4 {
+	case 0:
+		feature.Type, feature.Point = Point, []float64{69.01, y}
+	case 1:
+		ring := [][]float64{{69, y}, {69, 41}, {69.01, 41}, {69, y}}
+		feature.Type, feature.Polygons = Polygon, [][][][]float64{{ring}}
+	case 2:
+		ring := [][]float64{{69, 41}, {69, y}, {69.001, y}, {69, 41}}
+		feature.Type, feature.Polygons = MultiPolygon, [][][][]float64{{ring}, {ring}}
+	}
+	return feature
+}
+
+// checkGeometry reads the envelope and the first position of the blob
+func checkGeometry(t *testing.T, blob []byte, feature *Feature) {
+	t.Helper()
+	if len(blob) < 40 || string(blob[:2]) != "GP" || blob[3] != 0x03 || binary.LittleEndian.Uint32(blob[4:]) != gpkgSRS {
+		t.Fatalf("geometry header is %x", blob)
+	}
+	bounds := feature.bounds()
+	for i, want := range []float64{bounds[0], bounds[2], bounds[1], bounds[3]} {
+		if got := math.Float64frombits(binary.LittleEndian.Uint64(blob[8+8*i:])); got != want {
+			t.Fatalf("envelope is %v at %d, want %v", got, i, want)
+		}
+	}
+	wkb := blob[40:]
+	wkbType := map[string]uint32{Point: 1, Polygon: 3, MultiPolygon: 6}[feature.Type]
+	if wkb[0] != 1 || binary.LittleEndian.Uint32(wkb[1:]) != wkbType {
+		t.Fatalf("wkb header is %x", wkb[:5])
+	}
+	first := feature.Point
+	switch feature.Type {
+	case Polygon:
+		first, wkb = feature.Polygons[0][0][0], wkb[4+4:]
+	case MultiPolygon:
+		first, wkb = feature.Polygons[0][0][0], wkb[4+5+4+4:]
+	}
+	x := math.Float64frombits(binary.LittleEndian.Uint64(wkb[5:]))
+	y := math.Float64frombits(binary.LittleEndian.Uint64(wkb[13:]))
+	if x != first[0] || y != first[1] {
+		t.Fatalf("first position is %v %v, want %v", x, y, first)
+	}
+}
+
+// fileBuffer is an io.WriterAt in memory
+type fileBuffer []byte
+
+func (f *fileBuffer) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(*f) {
+		*f = append(*f, make([]byte, end-len(*f))...)
+	}
+	return copy((*f)[off:], p), nil
+}
+
+type sqliteRow struct {
+	rowid  int64
+	values []interface{}
+}
+
+type sqliteReader struct {
+	t     *testing.T
+	data  []byte
+	pages uint32
+	used  map[uint32]bool
+}
+
+func readSQLite(t *testing.T, data []byte) *sqliteReader {
+	t.Helper()
+	if len(data) < sqlitePageSize || len(data)%sqlitePageSize != 0 {
+		t.Fatalf("file size %d is not in pages", len(data))
+	}
+	if !bytes.Equal(data[:16], []byte("SQLite format 3\x00")) || binary.BigEndian.Uint16(data[16:]) != sqlitePageSize {
+		t.Fatalf("file header is %q", data[:18])
+	}
+	if id := binary.BigEndian.Uint32(data[68:]); id != gpkgApplicationID {
+		t.Fatalf("application id is %x", id)
+	}
+	if version := binary.BigEndian.Uint32(data[60:]); version != gpkgUserVersion {
+		t.Fatalf("user version is %d", version)
+	}
+	pages := binary.BigEndian.Uint32(data[28:])
+	if int(pages)*sqlitePageSize != len(data) {
+		t.Fatalf("header has %d pages, file %d", pages, len(data)/sqlitePageSize)
+	}
+	return &sqliteReader{t: t, data: data, pages: pages, used: map[uint32]bool{}}
+}
+
+// page marks the page as used, a page used twice is a broken file
+func (r *sqliteReader) page(number uint32) []byte {
+	r.t.Helper()
+	if number < 1 || number > r.pages || number == sqliteLockPage {
+		r.t.Fatalf("page %d is out of the file", number)
+	}
+	if r.used[number] {
+		r.t.Fatalf("page %d is used twice", number)
+	}
+	r.used[number] = true
+	return r.data[(number-1)*sqlitePageSize : number*sqlitePageSize]
+}
+
+// checkPages fails for pages no b-tree or overflow chain points to
+func (r *sqliteReader) checkPages() {
+	r.t.Helper()
+	for number := uint32(1); number <= r.pages; number++ {
+		if !r.used[number] && number != sqliteLockPage {
+			r.t.Fatalf("page %d of %d is never used", number, r.pages)
+		}
+	}
+}
+
+// table reads the rows of a table b-tree, which have to be in rowid order
+func (r *sqliteReader) table(root uint32) []sqliteRow {
+	r.t.Helper()
+	var rows []sqliteRow
+	r.tablePage(root, &rows, math.MinInt64, math.MaxInt64)
+	return rows
+}
+
+func (r *sqliteReader) tablePage(number uint32, rows *[]sqliteRow, after, upTo int64) {
+	r.t.Helper()
+	page := r.page(number)
+	header := page
+	if number == 1 {
+		header = page[sqliteHeaderSize:]
+	}
+	cells := r.cells(page, header)
+	switch header[0] {
+	case pageTableLeaf:
+		for _, cell := range cells {
+			size, n := readVarint(cell)
+			rowid, m := readVarint(cell[n:])
+			if int64(rowid) <= after || int64(rowid) > upTo {
+				r.t.Fatalf("page %d: rowid %d is not in (%d, %d]", number, rowid, after, upTo)
+			}
+			after = int64(rowid)
+			payload := r.payload(cell[n+m:], int(size), sqlitePageSize-35, (sqlitePageSize-12)*32/255-23)
+			*rows = append(*rows, sqliteRow{rowid: int64(rowid), values: r.record(payload)})
+		}
+	case pageTableInterior:
+		for _, cell := range cells {
+			key, _ := readVarint(cell[4:])
+			r.tablePage(binary.BigEndian.Uint32(cell), rows, after, int64(key))
+			after = int64(key)
+		}
+		r.tablePage(binary.BigEndian.Uint32(header[8:]), rows, after, upTo)
+	default:
+		r.t.Fatalf("page %d is of type %d, not a table", number, header[0])
+	}
+}
+
+// index reads the records of an index, only leaves are written
+func (r *sqliteReader) index(root uint32) {
+	r.t.Helper()
+	page := r.page(root)
+	if page[0] != pageIndexLeaf {
+		r.t.Fatalf("page %d is of type %d, not an index leaf", root, page[0])
+	}
+	for _, cell := range r.cells(page, page) {
+		size, n := readVarint(cell)
+		values := r.record(r.payload(cell[n:], int(size), indexMaxLocal, 0))
+		if _, ok := values[len(values)-1].(int64); !ok {
+			r.t.Fatalf("page %d: index record %v does not end with a rowid", root, values)
+		}
+	}
+}
+
+// cells returns the cells of the page from their pointers, which have to be
+// between the pointer array and the end of the page
+func (r *sqliteReader) cells(page, header []byte) [][]byte {
+	r.t.Helper()
+	headerSize := leafHeaderSize
+	if header[0] == pageTableInterior || header[0] == pageIndexInterior {
+		headerSize = interiorHeaderSize
+	}
+	count := int(binary.BigEndian.Uint16(header[3:]))
+	start := len(page) - len(header) + headerSize + 2*count
+	content := int(binary.BigEndian.Uint16(header[5:]))
+	if content == 0 {
+		content = 65536
+	}
+	if content < start || content > len(page) {
+		r.t.Fatalf("cell content starts at %d, pointers end at %d", content, start)
+	}
+	cells := make([][]byte, count)
+	for i := range cells {
+		pointer := int(binary.BigEndian.Uint16(header[headerSize+2*i:]))
+		if pointer < content || pointer >= len(page) {
+			r.t.Fatalf("cell %d at %d is outside the content area", i, pointer)
+		}
+		cells[i] = page[pointer:]
+	}
+	return cells
+}
+
+// payload joins the local part of a payload with its overflow pages, the
+// local size is the one of the file format
+func (r *sqliteReader) payload(cell []byte, size, maxLocal, minLocal int) []byte {
+	r.t.Helper()
+	if size <= maxLocal {
+		return cell[:size]
+	}
+	local := minLocal + (size-minLocal)%(sqlitePageSize-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	payload := append([]byte(nil), cell[:local]...)
+	next := binary.BigEndian.Uint32(cell[local:])
+	for len(payload) < size {
+		if next == 0 {
+			r.t.Fatalf("overflow chain ends %d bytes short", size-len(payload))
+		}
+		page := r.page(next)
+		next = binary.BigEndian.Uint32(page)
+		end := size - len(payload)
+		if end > sqlitePageSize-4 {
+			end = sqlitePageSize - 4
+		}
+		payload = append(payload, page[4:4+end]...)
+	}
+	if next != 0 {
+		r.t.Fatalf("overflow chain goes on to page %d", next)
+	}
+	return payload
+}
+
+func (r *sqliteReader) record(payload []byte) []interface{} {
+	r.t.Helper()
+	headerSize, n := readVarint(payload)
+	if int(headerSize) > len(payload) {
+		r.t.Fatalf("record header of %d bytes in %d", headerSize, len(payload))
+	}
+	header, body := payload[n:headerSize], payload[headerSize:]
+	var values []interface{}
+	for len(header) > 0 {
+		serialType, n := readVarint(header)
+		header = header[n:]
+		size := 0
+		switch {
+		case serialType == 0 || serialType == 8 || serialType == 9:
+		case serialType <= 4:
+			size = int(serialType)
+		case serialType == 5:
+			size = 6
+		case serialType == 6 || serialType == 7:
+			size = 8
+		case serialType >= 12:
+			size = int(serialType-12) / 2
+		default:
+			r.t.Fatalf("serial type %d is reserved", serialType)
+		}
+		if size > len(body) {
+			r.t.Fatalf("record body is %d bytes short", size-len(body))
+		}
+		value := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			values = append(values, nil)
+		case serialType == 8 || serialType == 9:
+			values = append(values, int64(serialType-8))
+		case serialType <= 6:
+			v := int64(int8(value[0]))
+			for _, b := range value[1:] {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		case serialType == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(value)))
+		case serialType%2 == 0:
+			values = append(values, append([]byte(nil), value...))
+		default:
+			values = append(values, string(value))
+		}
+	}
+	if len(body) != 0 {
+		r.t.Fatalf("record has %d bytes after its values", len(body))
+	}
+	return values
+}
+
+// readVarint is the SQLite varint of the file format, returned with its
+// length
+func readVarint(buf []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v = v<<7 | uint64(buf[i]&0x7f)
+		if buf[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return v<<8 | uint64(buf[8]), 9
+}
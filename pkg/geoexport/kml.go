@@ -0,0 +1,129 @@
+package geoexport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// kmlSchema is the id of the schema the extended data of placemarks follows
+const kmlSchema = "entity"
+
+type kmlWriter struct {
+	w       io.Writer
+	name    string
+	fields  []string
+	started bool
+}
+
+// NewKML writes a KML document, the fields are a schema so the values show
+// as a table in Google Earth and come in as attributes in QGIS
+func NewKML(w io.Writer, name string, fields []string) Writer {
+	return &kmlWriter{w: w, name: name, fields: fields}
+}
+
+func (k *kmlWriter) start(buf *bytes.Buffer) {
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>`)
+	escapeXML(buf, k.name)
+	buf.WriteString(`</name><Schema name="`)
+	escapeXML(buf, k.name)
+	buf.WriteString(`" id="` + kmlSchema + `">`)
+	for _, field := range k.fields {
+		buf.WriteString(`<SimpleField type="string" name="`)
+		escapeXML(buf, field)
+		buf.WriteString(`"/>`)
+	}
+	buf.WriteString("</Schema>\n")
+	k.started = true
+}
+
+func (k *kmlWriter) Write(feature *Feature) error {
+	var buf bytes.Buffer
+	if !k.started {
+		k.start(&buf)
+	}
+
+	buf.WriteString("<Placemark><name>")
+	escapeXML(&buf, feature.Name)
+	buf.WriteString(`</name><ExtendedData><SchemaData schemaUrl="#` + kmlSchema + `">`)
+	for i, field := range k.fields {
+		if i >= len(feature.Properties) {
+			break
+		}
+		buf.WriteString(`<SimpleData name="`)
+		escapeXML(&buf, field)
+		buf.WriteString(`">`)
+		escapeXML(&buf, feature.Properties[i])
+		buf.WriteString("</SimpleData>")
+	}
+	buf.WriteString("</SchemaData></ExtendedData>")
+
+	switch feature.Type {
+	case Point:
+		buf.WriteString("<Point><coordinates>")
+		writeKMLPosition(&buf, feature.Point)
+		buf.WriteString("</coordinates></Point>")
+	case Polygon, MultiPolygon:
+		if len(feature.Polygons) > 1 {
+			buf.WriteString("<MultiGeometry>")
+		}
+		for _, polygon := range feature.Polygons {
+			writeKMLPolygon(&buf, polygon)
+		}
+		if len(feature.Polygons) > 1 {
+			buf.WriteString("</MultiGeometry>")
+		}
+	}
+	buf.WriteString("</Placemark>\n")
+
+	_, err := k.w.Write(buf.Bytes())
+	return err
+}
+
+func (k *kmlWriter) Close() error {
+	var buf bytes.Buffer
+	if !k.started {
+		k.start(&buf)
+	}
+	buf.WriteString("</Document></kml>\n")
+	_, err := k.w.Write(buf.Bytes())
+	return err
+}
+
+func writeKMLPolygon(buf *bytes.Buffer, polygon [][][]float64) {
+	buf.WriteString("<Polygon>")
+	for i, ring := range polygon {
+		if i == 0 {
+			buf.WriteString("<outerBoundaryIs>")
+		} else {
+			buf.WriteString("<innerBoundaryIs>")
+		}
+		buf.WriteString("<LinearRing><coordinates>")
+		for j, position := range ring {
+			if j > 0 {
+				buf.WriteByte(' ')
+			}
+			writeKMLPosition(buf, position)
+		}
+		buf.WriteString("</coordinates></LinearRing>")
+		if i == 0 {
+			buf.WriteString("</outerBoundaryIs>")
+		} else {
+			buf.WriteString("</innerBoundaryIs>")
+		}
+	}
+	buf.WriteString("</Polygon>")
+}
+
+func writeKMLPosition(buf *bytes.Buffer, position []float64) {
+	buf.WriteString(strconv.FormatFloat(position[0], 'f', -1, 64))
+	buf.WriteByte(',')
+	buf.WriteString(strconv.FormatFloat(position[1], 'f', -1, 64))
+}
+
+func escapeXML(buf *bytes.Buffer, s string) {
+	// writing to a buffer does not fail
+	_ = xml.EscapeText(buf, []byte(s))
+}
@@ -0,0 +1,407 @@
+package geoexport
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// The SQLite file format, https://www.sqlite.org/fileformat2.html. Only what
+// a database written once needs is here: tables filled in rowid order and
+// indexes of a single page, no free pages and no journal.
+const (
+	sqlitePageSize   = 4096
+	sqliteHeaderSize = 100
+	// sqliteVersion is the library version the file claims to be written by
+	sqliteVersion = 3031001
+
+	pageIndexInterior = 0x02
+	pageTableInterior = 0x05
+	pageIndexLeaf     = 0x0a
+	pageTableLeaf     = 0x0d
+
+	leafHeaderSize     = 8
+	interiorHeaderSize = 12
+	// interiorCellSize is the largest interior table cell with its pointer:
+	// the child page, a rowid of up to nine bytes and two for the pointer
+	interiorCellSize = 4 + 9 + 2
+	// indexMaxLocal is the largest index payload kept on its page
+	indexMaxLocal = (sqlitePageSize-12)*64/255 - 23
+	// sqliteLockPage holds the lock bytes at 1 GiB and is never used
+	sqliteLockPage = 1<<30/sqlitePageSize + 1
+)
+
+var errIndexTooLarge = errors.New("index does not fit into one page")
+
+// sqliteWriter writes the pages of a database file. Table pages are written
+// as they fill up, so a table of any size takes little memory, and page 1
+// with the schema is written last by finish.
+type sqliteWriter struct {
+	file   io.WriterAt
+	pages  uint32
+	schema []*sqliteSchema
+	// header fields of the file an application sets
+	userVersion   uint32
+	applicationID uint32
+}
+
+type sqliteSchema struct {
+	kind      string
+	name      string
+	tableName string
+	root      uint32
+	sql       string
+}
+
+func newSQLiteWriter(file io.WriterAt) *sqliteWriter {
+	// page 1 is kept for the schema
+	return &sqliteWriter{file: file, pages: 1}
+}
+
+func (w *sqliteWriter) allocate() uint32 {
+	w.pages++
+	if w.pages == sqliteLockPage {
+		w.pages++
+	}
+	return w.pages
+}
+
+func (w *sqliteWriter) writePage(number uint32, page []byte) error {
+	if number == 1 {
+		w.fileHeader(page)
+	}
+	_, err := w.file.WriteAt(page, int64(number-1)*sqlitePageSize)
+	return err
+}
+
+func (w *sqliteWriter) fileHeader(page []byte) {
+	copy(page, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(page[16:], sqlitePageSize)
+	page[18], page[19] = 1, 1 // legacy journal
+	page[20] = 0              // no reserved space
+	page[21], page[22], page[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(page[24:], 1) // change counter
+	binary.BigEndian.PutUint32(page[28:], w.pages)
+	binary.BigEndian.PutUint32(page[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(page[44:], 4) // schema format
+	binary.BigEndian.PutUint32(page[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(page[60:], w.userVersion)
+	binary.BigEndian.PutUint32(page[68:], w.applicationID)
+	// the size in the header is valid for this change counter
+	binary.BigEndian.PutUint32(page[92:], 1)
+	binary.BigEndian.PutUint32(page[96:], sqliteVersion)
+}
+
+// addSchema records a finished table or index, automatic indexes have no
+// sql
+func (w *sqliteWriter) addSchema(kind, name, tableName string, root uint32, sql string) {
+	w.schema = append(w.schema, &sqliteSchema{kind: kind, name: name, tableName: tableName, root: root, sql: sql})
+}
+
+// finish writes sqlite_master, whose root is page 1
+func (w *sqliteWriter) finish() error {
+	master := w.newTable()
+	for i, entry := range w.schema {
+		var sql interface{}
+		if entry.sql != "" {
+			sql = entry.sql
+		}
+		record := sqliteRecord(entry.kind, entry.name, entry.tableName, int64(entry.root), sql)
+		if err := master.insert(int64(i+1), record); err != nil {
+			return err
+		}
+	}
+	_, err := master.finish(1)
+	return err
+}
+
+// writeIndex writes an index of records sorted by their key, the rowid of
+// the row being the last value
+func (w *sqliteWriter) writeIndex(records ...[]byte) (uint32, error) {
+	cells := make([][]byte, 0, len(records))
+	size := 0
+	for _, record := range records {
+		if len(record) > indexMaxLocal {
+			return 0, errIndexTooLarge
+		}
+		cell := append(putVarint(nil, uint64(len(record))), record...)
+		cells = append(cells, cell)
+		size += len(cell) + 2
+	}
+	if size > sqlitePageSize-leafHeaderSize {
+		return 0, errIndexTooLarge
+	}
+	root := w.allocate()
+	return root, w.writePage(root, layoutPage(pageIndexLeaf, cells, 0, 0))
+}
+
+type sqliteChild struct {
+	page  uint32
+	rowid int64
+}
+
+// sqliteTable is a table b-tree being filled in rowid order
+type sqliteTable struct {
+	w      *sqliteWriter
+	cells  [][]byte
+	size   int
+	rowid  int64
+	leaves []sqliteChild
+}
+
+func (w *sqliteWriter) newTable() *sqliteTable {
+	return &sqliteTable{w: w}
+}
+
+func (t *sqliteTable) insert(rowid int64, record []byte) error {
+	cell, err := t.w.tableLeafCell(rowid, record)
+	if err != nil {
+		return err
+	}
+	if len(t.cells) > 0 && t.size+len(cell)+2 > sqlitePageSize-leafHeaderSize {
+		if err := t.flush(); err != nil {
+			return err
+		}
+	}
+	t.cells = append(t.cells, cell)
+	t.size += len(cell) + 2
+	t.rowid = rowid
+	return nil
+}
+
+func (t *sqliteTable) flush() error {
+	page := t.w.allocate()
+	if err := t.w.writePage(page, layoutPage(pageTableLeaf, t.cells, 0, 0)); err != nil {
+		return err
+	}
+	t.leaves = append(t.leaves, sqliteChild{page: page, rowid: t.rowid})
+	t.cells, t.size = nil, 0
+	return nil
+}
+
+// finish writes the rest of the table and the interior pages above the
+// leaves, the root goes to the given page or a new one when it is zero
+func (t *sqliteTable) finish(root uint32) (uint32, error) {
+	offset := 0
+	if root == 1 {
+		offset = sqliteHeaderSize
+	}
+	if len(t.leaves) == 0 && t.size <= sqlitePageSize-offset-leafHeaderSize {
+		if root == 0 {
+			root = t.w.allocate()
+		}
+		return root, t.w.writePage(root, layoutPage(pageTableLeaf, t.cells, 0, offset))
+	}
+	if len(t.cells) > 0 {
+		if err := t.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	perPage := (sqlitePageSize - sqliteHeaderSize - interiorHeaderSize) / interiorCellSize
+	children := t.leaves
+	for {
+		groups := (len(children) + perPage - 1) / perPage
+		if groups == 1 {
+			if root == 0 {
+				root = t.w.allocate()
+			}
+			return root, t.w.writePage(root, interiorPage(children, offset))
+		}
+		// even groups keep every interior page with a few cells
+		size := (len(children) + groups - 1) / groups
+		parents := make([]sqliteChild, 0, groups)
+		for start := 0; start < len(children); start += size {
+			end := start + size
+			if end > len(children) {
+				end = len(children)
+			}
+			page := t.w.allocate()
+			if err := t.w.writePage(page, interiorPage(children[start:end], 0)); err != nil {
+				return 0, err
+			}
+			parents = append(parents, sqliteChild{page: page, rowid: children[end-1].rowid})
+		}
+		children = parents
+	}
+}
+
+// interiorPage points to the children, the last one is the right child
+func interiorPage(children []sqliteChild, offset int) []byte {
+	cells := make([][]byte, 0, len(children)-1)
+	for _, child := range children[:len(children)-1] {
+		cell := make([]byte, 4, interiorCellSize)
+		binary.BigEndian.PutUint32(cell, child.page)
+		cells = append(cells, putVarint(cell, uint64(child.rowid)))
+	}
+	return layoutPage(pageTableInterior, cells, children[len(children)-1].page, offset)
+}
+
+// tableLeafCell keeps as much of the record on the page as SQLite does and
+// writes the rest to overflow pages
+func (w *sqliteWriter) tableLeafCell(rowid int64, payload []byte) ([]byte, error) {
+	cell := putVarint(nil, uint64(len(payload)))
+	cell = putVarint(cell, uint64(rowid))
+	local := localPayload(len(payload))
+	cell = append(cell, payload[:local]...)
+	if local == len(payload) {
+		return cell, nil
+	}
+
+	overflow := payload[local:]
+	perPage := sqlitePageSize - 4
+	pages := make([]uint32, (len(overflow)+perPage-1)/perPage)
+	for i := range pages {
+		pages[i] = w.allocate()
+	}
+	for i, number := range pages {
+		page := make([]byte, sqlitePageSize)
+		if i+1 < len(pages) {
+			binary.BigEndian.PutUint32(page, pages[i+1])
+		}
+		copy(page[4:], overflow[i*perPage:])
+		if err := w.writePage(number, page); err != nil {
+			return nil, err
+		}
+	}
+	first := make([]byte, 4)
+	binary.BigEndian.PutUint32(first, pages[0])
+	return append(cell, first...), nil
+}
+
+// localPayload is how much of a table leaf payload stays on the page
+func localPayload(size int) int {
+	var (
+		maxLocal = sqlitePageSize - 35
+		minLocal = (sqlitePageSize-12)*32/255 - 23
+	)
+	if size <= maxLocal {
+		return size
+	}
+	local := minLocal + (size-minLocal)%(sqlitePageSize-4)
+	if local <= maxLocal {
+		return local
+	}
+	return minLocal
+}
+
+// layoutPage puts the cells at the end of the page in order, offset is 100
+// on page 1, which starts with the file header
+func layoutPage(kind byte, cells [][]byte, rightChild uint32, offset int) []byte {
+	var (
+		page       = make([]byte, sqlitePageSize)
+		headerSize = leafHeaderSize
+		content    = sqlitePageSize
+	)
+	if kind == pageTableInterior || kind == pageIndexInterior {
+		headerSize = interiorHeaderSize
+		binary.BigEndian.PutUint32(page[offset+8:], rightChild)
+	}
+	for i, cell := range cells {
+		content -= len(cell)
+		copy(page[content:], cell)
+		binary.BigEndian.PutUint16(page[offset+headerSize+2*i:], uint16(content))
+	}
+	page[offset] = kind
+	binary.BigEndian.PutUint16(page[offset+3:], uint16(len(cells)))
+	binary.BigEndian.PutUint16(page[offset+5:], uint16(content))
+	return page
+}
+
+// sqliteRecord encodes values that are nil, int64, float64, string or
+// []byte in the record format
+func sqliteRecord(values ...interface{}) []byte {
+	var header, body []byte
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			header = putVarint(header, 0)
+		case int64:
+			serialType, size := integerSerialType(v)
+			header = putVarint(header, serialType)
+			for i := size - 1; i >= 0; i-- {
+				body = append(body, byte(v>>(8*uint(i))))
+			}
+		case float64:
+			header = putVarint(header, 7)
+			var b [8]byte
+			binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+			body = append(body, b[:]...)
+		case string:
+			header = putVarint(header, uint64(len(v))*2+13)
+			body = append(body, v...)
+		case []byte:
+			header = putVarint(header, uint64(len(v))*2+12)
+			body = append(body, v...)
+		}
+	}
+	// the header size counts itself
+	size := len(header) + 1
+	for varintLength(uint64(size))+len(header) != size {
+		size = varintLength(uint64(size)) + len(header)
+	}
+	record := putVarint(make([]byte, 0, size+len(body)), uint64(size))
+	record = append(record, header...)
+	return append(record, body...)
+}
+
+// integerSerialType picks the smallest integer encoding, 0 and 1 take no
+// bytes at all
+func integerSerialType(v int64) (uint64, int) {
+	switch {
+	case v == 0:
+		return 8, 0
+	case v == 1:
+		return 9, 0
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return 1, 1
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2, 2
+	case v >= -1<<23 && v < 1<<23:
+		return 3, 3
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4, 4
+	case v >= -1<<47 && v < 1<<47:
+		return 5, 6
+	}
+	return 6, 8
+}
+
+// putVarint appends the SQLite varint, big endian groups of seven bits and
+// all eight in the ninth byte
+func putVarint(buf []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		var b [9]byte
+		b[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			b[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(buf, b[:]...)
+	}
+	var (
+		b [8]byte
+		n int
+	)
+	for {
+		b[n] = byte(v & 0x7f)
+		n++
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i > 0 {
+			b[i] |= 0x80
+		}
+		buf = append(buf, b[i])
+	}
+	return buf
+}
+
+func varintLength(v uint64) int {
+	return len(putVarint(nil, v))
+}
@@ -0,0 +1,177 @@
+package geoimport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// binaryDXF starts binary drawings, which AutoCAD writes only when asked
+const binaryDXF = "AutoCAD Binary DXF"
+
+type dxfPair struct {
+	code  int
+	value string
+}
+
+// parseDXF takes the closed LWPOLYLINE and POLYLINE entities of the ENTITIES
+// section. Arc segments are read as straight lines between their ends.
+func parseDXF(data []byte) ([]*Feature, error) {
+	if bytes.HasPrefix(data, []byte(binaryDXF)) {
+		return nil, fmt.Errorf("%w: binary DXF, save the drawing as ASCII DXF", ErrUnsupportedFormat)
+	}
+	pairs, err := dxfPairs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		features  []*Feature
+		inSection bool
+		section   string
+	)
+	for i := 0; i < len(pairs); i++ {
+		pair := pairs[i]
+		if pair.code != 0 {
+			continue
+		}
+		switch {
+		case pair.value == "SECTION":
+			inSection = true
+			if i+1 < len(pairs) && pairs[i+1].code == 2 {
+				section = pairs[i+1].value
+			}
+			continue
+		case pair.value == "ENDSEC":
+			inSection, section = false, ""
+			continue
+		case !inSection || section != "ENTITIES":
+			continue
+		}
+
+		var feature *Feature
+		switch pair.value {
+		case "LWPOLYLINE":
+			feature, i = dxfLWPolyline(pairs, i)
+		case "POLYLINE":
+			feature, i = dxfPolyline(pairs, i)
+		default:
+			continue
+		}
+		i--
+		if feature != nil {
+			feature.Name = fmt.Sprintf("%s #%d", feature.Name, len(features)+1)
+			features = append(features, feature)
+			if len(features) > MaxFeatures {
+				return nil, ErrTooManyFeatures
+			}
+		}
+	}
+	return features, nil
+}
+
+func dxfPairs(data []byte) ([]dxfPair, error) {
+	var (
+		pairs   []dxfPair
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+	)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		codeLine := strings.TrimSpace(scanner.Text())
+		if !scanner.Scan() {
+			break
+		}
+		code, err := strconv.Atoi(codeLine)
+		if err != nil {
+			return nil, fmt.Errorf("%w: group code %q", ErrMalformed, codeLine)
+		}
+		pairs = append(pairs, dxfPair{code: code, value: strings.TrimSpace(scanner.Text())})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+	return pairs, nil
+}
+
+// dxfLWPolyline reads the entity starting at i and returns the index of the
+// next entity
+func dxfLWPolyline(pairs []dxfPair, i int) (*Feature, int) {
+	var (
+		layer  string
+		closed bool
+		ring   [][]float64
+	)
+	for i++; i < len(pairs) && pairs[i].code != 0; i++ {
+		switch pairs[i].code {
+		case 8:
+			layer = pairs[i].value
+		case 70:
+			flags, _ := strconv.Atoi(pairs[i].value)
+			closed = flags&1 != 0
+		case 10:
+			x, _ := strconv.ParseFloat(pairs[i].value, 64)
+			ring = append(ring, []float64{x, 0})
+		case 20:
+			if len(ring) > 0 {
+				ring[len(ring)-1][1], _ = strconv.ParseFloat(pairs[i].value, 64)
+			}
+		}
+	}
+	return dxfFeature(layer, closed, ring), i
+}
+
+// dxfPolyline reads the POLYLINE header and its VERTEX entities up to SEQEND
+func dxfPolyline(pairs []dxfPair, i int) (*Feature, int) {
+	var (
+		layer  string
+		closed bool
+		ring   [][]float64
+	)
+	for i++; i < len(pairs) && pairs[i].code != 0; i++ {
+		switch pairs[i].code {
+		case 8:
+			layer = pairs[i].value
+		case 70:
+			flags, _ := strconv.Atoi(pairs[i].value)
+			closed = flags&1 != 0
+		}
+	}
+	for i < len(pairs) && pairs[i].value == "VERTEX" {
+		var x, y float64
+		for i++; i < len(pairs) && pairs[i].code != 0; i++ {
+			switch pairs[i].code {
+			case 10:
+				x, _ = strconv.ParseFloat(pairs[i].value, 64)
+			case 20:
+				y, _ = strconv.ParseFloat(pairs[i].value, 64)
+			}
+		}
+		ring = append(ring, []float64{x, y})
+	}
+	if i < len(pairs) && pairs[i].value == "SEQEND" {
+		for i++; i < len(pairs) && pairs[i].code != 0; i++ {
+		}
+	}
+	return dxfFeature(layer, closed, ring), i
+}
+
+// dxfFeature keeps polylines that are closed by the flag or by ending where
+// they start
+func dxfFeature(layer string, closed bool, ring [][]float64) *Feature {
+	if len(ring) < 3 {
+		return nil
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if !closed && (first[0] != last[0] || first[1] != last[1]) {
+		return nil
+	}
+	if layer == "" {
+		layer = "0"
+	}
+	return &Feature{
+		Name:     layer,
+		Polygons: [][][][]float64{{closeRing(ring)}},
+	}
+}
@@ -0,0 +1,123 @@
+// Package geoimport reads the polygons of survey files: AutoCAD DXF drawings,
+// ESRI Shapefiles and Google Earth KML. Coordinates are returned as they are
+// in the file, converting them is up to the caller. KML is always WGS84.
+package geoimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/e-space-uz/backend/pkg/unzip"
+)
+
+// MaxFeatures keeps a drawing of a whole district from being offered as
+// choices one by one
+const MaxFeatures = 500
+
+var (
+	ErrUnsupportedFormat = errors.New("file is not a DXF, Shapefile, KML or KMZ")
+	ErrNoPolygons        = errors.New("file has no closed polygons")
+	ErrTooManyFeatures   = fmt.Errorf("file has more than %d polygons", MaxFeatures)
+	ErrMalformed         = errors.New("file is malformed")
+)
+
+// Feature is one choice in the file: a closed DXF polyline, a Shapefile
+// record or a KML placemark. Polygons are GeoJSON coordinates, the first ring
+// is the outer one.
+type Feature struct {
+	Name     string
+	Polygons [][][][]float64
+	// WGS84 is set for KML, whose coordinates are WGS84 by the standard
+	WGS84 bool
+}
+
+// Parse tells the format by the file name. A zip may hold a Shapefile with
+// its .dbf or a KML, none of its files may unpack to more than limit bytes.
+func Parse(name string, data []byte, limit int64) ([]*Feature, error) {
+	var (
+		features []*Feature
+		err      error
+	)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".dxf":
+		features, err = parseDXF(data)
+	case ".kml":
+		features, err = parseKML(data)
+	case ".shp":
+		features, err = parseShapefile(data, nil)
+	case ".kmz", ".zip":
+		features, err = parseZip(data, limit)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(features) == 0 {
+		return nil, ErrNoPolygons
+	}
+	if len(features) > MaxFeatures {
+		return nil, ErrTooManyFeatures
+	}
+	return features, nil
+}
+
+func parseZip(data []byte, limit int64) ([]*Feature, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		ext := strings.ToLower(path.Ext(file.Name))
+		if ext != ".kml" && ext != ".shp" && ext != ".dbf" {
+			continue
+		}
+		if _, ok := files[ext]; ok {
+			continue
+		}
+		content, err := readZipFile(file, limit)
+		if err != nil {
+			return nil, err
+		}
+		files[ext] = content
+	}
+
+	switch {
+	case files[".shp"] != nil:
+		return parseShapefile(files[".shp"], files[".dbf"])
+	case files[".kml"] != nil:
+		return parseKML(files[".kml"])
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// readZipFile reports the files an archive can not give as malformed, files
+// over the limit as too large
+func readZipFile(file *zip.File, limit int64) ([]byte, error) {
+	content, err := unzip.ReadFile(file, limit)
+	if errors.Is(err, unzip.ErrTooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+	return content, nil
+}
+
+// closeRing appends the first position when the ring does not end with it
+func closeRing(ring [][]float64) [][]float64 {
+	if len(ring) == 0 {
+		return ring
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		ring = append(ring, []float64{first[0], first[1]})
+	}
+	return ring
+}
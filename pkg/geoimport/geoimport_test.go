@@ -0,0 +1,248 @@
+package geoimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/e-space-uz/backend/pkg/unzip"
+)
+
+const testLimit = 1 << 20
+
+var square = [][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}
+
+const testDXF = `0
+SECTION
+2
+ENTITIES
+0
+LWPOLYLINE
+8
+PARCELS
+70
+1
+10
+0
+20
+0
+10
+0
+20
+1
+10
+1
+20
+1
+10
+1
+20
+0
+0
+ENDSEC
+0
+EOF
+`
+
+const testKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2"><Document><Folder>
+<Placemark><name> Parcel </name><Polygon><outerBoundaryIs><LinearRing>
+<coordinates>0,0,0 0,1,0 1,1,0 1,0,0</coordinates>
+</LinearRing></outerBoundaryIs></Polygon></Placemark>
+</Folder></Document></kml>`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		data     []byte
+		features []*Feature
+		err      error
+	}{
+		{
+			name:     "dxf",
+			file:     "plan.DXF",
+			data:     []byte(testDXF),
+			features: []*Feature{{Name: "PARCELS #1", Polygons: [][][][]float64{{square}}}},
+		},
+		{
+			name:     "kml",
+			file:     "plan.kml",
+			data:     []byte(testKML),
+			features: []*Feature{{Name: "Parcel", Polygons: [][][][]float64{{square}}, WGS84: true}},
+		},
+		{
+			name:     "kmz",
+			file:     "plan.kmz",
+			data:     zipFiles(t, map[string][]byte{"doc.kml": []byte(testKML)}),
+			features: []*Feature{{Name: "Parcel", Polygons: [][][][]float64{{square}}, WGS84: true}},
+		},
+		{
+			name:     "shapefile",
+			file:     "plan.shp",
+			data:     shapefile(square),
+			features: []*Feature{{Name: "#1", Polygons: [][][][]float64{{square}}}},
+		},
+		{
+			name:     "zipped shapefile",
+			file:     "plan.zip",
+			data:     zipFiles(t, map[string][]byte{"plan.shp": shapefile(square), "readme.txt": []byte("survey")}),
+			features: []*Feature{{Name: "#1", Polygons: [][][][]float64{{square}}}},
+		},
+		{name: "unknown extension", file: "plan.pdf", data: []byte(testKML), err: ErrUnsupportedFormat},
+		{name: "zip without survey files", file: "plan.zip", data: zipFiles(t, map[string][]byte{"a.txt": nil}), err: ErrUnsupportedFormat},
+		{name: "binary dxf", file: "plan.dxf", data: []byte(binaryDXF + "\r\n\x1a\x00"), err: ErrUnsupportedFormat},
+		{name: "open polyline", file: "plan.dxf", data: []byte(strings.Replace(testDXF, "70\n1\n", "70\n0\n", 1)), err: ErrNoPolygons},
+		{
+			name: "entry over the limit",
+			file: "plan.kmz",
+			data: zipFiles(t, map[string][]byte{"doc.kml": append([]byte(testKML), make([]byte, testLimit)...)}),
+			err:  unzip.ErrTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features, err := Parse(tt.file, tt.data, testLimit)
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(features, tt.features) {
+				t.Fatalf("got %v, want %v", features, tt.features)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	cutRecord := shapefile(square)
+	cutRecord = cutRecord[:len(cutRecord)-8]
+	tooManyParts := shapefile(square)
+	binary.LittleEndian.PutUint32(tooManyParts[100+8+36:], math.MaxUint32)
+	partOutOfRange := shapefile(square)
+	binary.LittleEndian.PutUint32(partOutOfRange[100+8+44:], 100)
+
+	tests := []struct {
+		name string
+		file string
+		data []byte
+		err  error
+	}{
+		{name: "empty dxf", file: "plan.dxf", err: ErrNoPolygons},
+		{name: "dxf group code", file: "plan.dxf", data: []byte("x\nSECTION\n"), err: ErrMalformed},
+		{name: "dxf line too long", file: "plan.dxf", data: []byte("0\n" + strings.Repeat("x", 2<<20) + "\n"), err: ErrMalformed},
+		{name: "dxf cut in a polyline", file: "plan.dxf", data: []byte(testDXF[:len(testDXF)/2]), err: ErrNoPolygons},
+		{name: "empty kml", file: "plan.kml", err: ErrNoPolygons},
+		{name: "kml not closed", file: "plan.kml", data: []byte(testKML[:len(testKML)-20]), err: ErrMalformed},
+		{name: "kml coordinates", file: "plan.kml", data: []byte(strings.Replace(testKML, "0,1,0", "0;1", 1)), err: ErrMalformed},
+		{name: "kml latitude", file: "plan.kml", data: []byte(strings.Replace(testKML, "0,1,0", "0,north", 1)), err: ErrMalformed},
+		{name: "empty shapefile", file: "plan.shp", err: ErrMalformed},
+		{name: "shapefile code", file: "plan.shp", data: make([]byte, 100), err: ErrMalformed},
+		{name: "shapefile record cut", file: "plan.shp", data: cutRecord, err: ErrMalformed},
+		{name: "shapefile parts", file: "plan.shp", data: tooManyParts, err: ErrMalformed},
+		{name: "shapefile part out of range", file: "plan.shp", data: partOutOfRange, err: ErrMalformed},
+		{name: "empty zip", file: "plan.zip", err: ErrMalformed},
+		{name: "zip cut", file: "plan.kmz", data: zipFiles(t, map[string][]byte{"doc.kml": []byte(testKML)})[:40], err: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.file, tt.data, testLimit); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestParseMutated feeds damaged copies of valid files, whatever is in them
+// has to come back as polygons or an error
+func TestParseMutated(t *testing.T) {
+	samples := map[string][]byte{
+		"plan.dxf": []byte(testDXF),
+		"plan.kml": []byte(testKML),
+		"plan.shp": shapefile(square, square),
+		"plan.zip": zipFiles(t, map[string][]byte{"plan.shp": shapefile(square), "plan.dbf": nil}),
+	}
+	random := rand.New(rand.NewSource(1))
+	for file, sample := range samples {
+		for n := 0; n < 2000; n++ {
+			data := mutate(random, sample)
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("%s %q: %v", file, data, r)
+					}
+				}()
+				features, err := Parse(file, data, testLimit)
+				if err == nil && len(features) == 0 {
+					t.Fatalf("%s %q: no features and no error", file, data)
+				}
+			}()
+		}
+	}
+}
+
+func mutate(random *rand.Rand, sample []byte) []byte {
+	data := append([]byte(nil), sample...)
+	for i := random.Intn(4); i >= 0; i-- {
+		switch random.Intn(3) {
+		case 0:
+			data[random.Intn(len(data))] = byte(random.Intn(256))
+		case 1:
+			data = data[:random.Intn(len(data))+1]
+		case 2:
+			at := random.Intn(len(data))
+			data = append(data[:at], append([]byte{byte(random.Intn(256))}, data[at:]...)...)
+		}
+	}
+	return data
+}
+
+// shapefile writes a .shp with a polygon record for every ring
+func shapefile(rings ...[][]float64) []byte {
+	var records bytes.Buffer
+	for i, ring := range rings {
+		content := make([]byte, 44+4+16*len(ring))
+		binary.LittleEndian.PutUint32(content, shapePolygon)
+		binary.LittleEndian.PutUint32(content[36:], 1)
+		binary.LittleEndian.PutUint32(content[40:], uint32(len(ring)))
+		for j, position := range ring {
+			binary.LittleEndian.PutUint64(content[48+16*j:], math.Float64bits(position[0]))
+			binary.LittleEndian.PutUint64(content[48+16*j+8:], math.Float64bits(position[1]))
+		}
+		header := make([]byte, 8)
+		binary.BigEndian.PutUint32(header, uint32(i+1))
+		binary.BigEndian.PutUint32(header[4:], uint32(len(content)/2))
+		records.Write(header)
+		records.Write(content)
+	}
+
+	header := make([]byte, shapefileHeader)
+	binary.BigEndian.PutUint32(header, shapefileCode)
+	binary.BigEndian.PutUint32(header[24:], uint32((shapefileHeader+records.Len())/2))
+	binary.LittleEndian.PutUint32(header[28:], 1000)
+	binary.LittleEndian.PutUint32(header[32:], shapePolygon)
+	return append(header, records.Bytes()...)
+}
+
+func zipFiles(t *testing.T, files map[string][]byte) []byte {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,109 @@
+package geoimport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type kmlPlacemark struct {
+	Name     string       `xml:"name"`
+	Polygons []kmlPolygon `xml:"Polygon"`
+	Multi    []kmlPolygon `xml:"MultiGeometry>Polygon"`
+}
+
+type kmlPolygon struct {
+	Outer string   `xml:"outerBoundaryIs>LinearRing>coordinates"`
+	Inner []string `xml:"innerBoundaryIs>LinearRing>coordinates"`
+}
+
+// parseKML takes the polygons of every placemark, wherever it is in folders.
+// KML is always WGS84.
+func parseKML(data []byte) ([]*Feature, error) {
+	var (
+		features []*Feature
+		decoder  = xml.NewDecoder(bytes.NewReader(data))
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+
+		var placemark kmlPlacemark
+		if err := decoder.DecodeElement(&placemark, &start); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+		}
+		feature := &Feature{Name: strings.TrimSpace(placemark.Name), WGS84: true}
+		for _, polygon := range append(placemark.Polygons, placemark.Multi...) {
+			rings, err := kmlRings(polygon)
+			if err != nil {
+				return nil, err
+			}
+			if rings != nil {
+				feature.Polygons = append(feature.Polygons, rings)
+			}
+		}
+		if len(feature.Polygons) == 0 {
+			continue
+		}
+		if feature.Name == "" {
+			feature.Name = fmt.Sprintf("#%d", len(features)+1)
+		}
+		features = append(features, feature)
+		if len(features) > MaxFeatures {
+			return nil, ErrTooManyFeatures
+		}
+	}
+	return features, nil
+}
+
+func kmlRings(polygon kmlPolygon) ([][][]float64, error) {
+	outer, err := kmlCoordinates(polygon.Outer)
+	if err != nil || len(outer) == 0 {
+		return nil, err
+	}
+	rings := [][][]float64{outer}
+	for _, inner := range polygon.Inner {
+		hole, err := kmlCoordinates(inner)
+		if err != nil {
+			return nil, err
+		}
+		if len(hole) > 0 {
+			rings = append(rings, hole)
+		}
+	}
+	return rings, nil
+}
+
+// kmlCoordinates parses longitude,latitude[,altitude] tuples separated by
+// white space, the altitude is dropped
+func kmlCoordinates(text string) ([][]float64, error) {
+	var ring [][]float64
+	for _, tuple := range strings.Fields(text) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("%w: coordinates %q", ErrMalformed, tuple)
+		}
+		lon, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: coordinates %q", ErrMalformed, tuple)
+		}
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: coordinates %q", ErrMalformed, tuple)
+		}
+		ring = append(ring, []float64{lon, lat})
+	}
+	return closeRing(ring), nil
+}
@@ -0,0 +1,176 @@
+package geoimport
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/e-space-uz/backend/pkg/geo"
+)
+
+const (
+	shapefileCode    = 9994
+	shapefileHeader  = 100
+	shapeNull        = 0
+	shapePolygon     = 5
+	shapePolygonZ    = 15
+	shapePolygonM    = 25
+	dbfFieldSize     = 32
+	dbfFieldEnd      = 0x0d
+	dbfDeletedRecord = '*'
+)
+
+// parseShapefile reads the polygon records of a .shp, names come from the
+// first text field of the .dbf when there is one
+func parseShapefile(shp, dbf []byte) ([]*Feature, error) {
+	if len(shp) < shapefileHeader || binary.BigEndian.Uint32(shp) != shapefileCode {
+		return nil, fmt.Errorf("%w: not a shapefile", ErrMalformed)
+	}
+	switch binary.LittleEndian.Uint32(shp[32:]) {
+	case shapeNull, shapePolygon, shapePolygonZ, shapePolygonM:
+	default:
+		return nil, fmt.Errorf("%w: shapefile has no polygons", ErrUnsupportedFormat)
+	}
+	names := dbfNames(dbf)
+
+	var features []*Feature
+	for offset, record := shapefileHeader, 0; offset+8 <= len(shp); record++ {
+		length := int(binary.BigEndian.Uint32(shp[offset+4:])) * 2
+		content := offset + 8
+		if length < 4 || content+length > len(shp) {
+			return nil, fmt.Errorf("%w: record %d is cut off", ErrMalformed, record+1)
+		}
+		offset = content + length
+
+		polygons, err := shapePolygons(shp[content : content+length])
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", record+1, err)
+		}
+		if len(polygons) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("#%d", record+1)
+		if record < len(names) && names[record] != "" {
+			name = names[record]
+		}
+		features = append(features, &Feature{Name: name, Polygons: polygons})
+		if len(features) > MaxFeatures {
+			return nil, ErrTooManyFeatures
+		}
+	}
+	return features, nil
+}
+
+// shapePolygons groups the rings of a record: clockwise rings are outer ones
+// and counterclockwise holes belong to the outer ring around them
+func shapePolygons(content []byte) ([][][][]float64, error) {
+	shapeType := binary.LittleEndian.Uint32(content)
+	if shapeType == shapeNull {
+		return nil, nil
+	}
+	if shapeType != shapePolygon && shapeType != shapePolygonZ && shapeType != shapePolygonM {
+		return nil, fmt.Errorf("%w: shape type %d", ErrUnsupportedFormat, shapeType)
+	}
+	if len(content) < 44 {
+		return nil, fmt.Errorf("%w: polygon is cut off", ErrMalformed)
+	}
+	var (
+		numParts  = int(binary.LittleEndian.Uint32(content[36:]))
+		numPoints = int(binary.LittleEndian.Uint32(content[40:]))
+		points    = 44 + 4*numParts
+	)
+	if numParts < 0 || numPoints < 0 || points+16*numPoints > len(content) || points < 44 {
+		return nil, fmt.Errorf("%w: polygon is cut off", ErrMalformed)
+	}
+
+	var outer, holes [][][]float64
+	for part := 0; part < numParts; part++ {
+		start := int(binary.LittleEndian.Uint32(content[44+4*part:]))
+		end := numPoints
+		if part+1 < numParts {
+			end = int(binary.LittleEndian.Uint32(content[44+4*(part+1):]))
+		}
+		if start < 0 || start > end || end > numPoints {
+			return nil, fmt.Errorf("%w: part %d is out of range", ErrMalformed, part)
+		}
+		ring := make([][]float64, 0, end-start)
+		for i := start; i < end; i++ {
+			x := math.Float64frombits(binary.LittleEndian.Uint64(content[points+16*i:]))
+			y := math.Float64frombits(binary.LittleEndian.Uint64(content[points+16*i+8:]))
+			ring = append(ring, []float64{x, y})
+		}
+		if len(ring) < 3 {
+			continue
+		}
+		ring = closeRing(ring)
+		if geo.RingArea(ring) < 0 {
+			outer = append(outer, ring)
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	polygons := make([][][][]float64, 0, len(outer))
+	for _, ring := range outer {
+		polygons = append(polygons, [][][]float64{ring})
+	}
+	for _, hole := range holes {
+		placed := false
+		for i, polygon := range polygons {
+			if geo.PointInRing(hole[0][0], hole[0][1], polygon[0]) {
+				polygons[i] = append(polygons[i], hole)
+				placed = true
+				break
+			}
+		}
+		// a counterclockwise ring on its own is an outer ring drawn the
+		// other way round
+		if !placed {
+			polygons = append(polygons, [][][]float64{hole})
+		}
+	}
+	return polygons, nil
+}
+
+// dbfNames returns the first character field of every record, empty for
+// deleted records. Text that is not UTF-8 is left out.
+func dbfNames(dbf []byte) []string {
+	if len(dbf) < 32 {
+		return nil
+	}
+	var (
+		numRecords   = int(binary.LittleEndian.Uint32(dbf[4:]))
+		headerLength = int(binary.LittleEndian.Uint16(dbf[8:]))
+		recordLength = int(binary.LittleEndian.Uint16(dbf[10:]))
+		fieldOffset  = 1
+		nameOffset   = -1
+		nameLength   int
+	)
+	for field := 32; field+dbfFieldSize <= len(dbf) && dbf[field] != dbfFieldEnd; field += dbfFieldSize {
+		length := int(dbf[field+16])
+		if dbf[field+11] == 'C' {
+			nameOffset, nameLength = fieldOffset, length
+			break
+		}
+		fieldOffset += length
+	}
+	if nameOffset < 0 || recordLength == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, numRecords)
+	for record := 0; record < numRecords; record++ {
+		start := headerLength + record*recordLength
+		if start+recordLength > len(dbf) || nameOffset+nameLength > recordLength {
+			break
+		}
+		value := strings.TrimSpace(string(dbf[start+nameOffset : start+nameOffset+nameLength]))
+		if dbf[start] == dbfDeletedRecord || !utf8.ValidString(value) {
+			value = ""
+		}
+		names = append(names, value)
+	}
+	return names
+}
@@ -0,0 +1,156 @@
+// Package i18n negotiates the response language from Accept-Language and
+// replaces the texts of the dynamic forms and their option dictionaries with
+// their translations.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+const (
+	UzLatn = "uz-Latn"
+	UzCyrl = "uz-Cyrl"
+	Ru     = "ru"
+	En     = "en"
+
+	// Default is the language of the untranslated fields
+	Default = UzLatn
+)
+
+// Languages are the supported languages in order of preference
+var Languages = []string{UzLatn, UzCyrl, Ru, En}
+
+// Normalize maps a BCP 47 tag to one of the supported languages. Plain "uz"
+// and regional tags such as "uz-UZ" mean Latin script, the official one.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, "_", "-")))
+	switch {
+	case tag == "*":
+		return Default
+	case strings.HasPrefix(tag, "uz-cyrl"):
+		return UzCyrl
+	case tag == "uz" || strings.HasPrefix(tag, "uz-"):
+		return UzLatn
+	case tag == "ru" || strings.HasPrefix(tag, "ru-"):
+		return Ru
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return En
+	}
+	return ""
+}
+
+// Negotiate picks the supported language with the highest quality from an
+// Accept-Language header. It returns an empty string when nothing matches.
+func Negotiate(header string) string {
+	type candidate struct {
+		lang    string
+		quality float64
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		lang := Normalize(fields[0])
+		if lang == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{lang: lang, quality: quality})
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	return candidates[0].lang
+}
+
+// IsSupported reports whether lang is one of Languages
+func IsSupported(lang string) bool {
+	for _, supported := range Languages {
+		if supported == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalizeProperty replaces label, placeholder, description and option names
+// with the translations in lang. Fields without a translation keep the text
+// in the default language.
+func LocalizeProperty(property *models.Property, lang string) {
+	if property == nil {
+		return
+	}
+	if property.Translations != nil {
+		property.Label = property.Translations.Label.Get(lang, property.Label)
+		property.Placeholder = property.Translations.Placeholder.Get(lang, property.Placeholder)
+		property.Description = property.Translations.Description.Get(lang, property.Description)
+	}
+	localizeOptions(property.PropertyOptions, lang)
+}
+
+// LocalizeGetProperty is LocalizeProperty for the entity form of a property
+func LocalizeGetProperty(property *models.GetProperty, lang string) {
+	if property == nil {
+		return
+	}
+	if property.Translations != nil {
+		property.Label = property.Translations.Label.Get(lang, property.Label)
+		property.Placeholder = property.Translations.Placeholder.Get(lang, property.Placeholder)
+		property.Description = property.Translations.Description.Get(lang, property.Description)
+	}
+	localizeOptions(property.PropertyOptions, lang)
+}
+
+// LocalizeGroupProperties localizes the groups and their properties
+func LocalizeGroupProperties(groups []*models.GroupProperty, lang string) {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		if group.Translations != nil {
+			group.Name = group.Translations.Name.Get(lang, group.Name)
+			group.Description = group.Translations.Description.Get(lang, group.Description)
+		}
+		for _, property := range group.Properties {
+			LocalizeProperty(property, lang)
+		}
+	}
+}
+
+// LocalizeEntityProperties localizes the properties of an entity or a draft
+func LocalizeEntityProperties(entityProperties []*models.GetEntityProperty, lang string) {
+	for _, entityProperty := range entityProperties {
+		if entityProperty != nil {
+			LocalizeGetProperty(entityProperty.Property, lang)
+		}
+	}
+}
+
+func localizeOptions(options []*models.PropertyOption, lang string) {
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		option.Name = option.Translations.Get(lang, option.Name)
+	}
+}
@@ -0,0 +1,99 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// Font is either Helvetica, which every reader has, or a TrueType font that
+// is embedded into the document
+type Font interface {
+	// encode appends the character codes of s to codes and returns them with
+	// the width of s at size 1000. Glyphs put on a page are added to used.
+	encode(codes []byte, s string, used map[uint16]rune) ([]byte, float64)
+	writeObjects(ow *objectWriter, used map[uint16]rune) (int, error)
+}
+
+type helvetica struct{}
+
+// Helvetica covers Latin text only. Cyrillic is transliterated, so documents
+// in Russian and Uzbek Cyrillic need a TrueType font to look right.
+var Helvetica Font = helvetica{}
+
+// helveticaWidths are the widths of the printable ASCII characters from the
+// font metrics that come with every PDF reader
+var helveticaWidths = [95]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// winAnsi maps the characters WinAnsiEncoding has outside Latin-1
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+	// the Uzbek o‘ and g‘ are written with a turned comma
+	'ʻ': 0x91, 'ʼ': 0x92,
+}
+
+var cyrillicLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "j",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sh", 'ъ': "’", 'ы': "i", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'ў': "o‘", 'қ': "q", 'ғ': "g‘", 'ҳ': "h", '№': "No",
+}
+
+func (helvetica) encode(codes []byte, s string, used map[uint16]rune) ([]byte, float64) {
+	var width float64
+	for _, r := range s {
+		if latin, ok := transliterate(r); ok {
+			var w float64
+			codes, w = helvetica{}.encode(codes, latin, used)
+			width += w
+			continue
+		}
+		code, ok := winAnsiCode(r)
+		if !ok {
+			code = '?'
+		}
+		codes = append(codes, code)
+		if code >= 32 && code < 127 {
+			width += float64(helveticaWidths[code-32])
+		} else {
+			width += 556
+		}
+	}
+	return codes, width
+}
+
+func (helvetica) writeObjects(ow *objectWriter, used map[uint16]rune) (int, error) {
+	ref := ow.begin(0)
+	ow.printf("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	ow.end()
+	return ref, ow.err
+}
+
+func winAnsiCode(r rune) (byte, bool) {
+	if r >= 32 && r < 127 || r >= 0xA0 && r <= 0xFF {
+		return byte(r), true
+	}
+	code, ok := winAnsi[r]
+	return code, ok
+}
+
+func transliterate(r rune) (string, bool) {
+	lower := []rune(strings.ToLower(string(r)))[0]
+	latin, ok := cyrillicLatin[lower]
+	if !ok {
+		return "", false
+	}
+	if lower != r && latin != "" {
+		upper := []rune(latin)
+		upper[0] = []rune(strings.ToUpper(string(upper[0])))[0]
+		latin = string(upper)
+	}
+	return latin, true
+}
@@ -0,0 +1,51 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+var ErrUnsupportedImage = errors.New("only grayscale and RGB JPEG images are supported")
+
+// Image is a JPEG that is embedded as it is, readers decode it themselves
+type Image struct {
+	data       []byte
+	width      int
+	height     int
+	colorSpace string
+}
+
+func NewJPEG(data []byte) (*Image, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img := &Image{
+		data:   data,
+		width:  config.Width,
+		height: config.Height,
+	}
+	switch config.ColorModel {
+	case color.GrayModel:
+		img.colorSpace = "DeviceGray"
+	case color.YCbCrModel:
+		img.colorSpace = "DeviceRGB"
+	default:
+		return nil, ErrUnsupportedImage
+	}
+	return img, nil
+}
+
+// Size returns the size of the image in pixels
+func (img *Image) Size() image.Point {
+	return image.Point{X: img.width, Y: img.height}
+}
+
+func (img *Image) writeObject(ow *objectWriter) int {
+	return ow.stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s "+
+		"/BitsPerComponent 8 /Filter /DCTDecode", img.width, img.height, img.colorSpace), img.data, false)
+}
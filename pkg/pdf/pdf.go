@@ -0,0 +1,342 @@
+// Package pdf writes simple PDF 1.4 documents: text in one font, lines,
+// rectangles and JPEG images on A4 pages. It is enough for the documents the
+// service issues and needs nothing outside the standard library.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A4 page size in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Document is built in memory page by page and written with WriteTo. It is
+// not safe for concurrent use, a Font can be shared between documents.
+type Document struct {
+	font    Font
+	pages   []*Page
+	images  []*Image
+	used    map[uint16]rune
+	title   string
+	author  string
+	created time.Time
+}
+
+// Page coordinates are in points from the top left corner
+type Page struct {
+	doc     *Document
+	width   float64
+	height  float64
+	content bytes.Buffer
+}
+
+func New(font Font) *Document {
+	return &Document{
+		font:    font,
+		used:    map[uint16]rune{},
+		created: time.Now(),
+	}
+}
+
+// SetInfo fills the document information dictionary
+func (d *Document) SetInfo(title, author string, created time.Time) {
+	d.title = title
+	d.author = author
+	d.created = created
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{
+		doc:    d,
+		width:  A4Width,
+		height: A4Height,
+	}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// TextWidth returns the width of s in points
+func (d *Document) TextWidth(s string, size float64) float64 {
+	_, width := d.font.encode(nil, s, nil)
+	return width * size / 1000
+}
+
+// Wrap splits s into lines no wider than maxWidth. Words longer than a line
+// are put on a line of their own.
+func (d *Document) Wrap(s string, size, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && d.TextWidth(candidate, size) > maxWidth {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+func (p *Page) Width() float64 {
+	return p.width
+}
+
+func (p *Page) Height() float64 {
+	return p.height
+}
+
+// Text draws s with its baseline at y
+func (p *Page) Text(x, y, size float64, s string) {
+	codes, _ := p.doc.font.encode(nil, s, p.doc.used)
+	fmt.Fprintf(&p.content, "BT /F1 %s Tf %s %s Td <%x> Tj ET\n", num(size), num(x), num(p.height-y), codes)
+}
+
+// TextRight draws s so that it ends at x
+func (p *Page) TextRight(x, y, size float64, s string) {
+	p.Text(x-p.doc.TextWidth(s, size), y, size, s)
+}
+
+func (p *Page) Line(x1, y1, x2, y2, lineWidth float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(lineWidth), num(x1), num(p.height-y1), num(x2), num(p.height-y2))
+}
+
+// Rect strokes a rectangle whose top left corner is at x, y
+func (p *Page) Rect(x, y, width, height, lineWidth float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n",
+		num(lineWidth), num(x), num(p.height-y-height), num(width), num(height))
+}
+
+// FillRect fills a black rectangle whose top left corner is at x, y
+func (p *Page) FillRect(x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n",
+		num(x), num(p.height-y-height), num(width), num(height))
+}
+
+// Image draws img scaled to width and height with its top left corner at x, y
+func (p *Page) Image(img *Image, x, y, width, height float64) {
+	index := p.doc.addImage(img)
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		num(width), num(height), num(x), num(p.height-y-height), index)
+}
+
+func (d *Document) addImage(img *Image) int {
+	for i, added := range d.images {
+		if added == img {
+			return i + 1
+		}
+	}
+	d.images = append(d.images, img)
+	return len(d.images)
+}
+
+// WriteTo writes the whole document. Page contents are compressed.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	ow := newObjectWriter(w)
+	ow.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	catalog := ow.reserve()
+	pagesRef := ow.reserve()
+	info := ow.reserve()
+
+	fontRef, err := d.font.writeObjects(ow, d.used)
+	if err != nil {
+		return ow.written, err
+	}
+
+	imageRefs := make([]int, len(d.images))
+	for i, img := range d.images {
+		imageRefs[i] = img.writeObject(ow)
+	}
+	var resources strings.Builder
+	fmt.Fprintf(&resources, "<< /Font << /F1 %d 0 R >>", fontRef)
+	if len(imageRefs) > 0 {
+		resources.WriteString(" /XObject <<")
+		for i, ref := range imageRefs {
+			fmt.Fprintf(&resources, " /Im%d %d 0 R", i+1, ref)
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+
+	kids := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		content := ow.stream("", page.content.Bytes(), true)
+		pageRef := ow.begin(0)
+		ow.printf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			pagesRef, num(page.width), num(page.height), resources.String(), content)
+		ow.end()
+		kids[i] = fmt.Sprintf("%d 0 R", pageRef)
+	}
+
+	ow.begin(pagesRef)
+	ow.printf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+	ow.end()
+
+	ow.begin(catalog)
+	ow.printf("<< /Type /Catalog /Pages %d 0 R >>", pagesRef)
+	ow.end()
+
+	ow.begin(info)
+	ow.printf("<< /Producer %s /CreationDate %s", textString("e-space"), textString(pdfDate(d.created)))
+	if d.title != "" {
+		ow.printf(" /Title %s", textString(d.title))
+	}
+	if d.author != "" {
+		ow.printf(" /Author %s", textString(d.author))
+	}
+	ow.printf(" >>")
+	ow.end()
+
+	return ow.finish(catalog, info)
+}
+
+// objectWriter numbers objects and remembers their offsets for the
+// cross-reference table
+type objectWriter struct {
+	w       *bufio.Writer
+	written int64
+	offsets []int64
+	err     error
+}
+
+func newObjectWriter(w io.Writer) *objectWriter {
+	return &objectWriter{w: bufio.NewWriter(w)}
+}
+
+func (ow *objectWriter) printf(format string, args ...interface{}) {
+	if ow.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(ow.w, format, args...)
+	ow.written += int64(n)
+	ow.err = err
+}
+
+func (ow *objectWriter) write(p []byte) {
+	if ow.err != nil {
+		return
+	}
+	n, err := ow.w.Write(p)
+	ow.written += int64(n)
+	ow.err = err
+}
+
+// reserve returns the number of an object that is written later
+func (ow *objectWriter) reserve() int {
+	ow.offsets = append(ow.offsets, -1)
+	return len(ow.offsets)
+}
+
+// begin starts object ref, or a new object when ref is 0
+func (ow *objectWriter) begin(ref int) int {
+	if ref == 0 {
+		ref = ow.reserve()
+	}
+	ow.offsets[ref-1] = ow.written
+	ow.printf("%d 0 obj\n", ref)
+	return ref
+}
+
+func (ow *objectWriter) end() {
+	ow.printf("\nendobj\n")
+}
+
+// stream writes a stream object, dict holds the entries besides Length and
+// Filter
+func (ow *objectWriter) stream(dict string, data []byte, compress bool) int {
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+		data = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	ref := ow.begin(0)
+	ow.printf("<< /Length %d%s %s >>\nstream\n", len(data), filter, dict)
+	ow.write(data)
+	ow.printf("\nendstream")
+	ow.end()
+	return ref
+}
+
+func (ow *objectWriter) finish(catalog, info int) (int64, error) {
+	xref := ow.written
+	ow.printf("xref\n0 %d\n0000000000 65535 f \n", len(ow.offsets)+1)
+	for _, offset := range ow.offsets {
+		if offset < 0 {
+			ow.printf("0000000000 65535 f \n")
+			continue
+		}
+		ow.printf("%010d 00000 n \n", offset)
+	}
+	ow.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(ow.offsets)+1, catalog, info, xref)
+	if ow.err != nil {
+		return ow.written, ow.err
+	}
+	return ow.written, ow.w.Flush()
+}
+
+// num formats a number without exponent and trailing zeros
+func num(f float64) string {
+	s := fmt.Sprintf("%.3f", f)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// textString encodes s as UTF-16 with a byte order mark, which every reader
+// accepts in the information dictionary
+func textString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteString("<FEFF")
+	for _, r := range s {
+		for _, unit := range utf16Units(r) {
+			fmt.Fprintf(&buf, "%04X", unit)
+		}
+	}
+	buf.WriteString(">")
+	return buf.String()
+}
+
+func utf16Units(r rune) []uint16 {
+	if r < 0x10000 {
+		return []uint16{uint16(r)}
+	}
+	r -= 0x10000
+	return []uint16{uint16(0xD800 + (r >> 10)), uint16(0xDC00 + (r & 0x3FF))}
+}
+
+func pdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,360 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrNotTrueType       = errors.New("font is not a TrueType font")
+	ErrNoUnicodeCmap     = errors.New("font has no unicode character map")
+	ErrFontNotEmbeddable = errors.New("font license does not allow embedding")
+)
+
+// TrueType is a parsed TrueType font. The whole font file is embedded, text
+// is written as glyph ids, so any script the font covers can be used.
+type TrueType struct {
+	data       []byte
+	name       string
+	unitsPerEm float64
+	glyphs     map[rune]uint16
+	advances   []uint16
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+	capHeight  int16
+}
+
+// LoadTrueType reads and parses a .ttf file
+func LoadTrueType(path string) (*TrueType, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTrueType(data)
+}
+
+// ParseTrueType reads the tables needed to place text. Fonts with CFF
+// outlines and font collections are not supported.
+func ParseTrueType(data []byte) (*TrueType, error) {
+	if len(data) < 12 {
+		return nil, ErrNotTrueType
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x74727565 {
+		return nil, ErrNotTrueType
+	}
+	tables := map[string][]byte{}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < count; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, ErrNotTrueType
+		}
+		offset := binary.BigEndian.Uint32(data[record+8:])
+		length := binary.BigEndian.Uint32(data[record+12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("font table %q is out of bounds", data[record:record+4])
+		}
+		tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "cmap", "glyf"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("font has no %s table", tag)
+		}
+	}
+
+	font := &TrueType{data: data, name: "EmbeddedFont"}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, ErrNotTrueType
+	}
+	font.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if font.unitsPerEm == 0 {
+		return nil, ErrNotTrueType
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int16(binary.BigEndian.Uint16(head[36+2*i:]))
+	}
+
+	hhea := tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, ErrNotTrueType
+	}
+	font.ascent = int16(binary.BigEndian.Uint16(hhea[4:]))
+	font.descent = int16(binary.BigEndian.Uint16(hhea[6:]))
+	font.capHeight = font.ascent
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := tables["hmtx"]
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, ErrNotTrueType
+	}
+	font.advances = make([]uint16, metrics)
+	for i := range font.advances {
+		font.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+	}
+
+	if os2 := tables["OS/2"]; len(os2) >= 10 {
+		// fsType 2 is restricted license embedding
+		if binary.BigEndian.Uint16(os2[8:])&0x000F == 0x0002 {
+			return nil, ErrFontNotEmbeddable
+		}
+		if len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+			font.capHeight = int16(binary.BigEndian.Uint16(os2[88:]))
+		}
+	}
+	if name := fontName(tables["name"]); name != "" {
+		font.name = name
+	}
+
+	var err error
+	font.glyphs, err = parseCmap(tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	return font, nil
+}
+
+func (f *TrueType) encode(codes []byte, s string, used map[uint16]rune) ([]byte, float64) {
+	var width float64
+	for _, r := range s {
+		glyph := f.glyphs[r]
+		codes = append(codes, byte(glyph>>8), byte(glyph))
+		width += f.width(glyph)
+		if used != nil && glyph != 0 {
+			used[glyph] = r
+		}
+	}
+	return codes, width
+}
+
+func (f *TrueType) width(glyph uint16) float64 {
+	advance := f.advances[len(f.advances)-1]
+	if int(glyph) < len(f.advances) {
+		advance = f.advances[glyph]
+	}
+	return float64(advance) * 1000 / f.unitsPerEm
+}
+
+func (f *TrueType) scale(v int16) int {
+	return int(float64(v) * 1000 / f.unitsPerEm)
+}
+
+// writeObjects embeds the font as a CID font with Identity-H encoding. The
+// ToUnicode map lets readers copy and search the text.
+func (f *TrueType) writeObjects(ow *objectWriter, used map[uint16]rune) (int, error) {
+	glyphs := make([]int, 0, len(used))
+	for glyph := range used {
+		glyphs = append(glyphs, int(glyph))
+	}
+	sort.Ints(glyphs)
+
+	fontFile := ow.stream(fmt.Sprintf("/Length1 %d", len(f.data)), f.data, true)
+
+	descriptor := ow.begin(0)
+	ow.printf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 "+
+		"/Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight), fontFile)
+	ow.end()
+
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%s] ", glyph, num(f.width(uint16(glyph))))
+	}
+	cidFont := ow.begin(0)
+	ow.printf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /DW %s /W [%s] /CIDToGIDMap /Identity >>",
+		f.name, descriptor, num(f.width(0)), widths.String())
+	ow.end()
+
+	toUnicode := ow.stream("", toUnicodeCMap(glyphs, used), true)
+
+	ref := ow.begin(0)
+	ow.printf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+		"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", f.name, cidFont, toUnicode)
+	ow.end()
+	return ref, ow.err
+}
+
+func toUnicodeCMap(glyphs []int, used map[uint16]rune) []byte {
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(glyphs); start += 100 {
+		block := glyphs[start:]
+		if len(block) > 100 {
+			block = block[:100]
+		}
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(block))
+		for _, glyph := range block {
+			fmt.Fprintf(&cmap, "<%04X> <", glyph)
+			for _, unit := range utf16Units(used[uint16(glyph)]) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return []byte(cmap.String())
+}
+
+// parseCmap prefers the full unicode subtable (format 12) over the BMP one
+// (format 4)
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, ErrNoUnicodeCmap
+	}
+	var format4, format12 []byte
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count; i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+2 > len(cmap) {
+			continue
+		}
+		unicode := platform == 0 || platform == 3 && (encoding == 1 || encoding == 10)
+		if !unicode {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		return parseCmap12(format12)
+	case format4 != nil:
+		return parseCmap4(format4)
+	}
+	return nil, ErrNoUnicodeCmap
+}
+
+func parseCmap4(table []byte) (map[rune]uint16, error) {
+	if len(table) < 14 {
+		return nil, ErrNoUnicodeCmap
+	}
+	segments := int(binary.BigEndian.Uint16(table[6:])) / 2
+	ends := 14
+	starts := ends + 2*segments + 2
+	deltas := starts + 2*segments
+	rangeOffsets := deltas + 2*segments
+	if rangeOffsets+2*segments > len(table) {
+		return nil, ErrNoUnicodeCmap
+	}
+
+	glyphs := map[rune]uint16{}
+	for i := 0; i < segments; i++ {
+		end := binary.BigEndian.Uint16(table[ends+2*i:])
+		start := binary.BigEndian.Uint16(table[starts+2*i:])
+		delta := binary.BigEndian.Uint16(table[deltas+2*i:])
+		rangeOffset := int(binary.BigEndian.Uint16(table[rangeOffsets+2*i:]))
+		if start == 0xFFFF {
+			continue
+		}
+		for c := int(start); c <= int(end); c++ {
+			var glyph uint16
+			if rangeOffset == 0 {
+				glyph = uint16(c) + delta
+			} else {
+				// the offset is relative to the idRangeOffset entry itself
+				at := rangeOffsets + 2*i + rangeOffset + 2*(c-int(start))
+				if at+2 > len(table) {
+					continue
+				}
+				glyph = binary.BigEndian.Uint16(table[at:])
+				if glyph != 0 {
+					glyph += delta
+				}
+			}
+			if glyph != 0 {
+				glyphs[rune(c)] = glyph
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+func parseCmap12(table []byte) (map[rune]uint16, error) {
+	if len(table) < 16 {
+		return nil, ErrNoUnicodeCmap
+	}
+	groups := int(binary.BigEndian.Uint32(table[12:]))
+	if 16+12*groups > len(table) {
+		return nil, ErrNoUnicodeCmap
+	}
+	glyphs := map[rune]uint16{}
+	for i := 0; i < groups; i++ {
+		group := table[16+12*i:]
+		start := binary.BigEndian.Uint32(group)
+		end := binary.BigEndian.Uint32(group[4:])
+		glyph := binary.BigEndian.Uint32(group[8:])
+		if end < start || end > 0x10FFFF {
+			continue
+		}
+		for c := start; c <= end; c++ {
+			glyphs[rune(c)] = uint16(glyph + c - start)
+		}
+	}
+	return glyphs, nil
+}
+
+// fontName returns the PostScript name from the name table
+func fontName(table []byte) string {
+	if len(table) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count; i++ {
+		record := 6 + 12*i
+		if record+12 > len(table) {
+			return ""
+		}
+		platform := binary.BigEndian.Uint16(table[record:])
+		nameID := binary.BigEndian.Uint16(table[record+6:])
+		length := int(binary.BigEndian.Uint16(table[record+8:]))
+		offset := storage + int(binary.BigEndian.Uint16(table[record+10:]))
+		if nameID != 6 || offset+length > len(table) {
+			continue
+		}
+		raw := table[offset : offset+length]
+		var name strings.Builder
+		if platform == 1 {
+			name.Write(raw)
+		} else {
+			for j := 0; j+1 < len(raw); j += 2 {
+				name.WriteByte(raw[j+1])
+			}
+		}
+		return pdfName(name.String())
+	}
+	return ""
+}
+
+// pdfName keeps the characters that need no escaping in a PDF name
+func pdfName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 32 && r < 127 && !strings.ContainsRune("/()<>[]{}%#", r) {
+			return r
+		}
+		return -1
+	}, s)
+}
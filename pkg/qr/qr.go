@@ -0,0 +1,481 @@
+// Package qr encodes bytes as a QR code (ISO/IEC 18004) with error
+// correction level M. Versions 1 to 10 are supported, which is up to 213
+// bytes and plenty for a verification URL.
+package qr
+
+import (
+	"errors"
+)
+
+var ErrTooLong = errors.New("data is too long for a QR code")
+
+// Code is a square of modules, Size modules wide, without the quiet zone
+type Code struct {
+	Size    int
+	modules [][]bool
+}
+
+// Dark reports whether the module in column x of row y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// blockSpec is how the codewords of a version are split into blocks at level M
+type blockSpec struct {
+	ecPerBlock int
+	// groups of blocks as pairs of block count and data codewords per block
+	groups [][2]int
+}
+
+var levelM = [11]blockSpec{
+	1:  {10, [][2]int{{1, 16}}},
+	2:  {16, [][2]int{{1, 28}}},
+	3:  {26, [][2]int{{1, 44}}},
+	4:  {18, [][2]int{{2, 32}}},
+	5:  {24, [][2]int{{2, 43}}},
+	6:  {16, [][2]int{{4, 27}}},
+	7:  {18, [][2]int{{4, 31}}},
+	8:  {22, [][2]int{{2, 38}, {2, 39}}},
+	9:  {22, [][2]int{{3, 36}, {2, 37}}},
+	10: {26, [][2]int{{4, 43}, {1, 44}}},
+}
+
+var alignmentPositions = [11][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+func (s blockSpec) dataCodewords() int {
+	total := 0
+	for _, group := range s.groups {
+		total += group[0] * group[1]
+	}
+	return total
+}
+
+// Encode picks the smallest version that fits data and the mask with the
+// lowest penalty
+func Encode(data []byte) (*Code, error) {
+	version := 0
+	for v := 1; v < len(levelM); v++ {
+		if 4+countBits(v)+8*len(data) <= 8*levelM[v].dataCodewords() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := interleave(version, encodeData(version, data))
+
+	var best *matrix
+	bestPenalty := -1
+	for mask := 0; mask < 8; mask++ {
+		m := newMatrix(version)
+		m.placeFunctionPatterns(version, mask)
+		m.placeData(codewords, mask)
+		if penalty := m.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = m, penalty
+		}
+	}
+	return &Code{Size: best.size, modules: best.modules}, nil
+}
+
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData writes data in byte mode and pads it to the data capacity
+func encodeData(version int, data []byte) []byte {
+	capacity := levelM[version].dataCodewords()
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	terminator := 8*capacity - bits.len()
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	if rest := bits.len() % 8; rest != 0 {
+		bits.append(0, 8-rest)
+	}
+	codewords := bits.bytes
+	for pad := 0; len(codewords) < capacity; pad++ {
+		if pad%2 == 0 {
+			codewords = append(codewords, 0xEC)
+		} else {
+			codewords = append(codewords, 0x11)
+		}
+	}
+	return codewords
+}
+
+// interleave splits data into blocks, adds error correction to each block and
+// mixes the blocks codeword by codeword
+func interleave(version int, data []byte) []byte {
+	spec := levelM[version]
+	divisor := rsDivisor(spec.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	longest := 0
+	for _, group := range spec.groups {
+		for i := 0; i < group[0]; i++ {
+			block := data[:group[1]]
+			data = data[group[1]:]
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+			if len(block) > longest {
+				longest = len(block)
+			}
+		}
+	}
+
+	var result []byte
+	for i := 0; i < longest; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+type matrix struct {
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func newMatrix(version int) *matrix {
+	size := 17 + 4*version
+	c := &matrix{
+		size:       size,
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.isFunction[y] = make([]bool, size)
+	}
+	return c
+}
+
+func (c *matrix) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *matrix) placeFunctionPatterns(version, mask int) {
+	for i := 0; i < c.size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.placeFinder(3, 3)
+	c.placeFinder(c.size-4, 3)
+	c.placeFinder(3, c.size-4)
+
+	positions := alignmentPositions[version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// the corners taken by finder patterns
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			c.placeAlignment(x, y)
+		}
+	}
+
+	c.placeFormat(mask)
+	if version >= 7 {
+		c.placeVersion(version)
+	}
+}
+
+// placeFinder draws the finder pattern centered at x, y with its separator
+func (c *matrix) placeFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.size || yy < 0 || yy >= c.size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (c *matrix) placeAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// placeFormat writes the level and the mask twice, with BCH error correction
+func (c *matrix) placeFormat(mask int) {
+	// level M is 00
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.size-8, true)
+}
+
+func (c *matrix) placeVersion(version int) {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := version<<12 | rem
+	for i := 0; i < 18; i++ {
+		a, b := c.size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// placeData fills the modules that are not function patterns in the zigzag
+// order of the standard and applies the mask
+func (c *matrix) placeData(codewords []byte, mask int) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := 0; vertical < c.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vertical
+				}
+				if c.isFunction[y][x] {
+					continue
+				}
+				dark := false
+				if i < 8*len(codewords) {
+					dark = bit(int(codewords[i>>3]), 7-i&7)
+					i++
+				}
+				c.modules[y][x] = dark != masked(mask, x, y)
+			}
+		}
+	}
+}
+
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	}
+	return ((x+y)%2+x*y%3)%2 == 0
+}
+
+// penalty scores how hard the code is to scan, the four rules of the standard
+func (c *matrix) penalty() int {
+	penalty := 0
+	for i := 0; i < c.size; i++ {
+		penalty += c.linePenalty(func(j int) bool { return c.modules[i][j] })
+		penalty += c.linePenalty(func(j int) bool { return c.modules[j][i] })
+	}
+
+	dark := 0
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x < c.size-1 && y < c.size-1 {
+				color := c.modules[y][x]
+				if c.modules[y][x+1] == color && c.modules[y+1][x] == color && c.modules[y+1][x+1] == color {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	total := c.size * c.size
+	percent := dark * 100 / total
+	penalty += abs(percent-50) / 5 * 10
+	return penalty
+}
+
+// linePenalty scores runs of one color and patterns that look like finders
+func (c *matrix) linePenalty(module func(int) bool) int {
+	penalty := 0
+	run := 1
+	for j := 1; j < c.size; j++ {
+		if module(j) == module(j-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += 3 + run - 5
+		}
+		run = 1
+	}
+	if run >= 5 {
+		penalty += 3 + run - 5
+	}
+
+	finder := []bool{true, false, true, true, true, false, true}
+	for j := 0; j+7 <= c.size; j++ {
+		matches := true
+		for k, dark := range finder {
+			if module(j+k) != dark {
+				matches = false
+				break
+			}
+		}
+		if matches && (light(module, j-4, j, c.size) || light(module, j+7, j+11, c.size)) {
+			penalty += 40
+		}
+	}
+	return penalty
+}
+
+// light reports whether the modules from start to end are light, modules
+// outside the code count as light
+func light(module func(int) bool, start, end, size int) bool {
+	for j := start; j < end; j++ {
+		if j >= 0 && j < size && module(j) {
+			return false
+		}
+	}
+	return true
+}
+
+type bitBuffer struct {
+	bytes []byte
+	bits  int
+}
+
+func (b *bitBuffer) append(value, count int) {
+	for i := count - 1; i >= 0; i-- {
+		if b.bits%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if value>>i&1 == 1 {
+			b.bytes[len(b.bytes)-1] |= 0x80 >> (b.bits % 8)
+		}
+		b.bits++
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return b.bits
+}
+
+// rsDivisor returns the generator polynomial of the given degree without its
+// leading term
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func bit(value, i int) bool {
+	return value>>i&1 != 0
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
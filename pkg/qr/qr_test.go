@@ -0,0 +1,237 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestRSRemainder is the version 1-M "HELLO WORLD" of the thonky.com QR code
+// tutorial
+func TestRSRemainder(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+// formatM are the format strings of level M by mask from the table of the
+// standard
+var formatM = [8]string{
+	"101010000010010",
+	"101000100100101",
+	"101111001111100",
+	"101101101001011",
+	"100010111111001",
+	"100000011001110",
+	"100111110010111",
+	"100101010100000",
+}
+
+func TestFormat(t *testing.T) {
+	for mask, format := range formatM {
+		want, _ := strconv.ParseInt(format, 2, 32)
+		m := newMatrix(1)
+		m.placeFormat(mask)
+		first, second := readFormat(m)
+		if first != int(want) || second != int(want) {
+			t.Errorf("mask %d: got %015b and %015b, want %s", mask, first, second, format)
+		}
+	}
+}
+
+// TestVersion is the version information table of the standard
+func TestVersion(t *testing.T) {
+	for version, want := range map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3} {
+		m := newMatrix(version)
+		m.placeVersion(version)
+		var topRight, bottomLeft int
+		for i := 0; i < 18; i++ {
+			a, b := m.size-11+i%3, i/3
+			if m.modules[b][a] {
+				topRight |= 1 << i
+			}
+			if m.modules[a][b] {
+				bottomLeft |= 1 << i
+			}
+		}
+		if topRight != want || bottomLeft != want {
+			t.Errorf("version %d: got %018b and %018b, want %018b", version, topRight, bottomLeft, want)
+		}
+	}
+}
+
+// TestEncode reads every code back the way a scanner does: the mask from the
+// format, the codewords in zigzag order, and every block checked against its
+// error correction
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{0, 1}, {14, 1}, {15, 2}, {26, 2}, {27, 3}, {42, 3}, {43, 4}, {62, 4}, {63, 5},
+		{84, 5}, {85, 6}, {106, 6}, {107, 7}, {122, 7}, {123, 8}, {152, 8}, {153, 9},
+		{180, 9}, {181, 10}, {213, 10},
+	}
+	for _, tt := range tests {
+		data := []byte(strings.Repeat("https://e-space.uz/v/0123456789", 8)[:tt.length])
+		code, err := Encode(data)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", tt.length, err)
+		}
+		if want := 17 + 4*tt.version; code.Size != want {
+			t.Fatalf("%d bytes: size %d, want %d", tt.length, code.Size, want)
+		}
+		checkFunctionPatterns(t, code)
+		got, err := decode(code, tt.version)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", tt.length, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%d bytes: decoded %q", tt.length, got)
+		}
+	}
+
+	if _, err := Encode(make([]byte, 214)); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("got %v, want %v", err, ErrTooLong)
+	}
+}
+
+func readFormat(m *matrix) (int, int) {
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= b2i(m.modules[i][8]) << i
+	}
+	first |= b2i(m.modules[7][8]) << 6
+	first |= b2i(m.modules[8][8]) << 7
+	first |= b2i(m.modules[8][7]) << 8
+	for i := 9; i < 15; i++ {
+		first |= b2i(m.modules[8][14-i]) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= b2i(m.modules[8][m.size-1-i]) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= b2i(m.modules[m.size-15+i][8]) << i
+	}
+	return first, second
+}
+
+// checkFunctionPatterns looks at the finders, the timing patterns and the
+// dark module
+func checkFunctionPatterns(t *testing.T, code *Code) {
+	t.Helper()
+	for _, corner := range [][2]int{{0, 0}, {code.Size - 7, 0}, {0, code.Size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := max(abs(dx-3), abs(dy-3))
+				if want := ring != 2; code.Dark(corner[0]+dx, corner[1]+dy) != want {
+					t.Fatalf("size %d: finder at %v is wrong at %d,%d", code.Size, corner, dx, dy)
+				}
+			}
+		}
+	}
+	for i := 8; i < code.Size-8; i++ {
+		if code.Dark(i, 6) != (i%2 == 0) || code.Dark(6, i) != (i%2 == 0) {
+			t.Fatalf("size %d: timing pattern is wrong at %d", code.Size, i)
+		}
+	}
+	if !code.Dark(8, code.Size-8) {
+		t.Fatalf("size %d: dark module is light", code.Size)
+	}
+}
+
+func decode(code *Code, version int) ([]byte, error) {
+	m := &matrix{size: code.Size, modules: code.modules}
+	first, second := readFormat(m)
+	if first != second {
+		return nil, errors.New("format copies differ")
+	}
+	mask := -1
+	for i, format := range formatM {
+		if value, _ := strconv.ParseInt(format, 2, 32); int(value) == first {
+			mask = i
+		}
+	}
+	if mask < 0 {
+		return nil, errors.New("format is not level M")
+	}
+
+	functions := newMatrix(version)
+	functions.placeFunctionPatterns(version, mask)
+	var bits bitBuffer
+	for right := code.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := 0; vertical < code.Size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vertical
+				if (right+1)&2 == 0 {
+					y = code.Size - 1 - vertical
+				}
+				if !functions.isFunction[y][x] {
+					bits.append(b2i(code.Dark(x, y) != masked(mask, x, y)), 1)
+				}
+			}
+		}
+	}
+
+	spec := levelM[version]
+	var lengths []int
+	for _, group := range spec.groups {
+		for i := 0; i < group[0]; i++ {
+			lengths = append(lengths, group[1])
+		}
+	}
+	codewords := bits.bytes
+	dataBlocks := make([][]byte, len(lengths))
+	for i := 0; i < lengths[len(lengths)-1]; i++ {
+		for b, length := range lengths {
+			if i < length {
+				dataBlocks[b] = append(dataBlocks[b], codewords[0])
+				codewords = codewords[1:]
+			}
+		}
+	}
+	var data []byte
+	for b, block := range dataBlocks {
+		ec := make([]byte, spec.ecPerBlock)
+		for i := range ec {
+			ec[i] = codewords[i*len(dataBlocks)+b]
+		}
+		if !bytes.Equal(rsRemainder(block, rsDivisor(spec.ecPerBlock)), ec) {
+			return nil, fmt.Errorf("block %d does not match its error correction", b)
+		}
+		data = append(data, block...)
+	}
+
+	position := 0
+	read := func(count int) int {
+		value := 0
+		for i := 0; i < count; i++ {
+			value = value<<1 | int(data[position>>3]>>(7-position&7)&1)
+			position++
+		}
+		return value
+	}
+	if read(4) != 0x4 {
+		return nil, errors.New("not byte mode")
+	}
+	decoded := make([]byte, read(countBits(version)))
+	for i := range decoded {
+		decoded[i] = byte(read(8))
+	}
+	return decoded, nil
+}
+
+func b2i(dark bool) int {
+	if dark {
+		return 1
+	}
+	return 0
+}
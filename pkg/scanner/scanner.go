@@ -0,0 +1,31 @@
+// Package scanner checks uploaded files for malware
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Result of a scan. Signature names what was found in infected files.
+type Result struct {
+	Infected  bool   `json:"infected"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type Scanner interface {
+	// Scan reads r to the end. An error means the file could not be scanned,
+	// not that it is infected.
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+type noop struct{}
+
+// NewNoop returns a scanner that reports every file as clean, for
+// deployments without an antivirus
+func NewNoop() Scanner {
+	return noop{}
+}
+
+func (noop) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	return &Result{}, nil
+}
@@ -0,0 +1,85 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// Keyring verifies signatures by the key id they were made with, it holds
+// the key of the signer and the public keys of the keys used before it
+type Keyring struct {
+	current string
+	keys    map[string]crypto.PublicKey
+	pems    map[string][]byte
+}
+
+// PublicKey is a key of the keyring as auditors get it
+type PublicKey struct {
+	KeyID     string
+	Algorithm string
+	PEM       []byte
+}
+
+func NewKeyring(current *Signer) *Keyring {
+	return &Keyring{
+		current: current.KeyID(),
+		keys:    map[string]crypto.PublicKey{current.KeyID(): current.key.Public()},
+		pems:    map[string][]byte{current.KeyID(): current.PublicKeyPEM()},
+	}
+}
+
+// LoadFile adds the PUBLIC KEY blocks of a PEM file, the keys a server had
+// before its key was rotated
+func (k *Keyring) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for n := 1; ; n++ {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			return fmt.Errorf("%s: block %d is %s, not a PUBLIC KEY", path, n, block.Type)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%s: block %d: %w", path, n, err)
+		}
+		if algorithm(key) == "" {
+			return fmt.Errorf("%s: block %d: %w", path, n, ErrUnsupportedKey)
+		}
+		id := keyID(block.Bytes)
+		k.keys[id] = key
+		k.pems[id] = pem.EncodeToMemory(block)
+	}
+	return nil
+}
+
+// Verify checks the signature with the key it names
+func (k *Keyring) Verify(keyID string, message, signature []byte) error {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return verify(key, message, signature)
+}
+
+// Previous returns the keys other than the one of the signer by key id
+func (k *Keyring) Previous() []PublicKey {
+	keys := make([]PublicKey, 0, len(k.keys))
+	for id, key := range k.keys {
+		if id == k.current {
+			continue
+		}
+		keys = append(keys, PublicKey{KeyID: id, Algorithm: algorithm(key), PEM: k.pems[id]})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KeyID < keys[j].KeyID })
+	return keys
+}
@@ -0,0 +1,155 @@
+// Package signer signs with the key of the server. Auditors verify the
+// signatures with the public key, which has to be kept together with the
+// key id of every key the server has used.
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var (
+	ErrNoKey          = errors.New("no private key in the file")
+	ErrUnsupportedKey = errors.New("key type is not supported")
+	ErrBadSignature   = errors.New("signature does not match")
+	ErrUnknownKey     = errors.New("signature is by a key that is not in the keyring")
+)
+
+// Signer holds an Ed25519, ECDSA or RSA private key
+type Signer struct {
+	key       crypto.Signer
+	keyID     string
+	publicDER []byte
+}
+
+// Load reads a PEM encoded PKCS#8, SEC 1 or PKCS#1 private key, as openssl
+// genpkey writes it
+func Load(path string) (*Signer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoKey, path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return New(key)
+}
+
+// Generate returns a signer with a new Ed25519 key
+func Generate() (*Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return New(key)
+}
+
+func New(key interface{}) (*Signer, error) {
+	var s *Signer
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		s = &Signer{key: k}
+	case *ecdsa.PrivateKey:
+		s = &Signer{key: k}
+	case *rsa.PrivateKey:
+		s = &Signer{key: k}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	var err error
+	s.publicDER, err = x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	s.keyID = keyID(s.publicDER)
+	return s, nil
+}
+
+func keyID(publicDER []byte) string {
+	sum := sha256.Sum256(publicDER)
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeyID is the start of the SHA-256 of the public key, it tells which key a
+// signature was made with
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// Algorithm names the signature algorithm, ECDSA and RSA sign the SHA-256 of
+// the message
+func (s *Signer) Algorithm() string {
+	return algorithm(s.key.Public())
+}
+
+func algorithm(key crypto.PublicKey) string {
+	switch key.(type) {
+	case ed25519.PublicKey:
+		return "Ed25519"
+	case *ecdsa.PublicKey:
+		return "ECDSA-SHA256"
+	case *rsa.PublicKey:
+		return "RSA-PKCS1v15-SHA256"
+	}
+	return ""
+}
+
+// PublicKeyPEM is the public key for verifying signatures elsewhere
+func (s *Signer) PublicKeyPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: s.publicDER})
+}
+
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		return s.key.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(message)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (s *Signer) Verify(message, signature []byte) error {
+	return verify(s.key.Public(), message, signature)
+}
+
+func verify(key crypto.PublicKey, message, signature []byte) error {
+	var ok bool
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, message, signature)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		ok = ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !ok {
+		return ErrBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package soato
+
+import (
+	"strconv"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+// Compare tells what applying the classifier to the stored units changes. A
+// unit is the same when its level and code are. Where old data has a code
+// twice the active unit is taken, units stored without a code are not the
+// classifier's and are left alone. Without ruNames the classifier has no
+// Russian names and the stored ones are kept.
+func Compare(stored, classifier []*models.SoatoUnit, ruNames bool) *models.SoatoDiff {
+	var (
+		diff = &models.SoatoDiff{
+			Added:     []*models.SoatoChange{},
+			Renamed:   []*models.SoatoChange{},
+			Abolished: []*models.SoatoChange{},
+			Restored:  []*models.SoatoChange{},
+		}
+		byKey  = map[string]*models.SoatoUnit{}
+		listed = map[string]bool{}
+	)
+	for _, unit := range stored {
+		key := unitKey(unit)
+		if current := byKey[key]; current == nil || (!current.Active && unit.Active) {
+			byKey[key] = unit
+		}
+	}
+
+	for _, unit := range classifier {
+		key := unitKey(unit)
+		listed[key] = true
+		current := byKey[key]
+		change := &models.SoatoChange{
+			Level:       unit.Level,
+			Soato:       unit.Soato,
+			ParentSoato: unit.ParentSoato,
+			Name:        unit.Name,
+			RuName:      unit.RuName,
+		}
+		if !ruNames && current != nil {
+			change.RuName = current.RuName
+		}
+		switch {
+		case current == nil:
+			diff.Added = append(diff.Added, change)
+		case !current.Active:
+			setOld(change, current)
+			diff.Restored = append(diff.Restored, change)
+		case current.Name != change.Name || current.RuName != change.RuName:
+			setOld(change, current)
+			diff.Renamed = append(diff.Renamed, change)
+		default:
+			diff.Unchanged++
+		}
+	}
+
+	for _, unit := range stored {
+		key := unitKey(unit)
+		if listed[key] || !unit.Active || unit.Soato == 0 || byKey[key] != unit {
+			continue
+		}
+		diff.Abolished = append(diff.Abolished, &models.SoatoChange{
+			Level:       unit.Level,
+			Soato:       unit.Soato,
+			ParentSoato: unit.ParentSoato,
+			UnitID:      unit.ID,
+			Name:        unit.Name,
+			RuName:      unit.RuName,
+		})
+	}
+	return diff
+}
+
+func setOld(change *models.SoatoChange, current *models.SoatoUnit) {
+	change.UnitID = current.ID
+	if current.Name != change.Name {
+		change.OldName = current.Name
+	}
+	if current.RuName != change.RuName {
+		change.OldRuName = current.RuName
+	}
+}
+
+func unitKey(unit *models.SoatoUnit) string {
+	return unit.Level + "-" + strconv.FormatUint(uint64(unit.Soato), 10)
+}
@@ -0,0 +1,248 @@
+// Package soato reads the SOATO classifier of administrative units from CSV
+// or XLSX and compares it with the stored cities, regions and districts.
+//
+// Codes are hierarchical, the code of a unit starts with the code of the
+// unit it belongs to. Cities have four digits, regions seven and districts
+// ten, a file lists the parent of every region and district. The country
+// code, two digits, may be listed and is skipped.
+package soato
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+// Levels in order, a unit belongs to a unit of the level before
+var Levels = []string{models.AdminUnitCity, models.AdminUnitRegion, models.AdminUnitDistrict}
+
+// codeLengths are the digits of the codes of the levels
+var codeLengths = []int{4, 7, 10}
+
+var (
+	ErrUnsupportedFormat = errors.New("file is not a CSV or XLSX")
+	ErrMalformed         = errors.New("file is malformed")
+	ErrNoColumns         = errors.New("file needs soato and name columns")
+	ErrEmpty             = errors.New("file lists no units")
+	ErrCodeLength        = errors.New("soato has 4 digits for cities, 7 for regions and 10 for districts")
+	ErrParentNotListed   = errors.New("parent of the unit is not in the file")
+)
+
+// column names of the header row, matched in lower case
+var headers = map[string][]string{
+	"soato":   {"soato", "code", "kod", "soato kodi", "код", "соато", "код соато"},
+	"name":    {"name", "name_uz", "nomi", "nomlanishi", "uz", "наименование (узб)"},
+	"ru_name": {"ru_name", "name_ru", "ru", "наименование", "название", "наименование (рус)"},
+	"level":   {"level", "daraja", "уровень"},
+}
+
+// Parse tells the format by the file name. The first row is a header when
+// it names the columns, otherwise the columns are soato, name and ru_name.
+// ruNames tells whether the file has the ru_name column, without it the
+// stored Russian names are kept.
+func Parse(name string, data []byte) (units []*models.SoatoUnit, ruNames bool, err error) {
+	var rows [][]string
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv", ".txt":
+		rows, err = readCSV(data)
+	case ".xlsx":
+		rows, err = readXLSX(data)
+	default:
+		return nil, false, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(rows) == 0 {
+		return nil, false, ErrEmpty
+	}
+
+	columns, header := findColumns(rows[0])
+	if columns["soato"] < 0 || columns["name"] < 0 {
+		return nil, false, ErrNoColumns
+	}
+	first := 0
+	if header {
+		first = 1
+	}
+	ruNames = columns["ru_name"] >= 0
+	if !header {
+		ruNames = false
+		for _, row := range rows {
+			ruNames = ruNames || len(row) > columns["ru_name"]
+		}
+	}
+
+	var (
+		levels = map[uint32]string{}
+		codes  = map[string]*models.SoatoUnit{}
+	)
+	for i := first; i < len(rows); i++ {
+		row := rows[i]
+		code := strings.ReplaceAll(cell(row, columns["soato"]), " ", "")
+		if code == "" {
+			continue
+		}
+		soato, err := parseCode(code)
+		if err != nil {
+			return nil, false, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		code = strconv.FormatUint(uint64(soato), 10)
+		if len(code) <= 2 {
+			continue
+		}
+		if codes[code] != nil {
+			return nil, false, fmt.Errorf("row %d: soato %s is listed twice", i+1, code)
+		}
+		unit := &models.SoatoUnit{
+			Soato:  soato,
+			Name:   strings.TrimSpace(cell(row, columns["name"])),
+			RuName: strings.TrimSpace(cell(row, columns["ru_name"])),
+		}
+		if unit.Name == "" {
+			return nil, false, fmt.Errorf("row %d: name of %s is empty", i+1, code)
+		}
+		if level := strings.ToLower(strings.TrimSpace(cell(row, columns["level"]))); level != "" {
+			if levelIndex(level) < 0 {
+				return nil, false, fmt.Errorf("row %d: level must be one of %s", i+1, strings.Join(Levels, ", "))
+			}
+			levels[soato] = level
+		}
+		codes[code] = unit
+		units = append(units, unit)
+	}
+	if len(units) == 0 {
+		return nil, false, ErrEmpty
+	}
+	if err := setLevels(units, codes, levels); err != nil {
+		return nil, false, err
+	}
+	return units, ruNames, nil
+}
+
+// setLevels tells the level of every unit by the length of its code and
+// finds its parent, parents come first after it
+func setLevels(units []*models.SoatoUnit, codes map[string]*models.SoatoUnit, levels map[uint32]string) error {
+	sort.Slice(units, func(i, j int) bool {
+		a, b := strconv.FormatUint(uint64(units[i].Soato), 10), strconv.FormatUint(uint64(units[j].Soato), 10)
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	for _, unit := range units {
+		code := strconv.FormatUint(uint64(unit.Soato), 10)
+		depth := codeLevel(code)
+		if depth < 0 {
+			return fmt.Errorf("%w: %s", ErrCodeLength, code)
+		}
+		unit.Level = Levels[depth]
+		if level, ok := levels[unit.Soato]; ok && level != unit.Level {
+			return fmt.Errorf("soato %s is a %s by its code, not a %s", code, unit.Level, level)
+		}
+		if depth == 0 {
+			continue
+		}
+		parent := codes[code[:codeLengths[depth-1]]]
+		if parent == nil {
+			return fmt.Errorf("%w: %s", ErrParentNotListed, code)
+		}
+		unit.ParentSoato = parent.Soato
+	}
+	return nil
+}
+
+// ValidateCode checks the length of the code of the level and that it
+// continues the code of the parent, units without codes are not checked
+func ValidateCode(level string, parent, soato uint32) error {
+	if soato == 0 {
+		return nil
+	}
+	depth, code := levelIndex(level), strconv.FormatUint(uint64(soato), 10)
+	if depth < 0 || codeLevel(code) != depth {
+		return fmt.Errorf("%w: %s", ErrCodeLength, code)
+	}
+	if parent != 0 && depth > 0 && code[:codeLengths[depth-1]] != strconv.FormatUint(uint64(parent), 10) {
+		return fmt.Errorf("soato %s does not start with the soato %d of the parent", code, parent)
+	}
+	return nil
+}
+
+// ChildCodes are the codes the units under a unit of the level with the
+// code may have
+func ChildCodes(level string, soato uint32) (from, to uint64) {
+	depth := levelIndex(level)
+	if depth < 0 || depth+1 >= len(Levels) {
+		return 0, 0
+	}
+	scale := uint64(1)
+	for i := codeLengths[depth]; i < codeLengths[depth+1]; i++ {
+		scale *= 10
+	}
+	return uint64(soato) * scale, uint64(soato)*scale + scale - 1
+}
+
+func codeLevel(code string) int {
+	for i, length := range codeLengths {
+		if len(code) == length {
+			return i
+		}
+	}
+	return -1
+}
+
+func findColumns(row []string) (map[string]int, bool) {
+	columns := map[string]int{"soato": -1, "name": -1, "ru_name": -1, "level": -1}
+	header := false
+	for i, value := range row {
+		value = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(value, "\ufeff")))
+		for column, names := range headers {
+			for _, name := range names {
+				if value == name && columns[column] < 0 {
+					columns[column] = i
+					header = true
+				}
+			}
+		}
+	}
+	if !header {
+		return map[string]int{"soato": 0, "name": 1, "ru_name": 2, "level": -1}, false
+	}
+	return columns, true
+}
+
+func cell(row []string, column int) string {
+	if column < 0 || column >= len(row) {
+		return ""
+	}
+	return row[column]
+}
+
+// parseCode takes the code as text or as a number a spreadsheet kept, such
+// as 1.726E3
+func parseCode(code string) (uint32, error) {
+	code = strings.TrimPrefix(code, "\ufeff")
+	if value, err := strconv.ParseUint(code, 10, 32); err == nil {
+		return uint32(value), nil
+	}
+	value, err := strconv.ParseFloat(code, 64)
+	if err != nil || value < 0 || value > math.MaxUint32 || value != math.Trunc(value) {
+		return 0, fmt.Errorf("soato %q is not a code", code)
+	}
+	return uint32(value), nil
+}
+
+func levelIndex(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,397 @@
+package soato
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		units   []string
+		ruNames bool
+		err     error
+	}{
+		{
+			name: "header",
+			file: "soato.csv",
+			data: "soato,name,ru_name\n1726,Toshkent shahri,город Ташкент\n1726269,Yunusobod tumani,Юнусабадский район\n1726269501,Mahalla,Махалля\n",
+			units: []string{
+				"city 1726 0 Toshkent shahri/город Ташкент",
+				"region 1726269 1726 Yunusobod tumani/Юнусабадский район",
+				"district 1726269501 1726269 Mahalla/Махалля",
+			},
+			ruNames: true,
+		},
+		{
+			name:    "russian header with semicolons and BOM",
+			file:    "soato.csv",
+			data:    "\ufeffКод СОАТО;Наименование (узб);Наименование (рус)\n1703;Andijon viloyati;Андижанская область\n",
+			units:   []string{"city 1703 0 Andijon viloyati/Андижанская область"},
+			ruNames: true,
+		},
+		{
+			name:  "no ru_name column",
+			file:  "soato.csv",
+			data:  "soato,name\n1703,Andijon viloyati\n",
+			units: []string{"city 1703 0 Andijon viloyati/"},
+		},
+		{
+			name:  "no header, two columns",
+			file:  "soato.txt",
+			data:  "1703,Andijon viloyati\n1703202,Oltinko'l tumani\n",
+			units: []string{"city 1703 0 Andijon viloyati/", "region 1703202 1703 Oltinko'l tumani/"},
+		},
+		{
+			name:    "no header, three columns",
+			file:    "soato.csv",
+			data:    "1703,Andijon viloyati,Андижанская область\n",
+			units:   []string{"city 1703 0 Andijon viloyati/Андижанская область"},
+			ruNames: true,
+		},
+		{
+			name:  "country code and empty codes are skipped",
+			file:  "soato.csv",
+			data:  "soato,name\n17,O'zbekiston\n,\n1703,Andijon viloyati\n",
+			units: []string{"city 1703 0 Andijon viloyati/"},
+		},
+		{
+			name:  "codes kept as numbers",
+			file:  "soato.csv",
+			data:  "soato,name\n1.703E3,Andijon viloyati\n",
+			units: []string{"city 1703 0 Andijon viloyati/"},
+		},
+		{
+			name:  "children before parents",
+			file:  "soato.csv",
+			data:  "soato,name\n1703202,Oltinko'l tumani\n1703,Andijon viloyati\n",
+			units: []string{"city 1703 0 Andijon viloyati/", "region 1703202 1703 Oltinko'l tumani/"},
+		},
+		{name: "parent not listed", file: "soato.csv", data: "soato,name\n1703,Andijon\n1706202,Tuman\n", err: ErrParentNotListed},
+		{name: "district without region", file: "soato.csv", data: "soato,name\n1703,Andijon\n1703202501,Mahalla\n", err: ErrParentNotListed},
+		{name: "code of no level", file: "soato.csv", data: "soato,name\n17032,Andijon\n", err: ErrCodeLength},
+		{name: "code too long", file: "soato.csv", data: "soato,name\n1703,Andijon\n17032025011,Mahalla\n", err: errAny},
+		{name: "level column disagrees", file: "soato.csv", data: "soato,name,level\n1703,Andijon,region\n", err: errAny},
+		{name: "unknown level", file: "soato.csv", data: "soato,name,level\n1703,Andijon,country\n", err: errAny},
+		{name: "listed twice", file: "soato.csv", data: "soato,name\n1703,Andijon\n1703,Andijon\n", err: errAny},
+		{name: "empty name", file: "soato.csv", data: "soato,name\n1703,\n", err: errAny},
+		{name: "not a code", file: "soato.csv", data: "soato,name\nabc,Andijon\n", err: errAny},
+		{name: "negative code", file: "soato.csv", data: "soato,name\n-1703,Andijon\n", err: errAny},
+		{name: "no name column", file: "soato.csv", data: "soato,level\n1703,city\n", err: ErrNoColumns},
+		{name: "empty", file: "soato.csv", data: "", err: ErrEmpty},
+		{name: "header only", file: "soato.csv", data: "soato,name\n", err: ErrEmpty},
+		{name: "not UTF-8", file: "soato.csv", data: "soato,name\n1703,\xff\xfe\n", err: ErrMalformed},
+		{name: "unknown format", file: "soato.pdf", data: "%PDF", err: ErrUnsupportedFormat},
+		{name: "xlsx that is not a zip", file: "soato.xlsx", data: "soato,name", err: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, ruNames, err := Parse(tt.file, []byte(tt.data))
+			if tt.err != nil {
+				if err == nil || tt.err != errAny && !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(units); !reflect.DeepEqual(got, tt.units) {
+				t.Fatalf("got %q, want %q", got, tt.units)
+			}
+			if ruNames != tt.ruNames {
+				t.Fatalf("ruNames %t, want %t", ruNames, tt.ruNames)
+			}
+		})
+	}
+}
+
+// errAny stands for errors the test does not tell apart
+var errAny = errors.New("any error")
+
+func describe(units []*models.SoatoUnit) []string {
+	var result []string
+	for _, unit := range units {
+		result = append(result, fmt.Sprintf("%s %d %d %s/%s", unit.Level, unit.Soato, unit.ParentSoato, unit.Name, unit.RuName))
+	}
+	return result
+}
+
+func TestParseXLSX(t *testing.T) {
+	sharedStrings := `<sst><si><t>soato</t></si><si><t>name</t></si><si><r><t>Toshkent </t></r><r><t>shahri</t></r><rPh><t>x</t></rPh></si></sst>`
+	sheet := `<worksheet><sheetData>
+		<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+		<row r="3"><c r="A3"><v>1726</v></c><c r="B3" t="s"><v>2</v></c></row>
+		<row r="4"><c r="A4"><v>1726269</v></c><c r="B4" t="inlineStr"><is><t>Yunusobod</t></is></c></row>
+	</sheetData></worksheet>`
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		units []string
+		err   error
+	}{
+		{
+			name: "first sheet by relationship",
+			files: map[string]string{
+				"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="SOATO" r:id="rId7"/></sheets></workbook>`,
+				"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId7" Target="worksheets/data.xml"/></Relationships>`,
+				"xl/worksheets/data.xml":     sheet,
+				"xl/sharedStrings.xml":       sharedStrings,
+			},
+			units: []string{"city 1726 0 Toshkent shahri/", "region 1726269 1726 Yunusobod/"},
+		},
+		{
+			name: "sheet1 without workbook",
+			files: map[string]string{
+				"xl/worksheets/sheet1.xml": sheet,
+				"xl/sharedStrings.xml":     sharedStrings,
+			},
+			units: []string{"city 1726 0 Toshkent shahri/", "region 1726269 1726 Yunusobod/"},
+		},
+		{name: "no sheets", files: map[string]string{"docProps/app.xml": "<Properties/>"}, err: ErrMalformed},
+		{name: "broken sheet", files: map[string]string{"xl/worksheets/sheet1.xml": "<worksheet><sheetData><row>"}, err: ErrMalformed},
+		{
+			name: "shared string out of range",
+			files: map[string]string{
+				"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c t="s"><v>5</v></c></row></sheetData></worksheet>`,
+			},
+			err: ErrMalformed,
+		},
+		{
+			name: "cell far out of the sheet",
+			files: map[string]string{
+				"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="99999999"><c><v>1</v></c></row></sheetData></worksheet>`,
+			},
+			err: ErrMalformed,
+		},
+		{
+			name: "inflates far above the workbook",
+			files: map[string]string{
+				"xl/worksheets/sheet1.xml": "<worksheet>" + strings.Repeat(" ", 10<<20) + "</worksheet>",
+			},
+			err: ErrMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, _, err := Parse("soato.xlsx", zipFiles(t, tt.files))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(units); !reflect.DeepEqual(got, tt.units) {
+				t.Fatalf("got %q, want %q", got, tt.units)
+			}
+		})
+	}
+}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestCompare(t *testing.T) {
+	unit := func(id, level string, soato, parent uint32, name, ruName string, active bool) *models.SoatoUnit {
+		return &models.SoatoUnit{ID: id, Level: level, Soato: soato, ParentSoato: parent, Name: name, RuName: ruName, Active: active}
+	}
+	city, region := models.AdminUnitCity, models.AdminUnitRegion
+
+	tests := []struct {
+		name       string
+		stored     []*models.SoatoUnit
+		classifier []*models.SoatoUnit
+		ruNames    bool
+		want       models.SoatoDiff
+	}{
+		{
+			name:       "unchanged",
+			stored:     []*models.SoatoUnit{unit("c1", city, 1703, 0, "Andijon", "Андижан", true)},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "Андижан", false)},
+			ruNames:    true,
+			want:       models.SoatoDiff{Unchanged: 1},
+		},
+		{
+			name:       "added",
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "Андижан", false)},
+			ruNames:    true,
+			want: models.SoatoDiff{Added: []*models.SoatoChange{
+				{Level: city, Soato: 1703, Name: "Andijon", RuName: "Андижан"},
+			}},
+		},
+		{
+			name:       "renamed",
+			stored:     []*models.SoatoUnit{unit("c1", city, 1703, 0, "Andijon", "Андижан", true)},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon viloyati", "Андижанская область", false)},
+			ruNames:    true,
+			want: models.SoatoDiff{Renamed: []*models.SoatoChange{
+				{Level: city, Soato: 1703, UnitID: "c1", Name: "Andijon viloyati", OldName: "Andijon", RuName: "Андижанская область", OldRuName: "Андижан"},
+			}},
+		},
+		{
+			name:       "without ru names the stored ones stay",
+			stored:     []*models.SoatoUnit{unit("c1", city, 1703, 0, "Andijon", "Андижан", true)},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "", false)},
+			want:       models.SoatoDiff{Unchanged: 1},
+		},
+		{
+			name:       "renamed without ru names",
+			stored:     []*models.SoatoUnit{unit("c1", city, 1703, 0, "Andijon", "Андижан", true)},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon viloyati", "", false)},
+			want: models.SoatoDiff{Renamed: []*models.SoatoChange{
+				{Level: city, Soato: 1703, UnitID: "c1", Name: "Andijon viloyati", OldName: "Andijon", RuName: "Андижан"},
+			}},
+		},
+		{
+			name:       "ru name removed when the column is there",
+			stored:     []*models.SoatoUnit{unit("c1", city, 1703, 0, "Andijon", "Андижан", true)},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "", false)},
+			ruNames:    true,
+			want: models.SoatoDiff{Renamed: []*models.SoatoChange{
+				{Level: city, Soato: 1703, UnitID: "c1", Name: "Andijon", OldRuName: "Андижан"},
+			}},
+		},
+		{
+			name: "abolished",
+			stored: []*models.SoatoUnit{
+				unit("c1", city, 1703, 0, "Andijon", "", true),
+				unit("r1", region, 1703202, 1703, "Oltinko'l", "", true),
+			},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "", false)},
+			want: models.SoatoDiff{Unchanged: 1, Abolished: []*models.SoatoChange{
+				{Level: region, Soato: 1703202, ParentSoato: 1703, UnitID: "r1", Name: "Oltinko'l"},
+			}},
+		},
+		{
+			name:       "restored",
+			stored:     []*models.SoatoUnit{unit("c1", city, 1703, 0, "Andijon", "", false)},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "", false)},
+			want: models.SoatoDiff{Restored: []*models.SoatoChange{
+				{Level: city, Soato: 1703, UnitID: "c1", Name: "Andijon"},
+			}},
+		},
+		{
+			name: "the active one of a code given out again",
+			stored: []*models.SoatoUnit{
+				unit("old", city, 1703, 0, "Eski", "", false),
+				unit("new", city, 1703, 0, "Andijon", "", true),
+			},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "", false)},
+			want:       models.SoatoDiff{Unchanged: 1},
+		},
+		{
+			name: "units without codes and abolished units are left alone",
+			stored: []*models.SoatoUnit{
+				unit("c0", city, 0, 0, "Qo'lda", "", true),
+				unit("c9", city, 1799, 0, "Yo'q", "", false),
+			},
+			want: models.SoatoDiff{},
+		},
+		{
+			name:       "same code on another level",
+			stored:     []*models.SoatoUnit{unit("r1", region, 1703, 0, "Andijon", "", true)},
+			classifier: []*models.SoatoUnit{unit("", city, 1703, 0, "Andijon", "", false)},
+			want: models.SoatoDiff{
+				Added:     []*models.SoatoChange{{Level: city, Soato: 1703, Name: "Andijon"}},
+				Abolished: []*models.SoatoChange{{Level: region, Soato: 1703, UnitID: "r1", Name: "Andijon"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(tt.stored, tt.classifier, tt.ruNames)
+			for _, list := range []*[]*models.SoatoChange{&tt.want.Added, &tt.want.Renamed, &tt.want.Abolished, &tt.want.Restored} {
+				if *list == nil {
+					*list = []*models.SoatoChange{}
+				}
+			}
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Fatalf("got %s, want %s", dump(got), dump(&tt.want))
+			}
+		})
+	}
+}
+
+func dump(diff *models.SoatoDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "unchanged %d", diff.Unchanged)
+	for name, list := range map[string][]*models.SoatoChange{"added": diff.Added, "renamed": diff.Renamed, "abolished": diff.Abolished, "restored": diff.Restored} {
+		for _, change := range list {
+			fmt.Fprintf(&b, " %s%+v", name, *change)
+		}
+	}
+	return b.String()
+}
+
+func TestValidateCode(t *testing.T) {
+	tests := []struct {
+		level         string
+		parent, soato uint32
+		ok            bool
+	}{
+		{models.AdminUnitCity, 0, 1703, true},
+		{models.AdminUnitCity, 0, 170, false},
+		{models.AdminUnitCity, 0, 1703202, false},
+		{models.AdminUnitCity, 0, 0, true},
+		{models.AdminUnitRegion, 1703, 1703202, true},
+		{models.AdminUnitRegion, 1706, 1703202, false},
+		{models.AdminUnitRegion, 0, 1703202, true},
+		{models.AdminUnitRegion, 1703, 17032021, false},
+		{models.AdminUnitDistrict, 1703202, 1703202501, true},
+		{models.AdminUnitDistrict, 1703203, 1703202501, false},
+		{models.AdminUnitDistrict, 1703202, 1703202, false},
+		{"country", 0, 17, false},
+	}
+	for _, tt := range tests {
+		err := ValidateCode(tt.level, tt.parent, tt.soato)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s %d under %d: %v", tt.level, tt.soato, tt.parent, err)
+		}
+	}
+}
+
+func TestChildCodes(t *testing.T) {
+	tests := []struct {
+		level    string
+		soato    uint32
+		from, to uint64
+	}{
+		{models.AdminUnitCity, 1726, 1726000, 1726999},
+		{models.AdminUnitRegion, 1726269, 1726269000, 1726269999},
+		{models.AdminUnitRegion, 9999999, 9999999000, 9999999999},
+		{models.AdminUnitDistrict, 1726269501, 0, 0},
+	}
+	for _, tt := range tests {
+		if from, to := ChildCodes(tt.level, tt.soato); from != tt.from || to != tt.to {
+			t.Errorf("%s %d: %d-%d, want %d-%d", tt.level, tt.soato, from, to, tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package soato
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/e-space-uz/backend/pkg/unzip"
+)
+
+// readCSV takes commas or, as spreadsheets in Russian locales save them,
+// semicolons
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: CSV is not UTF-8", ErrMalformed)
+	}
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+	return rows, nil
+}
+
+// readXLSX reads the first sheet of an Office Open XML workbook
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	// a hundred times the workbook is far above what sheets compress to
+	limit := int64(len(data)) * 100
+	read := func(name string) ([]byte, error) {
+		file := files[name]
+		if file == nil {
+			return nil, nil
+		}
+		return readZipFile(file, limit)
+	}
+
+	sheet, err := firstSheet(read)
+	if err != nil {
+		return nil, err
+	}
+	content, err := read(sheet)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%w: workbook has no sheets", ErrMalformed)
+	}
+	shared, err := read("xl/sharedStrings.xml")
+	if err != nil {
+		return nil, err
+	}
+	sharedStrings, err := parseSharedStrings(shared)
+	if err != nil {
+		return nil, err
+	}
+	return parseSheet(content, sharedStrings)
+}
+
+// firstSheet follows the workbook to the part of its first sheet
+func firstSheet(read func(string) ([]byte, error)) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	workbook, err := read("xl/workbook.xml")
+	if err != nil || workbook == nil {
+		return fallback, err
+	}
+	relationships, err := read("xl/_rels/workbook.xml.rels")
+	if err != nil || relationships == nil {
+		return fallback, err
+	}
+
+	var book struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(workbook, &book); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+	if err := xml.Unmarshal(relationships, &rels); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+	if len(book.Sheets) == 0 {
+		return "", fmt.Errorf("%w: workbook has no sheets", ErrMalformed)
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != book.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// parseSharedStrings returns the texts cells of type s point to, the runs of
+// rich text are joined
+func parseSharedStrings(data []byte) ([]string, error) {
+	var (
+		values  []string
+		decoder = xml.NewDecoder(bytes.NewReader(data))
+		text    strings.Builder
+		inText  bool
+		// phonetic hints are not part of the text
+		inPhonetic bool
+	)
+	if data == nil {
+		return nil, nil
+	}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				text.Reset()
+			case "t":
+				inText = !inPhonetic
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				values = append(values, text.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+func parseSheet(data []byte, sharedStrings []string) ([][]string, error) {
+	var (
+		rows     [][]string
+		decoder  = xml.NewDecoder(bytes.NewReader(data))
+		row      = -1
+		column   int
+		cellType string
+		value    strings.Builder
+		inValue  bool
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row++
+				if number, err := strconv.Atoi(attribute(t, "r")); err == nil && number > 0 {
+					row = number - 1
+				}
+				column = -1
+			case "c":
+				column++
+				if ref := attribute(t, "r"); ref != "" {
+					column = columnIndex(ref)
+				}
+				cellType = attribute(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				text := value.String()
+				if cellType == "s" {
+					index, err := strconv.Atoi(text)
+					if err != nil || index < 0 || index >= len(sharedStrings) {
+						return nil, fmt.Errorf("%w: shared string %q", ErrMalformed, text)
+					}
+					text = sharedStrings[index]
+				}
+				if row < 0 || column < 0 || row > 1<<20 || column > 1<<14 {
+					return nil, fmt.Errorf("%w: cell out of the sheet", ErrMalformed)
+				}
+				for len(rows) <= row {
+					rows = append(rows, nil)
+				}
+				for len(rows[row]) <= column {
+					rows[row] = append(rows[row], "")
+				}
+				rows[row][column] = text
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+func attribute(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// columnIndex turns the letters of a reference such as AB12 into a column
+// from zero
+func columnIndex(ref string) int {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1
+}
+
+// readZipFile reports the files an archive can not give as malformed
+func readZipFile(file *zip.File, limit int64) ([]byte, error) {
+	content, err := unzip.ReadFile(file, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err)
+	}
+	return content, nil
+}
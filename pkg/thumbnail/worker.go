@@ -0,0 +1,127 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/logger"
+)
+
+// State is what the worker knows of the thumbnails of an image
+type State int
+
+const (
+	// Unknown has to be looked up in the file store
+	Unknown State = iota
+	// Ready thumbnails are in the file store
+	Ready
+	// Failed images could not be decoded, they are not queued again
+	Failed
+)
+
+// maxStates bounds the states kept, they are forgotten all at once past it
+const maxStates = 100000
+
+// Worker makes thumbnails in the background, so uploads do not wait for the
+// resizing
+type Worker struct {
+	store   filestore.FileStore
+	log     logger.Logger
+	jobs    chan string
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]bool
+	states  map[string]State
+}
+
+// NewWorker starts workers goroutines reading a queue of queueSize names
+func NewWorker(store filestore.FileStore, log logger.Logger, workers, queueSize int, timeout time.Duration) *Worker {
+	w := &Worker{
+		store:   store,
+		log:     log,
+		jobs:    make(chan string, queueSize),
+		timeout: timeout,
+		pending: map[string]bool{},
+		states:  map[string]State{},
+	}
+	for i := 0; i < workers; i++ {
+		go w.run()
+	}
+	return w
+}
+
+// State tells what is known of the thumbnails of an image
+func (w *Worker) State(name string) State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.states[name]
+}
+
+// SetState records what was found out of the thumbnails of an image
+func (w *Worker) SetState(name string, state State) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.states) >= maxStates {
+		w.states = map[string]State{}
+	}
+	w.states[name] = state
+}
+
+// Enqueue asks for the thumbnails of an image in the file store. It does not
+// block, names are dropped when the queue is full, already in it or could
+// not be decoded before.
+func (w *Worker) Enqueue(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending[name] || w.states[name] == Failed {
+		return
+	}
+
+	select {
+	case w.jobs <- name:
+		w.pending[name] = true
+	default:
+		w.log.Warn("thumbnail queue is full", logger.String("file", name))
+	}
+}
+
+func (w *Worker) run() {
+	for name := range w.jobs {
+		if err := w.generate(name); err != nil {
+			w.log.Error("error while generating thumbnails", logger.String("file", name), logger.Error(err))
+		}
+		w.mu.Lock()
+		delete(w.pending, name)
+		w.mu.Unlock()
+	}
+}
+
+func (w *Worker) generate(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	object, _, err := w.store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	thumbnails, err := GenerateFrom(object)
+	if err != nil {
+		// an image that can not be decoded will not decode next time either
+		w.SetState(name, Failed)
+		return err
+	}
+	for size, content := range thumbnails {
+		err := w.store.Put(ctx, Name(name, size), bytes.NewReader(content), int64(len(content)), "image/jpeg")
+		if err != nil {
+			return err
+		}
+	}
+	w.SetState(name, Ready)
+	return nil
+}
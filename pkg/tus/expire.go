@@ -0,0 +1,83 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/filestore"
+	"github.com/e-space-uz/backend/pkg/logger"
+)
+
+// expireBatch is how many uploads are removed per query
+const expireBatch = 100
+
+// Storage finds and removes upload records
+type Storage interface {
+	GetExpired(ctx context.Context, now time.Time, limit int64) ([]*models.Upload, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Expirer removes uploads that were not finished before they expired,
+// together with their chunks
+type Expirer struct {
+	store   filestore.FileStore
+	storage Storage
+	log     logger.Logger
+}
+
+func NewExpirer(store filestore.FileStore, storage Storage, log logger.Logger) *Expirer {
+	return &Expirer{
+		store:   store,
+		storage: storage,
+		log:     log,
+	}
+}
+
+// Schedule removes expired uploads every interval until ctx is done
+func (e *Expirer) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Expire(ctx); err != nil {
+				e.log.Error("error while removing expired uploads", logger.Error(err))
+			}
+		}
+	}
+}
+
+func (e *Expirer) Expire(ctx context.Context) error {
+	for {
+		uploads, err := e.storage.GetExpired(ctx, time.Now(), expireBatch)
+		if err != nil {
+			return err
+		}
+		for _, upload := range uploads {
+			if err := RemoveParts(ctx, e.store, upload); err != nil {
+				return err
+			}
+			if err := e.storage.Delete(ctx, upload.ID.Hex()); err != nil {
+				return err
+			}
+		}
+		if len(uploads) < expireBatch {
+			return nil
+		}
+	}
+}
+
+// RemoveParts deletes the chunks of an upload, chunks that are gone already
+// are skipped
+func RemoveParts(ctx context.Context, store filestore.FileStore, upload *models.Upload) error {
+	for _, part := range upload.Parts {
+		if err := store.Delete(ctx, part.Name); err != nil && !errors.Is(err, filestore.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Package tus has the parts of the tus 1.0 resumable upload protocol that
+// do not depend on HTTP handlers: metadata, chunk names, assembly of the
+// chunks and expiry of abandoned uploads. See https://tus.io/protocols/resumable-upload
+package tus
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/e-space-uz/backend/pkg/filestore"
+)
+
+const (
+	Version    = "1.0.0"
+	Extensions = "creation,termination,expiration"
+
+	// OffsetContentType is the content type of PATCH requests
+	OffsetContentType = "application/offset+octet-stream"
+
+	// Prefix is where chunks of unfinished uploads are kept in the file store
+	Prefix = "uploads/"
+)
+
+var ErrInvalidMetadata = errors.New("invalid Upload-Metadata")
+
+// ParseMetadata decodes the Upload-Metadata header: comma separated pairs of
+// a key and a base64 value, the value may be left out
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidMetadata, err)
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, ErrInvalidMetadata
+		}
+	}
+	return metadata, nil
+}
+
+// PartName is the file store name of the chunk of an upload starting at
+// offset. Requests racing for the same offset pass different nonces, so the
+// losing one can not overwrite the chunk of the winner.
+func PartName(uploadID string, offset int64, nonce string) string {
+	return fmt.Sprintf("%s%s/%020d-%s", Prefix, uploadID, offset, nonce)
+}
+
+// partsReader reads the chunks one after another, opening each one only when
+// the previous is done
+type partsReader struct {
+	ctx     context.Context
+	store   filestore.FileStore
+	parts   []string
+	current io.ReadCloser
+}
+
+// NewPartsReader returns the content of the chunks as one stream. The caller
+// closes it.
+func NewPartsReader(ctx context.Context, store filestore.FileStore, parts []string) io.ReadCloser {
+	return &partsReader{
+		ctx:   ctx,
+		store: store,
+		parts: parts,
+	}
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			part, _, err := r.store.Get(r.ctx, r.parts[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current = part
+			r.parts = r.parts[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			_ = r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/e-space-uz/backend/pkg/filestore"
+)
+
+func TestParseMetadata(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   map[string]string
+		err    error
+	}{
+		{
+			name:   "empty",
+			header: "",
+			want:   map[string]string{},
+		},
+		{
+			name:   "blank",
+			header: "  ",
+			want:   map[string]string{},
+		},
+		{
+			name:   "pairs",
+			header: "filename cGxhbi5wZGY=,filetype YXBwbGljYXRpb24vcGRm",
+			want:   map[string]string{"filename": "plan.pdf", "filetype": "application/pdf"},
+		},
+		{
+			name:   "spaces around pairs",
+			header: " filename cGxhbi5wZGY= , comment  0YHRhdC10LzQsA== ",
+			want:   map[string]string{"filename": "plan.pdf", "comment": "схема"},
+		},
+		{
+			name:   "key without value",
+			header: "is_confidential,filename cGxhbi5wZGY=",
+			want:   map[string]string{"is_confidential": "", "filename": "plan.pdf"},
+		},
+		{
+			name:   "empty value",
+			header: "comment ",
+			want:   map[string]string{"comment": ""},
+		},
+		{
+			name:   "later key wins",
+			header: "filename YS5wZGY=,filename Yi5wZGY=",
+			want:   map[string]string{"filename": "b.pdf"},
+		},
+		{
+			name:   "invalid base64",
+			header: "filename plan.pdf",
+			err:    ErrInvalidMetadata,
+		},
+		{
+			name:   "unpadded base64",
+			header: "filename cGxhbi5wZGY",
+			err:    ErrInvalidMetadata,
+		},
+		{
+			name:   "url alphabet",
+			header: "filename _-8=",
+			err:    ErrInvalidMetadata,
+		},
+		{
+			name:   "three fields",
+			header: "filename cGxhbi5wZGY= YQ==",
+			err:    ErrInvalidMetadata,
+		},
+		{
+			name:   "empty pair",
+			header: "filename cGxhbi5wZGY=,,filetype YQ==",
+			err:    ErrInvalidMetadata,
+		},
+		{
+			name:   "trailing comma",
+			header: "filename cGxhbi5wZGY=,",
+			err:    ErrInvalidMetadata,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMetadata(tt.header)
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPartName checks that names sort by offset, whatever the nonce
+func TestPartName(t *testing.T) {
+	names := []string{
+		PartName("u1", 1<<40, "a"),
+		PartName("u1", 10, "z"),
+		PartName("u1", 9, "b"),
+		PartName("u1", 0, "c"),
+	}
+	sort.Strings(names)
+	want := []string{
+		"uploads/u1/00000000000000000000-c",
+		"uploads/u1/00000000000000000009-b",
+		"uploads/u1/00000000000000000010-z",
+		"uploads/u1/00000001099511627776-a",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %q, want %q", names, want)
+	}
+}
+
+func TestPartsReader(t *testing.T) {
+	ctx := context.Background()
+	store, err := filestore.NewLocal(t.TempDir(), "http://localhost/files", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(name, content string) string {
+		if err := store.Put(ctx, name, strings.NewReader(content), int64(len(content)), "application/octet-stream"); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	first := put(PartName("u1", 0, "a"), "hello, ")
+	second := put(PartName("u1", 7, "b"), "w")
+	third := put(PartName("u1", 8, "c"), "orld")
+
+	tests := []struct {
+		name  string
+		parts []string
+		want  string
+		err   error
+	}{
+		{name: "no parts", parts: nil, want: ""},
+		{name: "one part", parts: []string{first}, want: "hello, "},
+		{name: "in order", parts: []string{first, second, third}, want: "hello, world"},
+		{name: "given order", parts: []string{third, first}, want: "orldhello, "},
+		{name: "missing part", parts: []string{first, "uploads/u1/missing"}, err: filestore.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewPartsReader(ctx, store, tt.parts)
+			defer reader.Close()
+			got, err := ioutil.ReadAll(reader)
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err == nil && string(got) != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// small reads cross every part boundary
+	reader := NewPartsReader(ctx, store, []string{first, second, third})
+	defer reader.Close()
+	if err := iotest.TestReader(reader, []byte("hello, world")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,32 @@
+// Package unzip reads the files of uploaded archives. The sizes an archive
+// claims are not trusted, a small upload can inflate to gigabytes.
+package unzip
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+var ErrTooLarge = errors.New("file in the archive is too large")
+
+// ReadFile reads the file of the archive, failing with ErrTooLarge past
+// limit bytes
+func ReadFile(file *zip.File, limit int64) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%w: %s", ErrTooLarge, file.Name)
+	}
+	return content, nil
+}
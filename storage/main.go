@@ -17,33 +17,57 @@ type StorageI interface {
 	EntityDraft() repo.EntityDraftI
 	EntityFiles() repo.EntityFilesI
 	GroupProperty() repo.GroupPropertyI
+	FormSchemaVersion() repo.FormSchemaVersionI
+	PhotoMetadata() repo.PhotoMetadataI
+	FileGC() repo.FileGCI
+	Upload() repo.UploadI
+	IssuedDocument() repo.IssuedDocumentI
+	Decision() repo.DecisionI
+	AdminBoundary() repo.AdminBoundaryI
+	SoatoImport() repo.SoatoImportI
 }
 
 type storageMongo struct {
-	applicantRepo     repo.ApplicantI
-	staffRepo         repo.StaffI
-	propertyRepo      repo.PropertyI
-	cityRepo          repo.CityI
-	regionRepo        repo.RegionI
-	districtRepo      repo.DistrictI
-	entityRepo        repo.EntityI
-	entityDraftRepo   repo.EntityDraftI
-	groupPropertyRepo repo.GroupPropertyI
-	entityFilesRepo   repo.EntityFilesI
+	applicantRepo         repo.ApplicantI
+	staffRepo             repo.StaffI
+	propertyRepo          repo.PropertyI
+	cityRepo              repo.CityI
+	regionRepo            repo.RegionI
+	districtRepo          repo.DistrictI
+	entityRepo            repo.EntityI
+	entityDraftRepo       repo.EntityDraftI
+	groupPropertyRepo     repo.GroupPropertyI
+	entityFilesRepo       repo.EntityFilesI
+	formSchemaVersionRepo repo.FormSchemaVersionI
+	photoMetadataRepo     repo.PhotoMetadataI
+	fileGCRepo            repo.FileGCI
+	uploadRepo            repo.UploadI
+	issuedDocumentRepo    repo.IssuedDocumentI
+	decisionRepo          repo.DecisionI
+	adminBoundaryRepo     repo.AdminBoundaryI
+	soatoImportRepo       repo.SoatoImportI
 }
 
 func NewStorageMongo(db *db.Database) StorageI {
 	return &storageMongo{
-		applicantRepo:     mongodb.NewApplicantRepo(db),
-		staffRepo:         mongodb.NewStaffRepo(db),
-		cityRepo:          mongodb.NewCityRepo(db),
-		regionRepo:        mongodb.NewRegionRepo(db),
-		districtRepo:      mongodb.NewDistrictRepo(db),
-		propertyRepo:      mongodb.NewPropertyRepo(db),
-		entityRepo:        mongodb.NewEntityRepo(db),
-		groupPropertyRepo: mongodb.NewGroupPropertyRepo(db),
-		entityFilesRepo:   mongodb.NewEntityFilesRepo(db),
-		entityDraftRepo:   mongodb.NewEntityDraftRepo(db),
+		applicantRepo:         mongodb.NewApplicantRepo(db),
+		staffRepo:             mongodb.NewStaffRepo(db),
+		cityRepo:              mongodb.NewCityRepo(db),
+		regionRepo:            mongodb.NewRegionRepo(db),
+		districtRepo:          mongodb.NewDistrictRepo(db),
+		propertyRepo:          mongodb.NewPropertyRepo(db),
+		entityRepo:            mongodb.NewEntityRepo(db),
+		groupPropertyRepo:     mongodb.NewGroupPropertyRepo(db),
+		entityFilesRepo:       mongodb.NewEntityFilesRepo(db),
+		entityDraftRepo:       mongodb.NewEntityDraftRepo(db),
+		formSchemaVersionRepo: mongodb.NewFormSchemaVersionRepo(db),
+		photoMetadataRepo:     mongodb.NewPhotoMetadataRepo(db),
+		fileGCRepo:            mongodb.NewFileGCRepo(db),
+		uploadRepo:            mongodb.NewUploadRepo(db),
+		issuedDocumentRepo:    mongodb.NewIssuedDocumentRepo(db),
+		decisionRepo:          mongodb.NewDecisionRepo(db),
+		adminBoundaryRepo:     mongodb.NewAdminBoundaryRepo(db),
+		soatoImportRepo:       mongodb.NewSoatoImportRepo(db),
 	}
 }
 
@@ -78,3 +102,35 @@ func (s *storageMongo) EntityDraft() repo.EntityDraftI {
 func (s *storageMongo) Staff() repo.StaffI {
 	return s.staffRepo
 }
+
+func (s *storageMongo) FormSchemaVersion() repo.FormSchemaVersionI {
+	return s.formSchemaVersionRepo
+}
+
+func (s *storageMongo) PhotoMetadata() repo.PhotoMetadataI {
+	return s.photoMetadataRepo
+}
+
+func (s *storageMongo) FileGC() repo.FileGCI {
+	return s.fileGCRepo
+}
+
+func (s *storageMongo) Upload() repo.UploadI {
+	return s.uploadRepo
+}
+
+func (s *storageMongo) IssuedDocument() repo.IssuedDocumentI {
+	return s.issuedDocumentRepo
+}
+
+func (s *storageMongo) Decision() repo.DecisionI {
+	return s.decisionRepo
+}
+
+func (s *storageMongo) AdminBoundary() repo.AdminBoundaryI {
+	return s.adminBoundaryRepo
+}
+
+func (s *storageMongo) SoatoImport() repo.SoatoImportI {
+	return s.soatoImportRepo
+}
@@ -0,0 +1,77 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type adminBoundaryRepo struct {
+	collection *mongo.Collection
+}
+
+func NewAdminBoundaryRepo(db *mongo.Database) repo.AdminBoundaryI {
+	return &adminBoundaryRepo{
+		collection: db.Collection(config.AdminBoundaryCollection),
+	}
+}
+
+func (ar *adminBoundaryRepo) CreateIndexes(ctx context.Context) error {
+	_, err := ar.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{primitive.E{Key: "boundary", Value: "2dsphere"}},
+	})
+	return err
+}
+
+func (ar *adminBoundaryRepo) Upsert(ctx context.Context, req *models.AdminBoundary) error {
+	req.ID = models.AdminBoundaryID(req.Level, req.UnitID)
+	_, err := ar.collection.ReplaceOne(ctx, bson.M{"_id": req.ID}, req, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (ar *adminBoundaryRepo) Get(ctx context.Context, level, unitID string) (*models.AdminBoundary, error) {
+	var boundary models.AdminBoundary
+	err := ar.collection.FindOne(ctx, bson.M{"_id": models.AdminBoundaryID(level, unitID)}).Decode(&boundary)
+	if err != nil {
+		return nil, err
+	}
+	return &boundary, nil
+}
+
+func (ar *adminBoundaryRepo) Delete(ctx context.Context, level, unitID string) error {
+	result, err := ar.collection.DeleteOne(ctx, bson.M{"_id": models.AdminBoundaryID(level, unitID)})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (ar *adminBoundaryRepo) Lookup(ctx context.Context, point *models.GeoPoint) ([]*models.AdminBoundary, error) {
+	var (
+		boundaries = []*models.AdminBoundary{}
+		filter     = bson.M{"boundary": bson.M{"$geoIntersects": bson.M{"$geometry": point}}}
+	)
+	// the polygons are not needed to tell the units apart
+	opts := options.Find().SetProjection(bson.M{"boundary": 0}).SetSort(bson.M{"_id": 1})
+	rows, err := ar.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &boundaries); err != nil {
+		return nil, err
+	}
+	return boundaries, nil
+}
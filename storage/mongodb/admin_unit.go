@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexNotFound is the code MongoDB answers dropping a missing index with
+const indexNotFound = 27
+
+// activeUnitFilter leaves out abolished cities, regions and districts
+func activeUnitFilter() primitive.E {
+	return primitive.E{Key: "active", Value: bson.D{primitive.E{Key: "$ne", Value: false}}}
+}
+
+// migrateUnits marks the units loaded by hand before they had the flag as
+// active. A code belongs to one active unit of the level, an abolished code
+// may be given out again. Units without a code are not the classifier's and
+// may be many.
+func migrateUnits(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"active": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"active": true}})
+	if err != nil {
+		return err
+	}
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{primitive.E{Key: "soato", Value: 1}},
+		Options: options.Index().
+			SetName("soato_active_coded").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"active": true, "soato": bson.M{"$gt": 0}}),
+	})
+	if err != nil {
+		return err
+	}
+	// the index before it took units without a code too
+	_, err = collection.Indexes().DropOne(ctx, "soato_active")
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == indexNotFound {
+		return nil
+	}
+	return err
+}
+
+func insertUnit(ctx context.Context, collection *mongo.Collection, unit interface{}) error {
+	_, err := collection.InsertOne(ctx, unit)
+	if mongo.IsDuplicateKeyError(err) {
+		return repo.ErrSoatoTaken
+	}
+	return err
+}
+
+func updateUnit(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, set bson.M) error {
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if mongo.IsDuplicateKeyError(err) {
+		return repo.ErrSoatoTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// countCodedOutside counts the units under the parent whose codes are not
+// between from and to
+func countCodedOutside(ctx context.Context, collection *mongo.Collection, parentKey, parentID string, from, to uint64) (int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(parentID)
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, bson.M{
+		parentKey: objectID,
+		"soato":   bson.M{"$gt": 0},
+		"$or": bson.A{
+			bson.M{"soato": bson.M{"$lt": from}},
+			bson.M{"soato": bson.M{"$gt": to}},
+		},
+	})
+}
+
+func countActiveUnits(ctx context.Context, collection *mongo.Collection, parentKey, parentID string) (int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(parentID)
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, bson.D{
+		primitive.E{Key: parentKey, Value: objectID},
+		activeUnitFilter(),
+	})
+}
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"time"
 
 	"github.com/e-space-uz/backend/config"
 	"github.com/e-space-uz/backend/models"
@@ -74,3 +75,26 @@ func (cr *cityRepo) GetAll(ctx context.Context, page, limit uint32) ([]*models.C
 	}
 	return response, uint32(count), nil
 }
+
+func (cr *cityRepo) Migrate(ctx context.Context) error {
+	return migrateUnits(ctx, cr.collection)
+}
+
+func (cr *cityRepo) Create(ctx context.Context, city *models.CreateUpdateCity) (string, error) {
+	city.UpdatedAt = time.Now()
+	if err := insertUnit(ctx, cr.collection, city); err != nil {
+		return "", err
+	}
+	return city.ID.Hex(), nil
+}
+
+func (cr *cityRepo) Update(ctx context.Context, city *models.CreateUpdateCity) error {
+	return updateUnit(ctx, cr.collection, city.ID, bson.M{
+		"name":       city.Name,
+		"ru_name":    city.RuName,
+		"soato":      city.Soato,
+		"code":       city.Code,
+		"active":     city.Active,
+		"updated_at": time.Now(),
+	})
+}
@@ -0,0 +1,71 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type decisionRepo struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func NewDecisionRepo(db *mongo.Database) repo.DecisionI {
+	return &decisionRepo{
+		client:     db.Client(),
+		collection: db.Collection(config.DecisionCollection),
+	}
+}
+
+// Create relies on the _id, which is made of the subject and the sequence.
+// The decision and the status change are kept together or not at all, which
+// needs MongoDB to run as a replica set.
+func (dr *decisionRepo) Create(ctx context.Context, req *models.Decision, changeStatus func(ctx context.Context) error) error {
+	session, err := dr.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		if _, err := dr.collection.InsertOne(sessionCtx, req); err != nil {
+			return nil, err
+		}
+		return nil, changeStatus(sessionCtx)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return repo.ErrDecisionConflict
+	}
+	return err
+}
+
+func (dr *decisionRepo) GetAll(ctx context.Context, req *models.GetDecisionsRequest) ([]*models.Decision, error) {
+	var (
+		decisions = []*models.Decision{}
+		filter    = bson.M{"entity_id": req.EntityID}
+	)
+	if req.EntityDraftID != "" {
+		filter = bson.M{"entity_draft_id": req.EntityDraftID}
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.M{"sequence": 1})
+	rows, err := dr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &decisions); err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/e-space-uz/backend/config"
 	"github.com/e-space-uz/backend/models"
@@ -129,12 +130,13 @@ func (cr *districtRepo) GetAllByCityRegion(ctx context.Context, regionID, cityID
 	if err != nil {
 		return nil, 0, err
 	}
-	filter = append(filter, bson.E{Key: "region_id", Value: regionObjectID}, bson.E{Key: "city_id", Value: cityObjectID})
+	filter = append(filter, bson.E{Key: "region_id", Value: regionObjectID}, bson.E{Key: "city_id", Value: cityObjectID}, activeUnitFilter())
 
 	pipeline = append(pipeline, bson.D{
 		primitive.E{Key: "$match", Value: bson.D{
 			primitive.E{Key: "city_id", Value: cityObjectID},
-			primitive.E{Key: "region_id", Value: regionObjectID}}}})
+			primitive.E{Key: "region_id", Value: regionObjectID},
+			activeUnitFilter()}}})
 
 	count, err := cr.collection.CountDocuments(context.Background(), filter)
 
@@ -179,3 +181,52 @@ func (cr *districtRepo) GetAllByCityRegion(ctx context.Context, regionID, cityID
 	}
 	return response, uint32(count), nil
 }
+
+func (cr *districtRepo) Migrate(ctx context.Context) error {
+	return migrateUnits(ctx, cr.collection)
+}
+
+func (cr *districtRepo) CountActiveByRegion(ctx context.Context, regionID string) (int64, error) {
+	return countActiveUnits(ctx, cr.collection, "region_id", regionID)
+}
+
+func (cr *districtRepo) CountCodedOutside(ctx context.Context, regionID string, from, to uint64) (int64, error) {
+	return countCodedOutside(ctx, cr.collection, "region_id", regionID, from, to)
+}
+
+func (cr *districtRepo) MoveRegion(ctx context.Context, regionID, cityID string) error {
+	regionObjectID, err := primitive.ObjectIDFromHex(regionID)
+	if err != nil {
+		return err
+	}
+	cityObjectID, err := primitive.ObjectIDFromHex(cityID)
+	if err != nil {
+		return err
+	}
+	_, err = cr.collection.UpdateMany(ctx,
+		bson.M{"region_id": regionObjectID},
+		bson.M{"$set": bson.M{"city_id": cityObjectID, "updated_at": time.Now()}})
+	return err
+}
+
+func (cr *districtRepo) Create(ctx context.Context, district *models.CreateUpdateDistrict) (string, error) {
+	district.UpdatedAt = time.Now()
+	if err := insertUnit(ctx, cr.collection, district); err != nil {
+		return "", err
+	}
+	return district.ID.Hex(), nil
+}
+
+func (cr *districtRepo) Update(ctx context.Context, district *models.CreateUpdateDistrict) error {
+	return updateUnit(ctx, cr.collection, district.ID, bson.M{
+		"city_id":     district.CityID,
+		"region_id":   district.RegionID,
+		"name":        district.Name,
+		"ru_name":     district.RuName,
+		"code":        district.Code,
+		"external_id": district.ExternalID,
+		"soato":       district.Soato,
+		"active":      district.Active,
+		"updated_at":  time.Now(),
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type entityRepo struct {
@@ -25,6 +26,11 @@ func NewEntityRepo(db *mongo.Database) repo.EntityI {
 	}
 }
 
+// CreateIndexes adds the 2dsphere indexes the geo filters use
+func (er *entityRepo) CreateIndexes(ctx context.Context) error {
+	return createGeoIndexes(ctx, er.collection)
+}
+
 func (er *entityRepo) Create(ctx context.Context, entity *models.CreateUpdateEntity) (string, error) {
 	var (
 		entitySoato = strconv.Itoa(int(entity.District.Soato))
@@ -42,6 +48,10 @@ func (er *entityRepo) Create(ctx context.Context, entity *models.CreateUpdateEnt
 		CreatedAt:          time.Now(),
 		UpdatedAt:          time.Now(),
 		EntityStatusUpdate: time.Now(),
+		FormVersions:       entity.FormVersions,
+		Location:           entity.Location,
+		Boundary:           entity.Boundary,
+		Topology:           entity.Topology,
 		City: &models.City{
 			ID:     entity.City.ID,
 			Name:   entity.City.Name,
@@ -91,6 +101,10 @@ func (er *entityRepo) Create(ctx context.Context, entity *models.CreateUpdateEnt
 		createEntity.EntityGallery = []string{}
 	}
 
+	if createEntity.FormVersions == nil {
+		createEntity.FormVersions = []*models.StepVersion{}
+	}
+
 	createEntity.EntityDrafts = []primitive.ObjectID{}
 	_, err = er.collection.InsertOne(
 		ctx,
@@ -138,14 +152,18 @@ func (er *entityRepo) Get(ctx context.Context, id string) (*models.Entity, error
 				primitive.E{Key: "as", Value: "entity_drafts"}}}},
 
 		bson.D{
-			primitive.E{Key: "$unwind", Value: "$entity_properties"}},
+			primitive.E{Key: "$unwind", Value: bson.D{
+				primitive.E{Key: "path", Value: "$entity_properties"}, {
+					Key: "preserveNullAndEmptyArrays", Value: true}}}},
 		bson.D{
 			primitive.E{Key: "$lookup", Value: bson.D{
 				primitive.E{Key: "from", Value: propertyCollection},
 				primitive.E{Key: "localField", Value: "entity_properties.property_id"},
 				primitive.E{Key: "foreignField", Value: "_id"},
 				primitive.E{Key: "as", Value: "entity_properties.property"}}}},
-		bson.D{primitive.E{Key: "$unwind", Value: bson.D{primitive.E{Key: "path", Value: "$entity_properties.property"}}}},
+		bson.D{primitive.E{Key: "$unwind", Value: bson.D{
+			primitive.E{Key: "path", Value: "$entity_properties.property"}, {
+				Key: "preserveNullAndEmptyArrays", Value: true}}}},
 
 		bson.D{
 			primitive.E{Key: "$group", Value: bson.D{
@@ -164,6 +182,10 @@ func (er *entityRepo) Get(ctx context.Context, id string) (*models.Entity, error
 					primitive.E{Key: "$first", Value: "$status"}}},
 				primitive.E{Key: "version", Value: bson.D{
 					primitive.E{Key: "$first", Value: "$version"}}},
+				primitive.E{Key: "entity_type_code", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$entity_type_code"}}},
+				primitive.E{Key: "form_versions", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$form_versions"}}},
 				primitive.E{Key: "city", Value: bson.D{
 					primitive.E{Key: "$first", Value: "$city"}}},
 				primitive.E{Key: "region", Value: bson.D{
@@ -184,6 +206,14 @@ func (er *entityRepo) Get(ctx context.Context, id string) (*models.Entity, error
 					primitive.E{Key: "$first", Value: "$organizations"}}},
 				primitive.E{Key: "deadline", Value: bson.D{
 					primitive.E{Key: "$first", Value: "$deadline"}}},
+				primitive.E{Key: "location", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$location"}}},
+				primitive.E{Key: "boundary", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$boundary"}}},
+				primitive.E{Key: "topology", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$topology"}}},
+				primitive.E{Key: "inspection", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$inspection"}}},
 				primitive.E{Key: "entity_properties", Value: bson.D{
 					primitive.E{Key: "$push", Value: "$entity_properties"}}}}},
 		})
@@ -250,6 +280,9 @@ func (er *entityRepo) GetAll(ctx context.Context, req *models.GetAllEntitiesRequ
 				primitive.E{Key: "region", Value: 1},
 				primitive.E{Key: "district", Value: 1},
 				primitive.E{Key: "entity_properties", Value: 1},
+				primitive.E{Key: "form_versions", Value: 1},
+				primitive.E{Key: "location", Value: 1},
+				primitive.E{Key: "boundary", Value: 1},
 				primitive.E{Key: "created_at", Value: 1},
 			}}},
 			bson.D{primitive.E{Key: "$sort", Value: bson.D{primitive.E{Key: "created_at", Value: -1}}}},
@@ -309,6 +342,10 @@ func (er *entityRepo) GetAllWithProperties(ctx context.Context, req *models.GetA
 			primitive.E{Key: "region", Value: 1},
 			primitive.E{Key: "district", Value: 1},
 			primitive.E{Key: "entity_properties", Value: 1},
+			primitive.E{Key: "entity_gallery", Value: 1},
+			primitive.E{Key: "form_versions", Value: 1},
+			primitive.E{Key: "location", Value: 1},
+			primitive.E{Key: "boundary", Value: 1},
 			primitive.E{Key: "created_at", Value: 1},
 		}}},
 		bson.D{primitive.E{Key: "$sort", Value: bson.D{primitive.E{Key: "created_at", Value: -1}}}},
@@ -377,22 +414,255 @@ func (er *entityRepo) UpdateEntityDrafts(ctx context.Context, entityID, entityDr
 	return err
 }
 
-// filter in one function
-func getAllFilter(req *models.GetAllEntitiesRequest) (bson.D, mongo.Pipeline, error) {
-	var (
-		filter   = bson.D{}
-		pipeline = mongo.Pipeline{}
-	)
+// UpdateEntityFiles adds and removes documents of the entity, the other
+// files, such as those given when the entity was created, are left
+func (er *entityRepo) UpdateEntityFiles(ctx context.Context, id string, add, remove []string) error {
+	entityObjectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	addIDs, err := objectIDs(add)
+	if err != nil {
+		return err
+	}
+	removeIDs, err := objectIDs(remove)
+	if err != nil {
+		return err
+	}
 
-	if req.RegionID != "" {
-		filter = append(filter, primitive.E{Key: "region.id", Value: req.RegionID})
-		pipeline = append(pipeline, bson.D{primitive.E{Key: "$match", Value: bson.D{primitive.E{Key: "region.id", Value: req.RegionID}}}})
+	// entities created without files keep null, which $pull and $addToSet
+	// refuse
+	_, err = er.collection.UpdateOne(ctx,
+		bson.M{"_id": entityObjectID, "entity_files": bson.M{"$not": bson.M{"$type": "array"}}},
+		bson.M{"$set": bson.M{"entity_files": bson.A{}}})
+	if err != nil {
+		return err
+	}
+	// a field can not be pulled from and added to in one update
+	updates := []bson.M{
+		{"$pull": bson.M{"entity_files": bson.M{"$in": removeIDs}}, "$set": bson.M{"updated_at": time.Now()}},
+		{"$addToSet": bson.M{"entity_files": bson.M{"$each": addIDs}}},
+	}
+	for _, update := range updates {
+		result, err := er.collection.UpdateOne(ctx, bson.M{"_id": entityObjectID}, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
 	}
-	if req.CityID != "" {
-		filter = append(filter, primitive.E{Key: "city.id", Value: req.CityID})
-		pipeline = append(pipeline, bson.D{primitive.E{Key: "$match", Value: bson.D{primitive.E{Key: "city.id", Value: req.CityID}}}})
+	return nil
+}
+
+func objectIDs(ids []string) ([]primitive.ObjectID, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
 	}
+	return objectIDs, nil
+}
 
+// getAllFilter returns entityFilter both as a filter and as the first stage
+// of a pipeline
+func getAllFilter(req *models.GetAllEntitiesRequest) (bson.D, mongo.Pipeline, error) {
+	filter := entityFilter(req)
+	pipeline := mongo.Pipeline{
+		bson.D{primitive.E{Key: "$match", Value: filter}},
+	}
 	return filter, pipeline, nil
+}
+
+// entityFilter is the filter of the entity list and of Each, so a list and
+// an export of the same request have the same entities. Units are matched by
+// the _id they are embedded with, soato and number exactly. Deleted entities
+// are left out.
+func entityFilter(req *models.GetAllEntitiesRequest) bson.D {
+	filter := bson.D{notDeletedEntityFilter()}
+	for _, field := range []struct {
+		key   string
+		value string
+	}{
+		{"region._id", req.RegionID},
+		{"city._id", req.CityID},
+		{"district._id", req.DistrictID},
+		{"entity_soato", req.EntitySoato},
+		{"entity_number", req.EntityNumber},
+	} {
+		if field.value != "" {
+			filter = append(filter, primitive.E{Key: field.key, Value: field.value})
+		}
+	}
+	if geoFilter := geoFilters(req); len(geoFilter) > 0 {
+		filter = append(filter, primitive.E{Key: "$and", Value: geoFilter})
+	}
+	return filter
+}
+
+func (er *entityRepo) UpdateLocation(ctx context.Context, id string, location *models.GeoPoint) error {
+	return er.updateFields(ctx, id, bson.M{"location": location})
+}
+
+func (er *entityRepo) UpdateBoundary(ctx context.Context, id string, boundary *models.GeoBoundary, topology *models.Topology) error {
+	return er.updateFields(ctx, id, bson.M{"boundary": boundary, "topology": topology})
+}
+
+func (er *entityRepo) UpdateInspection(ctx context.Context, id string, inspection *models.EntityInspection) error {
+	return er.updateFields(ctx, id, bson.M{"inspection": inspection})
+}
+
+func (er *entityRepo) UpdateStatus(ctx context.Context, id string, version uint64, status string) error {
+	entityObjectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := er.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": entityObjectID, "version": version},
+		bson.M{
+			"$set": bson.M{
+				"status":               status,
+				"entity_status_update": time.Now(),
+				"updated_at":           time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		count, err := er.collection.CountDocuments(ctx, bson.M{"_id": entityObjectID})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return repo.ErrVersionConflict
+	}
+	return nil
+}
+
+func (er *entityRepo) updateFields(ctx context.Context, id string, fields bson.M) error {
+	entityObjectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	fields["updated_at"] = time.Now()
+
+	result, err := er.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": entityObjectID},
+		bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (er *entityRepo) GetOverlapping(ctx context.Context, boundary *models.GeoBoundary, excludeID string, limit int64) ([]*models.EntityBoundary, error) {
+	filter := bson.D{
+		primitive.E{Key: "boundary", Value: bson.D{
+			primitive.E{Key: "$geoIntersects", Value: bson.D{primitive.E{Key: "$geometry", Value: boundary}}},
+		}},
+		notDeletedEntityFilter(),
+	}
+	if excludeID != "" {
+		excludeObjectID, err := primitive.ObjectIDFromHex(excludeID)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, primitive.E{Key: "_id", Value: bson.D{primitive.E{Key: "$ne", Value: excludeObjectID}}})
+	}
+	return er.findBoundaries(ctx, filter, options.Find().
+		SetSort(bson.D{primitive.E{Key: "_id", Value: 1}}).
+		SetLimit(limit))
+}
+
+// Each calls fn with the entities matching the request one at a time in the
+// order they were created, so exports of a whole district do not load it at
+// once. Page and Limit are ignored.
+func (er *entityRepo) Each(ctx context.Context, req *models.GetAllEntitiesRequest, fn func(*models.GetAllEntities) error) error {
+	filter := entityFilter(req)
+	opts := options.Find().
+		SetSort(bson.D{primitive.E{Key: "_id", Value: 1}}).
+		SetProjection(bson.D{
+			primitive.E{Key: "_id", Value: 1},
+			primitive.E{Key: "entity_number", Value: 1},
+			primitive.E{Key: "status", Value: 1},
+			primitive.E{Key: "entity_soato", Value: 1},
+			primitive.E{Key: "address", Value: 1},
+			primitive.E{Key: "city", Value: 1},
+			primitive.E{Key: "region", Value: 1},
+			primitive.E{Key: "district", Value: 1},
+			primitive.E{Key: "entity_properties", Value: 1},
+			primitive.E{Key: "location", Value: 1},
+			primitive.E{Key: "boundary", Value: 1},
+			primitive.E{Key: "created_at", Value: 1},
+		})
+	rows, err := er.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	for rows.Next(ctx) {
+		var entity models.GetAllEntities
+		if err := rows.Decode(&entity); err != nil {
+			return err
+		}
+		if err := fn(&entity); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetDistrictBoundaries returns every entity of the district that has a
+// boundary
+func (er *entityRepo) GetDistrictBoundaries(ctx context.Context, districtID string) ([]*models.EntityBoundary, error) {
+	filter := bson.D{
+		primitive.E{Key: "district._id", Value: districtID},
+		primitive.E{Key: "boundary", Value: bson.D{primitive.E{Key: "$exists", Value: true}}},
+		notDeletedEntityFilter(),
+	}
+	return er.findBoundaries(ctx, filter, options.Find().SetSort(bson.D{primitive.E{Key: "_id", Value: 1}}))
+}
+
+func (er *entityRepo) findBoundaries(ctx context.Context, filter bson.D, opts *options.FindOptions) ([]*models.EntityBoundary, error) {
+	boundaries := []*models.EntityBoundary{}
+	opts.SetProjection(bson.D{
+		primitive.E{Key: "_id", Value: 1},
+		primitive.E{Key: "entity_number", Value: 1},
+		primitive.E{Key: "boundary", Value: 1},
+	})
+	rows, err := er.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &boundaries); err != nil {
+		return nil, err
+	}
+	return boundaries, nil
+}
 
+// notDeletedEntityFilter leaves out deleted entities, entities are created
+// with a zero deleted_at
+func notDeletedEntityFilter() primitive.E {
+	return primitive.E{Key: "deleted_at", Value: bson.D{
+		primitive.E{Key: "$not", Value: bson.D{primitive.E{Key: "$gt", Value: time.Time{}}}}}}
 }
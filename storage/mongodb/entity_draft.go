@@ -14,6 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type entityDraftRepo struct {
@@ -25,6 +26,12 @@ func NewEntityDraftRepo(db *mongo.Database) repo.EntityDraftI {
 		collection: db.Collection(config.EntityDraftCollection),
 	}
 }
+
+// CreateIndexes adds the 2dsphere indexes of draft locations and boundaries
+func (cr entityDraftRepo) CreateIndexes(ctx context.Context) error {
+	return createGeoIndexes(ctx, cr.collection)
+}
+
 func (cr entityDraftRepo) Create(ctx context.Context, req *models.CreateEntityDraft) (string, error) {
 	soatoCount, err := cr.collection.CountDocuments(
 		ctx,
@@ -40,10 +47,17 @@ func (cr entityDraftRepo) Create(ctx context.Context, req *models.CreateEntityDr
 
 	createEntity := &models.CreateEntityDraft{
 		ID:                req.ID,
-		Status:            "new",
+		EntityID:          req.EntityID,
+		ApplicantID:       req.ApplicantID,
+		Status:            models.EntityDraftStatusNew,
 		Comment:           req.Comment,
 		EntityDraftNumber: draftNumber,
 		EntityDraftSoato:  req.EntityDraftSoato,
+		EntityTypeCode:    req.EntityTypeCode,
+		FormVersions:      req.FormVersions,
+		Location:          req.Location,
+		Boundary:          req.Boundary,
+		Topology:          req.Topology,
 
 		City: models.City{
 			ID:     req.City.ID,
@@ -69,6 +83,13 @@ func (cr entityDraftRepo) Create(ctx context.Context, req *models.CreateEntityDr
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	if createEntity.FormVersions == nil {
+		createEntity.FormVersions = []*models.StepVersion{}
+	}
+	createEntity.CompletedSteps = []uint32{}
+	if req.Wizard {
+		createEntity.Status = models.EntityDraftStatusInProgress
+	}
 	for _, property := range req.EntityProperties {
 		createEntity.EntityProperties = append(createEntity.EntityProperties, &models.CreateEntityProperty{
 			PropertyID: property.PropertyID,
@@ -115,14 +136,18 @@ func (cr entityDraftRepo) Get(ctx context.Context, id string) (*models.EntityDra
 				primitive.E{Key: "path", Value: "$status"}, {
 					Key: "preserveNullAndEmptyArrays", Value: false}}}},
 		bson.D{
-			primitive.E{Key: "$unwind", Value: "$entity_properties"}},
+			primitive.E{Key: "$unwind", Value: bson.D{
+				primitive.E{Key: "path", Value: "$entity_properties"}, {
+					Key: "preserveNullAndEmptyArrays", Value: true}}}},
 		bson.D{
 			primitive.E{Key: "$lookup", Value: bson.D{
 				primitive.E{Key: "from", Value: propertyCollection},
 				primitive.E{Key: "localField", Value: "entity_properties.property_id"},
 				primitive.E{Key: "foreignField", Value: "_id"},
 				primitive.E{Key: "as", Value: "entity_properties.property"}}}},
-		bson.D{primitive.E{Key: "$unwind", Value: bson.D{primitive.E{Key: "path", Value: "$entity_properties.property"}}}},
+		bson.D{primitive.E{Key: "$unwind", Value: bson.D{
+			primitive.E{Key: "path", Value: "$entity_properties.property"}, {
+				Key: "preserveNullAndEmptyArrays", Value: true}}}},
 		bson.D{
 			primitive.E{Key: "$lookup", Value: bson.D{
 				primitive.E{Key: "from", Value: entityCollection},
@@ -142,8 +167,24 @@ func (cr entityDraftRepo) Get(ctx context.Context, id string) (*models.EntityDra
 					primitive.E{Key: "$first", Value: "$entity_draft_soato"}}},
 				primitive.E{Key: "status", Value: bson.D{
 					primitive.E{Key: "$first", Value: "$status"}}},
+				primitive.E{Key: "applicant_id", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$applicant_id"}}},
+				primitive.E{Key: "version", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$version"}}},
 				primitive.E{Key: "comment", Value: bson.D{
 					primitive.E{Key: "$first", Value: "$comment"}}},
+				primitive.E{Key: "entity_type_code", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$entity_type_code"}}},
+				primitive.E{Key: "form_versions", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$form_versions"}}},
+				primitive.E{Key: "completed_steps", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$completed_steps"}}},
+				primitive.E{Key: "location", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$location"}}},
+				primitive.E{Key: "boundary", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$boundary"}}},
+				primitive.E{Key: "topology", Value: bson.D{
+					primitive.E{Key: "$first", Value: "$topology"}}},
 				primitive.E{Key: "city", Value: bson.D{
 					primitive.E{Key: "$first", Value: "$city"}}},
 				primitive.E{Key: "region", Value: bson.D{
@@ -194,6 +235,178 @@ func (cr entityDraftRepo) Get(ctx context.Context, id string) (*models.EntityDra
 	return &response, nil
 }
 
+// GetEntityApplicant returns the applicant of the latest draft filed for the
+// entity
+func (cr entityDraftRepo) GetEntityApplicant(ctx context.Context, entityID string) (string, error) {
+	var draft struct {
+		ApplicantID string `bson:"applicant_id"`
+	}
+	entityObjectID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return "", err
+	}
+	err = cr.collection.FindOne(
+		ctx,
+		bson.M{
+			"entity_id":    entityObjectID,
+			"applicant_id": bson.M{"$nin": bson.A{nil, ""}},
+		},
+		options.FindOne().SetSort(bson.M{"created_at": -1}),
+	).Decode(&draft)
+	return draft.ApplicantID, err
+}
+
+// UpdateStep replaces the entity properties and completed steps of a draft
+// that is still being filled in and still at the version they were read at
+func (cr entityDraftRepo) UpdateStep(ctx context.Context, req *models.UpdateEntityDraftStep) error {
+	if req.EntityProperties == nil {
+		req.EntityProperties = []*models.CreateEntityProperty{}
+	}
+	if req.CompletedSteps == nil {
+		req.CompletedSteps = []uint32{}
+	}
+
+	result, err := cr.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":     req.ID,
+			"status":  models.EntityDraftStatusInProgress,
+			"version": draftVersion(req.Version),
+		},
+		bson.M{
+			"$set": bson.M{
+				"entity_properties": req.EntityProperties,
+				"completed_steps":   req.CompletedSteps,
+				"updated_at":        time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return cr.inProgressConflict(ctx, req.ID)
+	}
+	return nil
+}
+
+// Submit hands an in progress draft over for review, unless it has changed
+// since it was checked
+func (cr entityDraftRepo) Submit(ctx context.Context, id string, version uint64, completedSteps []uint32) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := cr.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":     objectID,
+			"status":  models.EntityDraftStatusInProgress,
+			"version": draftVersion(version),
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":          models.EntityDraftStatusNew,
+				"completed_steps": completedSteps,
+				"submitted_at":    time.Now(),
+				"updated_at":      time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return cr.inProgressConflict(ctx, objectID)
+	}
+	return nil
+}
+
+// draftVersion matches drafts saved before they had a version at version 0
+func draftVersion(version uint64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// inProgressConflict tells a draft that is not in progress anymore from one
+// that was changed since it was read
+func (cr entityDraftRepo) inProgressConflict(ctx context.Context, id primitive.ObjectID) error {
+	count, err := cr.collection.CountDocuments(ctx, bson.M{"_id": id, "status": models.EntityDraftStatusInProgress})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return repo.ErrVersionConflict
+}
+
+func (cr entityDraftRepo) UpdateLocation(ctx context.Context, id string, location *models.GeoPoint) error {
+	return cr.updateFields(ctx, id, bson.M{"location": location})
+}
+
+func (cr entityDraftRepo) UpdateBoundary(ctx context.Context, id string, boundary *models.GeoBoundary, topology *models.Topology) error {
+	return cr.updateFields(ctx, id, bson.M{"boundary": boundary, "topology": topology})
+}
+
+func (cr entityDraftRepo) updateFields(ctx context.Context, id string, fields bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	fields["updated_at"] = time.Now()
+
+	result, err := cr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// SetStatus changes the status of a draft that is still in the given one and
+// at the version it was read at
+func (cr entityDraftRepo) SetStatus(ctx context.Context, id string, version uint64, from, to string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := cr.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":     objectID,
+			"status":  from,
+			"version": draftVersion(version),
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":     to,
+				"updated_at": time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		count, err := cr.collection.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return repo.ErrVersionConflict
+	}
+	return nil
+}
+
 func (cr entityDraftRepo) GetAll(ctx context.Context, req *models.GetAllEntityDraftsRequest) ([]*models.GetAllEntityDrafts, uint64, error) {
 	var (
 		response         []*models.GetAllEntityDrafts
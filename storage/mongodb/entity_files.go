@@ -51,13 +51,21 @@ func (sr *entityFilesRepo) Get(ctx context.Context, id string) (*models.EntityFi
 		return nil, err
 	}
 
-	return &models.EntityFiles{
-		ID:       entityFilesDecode.ID,
-		FileName: entityFilesDecode.FileName,
-		Url:      entityFilesDecode.Url,
-		Comment:  entityFilesDecode.Comment,
-		User:     entityFilesDecode.User,
-	}, nil
+	return &entityFilesDecode, nil
+}
+
+func (sr *entityFilesRepo) GetByObjectName(ctx context.Context, objectName string) (*models.EntityFiles, error) {
+	var entityFiles models.EntityFiles
+
+	if err := sr.collection.FindOne(
+		ctx,
+		bson.M{
+			"object_name": objectName,
+		}).Decode(&entityFiles); err != nil {
+		return nil, err
+	}
+
+	return &entityFiles, nil
 }
 
 func (sr *entityFilesRepo) GetAll(ctx context.Context, page, limit uint32, search string) ([]*models.EntityFiles, uint32, error) {
@@ -102,13 +110,7 @@ func (sr *entityFilesRepo) GetAll(ctx context.Context, page, limit uint32, searc
 		if err := rows.Decode(&entityFilesDecode); err != nil {
 			return nil, 0, err
 		}
-		entityFiles = &models.EntityFiles{
-			ID:       entityFilesDecode.ID,
-			FileName: entityFilesDecode.FileName,
-			Url:      entityFilesDecode.Url,
-			Comment:  entityFilesDecode.Comment,
-			User:     entityFilesDecode.User,
-		}
+		entityFiles = &entityFilesDecode
 		entityFileses = append(entityFileses, entityFiles)
 	}
 	r := new(big.Int)
@@ -141,10 +143,35 @@ func (sr *entityFilesRepo) Update(ctx context.Context, entityFiles *models.Entit
 
 	return nil
 }
+
+func (sr *entityFilesRepo) SetSignature(ctx context.Context, id string, signature *models.FileSignature) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := sr.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"signature":  signature,
+			"updated_at": time.Now(),
+		}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (sr *entityFilesRepo) Delete(ctx context.Context, id string) error {
-	filter := bson.M{"_id": bson.M{"$eq": id}}
-	_, err := sr.collection.DeleteOne(
-		context.Background(),
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": bson.M{"$eq": objectID}}
+	_, err = sr.collection.DeleteOne(
+		ctx,
 		filter)
 
 	if err != nil {
@@ -154,6 +181,243 @@ func (sr *entityFilesRepo) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// attachRetries bounds the attempts of Attach when another attach of the
+// document type takes the same version or the current flag
+const attachRetries = 5
+
+// CreateIndexes keeps one document per version and one current version of a
+// document type for each entity and draft, Attach relies on them when
+// attaches run at once. An object of the file store is registered once,
+// files uploaded before the file store have no object name.
+func (sr *entityFilesRepo) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{primitive.E{Key: "object_name", Value: 1}},
+			Options: options.Index().
+				SetName("object_name").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"object_name": bson.M{"$gt": ""}}),
+		},
+	}
+	for _, owner := range []string{"entity_id", "entity_draft_id"} {
+		ownerExists := bson.M{owner: bson.M{"$exists": true}}
+		indexes = append(indexes,
+			mongo.IndexModel{
+				Keys: bson.D{
+					primitive.E{Key: owner, Value: 1},
+					primitive.E{Key: "document_type", Value: 1},
+					primitive.E{Key: "version", Value: 1},
+				},
+				Options: options.Index().
+					SetName(owner + "_document_version").
+					SetUnique(true).
+					SetPartialFilterExpression(ownerExists),
+			},
+			mongo.IndexModel{
+				Keys: bson.D{
+					primitive.E{Key: owner, Value: 1},
+					primitive.E{Key: "document_type", Value: 1},
+				},
+				Options: options.Index().
+					SetName(owner + "_document_current").
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{owner: bson.M{"$exists": true}, "current": true}),
+			})
+	}
+	_, err := sr.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// Attach links the file to the entity or draft as the newest version of its
+// document type. Older versions stay in the collection with current unset.
+// When another version is attached at the same time the higher one is
+// current.
+func (sr *entityFilesRepo) Attach(ctx context.Context, req *models.AttachEntityFile) (*models.EntityFiles, error) {
+	file, err := sr.Get(ctx, req.FileID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if file.EntityID != "" || file.EntityDraftID != "" {
+		return nil, repo.ErrFileAlreadyAttached
+	}
+
+	version, err := sr.claimVersion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := sr.makeCurrent(ctx, req, version); err != nil {
+		return nil, err
+	}
+
+	return sr.Get(ctx, req.FileID.Hex())
+}
+
+// claimVersion links the file to the owner under the version after the last
+// one, the unique index turns away a version taken at the same time
+func (sr *entityFilesRepo) claimVersion(ctx context.Context, req *models.AttachEntityFile) (uint32, error) {
+	filter := documentOwnerFilter(req)
+	filter["document_type"] = req.DocumentType
+
+	for attempt := 1; ; attempt++ {
+		var last struct {
+			Version uint32 `bson:"version"`
+		}
+		err := sr.collection.FindOne(ctx, filter, options.FindOne().
+			SetSort(bson.D{primitive.E{Key: "version", Value: -1}}).
+			SetProjection(bson.M{"version": 1})).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return 0, err
+		}
+
+		set := bson.M{
+			"document_type": req.DocumentType,
+			"version":       last.Version + 1,
+			"current":       false,
+			"user":          req.User,
+			"attached_at":   time.Now(),
+			"updated_at":    time.Now(),
+		}
+		if req.Comment != "" {
+			set["comment"] = req.Comment
+		}
+		if !req.EntityID.IsZero() {
+			set["entity_id"] = req.EntityID
+		} else {
+			set["entity_draft_id"] = req.EntityDraftID
+		}
+		result, err := sr.collection.UpdateOne(ctx, bson.M{
+			"_id":             req.FileID,
+			"entity_id":       bson.M{"$exists": false},
+			"entity_draft_id": bson.M{"$exists": false},
+		}, bson.M{"$set": set})
+		if mongo.IsDuplicateKeyError(err) && attempt < attachRetries {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if result.MatchedCount == 0 {
+			return 0, repo.ErrFileAlreadyAttached
+		}
+		return last.Version + 1, nil
+	}
+}
+
+// makeCurrent moves the current flag to the version unless a higher one was
+// attached meanwhile
+func (sr *entityFilesRepo) makeCurrent(ctx context.Context, req *models.AttachEntityFile, version uint32) error {
+	filter := documentOwnerFilter(req)
+	filter["document_type"] = req.DocumentType
+
+	for attempt := 1; ; attempt++ {
+		newer := bson.M{"version": bson.M{"$gt": version}}
+		for key, value := range filter {
+			newer[key] = value
+		}
+		count, err := sr.collection.CountDocuments(ctx, newer)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		older := bson.M{"current": true, "version": bson.M{"$lt": version}}
+		for key, value := range filter {
+			older[key] = value
+		}
+		_, err = sr.collection.UpdateMany(ctx, older, bson.M{
+			"$set": bson.M{
+				"current":    false,
+				"updated_at": time.Now(),
+			}})
+		if err != nil {
+			return err
+		}
+		_, err = sr.collection.UpdateOne(ctx, bson.M{"_id": req.FileID}, bson.M{
+			"$set": bson.M{
+				"current":    true,
+				"updated_at": time.Now(),
+			}})
+		if mongo.IsDuplicateKeyError(err) && attempt < attachRetries {
+			continue
+		}
+		return err
+	}
+}
+
+// Detach removes the document from the current ones of its owner, the file
+// stays as an older version
+func (sr *entityFilesRepo) Detach(ctx context.Context, req *models.AttachEntityFile) error {
+	filter := documentOwnerFilter(req)
+	filter["_id"] = req.FileID
+	filter["current"] = true
+
+	result, err := sr.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{
+			"current":     false,
+			"detached_at": time.Now(),
+			"updated_at":  time.Now(),
+		}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (sr *entityFilesRepo) GetDocuments(ctx context.Context, req *models.GetEntityDocumentsRequest) ([]*models.EntityFiles, error) {
+	var (
+		documents = []*models.EntityFiles{}
+		owner     = &models.AttachEntityFile{}
+		err       error
+	)
+	if req.EntityID != "" {
+		if owner.EntityID, err = primitive.ObjectIDFromHex(req.EntityID); err != nil {
+			return nil, err
+		}
+	} else {
+		if owner.EntityDraftID, err = primitive.ObjectIDFromHex(req.EntityDraftID); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := documentOwnerFilter(owner)
+	if req.DocumentType != "" {
+		filter["document_type"] = req.DocumentType
+	}
+	if !req.AllVersions {
+		filter["current"] = true
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.D{
+		primitive.E{Key: "document_type", Value: 1},
+		primitive.E{Key: "version", Value: -1},
+	})
+	rows, err := sr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+func documentOwnerFilter(req *models.AttachEntityFile) bson.M {
+	if !req.EntityID.IsZero() {
+		return bson.M{"entity_id": req.EntityID}
+	}
+	return bson.M{"entity_draft_id": req.EntityDraftID}
+}
+
 func (sr *entityFilesRepo) EntityFileExists(ctx context.Context, id string) (bool, error) {
 	ObjectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -0,0 +1,107 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type fileGCRepo struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewFileGCRepo(db *mongo.Database) repo.FileGCI {
+	return &fileGCRepo{
+		db:         db,
+		collection: db.Collection(config.FileGCRunCollection),
+	}
+}
+
+func (fr *fileGCRepo) ReferencedObjects(ctx context.Context) (map[string]bool, error) {
+	referenced := map[string]bool{}
+
+	for _, collection := range []string{config.EntityCollection, config.EntityDraftCollection} {
+		err := fr.each(ctx, collection, bson.M{"entity_gallery": 1}, func(raw bson.Raw) error {
+			var doc struct {
+				EntityGallery []string `bson:"entity_gallery"`
+			}
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return err
+			}
+			for _, name := range doc.EntityGallery {
+				referenced[objectName(name)] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// every registered file is kept, detached documents are older versions
+	err := fr.each(ctx, config.EntityFilesCollection, bson.M{"object_name": 1, "url": 1}, func(raw bson.Raw) error {
+		var doc struct {
+			ObjectName string `bson:"object_name"`
+			Url        string `bson:"url"`
+		}
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		if doc.ObjectName != "" {
+			referenced[doc.ObjectName] = true
+		}
+		if doc.Url != "" {
+			referenced[objectName(doc.Url)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return referenced, nil
+}
+
+func (fr *fileGCRepo) CreateRun(ctx context.Context, req *models.FileGCRun) error {
+	_, err := fr.collection.InsertOne(ctx, req)
+	return err
+}
+
+// each stops at the first document fn fails on, a document that can not be
+// read may hold references and nothing must be collected then
+func (fr *fileGCRepo) each(ctx context.Context, collection string, projection bson.M, fn func(bson.Raw) error) error {
+	rows, err := fr.db.Collection(collection).Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+	for rows.Next(ctx) {
+		if err := fn(rows.Current); err != nil {
+			return fmt.Errorf("%s %s: %w", collection, rows.Current.Lookup("_id"), err)
+		}
+	}
+	return rows.Err()
+}
+
+// objectName turns urls stored by older clients into file store names
+func objectName(name string) string {
+	if !strings.HasPrefix(name, "http://") && !strings.HasPrefix(name, "https://") {
+		return name
+	}
+	u, err := url.Parse(name)
+	if err != nil {
+		return name
+	}
+	return path.Base(u.Path)
+}
@@ -0,0 +1,190 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/utils"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type formSchemaVersionRepo struct {
+	collection *mongo.Collection
+}
+
+func NewFormSchemaVersionRepo(db *mongo.Database) repo.FormSchemaVersionI {
+	return &formSchemaVersionRepo{
+		collection: db.Collection(config.FormSchemaVersionCollection),
+	}
+}
+
+// publishRetries bounds the attempts of Create when another publish of the
+// step takes the same version
+const publishRetries = 5
+
+// CreateIndexes keeps one snapshot per version of a (type, step), Create
+// relies on it when publishes run at once
+func (fr *formSchemaVersionRepo) CreateIndexes(ctx context.Context) error {
+	_, err := fr.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			primitive.E{Key: "type", Value: 1},
+			primitive.E{Key: "step", Value: 1},
+			primitive.E{Key: "version", Value: 1},
+		},
+		Options: options.Index().SetName("type_step_version").SetUnique(true),
+	})
+	return err
+}
+
+// Create stores a new snapshot. Versions are numbered per (type, step) and
+// the assigned number is written back to req.Version.
+func (fr *formSchemaVersionRepo) Create(ctx context.Context, req *models.CreateFormSchemaVersion) (string, error) {
+	req.CreatedAt = time.Now()
+	if req.GroupProperties == nil {
+		req.GroupProperties = []*models.GroupProperty{}
+	}
+
+	for attempt := 1; ; attempt++ {
+		var last models.StepVersion
+		err := fr.collection.FindOne(
+			ctx,
+			bson.D{
+				primitive.E{Key: "type", Value: req.Type},
+				primitive.E{Key: "step", Value: req.Step},
+			},
+			options.FindOne().SetSort(bson.M{"version": -1}),
+		).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return "", err
+		}
+		req.Version = last.Version + 1
+
+		_, err = fr.collection.InsertOne(ctx, req)
+		if mongo.IsDuplicateKeyError(err) && attempt < publishRetries {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return req.ID.Hex(), nil
+	}
+}
+
+func (fr *formSchemaVersionRepo) Get(ctx context.Context, id string) (*models.FormSchemaVersion, error) {
+	var response models.FormSchemaVersion
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fr.collection.FindOne(
+		ctx,
+		bson.M{
+			"_id": objectID,
+		}).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (fr *formSchemaVersionRepo) GetByVersion(ctx context.Context, typeOf, step, version uint32) (*models.FormSchemaVersion, error) {
+	var response models.FormSchemaVersion
+
+	if err := fr.collection.FindOne(
+		ctx,
+		bson.D{
+			primitive.E{Key: "type", Value: typeOf},
+			primitive.E{Key: "step", Value: step},
+			primitive.E{Key: "version", Value: version},
+		}).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (fr *formSchemaVersionRepo) GetAll(ctx context.Context, req *models.GetAllFormSchemaVersionsRequest) ([]*models.FormSchemaVersion, uint32, error) {
+	var (
+		response []*models.FormSchemaVersion
+		versions []*models.FormSchemaVersion
+		filter   = bson.D{}
+		skip     = (req.Page - 1) * req.Limit
+	)
+
+	if req.Type != 0 {
+		filter = append(filter, primitive.E{Key: "type", Value: req.Type})
+	}
+	if req.Step != nil {
+		filter = append(filter, primitive.E{Key: "step", Value: *req.Step})
+	}
+
+	count, err := fr.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find()
+	opts.SetLimit(int64(req.Limit))
+	opts.SetSkip(int64(skip))
+	opts.SetSort(bson.D{
+		primitive.E{Key: "type", Value: 1},
+		primitive.E{Key: "step", Value: 1},
+		primitive.E{Key: "version", Value: -1},
+	})
+
+	rows, err := fr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &versions); err != nil {
+		return nil, 0, err
+	}
+	if err := utils.MarshalUnmarshal(versions, &response); err != nil {
+		return nil, 0, err
+	}
+	return response, uint32(count), nil
+}
+
+// GetLatestVersions returns the newest published version of every step of the type
+func (fr *formSchemaVersionRepo) GetLatestVersions(ctx context.Context, typeOf uint32) ([]*models.StepVersion, error) {
+	var (
+		response = []*models.StepVersion{}
+		pipeline = mongo.Pipeline{}
+	)
+
+	pipeline = append(pipeline,
+		bson.D{primitive.E{Key: "$match", Value: bson.D{
+			primitive.E{Key: "type", Value: typeOf}}}},
+		bson.D{primitive.E{Key: "$group", Value: bson.D{
+			primitive.E{Key: "_id", Value: "$step"},
+			primitive.E{Key: "version", Value: bson.D{
+				primitive.E{Key: "$max", Value: "$version"}}}}}},
+		bson.D{primitive.E{Key: "$project", Value: bson.D{
+			primitive.E{Key: "_id", Value: 0},
+			primitive.E{Key: "step", Value: "$_id"},
+			primitive.E{Key: "version", Value: 1}}}},
+		bson.D{primitive.E{Key: "$sort", Value: bson.D{primitive.E{Key: "step", Value: 1}}}},
+	)
+
+	rows, err := fr.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
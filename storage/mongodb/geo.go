@@ -0,0 +1,59 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/pkg/geo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// createGeoIndexes indexes the location point and the boundary polygons,
+// documents without them are left out of a 2dsphere index
+func createGeoIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{primitive.E{Key: "location", Value: "2dsphere"}}},
+		{Keys: bson.D{primitive.E{Key: "boundary", Value: "2dsphere"}}},
+	})
+	return err
+}
+
+// geoFilters matches entities by location or boundary, all of the filters
+// have to match. The request is validated by the handler.
+func geoFilters(req *models.GetAllEntitiesRequest) bson.A {
+	var filters bson.A
+	if len(req.BBox) == 4 {
+		minLon, minLat, maxLon, maxLat := req.BBox[0], req.BBox[1], req.BBox[2], req.BBox[3]
+		box := bson.D{
+			primitive.E{Key: "type", Value: models.GeoJSONPolygon},
+			primitive.E{Key: "coordinates", Value: [][][]float64{{
+				{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat},
+			}}},
+		}
+		filters = append(filters, intersectsFilter(box))
+	}
+	if req.Within != nil {
+		filters = append(filters, intersectsFilter(req.Within))
+	}
+	if req.Near != nil && req.Radius > 0 {
+		filters = append(filters, bson.D{primitive.E{Key: "location", Value: bson.D{
+			primitive.E{Key: "$geoWithin", Value: bson.D{
+				primitive.E{Key: "$centerSphere", Value: bson.A{req.Near.Coordinates, req.Radius / geo.EarthRadius}},
+			}},
+		}}})
+	}
+	return filters
+}
+
+func intersectsFilter(geometry interface{}) bson.D {
+	return bson.D{primitive.E{Key: "$or", Value: bson.A{
+		bson.D{primitive.E{Key: "location", Value: bson.D{
+			primitive.E{Key: "$geoWithin", Value: bson.D{primitive.E{Key: "$geometry", Value: geometry}}},
+		}}},
+		bson.D{primitive.E{Key: "boundary", Value: bson.D{
+			primitive.E{Key: "$geoIntersects", Value: bson.D{primitive.E{Key: "$geometry", Value: geometry}}},
+		}}},
+	}}}
+}
@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"encoding/json"
+	"sort"
 	"time"
 
 	"github.com/e-space-uz/backend/config"
@@ -12,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type groupProperty struct {
@@ -24,29 +26,41 @@ func NewGroupPropertyRepo(db *mongo.Database) repo.GroupPropertyI {
 	}
 }
 func (sr *groupProperty) Create(ctx context.Context, groupProperty *models.CreateGroupProperty) (string, error) {
-	var groupPropertiesArray = make([]*models.CreateProperties, len(groupProperty.Properties))
-
-	for _, property := range groupProperty.Properties {
-		groupPropertiesArray[property.Order] = &models.CreateProperties{
-			PropertyID: property.PropertyID,
-			Order:      property.Order}
+	createGroupProperty := &models.CreateGroupProperty{
+		ID:            groupProperty.ID,
+		Step:          groupProperty.Step,
+		Name:          groupProperty.Name,
+		Type:          groupProperty.Type,
+		Description:   groupProperty.Description,
+		Organization:  groupProperty.Organization,
+		Properties:    sortProperties(groupProperty.Properties),
+		ReadStatuses:  groupProperty.ReadStatuses,
+		WriteStatuses: groupProperty.WriteStatuses,
+		Translations:  groupProperty.Translations,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if createGroupProperty.ReadStatuses == nil {
+		createGroupProperty.ReadStatuses = []primitive.ObjectID{}
+	}
+	if createGroupProperty.WriteStatuses == nil {
+		createGroupProperty.WriteStatuses = []primitive.ObjectID{}
 	}
 
 	_, err := sr.collection.InsertOne(
-		context.Background(),
-		groupPropertiesArray,
+		ctx,
+		createGroupProperty,
 	)
 	if err != nil {
 		return "", err
 	}
-	return groupProperty.ID.Hex(), nil
+	return createGroupProperty.ID.Hex(), nil
 }
 
 func (sr *groupProperty) Get(ctx context.Context, id string) (*models.GroupProperty, error) {
 	var (
 		groupPropertyDecode []*models.GroupProperty
 		response            *models.GroupProperty
-		propertyCollection  = config.PropertyCollection
 		pipeline            = mongo.Pipeline{}
 	)
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -56,25 +70,20 @@ func (sr *groupProperty) Get(ctx context.Context, id string) (*models.GroupPrope
 
 	pipeline = append(pipeline,
 		bson.D{
-			bson.E{Key: "$match", Value: bson.D{
-				bson.E{Key: "_id", Value: objectID}}}},
-		bson.D{
-			bson.E{Key: "$lookup", Value: bson.D{
-				bson.E{Key: "from", Value: propertyCollection},
-				bson.E{Key: "localField", Value: "properties.property_id"},
-				bson.E{Key: "foreignField", Value: "_id"},
-				bson.E{Key: "as", Value: "properties"}}}},
+			bson.E{Key: "$match", Value: append(bson.D{
+				bson.E{Key: "_id", Value: objectID}}, notDeletedFilter()...)}},
 	)
+	pipeline = append(pipeline, orderedPropertiesLookup()...)
 
-	rows, err := sr.collection.Aggregate(context.Background(), pipeline)
-	defer func() {
-		_ = rows.Close(context.Background())
-	}()
-
+	rows, err := sr.collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, errors.Wrap(err, "error")
 	}
-	if err := rows.All(context.Background(), &groupPropertyDecode); err != nil {
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &groupPropertyDecode); err != nil {
 		return nil, errors.Wrap(err, "error")
 	}
 	if len(groupPropertyDecode) == 0 {
@@ -95,29 +104,34 @@ func (sr *groupProperty) GetAll(ctx context.Context, page, limit uint32) ([]*mod
 	var (
 		groupPropertiesDecode   []*models.GetAllGroupProperty
 		groupPropertiesResponse []*models.GetAllGroupProperty
-		filterCount             = bson.D{}
+		filterCount             = notDeletedFilter()
 		skip                    = (page - 1) * limit
 		pipeline                = mongo.Pipeline{}
 	)
 
-	count, err := sr.collection.CountDocuments(context.Background(), filterCount)
+	count, err := sr.collection.CountDocuments(ctx, filterCount)
 
 	if err != nil {
 		return nil, 0, err
 	}
 	pipeline = append(pipeline,
+		bson.D{primitive.E{Key: "$match", Value: filterCount}},
+		bson.D{primitive.E{Key: "$sort", Value: bson.D{
+			primitive.E{Key: "type", Value: 1},
+			primitive.E{Key: "step", Value: 1},
+			primitive.E{Key: "_id", Value: 1}}}},
 		bson.D{primitive.E{Key: "$skip", Value: skip}},
 		bson.D{primitive.E{Key: "$limit", Value: limit}})
 
-	rows, err := sr.collection.Aggregate(context.Background(), pipeline)
-	defer func() {
-		_ = rows.Close(context.Background())
-	}()
-
+	rows, err := sr.collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, 0, err
 	}
-	if err := rows.All(context.Background(), &groupPropertiesDecode); err != nil {
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &groupPropertiesDecode); err != nil {
 		return nil, 0, err
 	}
 	byte, err := json.Marshal(groupPropertiesDecode)
@@ -132,57 +146,248 @@ func (sr *groupProperty) GetAll(ctx context.Context, page, limit uint32) ([]*mod
 }
 
 func (sr *groupProperty) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
 	update := bson.M{
 		"$set": bson.M{
 			"deleted_at": time.Now(),
 		}}
 
-	filter := bson.M{"_id": bson.M{"$eq": id}}
-	_, err := sr.collection.UpdateOne(
-		context.Background(),
+	filter := bson.M{"_id": bson.M{"$eq": objectID}}
+	result, err := sr.collection.UpdateOne(
+		ctx,
 		filter,
 		update,
 	)
-
 	if err != nil {
 		return err
 	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 
 	return nil
 }
-func (sr *groupProperty) Update(ctx context.Context, groupProperty *models.CreateGroupProperty) error {
-	org := models.OrganizationCreate{}
 
+// Update keeps the status, which only UpdateStatus changes
+func (sr *groupProperty) Update(ctx context.Context, groupProperty *models.CreateGroupProperty) error {
 	updateGroupProperty := models.CreateGroupProperty{
-		Name:         groupProperty.Name,
-		Step:         groupProperty.Step,
-		Type:         groupProperty.Type,
-		Status:       groupProperty.Status,
-		Description:  groupProperty.Description,
-		Organization: org,
-	}
-	for _, property := range groupProperty.Properties {
-		updateGroupProperty.Properties = append(updateGroupProperty.Properties, &models.CreateProperties{
-			PropertyID: property.PropertyID,
-			Order:      property.Order,
-		})
+		Name:          groupProperty.Name,
+		Step:          groupProperty.Step,
+		Type:          groupProperty.Type,
+		Description:   groupProperty.Description,
+		Organization:  groupProperty.Organization,
+		Properties:    sortProperties(groupProperty.Properties),
+		ReadStatuses:  groupProperty.ReadStatuses,
+		WriteStatuses: groupProperty.WriteStatuses,
+	}
+	if updateGroupProperty.ReadStatuses == nil {
+		updateGroupProperty.ReadStatuses = []primitive.ObjectID{}
+	}
+	if updateGroupProperty.WriteStatuses == nil {
+		updateGroupProperty.WriteStatuses = []primitive.ObjectID{}
 	}
 
+	set := bson.M{
+		"name":           updateGroupProperty.Name,
+		"step":           updateGroupProperty.Step,
+		"type":           updateGroupProperty.Type,
+		"description":    updateGroupProperty.Description,
+		"properties":     updateGroupProperty.Properties,
+		"write_statuses": updateGroupProperty.WriteStatuses,
+		"read_statuses":  updateGroupProperty.ReadStatuses,
+		"organization":   updateGroupProperty.Organization,
+		"updated_at":     time.Now(),
+	}
+	// translations are managed separately, keep them unless new ones are sent
+	if groupProperty.Translations != nil {
+		set["translations"] = groupProperty.Translations
+	}
+	update := bson.M{"$set": set}
+
+	return sr.updateOne(ctx, groupProperty.ID, update)
+}
+
+func (sr *groupProperty) UpdateStatus(ctx context.Context, id string, status bool) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
 	update := bson.M{
 		"$set": bson.M{
-			"name":           updateGroupProperty.Name,
-			"step":           updateGroupProperty.Step,
-			"type":           updateGroupProperty.Type,
-			"status":         updateGroupProperty.Status,
-			"description":    updateGroupProperty.Description,
-			"properties":     updateGroupProperty.Properties,
-			"write_statuses": updateGroupProperty.WriteStatuses,
-			"read_statuses":  updateGroupProperty.ReadStatuses,
-			"organization":   updateGroupProperty.Organization,
-			"updated_at":     time.Now(),
+			"status":     status,
+			"updated_at": time.Now(),
 		}}
-	_ = update
-	return nil
+
+	return sr.updateOne(ctx, objectID, update)
+}
+
+// AddProperty inserts the property at the given order, shifting the following ones down
+func (sr *groupProperty) AddProperty(ctx context.Context, id string, property *models.CreateProperties) error {
+	group, err := sr.getRaw(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	properties := sortProperties(group.Properties)
+	for _, p := range properties {
+		if p.PropertyID == property.PropertyID {
+			return repo.ErrPropertyAlreadyInGroup
+		}
+	}
+
+	position := int(property.Order)
+	if position > len(properties) {
+		position = len(properties)
+	}
+	properties = append(properties, nil)
+	copy(properties[position+1:], properties[position:])
+	properties[position] = &models.CreateProperties{
+		PropertyID: property.PropertyID,
+	}
+
+	return sr.setProperties(ctx, group.ID, properties)
+}
+
+func (sr *groupProperty) RemoveProperty(ctx context.Context, id, propertyID string) error {
+	propertyObjectID, err := primitive.ObjectIDFromHex(propertyID)
+	if err != nil {
+		return err
+	}
+	group, err := sr.getRaw(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var (
+		properties = []*models.CreateProperties{}
+		found      bool
+	)
+	for _, p := range sortProperties(group.Properties) {
+		if p.PropertyID == propertyObjectID {
+			found = true
+			continue
+		}
+		properties = append(properties, p)
+	}
+	if !found {
+		return repo.ErrPropertyNotInGroup
+	}
+
+	return sr.setProperties(ctx, group.ID, properties)
+}
+
+// ReorderProperties replaces the order of the group's properties. The request
+// must list the same properties the group already has.
+func (sr *groupProperty) ReorderProperties(ctx context.Context, id string, properties []*models.CreateProperties) error {
+	group, err := sr.getRaw(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(properties) != len(group.Properties) {
+		return repo.ErrPropertiesMismatch
+	}
+	existing := make(map[primitive.ObjectID]bool, len(group.Properties))
+	for _, p := range group.Properties {
+		existing[p.PropertyID] = true
+	}
+	for _, p := range properties {
+		if !existing[p.PropertyID] {
+			return repo.ErrPropertiesMismatch
+		}
+		delete(existing, p.PropertyID)
+	}
+
+	return sr.setProperties(ctx, group.ID, sortProperties(properties))
+}
+
+// Clone copies the group property with its properties under a new id. The copy
+// starts inactive so it can be edited before it is shown to applicants.
+func (sr *groupProperty) Clone(ctx context.Context, id, name string) (string, error) {
+	group, err := sr.getRaw(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	group.ID = primitive.NewObjectID()
+	group.Status = false
+	if name != "" {
+		group.Name = name
+	} else {
+		group.Name = group.Name + " (copy)"
+	}
+
+	return sr.Create(ctx, group)
+}
+
+// UpdateTranslations merges the texts into the stored translations
+func (sr *groupProperty) UpdateTranslations(ctx context.Context, id string, req *models.UpdateGroupPropertyTranslationsSwag) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := newTranslationsUpdate()
+	update.add("translations.name", req.Name)
+	update.add("translations.description", req.Description)
+
+	return sr.updateOne(ctx, objectID, update.document())
+}
+
+// GetMissingTranslations lists the names and descriptions of the groups that
+// are not translated to every one of the languages
+func (sr *groupProperty) GetMissingTranslations(ctx context.Context, languages []string, defaultLanguage string) ([]*models.MissingTranslation, error) {
+	var (
+		response = []*models.MissingTranslation{}
+		groups   []*models.CreateGroupProperty
+	)
+
+	opts := options.Find()
+	opts.SetSort(bson.D{primitive.E{Key: "name", Value: 1}})
+
+	rows, err := sr.collection.Find(ctx, notDeletedFilter(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+	if err := rows.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		translations := group.Translations
+		if translations == nil {
+			translations = &models.GroupPropertyTranslations{}
+		}
+		fields := []struct {
+			field, text  string
+			translations models.Translations
+		}{
+			{"name", group.Name, translations.Name},
+			{"description", group.Description, translations.Description},
+		}
+		for _, f := range fields {
+			if f.text == "" {
+				continue
+			}
+			if missing := f.translations.Missing(languages, defaultLanguage); len(missing) != 0 {
+				response = append(response, &models.MissingTranslation{
+					Kind:    "group_property",
+					ID:      group.ID.Hex(),
+					Name:    group.Name,
+					Field:   f.field,
+					Text:    f.text,
+					Missing: missing,
+				})
+			}
+		}
+	}
+	return response, nil
 }
 
 func (cr *groupProperty) GroupPropertyExists(ctx context.Context, id string) (bool, error) {
@@ -200,46 +405,39 @@ func (cr *groupProperty) GroupPropertyExists(ctx context.Context, id string) (bo
 	return res == 1, nil
 }
 
-func (sr *groupProperty) GetAllByType(ctx context.Context, typeOf, step uint32) ([]*models.GroupProperty, uint32, error) {
+// GetAllByType returns the group properties of every step when step is nil
+func (sr *groupProperty) GetAllByType(ctx context.Context, typeOf uint32, step *uint32) ([]*models.GroupProperty, uint32, error) {
 	var (
 		groupPropertiesDecode []*models.GroupProperty
 		groupProperties       []*models.GroupProperty
-		filter                = bson.D{}
-		propertyCollection    = config.PropertyCollection
+		filter                = notDeletedFilter()
 		pipeline              = mongo.Pipeline{}
 	)
 
-	if step != 0 {
-		filter = append(filter, primitive.E{Key: "step", Value: step})
-		pipeline = append(pipeline, bson.D{primitive.E{Key: "$match", Value: bson.D{primitive.E{Key: "step", Value: step}}}})
+	if step != nil {
+		filter = append(filter, primitive.E{Key: "step", Value: *step})
 	}
 	if typeOf != 0 {
 		filter = append(filter, primitive.E{Key: "type", Value: typeOf})
-		pipeline = append(pipeline, bson.D{primitive.E{Key: "$match", Value: bson.D{primitive.E{Key: "type", Value: typeOf}}}})
 	}
+	pipeline = append(pipeline, bson.D{primitive.E{Key: "$match", Value: filter}})
 
-	count, err := sr.collection.CountDocuments(context.Background(), filter)
+	count, err := sr.collection.CountDocuments(ctx, filter)
 
 	if err != nil {
 		return nil, 0, err
 	}
-	pipeline = append(pipeline, bson.D{
-		bson.E{Key: "$lookup", Value: bson.D{
-			bson.E{Key: "from", Value: propertyCollection},
-			bson.E{Key: "localField", Value: "properties.property_id"},
-			bson.E{Key: "foreignField", Value: "_id"},
-			bson.E{Key: "as", Value: "properties"}}}},
-	)
-
-	rows, err := sr.collection.Aggregate(context.Background(), pipeline)
-	defer func() {
-		_ = rows.Close(context.Background())
-	}()
+	pipeline = append(pipeline, orderedPropertiesLookup()...)
 
+	rows, err := sr.collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, 0, err
 	}
-	if err := rows.All(context.Background(), &groupPropertiesDecode); err != nil {
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+
+	if err := rows.All(ctx, &groupPropertiesDecode); err != nil {
 		return nil, 0, err
 	}
 	byte, err := json.Marshal(groupPropertiesDecode)
@@ -252,3 +450,116 @@ func (sr *groupProperty) GetAllByType(ctx context.Context, typeOf, step uint32)
 
 	return groupProperties, uint32(count), nil
 }
+
+func (sr *groupProperty) getRaw(ctx context.Context, id string) (*models.CreateGroupProperty, error) {
+	var group models.CreateGroupProperty
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	filter := notDeletedFilter()
+	filter = append(filter, primitive.E{Key: "_id", Value: objectID})
+
+	if err := sr.collection.FindOne(ctx, filter).Decode(&group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (sr *groupProperty) setProperties(ctx context.Context, id primitive.ObjectID, properties []*models.CreateProperties) error {
+	for i, p := range properties {
+		p.Order = uint32(i)
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"properties": properties,
+			"updated_at": time.Now(),
+		}}
+
+	return sr.updateOne(ctx, id, update)
+}
+
+func (sr *groupProperty) updateOne(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	filter := notDeletedFilter()
+	filter = append(filter, primitive.E{Key: "_id", Value: id})
+
+	result, err := sr.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// sortProperties orders properties by Order and renumbers them from zero
+func sortProperties(properties []*models.CreateProperties) []*models.CreateProperties {
+	sorted := make([]*models.CreateProperties, 0, len(properties))
+	for _, p := range properties {
+		sorted = append(sorted, &models.CreateProperties{
+			PropertyID: p.PropertyID,
+			Order:      p.Order,
+		})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	for i, p := range sorted {
+		p.Order = uint32(i)
+	}
+	return sorted
+}
+
+func notDeletedFilter() bson.D {
+	return bson.D{primitive.E{Key: "deleted_at", Value: bson.D{
+		primitive.E{Key: "$exists", Value: false}}}}
+}
+
+// orderedPropertiesLookup replaces properties.property_id with the property
+// documents, keeping them sorted by their order inside the group
+func orderedPropertiesLookup() mongo.Pipeline {
+	return mongo.Pipeline{
+		bson.D{
+			bson.E{Key: "$unwind", Value: bson.D{
+				bson.E{Key: "path", Value: "$properties"},
+				bson.E{Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		bson.D{
+			bson.E{Key: "$sort", Value: bson.D{
+				bson.E{Key: "step", Value: 1},
+				bson.E{Key: "_id", Value: 1},
+				bson.E{Key: "properties.order", Value: 1}}}},
+		bson.D{
+			bson.E{Key: "$lookup", Value: bson.D{
+				bson.E{Key: "from", Value: config.PropertyCollection},
+				bson.E{Key: "localField", Value: "properties.property_id"},
+				bson.E{Key: "foreignField", Value: "_id"},
+				bson.E{Key: "as", Value: "properties.property"}}}},
+		bson.D{
+			bson.E{Key: "$unwind", Value: bson.D{
+				bson.E{Key: "path", Value: "$properties.property"},
+				bson.E{Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		bson.D{
+			bson.E{Key: "$group", Value: bson.D{
+				bson.E{Key: "_id", Value: "$_id"},
+				bson.E{Key: "name", Value: bson.D{
+					bson.E{Key: "$first", Value: "$name"}}},
+				bson.E{Key: "step", Value: bson.D{
+					bson.E{Key: "$first", Value: "$step"}}},
+				bson.E{Key: "type", Value: bson.D{
+					bson.E{Key: "$first", Value: "$type"}}},
+				bson.E{Key: "description", Value: bson.D{
+					bson.E{Key: "$first", Value: "$description"}}},
+				bson.E{Key: "status", Value: bson.D{
+					bson.E{Key: "$first", Value: "$status"}}},
+				bson.E{Key: "translations", Value: bson.D{
+					bson.E{Key: "$first", Value: "$translations"}}},
+				bson.E{Key: "properties", Value: bson.D{
+					bson.E{Key: "$push", Value: "$properties.property"}}}}}},
+		bson.D{
+			bson.E{Key: "$sort", Value: bson.D{
+				bson.E{Key: "step", Value: 1},
+				bson.E{Key: "_id", Value: 1}}}},
+	}
+}
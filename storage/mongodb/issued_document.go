@@ -0,0 +1,42 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type issuedDocumentRepo struct {
+	collection *mongo.Collection
+}
+
+func NewIssuedDocumentRepo(db *mongo.Database) repo.IssuedDocumentI {
+	return &issuedDocumentRepo{
+		collection: db.Collection(config.IssuedDocumentCollection),
+	}
+}
+
+// Create fails on a code that is taken already, the code is the _id
+func (ir *issuedDocumentRepo) Create(ctx context.Context, req *models.IssuedDocument) error {
+	_, err := ir.collection.InsertOne(ctx, req)
+	return err
+}
+
+func (ir *issuedDocumentRepo) Get(ctx context.Context, code string) (*models.IssuedDocument, error) {
+	var document models.IssuedDocument
+
+	if err := ir.collection.FindOne(ctx, bson.M{"_id": code}).Decode(&document); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// Delete forgets a document that could not be issued after all
+func (ir *issuedDocumentRepo) Delete(ctx context.Context, code string) error {
+	_, err := ir.collection.DeleteOne(ctx, bson.M{"_id": code})
+	return err
+}
@@ -0,0 +1,42 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type photoMetadataRepo struct {
+	collection *mongo.Collection
+}
+
+func NewPhotoMetadataRepo(db *mongo.Database) repo.PhotoMetadataI {
+	return &photoMetadataRepo{
+		collection: db.Collection(config.PhotoMetadataCollection),
+	}
+}
+
+func (pr *photoMetadataRepo) Create(ctx context.Context, req *models.PhotoMetadata) error {
+	_, err := pr.collection.InsertOne(ctx, req)
+	return err
+}
+
+func (pr *photoMetadataRepo) GetByObjectNames(ctx context.Context, objectNames []string) ([]*models.PhotoMetadata, error) {
+	var photos []*models.PhotoMetadata
+
+	rows, err := pr.collection.Find(ctx, bson.M{"_id": bson.M{"$in": objectNames}})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+	if err := rows.All(ctx, &photos); err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
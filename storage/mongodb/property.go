@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/big"
 	"time"
 
 	"github.com/e-space-uz/backend/config"
@@ -13,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type propertyRepo struct {
@@ -26,21 +26,23 @@ func NewPropertyRepo(db *mongo.Database) repo.PropertyI {
 }
 func (pr *propertyRepo) Create(ctx context.Context, property *models.CreateUpdateProperty) (string, error) {
 	createUpdateProperty := &models.CreateUpdateProperty{
-		ID:          property.ID,
-		Name:        property.Name,
-		Type:        property.Type,
-		Label:       property.Label,
-		Placeholder: property.Placeholder,
-		IsRequired:  property.IsRequired,
-		Description: property.Description,
-		Validation:  property.Validation,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:           property.ID,
+		Name:         property.Name,
+		Type:         property.Type,
+		Label:        property.Label,
+		Placeholder:  property.Placeholder,
+		IsRequired:   property.IsRequired,
+		Description:  property.Description,
+		Validation:   property.Validation,
+		Translations: property.Translations,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 	for _, option := range property.PropertyOptions {
 		createUpdateProperty.PropertyOptions = append(createUpdateProperty.PropertyOptions, &models.PropertyOption{
-			Name:  option.Name,
-			Value: option.Value,
+			Name:         option.Name,
+			Value:        option.Value,
+			Translations: option.Translations,
 		})
 	}
 
@@ -65,8 +67,8 @@ func (pr *propertyRepo) Get(ctx context.Context, id string) (*models.Property, e
 		return nil, err
 	}
 	matchPropertyID := bson.D{
-		primitive.E{Key: "$match", Value: bson.D{
-			primitive.E{Key: "_id", Value: objectID}}}}
+		primitive.E{Key: "$match", Value: append(bson.D{
+			primitive.E{Key: "_id", Value: objectID}}, notDeletedFilter()...)}}
 
 	row, err := pr.collection.Aggregate(
 		context.Background(),
@@ -106,20 +108,16 @@ func (pr *propertyRepo) GetAll(ctx context.Context, page, limit uint32, search s
 		properties  []*models.Property
 		pipeline    = mongo.Pipeline{}
 		filter      = bson.D{}
-		filterCount = bson.D{}
+		filterCount = notDeletedFilter()
 		skip        = (page - 1) * limit
 	)
-	start := time.Now()
 	if search != "" {
-		filter = bson.D{primitive.E{Key: "$match", Value: bson.D{
-			primitive.E{Key: "name", Value: bson.D{
-				primitive.E{Key: "$regex", Value: search},
-				primitive.E{Key: "$options", Value: "im"}}}}}}
-		pipeline = append(pipeline, filter)
-		filterCount = bson.D{primitive.E{Key: "name", Value: bson.D{
+		filterCount = append(filterCount, primitive.E{Key: "name", Value: bson.D{
 			primitive.E{Key: "$regex", Value: search},
-			primitive.E{Key: "$options", Value: "im"}}}}
+			primitive.E{Key: "$options", Value: "im"}}})
 	}
+	filter = bson.D{primitive.E{Key: "$match", Value: filterCount}}
+	pipeline = append(pipeline, filter)
 
 	count, err := pr.collection.CountDocuments(context.Background(), filterCount)
 
@@ -135,13 +133,12 @@ func (pr *propertyRepo) GetAll(ctx context.Context, page, limit uint32, search s
 	rows, err := pr.collection.Aggregate(
 		context.Background(),
 		pipeline)
-	defer func() {
-		_ = rows.Close(context.Background())
-	}()
-
 	if err != nil {
 		return nil, 0, err
 	}
+	defer func() {
+		_ = rows.Close(context.Background())
+	}()
 
 	if err := rows.All(context.Background(), &properties); err != nil {
 		return nil, 0, err
@@ -154,33 +151,69 @@ func (pr *propertyRepo) GetAll(ctx context.Context, page, limit uint32, search s
 	if err := json.Unmarshal(byte, &response); err != nil {
 		return nil, 0, err
 	}
-	r := new(big.Int)
-	fmt.Println(r.Binomial(1000, 10))
-
-	elapsed := time.Since(start)
-	fmt.Printf("Binomial took %s", elapsed)
 	return response, uint32(count), nil
 }
 
-func (pr *propertyRepo) Delete(id string) error {
+func (pr *propertyRepo) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
 	update := bson.M{
 		"$set": bson.M{
 			"deleted_at": time.Now(),
 		}}
 
-	filter := bson.M{"_id": bson.M{"$eq": id}}
-	_, err := pr.collection.UpdateOne(
-		context.Background(),
+	filter := bson.M{"_id": bson.M{"$eq": objectID}}
+	result, err := pr.collection.UpdateOne(
+		ctx,
 		filter,
 		update,
 	)
-
 	if err != nil {
 		return err
 	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 
 	return nil
 }
+
+// GetUsage counts the documents that still reference the property
+func (pr *propertyRepo) GetUsage(ctx context.Context, id string) (*models.PropertyUsage, error) {
+	var (
+		db       = pr.collection.Database()
+		response = &models.PropertyUsage{PropertyID: id}
+	)
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Entities, err = db.Collection(config.EntityCollection).CountDocuments(ctx, bson.M{
+		"entity_properties.property_id": objectID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response.EntityDrafts, err = db.Collection(config.EntityDraftCollection).CountDocuments(ctx, bson.M{
+		"entity_properties.property_id": objectID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	groupFilter := notDeletedFilter()
+	groupFilter = append(groupFilter, primitive.E{Key: "properties.property_id", Value: objectID})
+	response.GroupProperties, err = db.Collection(config.GroupPropertyCollection).CountDocuments(ctx, groupFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	response.InUse = response.Entities+response.EntityDrafts+response.GroupProperties > 0
+	return response, nil
+}
+
 func (pr *propertyRepo) Update(ctx context.Context, property *models.CreateUpdateProperty) error {
 	updateProperty := &models.CreateUpdateProperty{
 		ID:          property.ID,
@@ -192,28 +225,55 @@ func (pr *propertyRepo) Update(ctx context.Context, property *models.CreateUpdat
 		Description: property.Description,
 		Validation:  property.Validation,
 	}
+	// option translations are kept by value, the sent ones are merged in
+	var stored models.CreateUpdateProperty
+	err := pr.collection.FindOne(ctx, bson.M{"_id": property.ID}).Decode(&stored)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	storedTranslations := map[string]models.Translations{}
+	for _, option := range stored.PropertyOptions {
+		if option != nil {
+			storedTranslations[option.Value] = option.Translations
+		}
+	}
 	for _, option := range property.PropertyOptions {
+		translations := models.Translations{}
+		for lang, text := range storedTranslations[option.Value] {
+			translations[lang] = text
+		}
+		for lang, text := range option.Translations {
+			translations[lang] = text
+		}
+		if len(translations) == 0 {
+			translations = nil
+		}
 		updateProperty.PropertyOptions = append(updateProperty.PropertyOptions, &models.PropertyOption{
-			Name:  option.Name,
-			Value: option.Value,
+			Name:         option.Name,
+			Value:        option.Value,
+			Translations: translations,
 		})
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"name":             updateProperty.Name,
-			"type":             updateProperty.Type,
-			"label":            updateProperty.Label,
-			"placeholder":      updateProperty.Placeholder,
-			"is_required":      updateProperty.IsRequired,
-			"validation":       updateProperty.Validation,
-			"description":      updateProperty.Description,
-			"property_options": updateProperty.PropertyOptions,
-			"updated_at":       time.Now(),
-		}}
+	set := bson.M{
+		"name":             updateProperty.Name,
+		"type":             updateProperty.Type,
+		"label":            updateProperty.Label,
+		"placeholder":      updateProperty.Placeholder,
+		"is_required":      updateProperty.IsRequired,
+		"validation":       updateProperty.Validation,
+		"description":      updateProperty.Description,
+		"property_options": updateProperty.PropertyOptions,
+		"updated_at":       time.Now(),
+	}
+	// translations are managed separately, keep them unless new ones are sent
+	if property.Translations != nil {
+		set["translations"] = property.Translations
+	}
+	update := bson.M{"$set": set}
 
 	filter := bson.M{"_id": bson.M{"$eq": property.ID}}
-	_, err := pr.collection.UpdateOne(
+	_, err = pr.collection.UpdateOne(
 		context.Background(),
 		filter,
 		update,
@@ -225,3 +285,113 @@ func (pr *propertyRepo) Update(ctx context.Context, property *models.CreateUpdat
 
 	return nil
 }
+
+// UpdateTranslations merges the texts into the stored translations. Option
+// translations are matched by PropertyOption.Value.
+func (pr *propertyRepo) UpdateTranslations(ctx context.Context, id string, req *models.UpdatePropertyTranslationsSwag) error {
+	var (
+		update       = newTranslationsUpdate()
+		arrayFilters = []interface{}{}
+		i            int
+	)
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update.add("translations.label", req.Label)
+	update.add("translations.placeholder", req.Placeholder)
+	update.add("translations.description", req.Description)
+	for value, translations := range req.Options {
+		identifier := fmt.Sprintf("option%d", i)
+		update.add(fmt.Sprintf("property_options.$[%s].translations", identifier), translations)
+		arrayFilters = append(arrayFilters, bson.M{identifier + ".value": value})
+		i++
+	}
+
+	opts := options.Update()
+	if len(arrayFilters) != 0 {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+	}
+
+	filter := notDeletedFilter()
+	filter = append(filter, primitive.E{Key: "_id", Value: objectID})
+	result, err := pr.collection.UpdateOne(ctx, filter, update.document(), opts)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetMissingTranslations lists the labels, placeholders, descriptions and
+// option names that are not translated to every one of the languages
+func (pr *propertyRepo) GetMissingTranslations(ctx context.Context, languages []string, defaultLanguage string) ([]*models.MissingTranslation, error) {
+	var (
+		response   = []*models.MissingTranslation{}
+		properties []*models.CreateUpdateProperty
+	)
+
+	opts := options.Find()
+	opts.SetSort(bson.D{primitive.E{Key: "name", Value: 1}})
+
+	rows, err := pr.collection.Find(ctx, notDeletedFilter(), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+	if err := rows.All(ctx, &properties); err != nil {
+		return nil, err
+	}
+
+	for _, property := range properties {
+		translations := property.Translations
+		if translations == nil {
+			translations = &models.PropertyTranslations{}
+		}
+		fields := []struct {
+			field, text  string
+			translations models.Translations
+		}{
+			{"label", property.Label, translations.Label},
+			{"placeholder", property.Placeholder, translations.Placeholder},
+			{"description", property.Description, translations.Description},
+		}
+		for _, f := range fields {
+			if f.text == "" {
+				continue
+			}
+			if missing := f.translations.Missing(languages, defaultLanguage); len(missing) != 0 {
+				response = append(response, &models.MissingTranslation{
+					Kind:    "property",
+					ID:      property.ID.Hex(),
+					Name:    property.Name,
+					Field:   f.field,
+					Text:    f.text,
+					Missing: missing,
+				})
+			}
+		}
+		for _, option := range property.PropertyOptions {
+			if option == nil || option.Name == "" {
+				continue
+			}
+			if missing := option.Translations.Missing(languages, defaultLanguage); len(missing) != 0 {
+				response = append(response, &models.MissingTranslation{
+					Kind:    "property_option",
+					ID:      property.ID.Hex(),
+					Name:    property.Name,
+					Field:   "name",
+					Option:  option.Value,
+					Text:    option.Name,
+					Missing: missing,
+				})
+			}
+		}
+	}
+	return response, nil
+}
@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/e-space-uz/backend/config"
 	"github.com/e-space-uz/backend/models"
@@ -147,12 +148,13 @@ func (rr *regionRepo) GetAllByCity(ctx context.Context, cityID, name string) ([]
 	if err != nil {
 		return nil, 0, err
 	}
-	filter = append(filter, bson.E{Key: "city_id", Value: ID})
+	filter = append(filter, bson.E{Key: "city_id", Value: ID}, activeUnitFilter())
 
 	pipeline = append(pipeline,
 		bson.D{
 			primitive.E{Key: "$match", Value: bson.D{
-				primitive.E{Key: "city_id", Value: ID}}}},
+				primitive.E{Key: "city_id", Value: ID},
+				activeUnitFilter()}}},
 		bson.D{
 			primitive.E{Key: "$lookup", Value: bson.D{
 				primitive.E{Key: "from", Value: cityCollection},
@@ -184,3 +186,36 @@ func (rr *regionRepo) GetAllByCity(ctx context.Context, cityID, name string) ([]
 	}
 	return response, uint32(count), nil
 }
+
+func (rr *regionRepo) Migrate(ctx context.Context) error {
+	return migrateUnits(ctx, rr.collection)
+}
+
+func (rr *regionRepo) CountActiveByCity(ctx context.Context, cityID string) (int64, error) {
+	return countActiveUnits(ctx, rr.collection, "city_id", cityID)
+}
+
+func (rr *regionRepo) CountCodedOutside(ctx context.Context, cityID string, from, to uint64) (int64, error) {
+	return countCodedOutside(ctx, rr.collection, "city_id", cityID, from, to)
+}
+
+func (rr *regionRepo) Create(ctx context.Context, region *models.CreateUpdateRegion) (string, error) {
+	region.UpdatedAt = time.Now()
+	if err := insertUnit(ctx, rr.collection, region); err != nil {
+		return "", err
+	}
+	return region.ID.Hex(), nil
+}
+
+func (rr *regionRepo) Update(ctx context.Context, region *models.CreateUpdateRegion) error {
+	return updateUnit(ctx, rr.collection, region.ID, bson.M{
+		"city_id":     region.CityID,
+		"name":        region.Name,
+		"ru_name":     region.RuName,
+		"code":        region.Code,
+		"external_id": region.ExternalID,
+		"soato":       region.Soato,
+		"active":      region.Active,
+		"updated_at":  time.Now(),
+	})
+}
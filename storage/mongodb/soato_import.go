@@ -0,0 +1,261 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type soatoImportRepo struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+	// units are the collections of the levels
+	units      map[string]*mongo.Collection
+	boundaries *mongo.Collection
+}
+
+func NewSoatoImportRepo(db *mongo.Database) repo.SoatoImportI {
+	return &soatoImportRepo{
+		client:     db.Client(),
+		collection: db.Collection(config.SoatoImportCollection),
+		units: map[string]*mongo.Collection{
+			models.AdminUnitCity:     db.Collection(config.CityCollection),
+			models.AdminUnitRegion:   db.Collection(config.RegionCollection),
+			models.AdminUnitDistrict: db.Collection(config.DistrictCollection),
+		},
+		boundaries: db.Collection(config.AdminBoundaryCollection),
+	}
+}
+
+func (sr *soatoImportRepo) Create(ctx context.Context, req *models.SoatoImport) error {
+	_, err := sr.collection.InsertOne(ctx, req)
+	return err
+}
+
+func (sr *soatoImportRepo) Get(ctx context.Context, id string) (*models.SoatoImport, error) {
+	var soatoImport models.SoatoImport
+	if err := sr.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&soatoImport); err != nil {
+		return nil, err
+	}
+	return &soatoImport, nil
+}
+
+// storedUnit is a city, region or district as the collections keep it
+type storedUnit struct {
+	ID       string `bson:"_id"`
+	CityID   string `bson:"city_id"`
+	RegionID string `bson:"region_id"`
+	Name     string `bson:"name"`
+	RuName   string `bson:"ru_name"`
+	Soato    uint32 `bson:"soato"`
+	Active   *bool  `bson:"active"`
+}
+
+func (sr *soatoImportRepo) GetUnits(ctx context.Context) ([]*models.SoatoUnit, error) {
+	var (
+		units []*models.SoatoUnit
+		// codes of the cities and regions by id, for the parents
+		codes = map[string]uint32{}
+	)
+	for _, level := range []string{models.AdminUnitCity, models.AdminUnitRegion, models.AdminUnitDistrict} {
+		var stored []*storedUnit
+		rows, err := sr.units[level].Find(ctx, bson.M{}, options.Find().
+			SetSort(bson.D{primitive.E{Key: "soato", Value: 1}}).
+			SetProjection(bson.D{
+				primitive.E{Key: "_id", Value: 1},
+				primitive.E{Key: "city_id", Value: 1},
+				primitive.E{Key: "region_id", Value: 1},
+				primitive.E{Key: "name", Value: 1},
+				primitive.E{Key: "ru_name", Value: 1},
+				primitive.E{Key: "soato", Value: 1},
+				primitive.E{Key: "active", Value: 1},
+			}))
+		if err != nil {
+			return nil, err
+		}
+		err = rows.All(ctx, &stored)
+		_ = rows.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, unit := range stored {
+			parentID := unit.CityID
+			if level == models.AdminUnitDistrict {
+				parentID = unit.RegionID
+			}
+			units = append(units, &models.SoatoUnit{
+				ID:          unit.ID,
+				Level:       level,
+				Soato:       unit.Soato,
+				ParentSoato: codes[parentID],
+				Name:        unit.Name,
+				RuName:      unit.RuName,
+				// units loaded before the flag are active
+				Active: unit.Active == nil || *unit.Active,
+			})
+		}
+		for _, unit := range stored {
+			codes[unit.ID] = unit.Soato
+		}
+	}
+	return units, nil
+}
+
+func (sr *soatoImportRepo) Claim(ctx context.Context, id, staffID string) error {
+	result, err := sr.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.SoatoImportPreview},
+		bson.M{"$set": bson.M{"status": models.SoatoImportApplying, "applied_by": staffID}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	count, err := sr.collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return repo.ErrSoatoImportApplied
+}
+
+// Apply writes the changes in one transaction, a failed import leaves the
+// units as they were
+func (sr *soatoImportRepo) Apply(ctx context.Context, req *models.SoatoImport) error {
+	session, err := sr.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, sr.apply(sessionCtx, req)
+	})
+	return err
+}
+
+func (sr *soatoImportRepo) apply(ctx context.Context, req *models.SoatoImport) error {
+	now := time.Now()
+	for _, change := range req.Diff.Abolished {
+		if err := sr.updateUnit(ctx, change, bson.M{"active": false, "updated_at": now}); err != nil {
+			return err
+		}
+		// abolished units are no longer looked up by location
+		_, err := sr.boundaries.DeleteOne(ctx, bson.M{"_id": models.AdminBoundaryID(change.Level, change.UnitID)})
+		if err != nil {
+			return err
+		}
+	}
+	for _, change := range req.Diff.Restored {
+		if err := sr.updateUnit(ctx, change, bson.M{"name": change.Name, "ru_name": change.RuName, "active": true, "updated_at": now}); err != nil {
+			return err
+		}
+	}
+	for _, change := range req.Diff.Renamed {
+		if err := sr.updateUnit(ctx, change, bson.M{"name": change.Name, "ru_name": change.RuName, "updated_at": now}); err != nil {
+			return err
+		}
+	}
+	if len(req.Diff.Added) == 0 {
+		return nil
+	}
+
+	// the active units by level and code, with the code of their parent
+	var (
+		ids     = map[string]map[uint32]primitive.ObjectID{}
+		parents = map[uint32]uint32{}
+	)
+	stored, err := sr.GetUnits(ctx)
+	if err != nil {
+		return err
+	}
+	for _, unit := range stored {
+		if !unit.Active {
+			continue
+		}
+		objectID, err := primitive.ObjectIDFromHex(unit.ID)
+		if err != nil {
+			return err
+		}
+		if ids[unit.Level] == nil {
+			ids[unit.Level] = map[uint32]primitive.ObjectID{}
+		}
+		ids[unit.Level][unit.Soato] = objectID
+		parents[unit.Soato] = unit.ParentSoato
+	}
+	parentID := func(level string, soato uint32) (primitive.ObjectID, error) {
+		id, ok := ids[level][soato]
+		if !ok {
+			return id, fmt.Errorf("%s %d is not stored", level, soato)
+		}
+		return id, nil
+	}
+
+	// added units come parents first
+	for _, change := range req.Diff.Added {
+		var (
+			id   = primitive.NewObjectID()
+			unit interface{}
+		)
+		switch change.Level {
+		case models.AdminUnitCity:
+			unit = &models.CreateUpdateCity{ID: id, Name: change.Name, RuName: change.RuName, Soato: change.Soato, Active: true, UpdatedAt: now}
+		case models.AdminUnitRegion:
+			cityID, err := parentID(models.AdminUnitCity, change.ParentSoato)
+			if err != nil {
+				return err
+			}
+			unit = &models.CreateUpdateRegion{ID: id, CityID: cityID, Name: change.Name, RuName: change.RuName, Soato: change.Soato, Active: true, UpdatedAt: now}
+		default:
+			regionID, err := parentID(models.AdminUnitRegion, change.ParentSoato)
+			if err != nil {
+				return err
+			}
+			cityID, err := parentID(models.AdminUnitCity, parents[change.ParentSoato])
+			if err != nil {
+				return err
+			}
+			unit = &models.CreateUpdateDistrict{ID: id, CityID: cityID, RegionID: regionID, Name: change.Name, RuName: change.RuName, Soato: change.Soato, Active: true, UpdatedAt: now}
+		}
+		if err := insertUnit(ctx, sr.units[change.Level], unit); err != nil {
+			return fmt.Errorf("%s %d: %w", change.Level, change.Soato, err)
+		}
+		if ids[change.Level] == nil {
+			ids[change.Level] = map[uint32]primitive.ObjectID{}
+		}
+		ids[change.Level][change.Soato] = id
+		parents[change.Soato] = change.ParentSoato
+	}
+	return nil
+}
+
+func (sr *soatoImportRepo) updateUnit(ctx context.Context, change *models.SoatoChange, set bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(change.UnitID)
+	if err != nil {
+		return err
+	}
+	if err := updateUnit(ctx, sr.units[change.Level], objectID, set); err != nil {
+		return fmt.Errorf("%s %d: %w", change.Level, change.Soato, err)
+	}
+	return nil
+}
+
+func (sr *soatoImportRepo) Finish(ctx context.Context, id string, applyErr error) error {
+	set := bson.M{"status": models.SoatoImportApplied, "applied_at": time.Now()}
+	if applyErr != nil {
+		set = bson.M{"status": models.SoatoImportFailed, "error": applyErr.Error()}
+	}
+	_, err := sr.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
@@ -0,0 +1,41 @@
+package mongodb
+
+import (
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// translationsUpdate collects the $set and $unset of a translations update,
+// so a request only touches the languages it mentions
+type translationsUpdate struct {
+	set   bson.M
+	unset bson.M
+}
+
+func newTranslationsUpdate() *translationsUpdate {
+	return &translationsUpdate{
+		set:   bson.M{"updated_at": time.Now()},
+		unset: bson.M{},
+	}
+}
+
+// add sets path.<lang> for every text, an empty text unsets it
+func (tu *translationsUpdate) add(path string, translations models.Translations) {
+	for lang, text := range translations {
+		if text == "" {
+			tu.unset[path+"."+lang] = ""
+			continue
+		}
+		tu.set[path+"."+lang] = text
+	}
+}
+
+func (tu *translationsUpdate) document() bson.M {
+	update := bson.M{"$set": tu.set}
+	if len(tu.unset) != 0 {
+		update["$unset"] = tu.unset
+	}
+	return update
+}
@@ -0,0 +1,136 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/e-space-uz/backend/config"
+	"github.com/e-space-uz/backend/models"
+	"github.com/e-space-uz/backend/storage/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type uploadRepo struct {
+	collection *mongo.Collection
+}
+
+func NewUploadRepo(db *mongo.Database) repo.UploadI {
+	return &uploadRepo{
+		collection: db.Collection(config.UploadCollection),
+	}
+}
+
+func (ur *uploadRepo) Create(ctx context.Context, req *models.Upload) error {
+	_, err := ur.collection.InsertOne(ctx, req)
+	return err
+}
+
+func (ur *uploadRepo) Get(ctx context.Context, id string) (*models.Upload, error) {
+	var upload models.Upload
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ur.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (ur *uploadRepo) AppendPart(ctx context.Context, id string, part *models.UploadPart, expiresAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	// matching the offset makes concurrent PATCH requests fail instead of
+	// overlapping
+	result, err := ur.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":       objectID,
+			"offset":    part.Offset,
+			"completed": false,
+		},
+		bson.M{
+			"$push": bson.M{"parts": part},
+			"$inc":  bson.M{"offset": part.Size},
+			"$set": bson.M{
+				"updated_at": time.Now(),
+				"expires_at": expiresAt,
+			},
+		})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repo.ErrUploadOffsetConflict
+	}
+	return nil
+}
+
+func (ur *uploadRepo) Complete(ctx context.Context, id, entityFileID string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := ur.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$set": bson.M{
+			"completed":      true,
+			"entity_file_id": entityFileID,
+			"parts":          []*models.UploadPart{},
+			"updated_at":     time.Now(),
+		}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (ur *uploadRepo) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := ur.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (ur *uploadRepo) GetExpired(ctx context.Context, now time.Time, limit int64) ([]*models.Upload, error) {
+	var uploads []*models.Upload
+
+	rows, err := ur.collection.Find(
+		ctx,
+		bson.M{
+			"completed":  false,
+			"expires_at": bson.M{"$lt": now},
+		},
+		options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close(ctx)
+	}()
+	if err := rows.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
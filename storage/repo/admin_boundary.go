@@ -0,0 +1,17 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+type AdminBoundaryI interface {
+	CreateIndexes(ctx context.Context) error
+	// Upsert replaces the boundary of the unit
+	Upsert(ctx context.Context, req *models.AdminBoundary) error
+	Get(ctx context.Context, level, unitID string) (*models.AdminBoundary, error)
+	Delete(ctx context.Context, level, unitID string) error
+	// Lookup returns the boundaries of every level that contain the point
+	Lookup(ctx context.Context, point *models.GeoPoint) ([]*models.AdminBoundary, error)
+}
@@ -2,11 +2,21 @@ package repo
 
 import (
 	"context"
+	"errors"
 
 	"github.com/e-space-uz/backend/models"
 )
 
+// ErrSoatoTaken is returned when an active unit of the level has the code
+var ErrSoatoTaken = errors.New("soato is taken by an active unit")
+
 type CityI interface {
+	// Migrate marks units stored before the active flag as active and
+	// indexes the codes of active units
+	Migrate(ctx context.Context) error
 	Get(ctx context.Context, id string) (*models.City, error)
 	GetAll(ctx context.Context, page, limit uint32) ([]*models.City, uint32, error)
+	// Create and Update fail with ErrSoatoTaken
+	Create(ctx context.Context, req *models.CreateUpdateCity) (string, error)
+	Update(ctx context.Context, req *models.CreateUpdateCity) error
 }
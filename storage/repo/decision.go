@@ -0,0 +1,21 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+var (
+	ErrDecisionConflict = errors.New("another decision was taken at the same time")
+	ErrVersionConflict  = errors.New("entity has changed since it was read")
+)
+
+type DecisionI interface {
+	// Create adds the decision and runs changeStatus in one transaction, it
+	// fails with ErrDecisionConflict when the sequence is taken
+	Create(ctx context.Context, req *models.Decision, changeStatus func(ctx context.Context) error) error
+	// GetAll returns the decisions of the entity or the draft by sequence
+	GetAll(ctx context.Context, req *models.GetDecisionsRequest) ([]*models.Decision, error)
+}
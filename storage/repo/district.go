@@ -7,7 +7,17 @@ import (
 )
 
 type DistrictI interface {
+	Migrate(ctx context.Context) error
 	Get(ctx context.Context, id string) (*models.District, error)
 	GetAll(ctx context.Context, page, limit uint32) ([]*models.District, uint32, error)
+	// GetAllByCityRegion leaves out abolished districts
 	GetAllByCityRegion(ctx context.Context, regionID, cityID, name string) ([]*models.District, uint32, error)
+	CountActiveByRegion(ctx context.Context, regionID string) (int64, error)
+	// CountCodedOutside counts the districts of the region with codes
+	// outside from and to
+	CountCodedOutside(ctx context.Context, regionID string, from, to uint64) (int64, error)
+	// MoveRegion sets the city of the districts of the region
+	MoveRegion(ctx context.Context, regionID, cityID string) error
+	Create(ctx context.Context, req *models.CreateUpdateDistrict) (string, error)
+	Update(ctx context.Context, req *models.CreateUpdateDistrict) error
 }
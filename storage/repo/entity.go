@@ -7,12 +7,29 @@ import (
 )
 
 type EntityI interface {
+	CreateIndexes(ctx context.Context) error
 	// Read request
 	Get(ctx context.Context, id string) (*models.Entity, error)
 	GetAll(ctx context.Context, req *models.GetAllEntitiesRequest) ([]*models.GetAllEntities, uint64, error)
 	GetAllWithProperties(ctx context.Context, req *models.GetAllEntitiesRequest) ([]*models.GetAllEntities, error)
+	// Each reads every entity matching the request without paging, it stops
+	// at the first error of fn
+	Each(ctx context.Context, req *models.GetAllEntitiesRequest, fn func(*models.GetAllEntities) error) error
+	// GetOverlapping returns the first limit entities by id whose boundaries
+	// touch or cross the boundary, apart from the excluded one
+	GetOverlapping(ctx context.Context, boundary *models.GeoBoundary, excludeID string, limit int64) ([]*models.EntityBoundary, error)
+	GetDistrictBoundaries(ctx context.Context, districtID string) ([]*models.EntityBoundary, error)
 	// Dashboard
 	// Write request
 	Create(ctx context.Context, req *models.CreateUpdateEntity) (string, error)
 	Delete(ctx context.Context, id string) error
+	// UpdateEntityFiles adds and removes files of the entity, the others stay
+	UpdateEntityFiles(ctx context.Context, id string, add, remove []string) error
+	UpdateLocation(ctx context.Context, id string, location *models.GeoPoint) error
+	// UpdateBoundary sets the boundary with what its check found
+	UpdateBoundary(ctx context.Context, id string, boundary *models.GeoBoundary, topology *models.Topology) error
+	UpdateInspection(ctx context.Context, id string, inspection *models.EntityInspection) error
+	// UpdateStatus fails with ErrVersionConflict when the entity is not at
+	// the version anymore
+	UpdateStatus(ctx context.Context, id string, version uint64, status string) error
 }
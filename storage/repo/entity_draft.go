@@ -7,7 +7,16 @@ import (
 )
 
 type EntityDraftI interface {
+	CreateIndexes(ctx context.Context) error
 	Create(ctx context.Context, req *models.CreateEntityDraft) (string, error)
 	Get(ctx context.Context, id string) (*models.EntityDraft, error)
+	GetEntityApplicant(ctx context.Context, entityID string) (string, error)
 	GetAll(ctx context.Context, req *models.GetAllEntityDraftsRequest) ([]*models.GetAllEntityDrafts, uint64, error)
+	// UpdateStep, Submit and SetStatus fail with ErrVersionConflict when the
+	// draft is not at the version anymore
+	UpdateStep(ctx context.Context, req *models.UpdateEntityDraftStep) error
+	Submit(ctx context.Context, id string, version uint64, completedSteps []uint32) error
+	SetStatus(ctx context.Context, id string, version uint64, from, to string) error
+	UpdateLocation(ctx context.Context, id string, location *models.GeoPoint) error
+	UpdateBoundary(ctx context.Context, id string, boundary *models.GeoBoundary, topology *models.Topology) error
 }
@@ -2,14 +2,23 @@ package repo
 
 import (
 	"context"
+	"errors"
 
 	"github.com/e-space-uz/backend/models"
 )
 
+var ErrFileAlreadyAttached = errors.New("file is already attached")
+
 type EntityFilesI interface {
+	CreateIndexes(ctx context.Context) error
 	Create(ctx context.Context, req *models.CreateEntityFiles) (string, error)
 	Get(ctx context.Context, id string) (*models.EntityFiles, error)
+	GetByObjectName(ctx context.Context, objectName string) (*models.EntityFiles, error)
 	GetAll(ctx context.Context, page, limit uint32, search string) ([]*models.EntityFiles, uint32, error)
 	Update(ctx context.Context, req *models.EntityFiles) error
+	SetSignature(ctx context.Context, id string, signature *models.FileSignature) error
 	Delete(ctx context.Context, id string) error
+	Attach(ctx context.Context, req *models.AttachEntityFile) (*models.EntityFiles, error)
+	Detach(ctx context.Context, req *models.AttachEntityFile) error
+	GetDocuments(ctx context.Context, req *models.GetEntityDocumentsRequest) ([]*models.EntityFiles, error)
 }
@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+type FileGCI interface {
+	// ReferencedObjects returns the file store names used by entities,
+	// drafts and registered files
+	ReferencedObjects(ctx context.Context) (map[string]bool, error)
+	CreateRun(ctx context.Context, req *models.FileGCRun) error
+}
@@ -0,0 +1,16 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+type FormSchemaVersionI interface {
+	CreateIndexes(ctx context.Context) error
+	Create(ctx context.Context, req *models.CreateFormSchemaVersion) (string, error)
+	Get(ctx context.Context, id string) (*models.FormSchemaVersion, error)
+	GetByVersion(ctx context.Context, typeOf, step, version uint32) (*models.FormSchemaVersion, error)
+	GetAll(ctx context.Context, req *models.GetAllFormSchemaVersionsRequest) ([]*models.FormSchemaVersion, uint32, error)
+	GetLatestVersions(ctx context.Context, typeOf uint32) ([]*models.StepVersion, error)
+}
@@ -2,15 +2,29 @@ package repo
 
 import (
 	"context"
+	"errors"
 
 	"github.com/e-space-uz/backend/models"
 )
 
+var (
+	ErrPropertyAlreadyInGroup = errors.New("property already exists in group property")
+	ErrPropertyNotInGroup     = errors.New("property does not exist in group property")
+	ErrPropertiesMismatch     = errors.New("properties must contain every property of the group exactly once")
+)
+
 type GroupPropertyI interface {
 	Create(ctx context.Context, req *models.CreateGroupProperty) (string, error)
 	Get(ctx context.Context, id string) (*models.GroupProperty, error)
 	GetAll(ctx context.Context, page, limit uint32) ([]*models.GetAllGroupProperty, uint32, error)
 	Update(ctx context.Context, req *models.CreateGroupProperty) error
 	Delete(ctx context.Context, id string) error
-	GetAllByType(ctx context.Context, typeOf, step uint32) ([]*models.GroupProperty, uint32, error)
+	GetAllByType(ctx context.Context, typeOf uint32, step *uint32) ([]*models.GroupProperty, uint32, error)
+	UpdateStatus(ctx context.Context, id string, status bool) error
+	AddProperty(ctx context.Context, id string, property *models.CreateProperties) error
+	RemoveProperty(ctx context.Context, id, propertyID string) error
+	ReorderProperties(ctx context.Context, id string, properties []*models.CreateProperties) error
+	Clone(ctx context.Context, id, name string) (string, error)
+	UpdateTranslations(ctx context.Context, id string, req *models.UpdateGroupPropertyTranslationsSwag) error
+	GetMissingTranslations(ctx context.Context, languages []string, defaultLanguage string) ([]*models.MissingTranslation, error)
 }
@@ -0,0 +1,13 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+type IssuedDocumentI interface {
+	Create(ctx context.Context, req *models.IssuedDocument) error
+	Get(ctx context.Context, code string) (*models.IssuedDocument, error)
+	Delete(ctx context.Context, code string) error
+}
@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+type PhotoMetadataI interface {
+	Create(ctx context.Context, req *models.PhotoMetadata) error
+	GetByObjectNames(ctx context.Context, objectNames []string) ([]*models.PhotoMetadata, error)
+}
@@ -11,4 +11,8 @@ type PropertyI interface {
 	Get(ctx context.Context, id string) (*models.Property, error)
 	GetAll(ctx context.Context, page, limit uint32, name string) ([]*models.Property, uint32, error)
 	Update(ctx context.Context, req *models.CreateUpdateProperty) error
+	Delete(ctx context.Context, id string) error
+	GetUsage(ctx context.Context, id string) (*models.PropertyUsage, error)
+	UpdateTranslations(ctx context.Context, id string, req *models.UpdatePropertyTranslationsSwag) error
+	GetMissingTranslations(ctx context.Context, languages []string, defaultLanguage string) ([]*models.MissingTranslation, error)
 }
@@ -7,7 +7,15 @@ import (
 )
 
 type RegionI interface {
+	Migrate(ctx context.Context) error
 	Get(ctx context.Context, id string) (*models.Region, error)
 	GetAll(ctx context.Context, page, limit uint32) ([]*models.Region, uint32, error)
+	// GetAllByCity leaves out abolished regions
 	GetAllByCity(ctx context.Context, cityID, name string) ([]*models.Region, uint32, error)
+	CountActiveByCity(ctx context.Context, cityID string) (int64, error)
+	// CountCodedOutside counts the regions of the city with codes outside
+	// from and to
+	CountCodedOutside(ctx context.Context, cityID string, from, to uint64) (int64, error)
+	Create(ctx context.Context, req *models.CreateUpdateRegion) (string, error)
+	Update(ctx context.Context, req *models.CreateUpdateRegion) error
 }
@@ -0,0 +1,26 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+var ErrSoatoImportApplied = errors.New("import is applied already")
+
+type SoatoImportI interface {
+	Create(ctx context.Context, req *models.SoatoImport) error
+	Get(ctx context.Context, id string) (*models.SoatoImport, error)
+	// GetUnits returns every stored city, region and district with the
+	// abolished ones
+	GetUnits(ctx context.Context) ([]*models.SoatoUnit, error)
+	// Claim moves a previewed import to applying, it fails with
+	// ErrSoatoImportApplied for any other status
+	Claim(ctx context.Context, id, staffID string) error
+	// Apply writes the diff of the import to the units all at once or not
+	// at all, parents of added units are found by their codes
+	Apply(ctx context.Context, req *models.SoatoImport) error
+	// Finish records whether applying succeeded
+	Finish(ctx context.Context, id string, applyErr error) error
+}
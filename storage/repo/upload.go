@@ -0,0 +1,23 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/e-space-uz/backend/models"
+)
+
+// ErrUploadOffsetConflict means another request moved the upload offset
+var ErrUploadOffsetConflict = errors.New("upload offset has changed")
+
+type UploadI interface {
+	Create(ctx context.Context, req *models.Upload) error
+	Get(ctx context.Context, id string) (*models.Upload, error)
+	// AppendPart adds a chunk that starts at the current offset of the upload
+	AppendPart(ctx context.Context, id string, part *models.UploadPart, expiresAt time.Time) error
+	Complete(ctx context.Context, id, entityFileID string) error
+	Delete(ctx context.Context, id string) error
+	// GetExpired returns unfinished uploads that expired before now
+	GetExpired(ctx context.Context, now time.Time, limit int64) ([]*models.Upload, error)
+}